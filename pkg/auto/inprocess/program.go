@@ -0,0 +1,122 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inprocess
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/rpcutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// programServer is a language runtime that runs an inline program in this process. The engine connects to it as it
+// would to the language runtime of the CLI's `--client` flag.
+type programServer struct {
+	fn      pulumi.RunFunc
+	address string
+
+	running sync.WaitGroup
+	cancel  chan bool
+	done    chan error
+}
+
+func startProgramServer(fn pulumi.RunFunc) (*programServer, error) {
+	s := &programServer{
+		fn:     fn,
+		cancel: make(chan bool),
+	}
+	port, done, err := rpcutil.Serve(0, s.cancel, []func(*grpc.Server) error{
+		func(srv *grpc.Server) error {
+			pulumirpc.RegisterLanguageRuntimeServer(srv, s)
+			return nil
+		},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting the language runtime for the program: %w", err)
+	}
+	s.address, s.done = fmt.Sprintf("127.0.0.1:%d", port), done
+	return s, nil
+}
+
+// Close waits for the program to finish running, if it is, and stops the server.
+func (s *programServer) Close() error {
+	s.running.Wait()
+	s.cancel <- true
+	close(s.cancel)
+	return <-s.done
+}
+
+func (s *programServer) GetRequiredPlugins(ctx context.Context,
+	req *pulumirpc.GetRequiredPluginsRequest) (*pulumirpc.GetRequiredPluginsResponse, error) {
+	return &pulumirpc.GetRequiredPluginsResponse{}, nil
+}
+
+func (s *programServer) Run(ctx context.Context, req *pulumirpc.RunRequest) (*pulumirpc.RunResponse, error) {
+	s.running.Add(1)
+	defer s.running.Done()
+
+	var engineAddress string
+	if len(req.Args) > 0 {
+		engineAddress = req.Args[0]
+	}
+	pulumiCtx, err := pulumi.NewContext(ctx, pulumi.RunInfo{
+		EngineAddr:       engineAddress,
+		MonitorAddr:      req.GetMonitorAddress(),
+		Config:           req.GetConfig(),
+		ConfigSecretKeys: req.GetConfigSecretKeys(),
+		Project:          req.GetProject(),
+		Stack:            req.GetStack(),
+		Parallel:         int(req.GetParallel()),
+		DryRun:           req.GetDryRun(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				if pErr, ok := r.(error); ok {
+					err = fmt.Errorf("go inline source runtime error, an unhandled error occurred: %w", pErr)
+				} else {
+					err = fmt.Errorf("go inline source runtime error, an unhandled error occurred: %v", r)
+				}
+			}
+		}()
+		return pulumi.RunWithContext(pulumiCtx, s.fn)
+	}()
+	if err != nil {
+		return &pulumirpc.RunResponse{Error: err.Error()}, nil
+	}
+	return &pulumirpc.RunResponse{}, nil
+}
+
+func (s *programServer) GetPluginInfo(ctx context.Context, req *pbempty.Empty) (*pulumirpc.PluginInfo, error) {
+	return &pulumirpc.PluginInfo{
+		Version: "1.0.0",
+	}, nil
+}
+
+func (s *programServer) InstallDependencies(
+	req *pulumirpc.InstallDependenciesRequest,
+	server pulumirpc.LanguageRuntime_InstallDependenciesServer) error {
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inprocess
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/httpstate"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/age"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/cloud"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/passphrase"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/service"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+// secretsManager returns the secrets manager of the stack with the given name.
+func (w *Workspace) secretsManager(ctx context.Context, stackName string) (secrets.Manager, error) {
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	ps, path, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return nil, err
+	}
+	sm, err := newSecretsManager(s, ps, path, "" /*secretsProvider*/, false /*rotate*/)
+	if err != nil {
+		return nil, fmt.Errorf("getting secrets manager: %w", err)
+	}
+	return sm, nil
+}
+
+func (w *Workspace) decrypter(ctx context.Context, stackName string) (config.Decrypter, error) {
+	sm, err := w.secretsManager(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	return sm.Decrypter()
+}
+
+func (w *Workspace) encrypter(ctx context.Context, stackName string) (config.Encrypter, error) {
+	sm, err := w.secretsManager(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	return sm.Encrypter()
+}
+
+// newSecretsManager returns the secrets manager of a stack, as the CLI does. If a secrets provider is given, the
+// stack's settings are configured to use it; otherwise, the provider that they name is used, or the default provider
+// of the stack's backend if they don't name one. The settings are saved to the given path if they change.
+func newSecretsManager(s backend.Stack, ps *workspace.ProjectStack, path string, secretsProvider string,
+	rotate bool) (secrets.Manager, error) {
+	sm, err := func() (secrets.Manager, error) {
+		switch {
+		case secretsProvider == "" || secretsProvider == "default":
+			switch {
+			case ps.SecretsProvider == age.Type:
+				return newAgeSecretsManager(ps, path, ps.SecretsProvider)
+			case ps.SecretsProvider != "" && ps.SecretsProvider != passphrase.Type && ps.SecretsProvider != "default":
+				return newCloudSecretsManager(ps, path, ps.SecretsProvider)
+			case ps.EncryptionSalt != "":
+				return newPassphraseSecretsManager(ps, path, false /*rotate*/)
+			}
+			if hs, ok := s.(httpstate.Stack); ok {
+				return newServiceSecretsManager(hs, ps, path)
+			}
+			return newPassphraseSecretsManager(ps, path, false /*rotate*/)
+		case secretsProvider == passphrase.Type:
+			return newPassphraseSecretsManager(ps, path, rotate)
+		case age.IsSecretsProvider(secretsProvider):
+			return newAgeSecretsManager(ps, path, secretsProvider)
+		default:
+			return newCloudSecretsManager(ps, path, secretsProvider)
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	return stack.NewCachingSecretsManager(sm), nil
+}
+
+func newPassphraseSecretsManager(ps *workspace.ProjectStack, path string, rotate bool) (secrets.Manager, error) {
+	if rotate {
+		ps.EncryptionSalt = ""
+	}
+	// The passphrase provider only uses the encryption salt.
+	ps.EncryptedKey, ps.SecretsProvider, ps.SecretsRecipients = "", "", nil
+
+	if ps.EncryptionSalt != "" {
+		return passphrase.NewPromptingPassphraseSecretsManager(ps.EncryptionSalt)
+	}
+	salt, sm, err := passphrase.PromptForNewPassphrase(rotate)
+	if err != nil {
+		return nil, err
+	}
+	ps.EncryptionSalt = salt
+	if err = ps.Save(path); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+func newCloudSecretsManager(ps *workspace.ProjectStack, path, secretsProvider string) (secrets.Manager, error) {
+	// Only passphrase providers have an encryption salt, and only age providers have recipients.
+	ps.EncryptionSalt, ps.SecretsRecipients = "", nil
+
+	// Generate a new data key if there is none, or if the secrets provider is changing.
+	if ps.EncryptedKey == "" || ps.SecretsProvider != secretsProvider {
+		dataKey, err := cloud.GenerateNewDataKey(secretsProvider)
+		if err != nil {
+			return nil, err
+		}
+		ps.EncryptedKey = base64.StdEncoding.EncodeToString(dataKey)
+	}
+	ps.SecretsProvider = secretsProvider
+	if err := ps.Save(path); err != nil {
+		return nil, err
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(ps.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	return cloud.NewCloudSecretsManager(secretsProvider, dataKey)
+}
+
+func newAgeSecretsManager(ps *workspace.ProjectStack, path, secretsProvider string) (secrets.Manager, error) {
+	// Only passphrase providers have an encryption salt.
+	ps.EncryptionSalt = ""
+
+	// Generate a new data key if there is none, or if the secrets provider is changing or names new recipients.
+	if ps.SecretsProvider != age.Type || ps.EncryptedKey == "" || secretsProvider != age.Type {
+		recipients, err := age.ParseSecretsProvider(secretsProvider)
+		if err != nil {
+			return nil, err
+		}
+		dataKey, err := age.GenerateNewDataKey(recipients)
+		if err != nil {
+			return nil, err
+		}
+		ps.SecretsRecipients = recipients
+		ps.EncryptedKey = base64.StdEncoding.EncodeToString(dataKey)
+	}
+	ps.SecretsProvider = age.Type
+	if err := ps.Save(path); err != nil {
+		return nil, err
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(ps.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	return age.NewAgeSecretsManager(ps.SecretsRecipients, dataKey)
+}
+
+func newServiceSecretsManager(s httpstate.Stack, ps *workspace.ProjectStack, path string) (secrets.Manager, error) {
+	// The settings of stacks that use the service's secrets provider don't name a provider, so remove any remnants
+	// of the previous one.
+	if ps.SecretsProvider != "" || ps.EncryptedKey != "" || ps.EncryptionSalt != "" || len(ps.SecretsRecipients) != 0 {
+		ps.SecretsProvider, ps.EncryptedKey, ps.EncryptionSalt, ps.SecretsRecipients = "", "", "", nil
+		if err := ps.Save(path); err != nil {
+			return nil, err
+		}
+	}
+	client := s.Backend().(httpstate.Backend).Client()
+	return service.NewServiceSecretsManager(client, s.StackIdentifier())
+}
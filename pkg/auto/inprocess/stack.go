@@ -0,0 +1,772 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inprocess
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/pkg/v3/util/cancel"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/opthistory"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/constant"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	sdkDisplay "github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+// Stack is a stack of a Workspace. Its operations run the Pulumi engine in the current process.
+type Stack struct {
+	name      string
+	workspace *Workspace
+}
+
+// NewStack creates a new stack using the given workspace, and stack name.
+// It fails if a stack with that name already exists.
+func NewStack(ctx context.Context, stackName string, w *Workspace) (Stack, error) {
+	if err := w.CreateStack(ctx, stackName); err != nil {
+		return Stack{}, err
+	}
+	return Stack{name: stackName, workspace: w}, nil
+}
+
+// SelectStack selects stack using the given workspace, and stack name.
+// It returns an error if the given Stack does not exist.
+func SelectStack(ctx context.Context, stackName string, w *Workspace) (Stack, error) {
+	if err := w.SelectStack(ctx, stackName); err != nil {
+		return Stack{}, err
+	}
+	return Stack{name: stackName, workspace: w}, nil
+}
+
+// UpsertStack tries to select a stack using the given workspace and stack name, or falls back to trying to create
+// the stack if it does not exist.
+func UpsertStack(ctx context.Context, stackName string, w *Workspace) (Stack, error) {
+	s, err := NewStack(ctx, stackName, w)
+	var exists *backend.StackAlreadyExistsError
+	if errors.As(err, &exists) {
+		return SelectStack(ctx, stackName, w)
+	}
+	return s, err
+}
+
+// Name returns the stack name.
+func (s Stack) Name() string {
+	return s.name
+}
+
+// Workspace returns the underlying Workspace backing the Stack.
+func (s Stack) Workspace() *Workspace {
+	return s.workspace
+}
+
+// Preview previews the changes that an update of the stack would make, by running the program in the Workspace.
+// If events is not nil, the engine events of the preview are sent to it, and it is closed once the preview finishes
+// or fails. The preview waits for each event to be received. Debug logging options are ignored.
+func (s Stack) Preview(ctx context.Context, events chan<- engine.Event,
+	opts ...optpreview.Option) (auto.PreviewResult, error) {
+	var res auto.PreviewResult
+
+	preOpts := &optpreview.Options{}
+	for _, o := range opts {
+		o.ApplyOption(preOpts)
+	}
+
+	op := operation{
+		kind:              apitype.PreviewUpdate,
+		message:           preOpts.Message,
+		color:             preOpts.Color,
+		userAgent:         preOpts.UserAgent,
+		diff:              preOpts.Diff,
+		progressStreams:   preOpts.ProgressStreams,
+		eventStreams:      preOpts.EventStreams,
+		events:            events,
+		policyPacks:       preOpts.PolicyPacks,
+		policyPackConfigs: preOpts.PolicyPackConfigs,
+		collectEvents:     true,
+		engine: engine.UpdateOptions{
+			Parallel:          preOpts.Parallel,
+			ReplaceTargets:    urns(preOpts.Replace),
+			UpdateTargets:     urns(preOpts.Target),
+			TargetDependents:  preOpts.TargetDependents,
+			ExcludeTargets:    urns(preOpts.Exclude),
+			ExcludeDependents: preOpts.ExcludeDependents,
+			ExperimentalPlans: preOpts.Plan != "",
+		},
+	}
+	out, err := s.run(ctx, op, func(ctx context.Context, bs backend.Stack, op backend.UpdateOperation,
+	) (sdkDisplay.ResourceChanges, result.Result) {
+		p, changes, res := bs.Preview(ctx, op)
+		if res == nil && preOpts.Plan != "" {
+			enc, err := op.SecretsManager.Encrypter()
+			if err != nil {
+				return nil, result.FromError(err)
+			}
+			if err = writePlan(preOpts.Plan, p, enc); err != nil {
+				return nil, result.FromError(fmt.Errorf("writing plan: %w", err))
+			}
+		}
+		return changes, res
+	})
+	res.StdOut, res.StdErr = out.stdout, out.stderr
+	if err != nil {
+		return res, fmt.Errorf("failed to run preview: %w", err)
+	}
+	if preOpts.ExpectNoChanges && engine.HasChanges(out.changes) {
+		return res, errors.New("failed to run preview: no changes were expected but changes were proposed")
+	}
+	res.ChangeSummary = make(map[apitype.OpType]int)
+	for op, count := range out.changes {
+		res.ChangeSummary[apitype.OpType(op)] = count
+	}
+	res.Steps, res.PolicyViolations = auto.PreviewSteps(out.events)
+	if preOpts.Plan != "" {
+		if res.Plan, err = os.ReadFile(preOpts.Plan); err != nil {
+			return res, fmt.Errorf("failed to read the saved plan: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// Up creates or updates the resources in a stack by running the program in the Workspace.
+// If events is not nil, the engine events of the update are sent to it, and it is closed once the update finishes
+// or fails. The update waits for each event to be received. Debug logging options are ignored.
+func (s Stack) Up(ctx context.Context, events chan<- engine.Event, opts ...optup.Option) (auto.UpResult, error) {
+	var res auto.UpResult
+
+	upOpts := &optup.Options{}
+	for _, o := range opts {
+		o.ApplyOption(upOpts)
+	}
+
+	op := operation{
+		kind:              apitype.UpdateUpdate,
+		message:           upOpts.Message,
+		color:             upOpts.Color,
+		userAgent:         upOpts.UserAgent,
+		diff:              upOpts.Diff,
+		progressStreams:   upOpts.ProgressStreams,
+		eventStreams:      upOpts.EventStreams,
+		events:            events,
+		policyPacks:       upOpts.PolicyPacks,
+		policyPackConfigs: upOpts.PolicyPackConfigs,
+		plan:              upOpts.Plan,
+		engine: engine.UpdateOptions{
+			Parallel:          upOpts.Parallel,
+			ReplaceTargets:    urns(upOpts.Replace),
+			UpdateTargets:     urns(upOpts.Target),
+			TargetDependents:  upOpts.TargetDependents,
+			ExcludeTargets:    urns(upOpts.Exclude),
+			ExcludeDependents: upOpts.ExcludeDependents,
+			ContinueOnError:   upOpts.ContinueOnError,
+			ExperimentalPlans: upOpts.Plan != "",
+		},
+	}
+	out, err := s.run(ctx, op, func(ctx context.Context, bs backend.Stack, op backend.UpdateOperation,
+	) (sdkDisplay.ResourceChanges, result.Result) {
+		return bs.Update(ctx, op)
+	})
+	res.StdOut, res.StdErr = out.stdout, out.stderr
+	if err != nil {
+		return res, fmt.Errorf("failed to run update: %w", err)
+	}
+	if upOpts.ExpectNoChanges && engine.HasChanges(out.changes) {
+		return res, errors.New("failed to run update: no changes were expected but changes occurred")
+	}
+
+	if res.Outputs, err = s.Outputs(ctx); err != nil {
+		return res, err
+	}
+	if res.Summary, err = s.lastUpdate(ctx, upOpts.ShowSecrets); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// Refresh compares the current stack's resource state with the state known to exist in the actual
+// cloud provider. Any such changes are adopted into the current stack.
+// If events is not nil, the engine events of the refresh are sent to it, and it is closed once the refresh finishes
+// or fails. The refresh waits for each event to be received. Debug logging options are ignored.
+func (s Stack) Refresh(ctx context.Context, events chan<- engine.Event,
+	opts ...optrefresh.Option) (auto.RefreshResult, error) {
+	var res auto.RefreshResult
+
+	refreshOpts := &optrefresh.Options{}
+	for _, o := range opts {
+		o.ApplyOption(refreshOpts)
+	}
+
+	op := operation{
+		kind:            apitype.RefreshUpdate,
+		message:         refreshOpts.Message,
+		color:           refreshOpts.Color,
+		userAgent:       refreshOpts.UserAgent,
+		progressStreams: refreshOpts.ProgressStreams,
+		eventStreams:    refreshOpts.EventStreams,
+		events:          events,
+		engine: engine.UpdateOptions{
+			Parallel:          refreshOpts.Parallel,
+			RefreshTargets:    urns(refreshOpts.Target),
+			ExcludeTargets:    urns(refreshOpts.Exclude),
+			ExcludeDependents: refreshOpts.ExcludeDependents,
+		},
+	}
+	out, err := s.run(ctx, op, func(ctx context.Context, bs backend.Stack, op backend.UpdateOperation,
+	) (sdkDisplay.ResourceChanges, result.Result) {
+		return bs.Refresh(ctx, op)
+	})
+	res.StdOut, res.StdErr = out.stdout, out.stderr
+	if err != nil {
+		return res, fmt.Errorf("failed to refresh stack: %w", err)
+	}
+	if refreshOpts.ExpectNoChanges && engine.HasChanges(out.changes) {
+		return res, errors.New("failed to refresh stack: no changes were expected but changes occurred")
+	}
+
+	if res.Summary, err = s.lastUpdate(ctx, refreshOpts.ShowSecrets); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// Destroy deletes all resources in a stack, leaving all history and configuration intact.
+// If events is not nil, the engine events of the destroy are sent to it, and it is closed once the destroy finishes
+// or fails. The destroy waits for each event to be received. Debug logging options are ignored.
+func (s Stack) Destroy(ctx context.Context, events chan<- engine.Event,
+	opts ...optdestroy.Option) (auto.DestroyResult, error) {
+	var res auto.DestroyResult
+
+	destroyOpts := &optdestroy.Options{}
+	for _, o := range opts {
+		o.ApplyOption(destroyOpts)
+	}
+
+	op := operation{
+		kind:            apitype.DestroyUpdate,
+		message:         destroyOpts.Message,
+		color:           destroyOpts.Color,
+		userAgent:       destroyOpts.UserAgent,
+		progressStreams: destroyOpts.ProgressStreams,
+		eventStreams:    destroyOpts.EventStreams,
+		events:          events,
+		engine: engine.UpdateOptions{
+			Parallel:          destroyOpts.Parallel,
+			DestroyTargets:    urns(destroyOpts.Target),
+			TargetDependents:  destroyOpts.TargetDependents,
+			ExcludeTargets:    urns(destroyOpts.Exclude),
+			ExcludeDependents: destroyOpts.ExcludeDependents,
+		},
+	}
+	out, err := s.run(ctx, op, func(ctx context.Context, bs backend.Stack, op backend.UpdateOperation,
+	) (sdkDisplay.ResourceChanges, result.Result) {
+		return bs.Destroy(ctx, op)
+	})
+	res.StdOut, res.StdErr = out.stdout, out.stderr
+	if err != nil {
+		return res, fmt.Errorf("failed to destroy stack: %w", err)
+	}
+
+	if res.Summary, err = s.lastUpdate(ctx, destroyOpts.ShowSecrets); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// Outputs get the current set of Stack outputs from the last Stack.Up().
+func (s Stack) Outputs(ctx context.Context) (auto.OutputMap, error) {
+	return s.workspace.StackOutputs(ctx, s.name)
+}
+
+// History returns a list summarizing all previous and current results from Stack lifecycle operations
+// (up/preview/refresh/destroy). Secret configuration values are shown unless the ShowSecrets option is false.
+func (s Stack) History(ctx context.Context,
+	pageSize int, page int, opts ...opthistory.Option) ([]auto.UpdateSummary, error) {
+	var options opthistory.Options
+	for _, opt := range opts {
+		opt.ApplyOption(&options)
+	}
+	showSecrets := options.ShowSecrets == nil || *options.ShowSecrets
+	if pageSize > 0 && page < 1 {
+		// default page=1 if unset when pageSize is set
+		page = 1
+	}
+
+	bs, err := s.workspace.getStack(ctx, s.name)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := bs.Backend().GetHistory(ctx, bs.Ref(), pageSize, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack history: %w", err)
+	}
+
+	var dec config.Decrypter
+	if showSecrets {
+		if dec, err = s.workspace.decrypter(ctx, s.name); err != nil {
+			return nil, err
+		}
+	}
+	history := make([]auto.UpdateSummary, len(updates))
+	for i, update := range updates {
+		summary := auto.UpdateSummary{
+			Version:     update.Version,
+			Kind:        string(update.Kind),
+			StartTime:   time.Unix(update.StartTime, 0).UTC().Format(timeFormat),
+			Message:     update.Message,
+			Environment: update.Environment,
+			Config:      make(auto.ConfigMap),
+			Result:      string(update.Result),
+		}
+		for k, v := range update.Config {
+			value := auto.ConfigValue{Secret: v.Secure()}
+			if !v.Secure() || dec != nil {
+				if value.Value, err = v.Value(dec); err != nil {
+					// As the CLI does, mark values that can't be decrypted rather than failing.
+					value.Value = "ERROR_UNABLE_TO_DECRYPT"
+				}
+			}
+			summary.Config[k.String()] = value
+		}
+		if update.Result != backend.InProgressResult {
+			endTime := time.Unix(update.EndTime, 0).UTC().Format(timeFormat)
+			resourceChanges := make(map[string]int)
+			for op, count := range update.ResourceChanges {
+				resourceChanges[string(op)] = count
+			}
+			summary.EndTime, summary.ResourceChanges = &endTime, &resourceChanges
+		}
+		history[i] = summary
+	}
+	return history, nil
+}
+
+// lastUpdate returns the summary of the stack's last update.
+func (s Stack) lastUpdate(ctx context.Context, showSecrets *bool) (auto.UpdateSummary, error) {
+	var opts []opthistory.Option
+	if showSecrets != nil {
+		opts = append(opts, opthistory.ShowSecrets(*showSecrets))
+	}
+	history, err := s.History(ctx, 1 /*pageSize*/, 1 /*page*/, opts...)
+	if err != nil || len(history) == 0 {
+		return auto.UpdateSummary{}, err
+	}
+	return history[0], nil
+}
+
+// Export exports the deployment state of the stack.
+// This can be combined with Stack.Import to edit a stack's state (such as recovery from failed deployments).
+func (s Stack) Export(ctx context.Context) (apitype.UntypedDeployment, error) {
+	return s.workspace.ExportStack(ctx, s.name)
+}
+
+// Import imports the specified deployment state into the stack.
+// This can be combined with Stack.Export to edit a stack's state (such as recovery from failed deployments).
+func (s Stack) Import(ctx context.Context, state apitype.UntypedDeployment) error {
+	return s.workspace.ImportStack(ctx, s.name, state)
+}
+
+// Cancel stops a stack's currently running update. Operations run by this process are cancelled by cancelling their
+// context instead; Cancel cancels updates run by other processes, and is only supported by the Pulumi Service
+// backend.
+func (s Stack) Cancel(ctx context.Context) error {
+	bs, err := s.workspace.getStack(ctx, s.name)
+	if err != nil {
+		return err
+	}
+	if err = bs.Backend().CancelCurrentUpdate(ctx, bs.Ref()); err != nil {
+		return fmt.Errorf("failed to cancel update: %w", err)
+	}
+	return nil
+}
+
+// GetConfig returns the config value associated with the specified key.
+func (s Stack) GetConfig(ctx context.Context, key string) (auto.ConfigValue, error) {
+	return s.workspace.GetConfig(ctx, s.name, key)
+}
+
+// GetAllConfig returns the full config map.
+func (s Stack) GetAllConfig(ctx context.Context) (auto.ConfigMap, error) {
+	return s.workspace.GetAllConfig(ctx, s.name)
+}
+
+// SetConfig sets the specified config key-value pair.
+func (s Stack) SetConfig(ctx context.Context, key string, val auto.ConfigValue) error {
+	return s.workspace.SetConfig(ctx, s.name, key, val)
+}
+
+// SetAllConfig sets all values in the provided config map.
+func (s Stack) SetAllConfig(ctx context.Context, config auto.ConfigMap) error {
+	return s.workspace.SetAllConfig(ctx, s.name, config)
+}
+
+// RemoveConfig removes the provided config key-value pair.
+func (s Stack) RemoveConfig(ctx context.Context, key string) error {
+	return s.workspace.RemoveConfig(ctx, s.name, key)
+}
+
+// RemoveAllConfig removes all values in the provided list of keys.
+func (s Stack) RemoveAllConfig(ctx context.Context, keys []string) error {
+	return s.workspace.RemoveAllConfig(ctx, s.name, keys)
+}
+
+// RefreshConfig gets and sets the config map used with the last Update.
+func (s Stack) RefreshConfig(ctx context.Context) (auto.ConfigMap, error) {
+	return s.workspace.RefreshConfig(ctx, s.name)
+}
+
+// loadStackConfig loads the config of a stack, layered from its stack settings file at path and the files that it
+// extends. The secure values from the files that it extends are reencrypted by the stack's secrets manager.
+func loadStackConfig(path string, sm secrets.Manager) (config.Map, error) {
+	layers, err := workspace.LoadProjectStackLayers(path)
+	if err != nil {
+		return nil, err
+	}
+	decrypters := make(map[string]config.Decrypter)
+	cfg, _, err := workspace.MergeConfigLayers(layers,
+		func(layer workspace.ProjectStackLayer, v config.Value) (config.Value, error) {
+			dec, ok := decrypters[layer.Path]
+			if !ok {
+				var err error
+				if dec, err = stack.NewConfigLayerDecrypter(layer); err != nil {
+					return config.Value{}, err
+				}
+				decrypters[layer.Path] = dec
+			}
+			enc, err := sm.Encrypter()
+			if err != nil {
+				return config.Value{}, err
+			}
+			return v.Copy(dec, enc)
+		})
+	return cfg, err
+}
+
+// operation describes an operation to run on a stack.
+type operation struct {
+	kind              apitype.UpdateKind
+	message           string
+	color             string
+	userAgent         string
+	diff              bool
+	progressStreams   []io.Writer
+	eventStreams      []chan<- events.EngineEvent
+	events            chan<- engine.Event
+	policyPacks       []string
+	policyPackConfigs []string
+	// plan is the path of the plan that constrains an update.
+	plan string
+	// collectEvents is set to return the operation's engine events in its output.
+	collectEvents bool
+	engine        engine.UpdateOptions
+}
+
+// operationOutput is what an operation displayed, and the changes that it made or proposed.
+type operationOutput struct {
+	stdout  string
+	stderr  string
+	changes sdkDisplay.ResourceChanges
+	// events are the engine events of the operation, if the operation collected them.
+	events []apitype.EngineEvent
+}
+
+// run runs an operation on the stack by calling the given function with the backend stack and the description of
+// the operation.
+func (s Stack) run(ctx context.Context, op operation,
+	fn func(context.Context, backend.Stack, backend.UpdateOperation) (sdkDisplay.ResourceChanges, result.Result),
+) (operationOutput, error) {
+	// The caller's event channels are closed once the operation's events have been forwarded to them, or as soon as the
+	// operation fails if it fails before it starts.
+	closeEvents := func() {
+		if op.events != nil {
+			close(op.events)
+		}
+		for _, stream := range op.eventStreams {
+			close(stream)
+		}
+	}
+	forwarding := false
+	defer func() {
+		if !forwarding {
+			closeEvents()
+		}
+	}()
+
+	var out operationOutput
+	if err := ctx.Err(); err != nil {
+		return out, err
+	}
+
+	w := s.workspace
+	bs, err := w.getStack(ctx, s.name)
+	if err != nil {
+		return out, err
+	}
+	ps, path, err := w.loadStackSettings(s.name)
+	if err != nil {
+		return out, err
+	}
+	sm, err := newSecretsManager(bs, ps, path, "" /*secretsProvider*/, false /*rotate*/)
+	if err != nil {
+		return out, fmt.Errorf("getting secrets manager: %w", err)
+	}
+	stackConfig, err := loadStackConfig(path, sm)
+	if err != nil {
+		return out, fmt.Errorf("loading stack configuration: %w", err)
+	}
+	cfg := backend.StackConfiguration{Decrypter: config.NewPanicCrypter()}
+	var dec config.Decrypter
+	if stackConfig.HasSecureValue() {
+		if cfg.Decrypter, err = sm.Decrypter(); err != nil {
+			return out, fmt.Errorf("getting configuration decrypter: %w", err)
+		}
+		dec = cfg.Decrypter
+	}
+	// As in the CLI, only the operations that run the program enforce the project's config schema.
+	if op.kind == apitype.UpdateUpdate || op.kind == apitype.PreviewUpdate {
+		if cfg.Config, err = w.project.ApplyConfig(stackConfig, dec); err != nil {
+			return out, fmt.Errorf("validating stack configuration: %w", err)
+		}
+	} else if cfg.Config, err = w.project.MergeConfigDefaults(stackConfig); err != nil {
+		return out, fmt.Errorf("loading stack configuration: %w", err)
+	}
+
+	// Inline programs are run by a language runtime in this process, which the engine connects to as it does to the
+	// runtime of the CLI's `--client` flag.
+	proj := *w.project
+	kind := constant.ExecKindAutoLocal
+	if w.program != nil {
+		server, err := startProgramServer(w.program)
+		if err != nil {
+			return out, err
+		}
+		defer contract.IgnoreClose(server)
+
+		proj.Runtime = workspace.NewProjectRuntimeInfo("client", map[string]interface{}{
+			"address": server.address,
+		})
+		kind = constant.ExecKindAutoInline
+	}
+
+	opts := op.engine
+	opts.LocalPolicyPacks = engine.MakeLocalPolicyPacks(op.policyPacks, op.policyPackConfigs)
+	if op.kind != apitype.RefreshUpdate && proj.Options != nil && proj.Options.Refresh == "always" {
+		opts.Refresh = true
+	}
+	if op.plan != "" {
+		if opts.Plan, err = readPlan(op.plan, sm); err != nil {
+			return out, fmt.Errorf("reading plan: %w", err)
+		}
+	}
+
+	m := &backend.UpdateMetadata{
+		Message:     op.message,
+		Environment: map[string]string{backend.ExecutionKind: kind},
+	}
+	if op.userAgent != "" {
+		m.Environment[backend.ExecutionAgent] = op.userAgent
+	}
+
+	var stdout, stderr bytes.Buffer
+	displayOpts := display.Options{
+		Color:         colorization(op.color),
+		IsInteractive: false,
+		Type:          display.DisplayProgress,
+		Stdout:        io.MultiWriter(append([]io.Writer{&stdout}, op.progressStreams...)...),
+		Stderr:        &stderr,
+	}
+	if op.diff {
+		displayOpts.Type = display.DisplayDiff
+	}
+
+	engineEvents := make(chan engine.Event)
+	eventsDone := make(chan bool)
+	var errorMessages []string
+	var apiEvents []apitype.EngineEvent
+	forwarding = true
+	go func() {
+		seq := 0
+		for e := range engineEvents {
+			if e.Type == engine.DiagEvent {
+				payload := e.Payload().(engine.DiagEventPayload)
+				if payload.Severity == diag.Error {
+					errorMessages = append(errorMessages, strings.TrimSpace(colors.Never.Colorize(payload.Message)))
+				}
+			}
+			if op.events != nil {
+				op.events <- e
+			}
+			if len(op.eventStreams) > 0 || op.collectEvents {
+				apiEvent, err := display.ConvertEngineEvent(e, false /*showSecrets*/)
+				apiEvent.Sequence, apiEvent.Timestamp = seq, int(time.Now().Unix())
+				seq++
+				if op.collectEvents && err == nil {
+					apiEvents = append(apiEvents, apiEvent)
+				}
+				for _, stream := range op.eventStreams {
+					stream <- events.EngineEvent{EngineEvent: apiEvent, Error: err}
+				}
+			}
+		}
+		closeEvents()
+		close(eventsDone)
+	}()
+
+	// The backend is given a context that isn't cancelled, so that it can record the outcome of the operation once
+	// the engine stops. Cancelling ctx cancels the operation through its cancellation scopes.
+	changes, res := fn(context.Background(), bs, backend.UpdateOperation{
+		Proj:               &proj,
+		Root:               w.workDir,
+		M:                  m,
+		Opts:               backend.UpdateOptions{Engine: opts, Display: displayOpts, AutoApprove: true, SkipPreview: true},
+		StackConfiguration: cfg,
+		SecretsManager:     sm,
+		Scopes:             cancellationScopeSource{ctx: ctx},
+		Events:             engineEvents,
+	})
+	close(engineEvents)
+	<-eventsDone
+
+	out.stdout, out.stderr, out.changes = stdout.String(), stderr.String(), changes
+	out.events = apiEvents
+	switch {
+	case res == nil:
+		return out, nil
+	case res.Error() != nil && errors.Is(res.Error(), context.Canceled):
+		return out, errors.New("operation cancelled")
+	case res.Error() != nil:
+		return out, res.Error()
+	case len(errorMessages) > 0:
+		return out, errors.New(strings.Join(errorMessages, "\n"))
+	default:
+		return out, fmt.Errorf("the %s failed", strings.ToLower(string(op.kind)))
+	}
+}
+
+// colorization returns the colorization named by an operation's Color option. The display of an operation isn't
+// a terminal, so it isn't colorized by default.
+func colorization(color string) colors.Colorization {
+	switch color {
+	case "always":
+		return colors.Always
+	case "raw":
+		return colors.Raw
+	default:
+		return colors.Never
+	}
+}
+
+func urns(targets []string) []resource.URN {
+	if len(targets) == 0 {
+		return nil
+	}
+	result := make([]resource.URN, len(targets))
+	for i, t := range targets {
+		result[i] = resource.URN(t)
+	}
+	return result
+}
+
+func readPlan(path string, sm secrets.Manager) (*deploy.Plan, error) {
+	dec, err := sm.Decrypter()
+	if err != nil {
+		return nil, err
+	}
+	enc, err := sm.Encrypter()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(f)
+
+	var deploymentPlan apitype.DeploymentPlanV1
+	if err := json.NewDecoder(f).Decode(&deploymentPlan); err != nil {
+		return nil, err
+	}
+	return stack.DeserializePlan(deploymentPlan, dec, enc)
+}
+
+func writePlan(path string, plan *deploy.Plan, enc config.Encrypter) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer contract.IgnoreClose(f)
+
+	deploymentPlan, err := stack.SerializePlan(plan, enc, false /*showSecrets*/)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(deploymentPlan)
+}
+
+// cancellationScopeSource provides cancellation scopes that cancel an operation when a context is done.
+type cancellationScopeSource struct {
+	ctx context.Context
+}
+
+func (s cancellationScopeSource) NewScope(events chan<- engine.Event, isPreview bool) backend.CancellationScope {
+	cancelContext, cancelSource := cancel.NewContext(context.Background())
+	scope := &cancellationScope{context: cancelContext, done: make(chan bool)}
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			cancelSource.Cancel()
+		case <-scope.done:
+		}
+	}()
+	return scope
+}
+
+type cancellationScope struct {
+	context *cancel.Context
+	done    chan bool
+}
+
+func (s *cancellationScope) Context() *cancel.Context {
+	return s.context
+}
+
+func (s *cancellationScope) Close() {
+	close(s.done)
+}
@@ -0,0 +1,299 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inprocess
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	agelib "filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/age"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+func newTestWorkspace(t *testing.T, program pulumi.RunFunc) *Workspace {
+	t.Setenv("PULUMI_CONFIG_PASSPHRASE", "correct horse battery staple")
+
+	w, err := NewWorkspace(context.Background(),
+		WorkDir(t.TempDir()),
+		PulumiHome(t.TempDir()),
+		Program(program),
+		Project(workspace.Project{
+			Name:    tokens.PackageName("inprocess-test"),
+			Runtime: workspace.NewProjectRuntimeInfo("go", nil),
+			Backend: &workspace.ProjectBackend{URL: "file://" + t.TempDir()},
+		}))
+	require.NoError(t, err)
+	return w
+}
+
+func TestStackLifecycle(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWorkspace(t, func(ctx *pulumi.Context) error {
+		c := config.New(ctx, "")
+		ctx.Export("greeting", pulumi.String("hello "+c.Require("name")))
+		ctx.Export("password", c.RequireSecret("password"))
+		return nil
+	})
+
+	s, err := NewStack(ctx, "dev", w)
+	require.NoError(t, err)
+	_, err = NewStack(ctx, "dev", w)
+	assert.Error(t, err)
+	s, err = UpsertStack(ctx, "dev", w)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetAllConfig(ctx, auto.ConfigMap{
+		"name":     auto.ConfigValue{Value: "world"},
+		"password": auto.ConfigValue{Value: "hunter2", Secret: true},
+	}))
+	value, err := s.GetConfig(ctx, "password")
+	require.NoError(t, err)
+	assert.Equal(t, auto.ConfigValue{Value: "hunter2", Secret: true}, value)
+
+	// Preview proposes creating the stack resource.
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	preview, err := s.Preview(ctx, nil /*events*/, optpreview.Plan(planPath))
+	require.NoError(t, err)
+	assert.Equal(t, 1, preview.ChangeSummary[apitype.OpCreate])
+	require.Len(t, preview.Steps, 1)
+	assert.Equal(t, string(apitype.OpCreate), preview.Steps[0].Op)
+	assert.Equal(t, tokens.Type("pulumi:pulumi:Stack"), preview.Steps[0].Type)
+	assert.NotEmpty(t, preview.Plan)
+
+	// Up sends its engine events, and closes the channel once it finishes.
+	events := make(chan engine.Event)
+	var eventTypes []engine.EventType
+	done := make(chan bool)
+	go func() {
+		for e := range events {
+			eventTypes = append(eventTypes, e.Type)
+		}
+		close(done)
+	}()
+	up, err := s.Up(ctx, events, optup.Message("first update"))
+	require.NoError(t, err)
+	<-done
+	assert.Contains(t, eventTypes, engine.PreludeEvent)
+	assert.Contains(t, eventTypes, engine.SummaryEvent)
+
+	assert.Equal(t, auto.OutputValue{Value: "hello world"}, up.Outputs["greeting"])
+	assert.Equal(t, auto.OutputValue{Value: "hunter2", Secret: true}, up.Outputs["password"])
+	assert.Equal(t, "update", up.Summary.Kind)
+	assert.Equal(t, "succeeded", up.Summary.Result)
+	assert.Equal(t, "first update", up.Summary.Message)
+	assert.Equal(t, auto.ConfigValue{Value: "hunter2", Secret: true}, up.Summary.Config["inprocess-test:password"])
+
+	// Nothing changes in a second update, as expected.
+	_, err = s.Preview(ctx, nil /*events*/, optpreview.ExpectNoChanges())
+	require.NoError(t, err)
+	_, err = s.Up(ctx, nil /*events*/, optup.ExpectNoChanges())
+	require.NoError(t, err)
+
+	// Stacks round-trip through export and import.
+	state, err := s.Export(ctx)
+	require.NoError(t, err)
+	require.NoError(t, s.Import(ctx, state))
+
+	refresh, err := s.Refresh(ctx, nil /*events*/)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh", refresh.Summary.Kind)
+
+	destroy, err := s.Destroy(ctx, nil /*events*/)
+	require.NoError(t, err)
+	assert.Equal(t, "destroy", destroy.Summary.Kind)
+
+	history, err := s.History(ctx, 0 /*pageSize*/, 0 /*page*/)
+	require.NoError(t, err)
+	assert.Len(t, history, 4)
+
+	outputs, err := s.Outputs(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, outputs)
+}
+
+func TestStackProgramError(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWorkspace(t, func(ctx *pulumi.Context) error {
+		return assert.AnError
+	})
+
+	s, err := NewStack(ctx, "dev", w)
+	require.NoError(t, err)
+	_, err = s.Up(ctx, nil /*events*/)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), assert.AnError.Error())
+}
+
+func TestStackCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := newTestWorkspace(t, func(ctx *pulumi.Context) error {
+		return nil
+	})
+
+	s, err := NewStack(context.Background(), "dev", w)
+	require.NoError(t, err)
+	_, err = s.Up(ctx, nil /*events*/)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Event channels are closed even if the operation fails before it starts.
+	engineEvents := make(chan engine.Event, 1)
+	streamEvents := make(chan events.EngineEvent, 1)
+	_, err = s.Up(ctx, engineEvents, optup.EventStreams(streamEvents))
+	assert.ErrorIs(t, err, context.Canceled)
+	_, open := <-engineEvents
+	assert.False(t, open)
+	_, open = <-streamEvents
+	assert.False(t, open)
+
+	engineEvents = make(chan engine.Event, 1)
+	_, err = s.Up(context.Background(), engineEvents, optup.Plan(filepath.Join(t.TempDir(), "missing.json")))
+	assert.Error(t, err)
+	_, open = <-engineEvents
+	assert.False(t, open)
+}
+
+func TestStackProjectConfig(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWorkspace(t, func(ctx *pulumi.Context) error {
+		c := config.New(ctx, "")
+		ctx.Export("greeting", pulumi.String(c.Require("greeting")+" "+c.Require("name")))
+		return nil
+	})
+
+	proj, err := w.ProjectSettings(ctx)
+	require.NoError(t, err)
+	proj.Config = map[string]workspace.ProjectConfigType{
+		"greeting": {Default: "hello"},
+		"name":     {Required: true},
+		"count":    {Type: workspace.ConfigTypeInteger},
+	}
+	require.NoError(t, w.SaveProjectSettings(ctx, proj))
+
+	s, err := NewStack(ctx, "dev", w)
+	require.NoError(t, err)
+
+	// Required config must be set before the program runs.
+	_, err = s.Preview(ctx, nil /*events*/)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required config key 'inprocess-test:name'")
+
+	// Operations that don't run the program don't enforce the schema.
+	_, err = s.Destroy(ctx, nil /*events*/)
+	require.NoError(t, err)
+
+	// Config is checked as it is set.
+	err = s.SetConfig(ctx, "nmae", auto.ConfigValue{Value: "world"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did you mean inprocess-test:name?")
+	err = s.SetConfig(ctx, "count", auto.ConfigValue{Value: "many"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected an integer")
+
+	// The program sees the defaults of config that the stack doesn't set.
+	require.NoError(t, s.SetConfig(ctx, "name", auto.ConfigValue{Value: "world"}))
+	up, err := s.Up(ctx, nil /*events*/)
+	require.NoError(t, err)
+	assert.Equal(t, auto.OutputValue{Value: "hello world"}, up.Outputs["greeting"])
+
+	// The defaults aren't saved with the stack's config.
+	cfg, err := s.GetAllConfig(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, auto.ConfigMap{"inprocess-test:name": auto.ConfigValue{Value: "world"}}, cfg)
+}
+
+func TestStackConfigLayers(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWorkspace(t, func(ctx *pulumi.Context) error {
+		c := config.New(ctx, "")
+		ctx.Export("region", pulumi.String(c.Require("region")))
+		ctx.Export("size", pulumi.String(c.Require("size")))
+		ctx.Export("token", c.RequireSecret("token"))
+		return nil
+	})
+
+	// The base stack's settings are shared by the dev stack, secrets and all.
+	base, err := NewStack(ctx, "base", w)
+	require.NoError(t, err)
+	require.NoError(t, base.SetAllConfig(ctx, auto.ConfigMap{
+		"region": auto.ConfigValue{Value: "us-east-1"},
+		"size":   auto.ConfigValue{Value: "small"},
+		"token":  auto.ConfigValue{Value: "hunter2", Secret: true},
+	}))
+
+	dev, err := NewStack(ctx, "dev", w)
+	require.NoError(t, err)
+	require.NoError(t, dev.SetConfig(ctx, "size", auto.ConfigValue{Value: "large"}))
+	ps, err := w.StackSettings(ctx, "dev")
+	require.NoError(t, err)
+	ps.Extends = []string{"Pulumi.base.yaml"}
+	require.NoError(t, w.SaveStackSettings(ctx, "dev", ps))
+
+	up, err := dev.Up(ctx, nil /*events*/)
+	require.NoError(t, err)
+	assert.Equal(t, auto.OutputValue{Value: "us-east-1"}, up.Outputs["region"])
+	assert.Equal(t, auto.OutputValue{Value: "large"}, up.Outputs["size"])
+	assert.Equal(t, auto.OutputValue{Value: "hunter2", Secret: true}, up.Outputs["token"])
+}
+
+func TestStackAgeSecretsProvider(t *testing.T) {
+	ctx := context.Background()
+	id, err := agelib.GenerateX25519Identity()
+	require.NoError(t, err)
+	t.Setenv(age.KeyEnvVar, id.String())
+
+	w, err := NewWorkspace(ctx,
+		WorkDir(t.TempDir()),
+		PulumiHome(t.TempDir()),
+		SecretsProvider("age"),
+		Program(func(ctx *pulumi.Context) error {
+			ctx.Export("password", config.New(ctx, "").RequireSecret("password"))
+			return nil
+		}),
+		Project(workspace.Project{
+			Name:    tokens.PackageName("inprocess-test"),
+			Runtime: workspace.NewProjectRuntimeInfo("go", nil),
+			Backend: &workspace.ProjectBackend{URL: "file://" + t.TempDir()},
+		}))
+	require.NoError(t, err)
+
+	s, err := NewStack(ctx, "dev", w)
+	require.NoError(t, err)
+	require.NoError(t, s.SetConfig(ctx, "password", auto.ConfigValue{Value: "hunter2", Secret: true}))
+
+	// The stack's data key is encrypted to the recipient of the identity in the environment.
+	ps, err := w.StackSettings(ctx, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, age.Type, ps.SecretsProvider)
+	assert.Equal(t, []string{id.Recipient().String()}, ps.SecretsRecipients)
+
+	up, err := s.Up(ctx, nil /*events*/)
+	require.NoError(t, err)
+	assert.Equal(t, auto.OutputValue{Value: "hunter2", Secret: true}, up.Outputs["password"])
+}
@@ -0,0 +1,1260 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inprocess is an implementation of the Automation API that runs the Pulumi engine in the current process,
+// instead of running the Pulumi CLI as `github.com/pulumi/pulumi/sdk/v3/go/auto` does. Operations report their
+// progress as typed engine events, are cancelled by cancelling their context, and don't depend on the version of the
+// CLI installed on the machine.
+//
+// A Workspace implements auto.Workspace, so it can be used wherever the Automation API expects one, but stacks must be
+// created, selected and operated on with this package's Stack, since auto.Stack runs the CLI:
+//
+//	w, err := inprocess.NewWorkspace(ctx, inprocess.Project(project), inprocess.Program(program))
+//	s, err := inprocess.UpsertStack(ctx, "dev", w)
+//	res, err := s.Up(ctx, nil /*events*/, optup.Message("deployed in-process"))
+//
+// Workspaces are not isolated from each other. Because everything runs in the current process, a Workspace shares the
+// global state of the process with every other Workspace in it:
+//
+//   - Environment variables set through EnvVars, SetEnvVar and SetEnvVars are set on the process itself with
+//     os.Setenv, so they are seen by every Workspace and every operation that runs in the process, and by the plugins
+//     that they start, until they are unset. A service that runs operations for several tenants in one process must
+//     not pass per-tenant credentials, such as PULUMI_CONFIG_PASSPHRASE or cloud provider keys, this way.
+//   - NewWorkspace disables interactive prompts for the whole process, by setting cmdutil.DisableInteractive, and
+//     doesn't restore them. Operations never prompt: secrets providers that need a passphrase read it from
+//     PULUMI_CONFIG_PASSPHRASE or PULUMI_CONFIG_PASSPHRASE_FILE.
+//   - The Pulumi home directory applies to the whole process.
+//
+// When using the Pulumi Service backend, stack names that don't include a project are qualified with the name of the
+// Workspace's project.
+package inprocess
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/blang/semver"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/pkg/v3/backend/httpstate"
+	resourceanalyzer "github.com/pulumi/pulumi/pkg/v3/resource/analyzer"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/version"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpolicy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optremove"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// timeFormat is the format of the times reported in stack summaries and update summaries, as the CLI formats them.
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// settingsExtensions are the extensions of the project and stack settings files, in order of preference.
+var settingsExtensions = []string{".yaml", ".yml", ".json"}
+
+// Workspace is an auto.Workspace that runs the Pulumi engine in the current process. A Workspace holds a single
+// project, whose settings and stack settings are stored in its WorkDir, and the currently selected stack, which is
+// only known to the Workspace.
+type Workspace struct {
+	workDir         string
+	pulumiHome      string
+	program         pulumi.RunFunc
+	project         *workspace.Project
+	secretsProvider string
+	envvars         map[string]string
+	current         string
+
+	sink     diag.Sink
+	lock     sync.Mutex
+	backends map[string]backend.Backend
+}
+
+var _ auto.Workspace = (*Workspace)(nil)
+
+// NewWorkspace creates and configures a Workspace. Options can be used to configure things like the working
+// directory, the program to execute and the project settings. If no project settings are given, they are read from
+// the Pulumi.yaml file of the working directory. NewWorkspace disables interactive prompts for the whole process.
+func NewWorkspace(ctx context.Context, opts ...Option) (*Workspace, error) {
+	wOpts := &options{}
+	// for merging options, last specified value wins
+	for _, opt := range opts {
+		opt.applyOption(wOpts)
+	}
+
+	workDir := wOpts.WorkDir
+	if workDir == "" {
+		dir, err := ioutil.TempDir("", "pulumi_auto")
+		if err != nil {
+			return nil, fmt.Errorf("unable to create tmp directory for workspace: %w", err)
+		}
+		workDir = dir
+	}
+
+	// Operations never prompt; everything they need must be configured ahead of time. This disables prompts for the
+	// whole process, as documented above.
+	cmdutil.DisableInteractive = true
+
+	w := &Workspace{
+		workDir:         workDir,
+		program:         wOpts.Program,
+		secretsProvider: wOpts.SecretsProvider,
+		sink:            diag.DefaultSink(os.Stderr, os.Stderr, diag.FormatOptions{Color: colors.Never}),
+		backends:        make(map[string]backend.Backend),
+	}
+
+	if wOpts.PulumiHome != "" {
+		w.pulumiHome = wOpts.PulumiHome
+		w.SetEnvVar(workspace.PulumiHomeEnvVar, wOpts.PulumiHome)
+	}
+	if wOpts.EnvVars != nil {
+		if err := w.SetEnvVars(wOpts.EnvVars); err != nil {
+			return nil, fmt.Errorf("failed to set environment values: %w", err)
+		}
+	}
+
+	if wOpts.Project != nil {
+		if err := w.SaveProjectSettings(ctx, wOpts.Project); err != nil {
+			return nil, fmt.Errorf("failed to create workspace, unable to save project settings: %w", err)
+		}
+	} else {
+		proj, err := readProjectSettings(workDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workspace: %w", err)
+		}
+		w.project = proj
+	}
+
+	for stackName := range wOpts.Stacks {
+		s := wOpts.Stacks[stackName]
+		if err := w.SaveStackSettings(ctx, stackName, &s); err != nil {
+			return nil, fmt.Errorf("failed to create workspace: %w", err)
+		}
+	}
+
+	return w, nil
+}
+
+type options struct {
+	// WorkDir is the directory that stores the project and stack settings, and that the program runs in.
+	// Defaults to a tmp dir.
+	WorkDir string
+	// Program is the Pulumi program to execute. If none is supplied,
+	// the program identified in $WORKDIR/Pulumi.yaml will be used instead.
+	Program pulumi.RunFunc
+	// PulumiHome overrides the metadata directory of the process.
+	PulumiHome string
+	// Project is the project settings for the workspace.
+	Project *workspace.Project
+	// Stacks is a map of [stackName -> stack settings objects] to seed the workspace.
+	Stacks map[string]workspace.ProjectStack
+	// SecretsProvider is the secrets provider of the stacks created by the workspace.
+	SecretsProvider string
+	// EnvVars is a map of environment values to set on the process. They apply to every Workspace in the process.
+	EnvVars map[string]string
+}
+
+// Option is used to customize and configure a Workspace at initialization time.
+// See WorkDir, Program, PulumiHome, Project, Stacks, SecretsProvider and EnvVars for concrete options.
+type Option interface {
+	applyOption(*options)
+}
+
+type optionFunc func(*options)
+
+func (o optionFunc) applyOption(opts *options) {
+	o(opts)
+}
+
+// WorkDir is the directory that stores the project and stack settings, and that the program runs in.
+func WorkDir(workDir string) Option {
+	return optionFunc(func(o *options) {
+		o.WorkDir = workDir
+	})
+}
+
+// Program is the Pulumi program to execute. If none is supplied,
+// the program identified in $WORKDIR/Pulumi.yaml will be used instead.
+func Program(program pulumi.RunFunc) Option {
+	return optionFunc(func(o *options) {
+		o.Program = program
+	})
+}
+
+// PulumiHome overrides the metadata directory of the process, where credentials are stored and plugins are installed.
+func PulumiHome(dir string) Option {
+	return optionFunc(func(o *options) {
+		o.PulumiHome = dir
+	})
+}
+
+// Project sets project settings for the workspace.
+func Project(settings workspace.Project) Option {
+	return optionFunc(func(o *options) {
+		o.Project = &settings
+	})
+}
+
+// Stacks is a list of stack settings objects to seed the workspace.
+func Stacks(settings map[string]workspace.ProjectStack) Option {
+	return optionFunc(func(o *options) {
+		o.Stacks = settings
+	})
+}
+
+// SecretsProvider is the secrets provider of the stacks created by the workspace.
+func SecretsProvider(secretsProvider string) Option {
+	return optionFunc(func(o *options) {
+		o.SecretsProvider = secretsProvider
+	})
+}
+
+// EnvVars is a map of environment values to set on the process.
+func EnvVars(envvars map[string]string) Option {
+	return optionFunc(func(o *options) {
+		o.EnvVars = envvars
+	})
+}
+
+// ProjectSettings returns the settings object for the current project.
+func (w *Workspace) ProjectSettings(ctx context.Context) (*workspace.Project, error) {
+	return w.project, nil
+}
+
+// SaveProjectSettings overwrites the settings object of the project, and writes it to the Pulumi.yaml file in
+// Workspace.WorkDir().
+func (w *Workspace) SaveProjectSettings(ctx context.Context, settings *workspace.Project) error {
+	if err := settings.Save(filepath.Join(w.workDir, "Pulumi.yaml")); err != nil {
+		return err
+	}
+	w.project = settings
+	return nil
+}
+
+// StackSettings returns the settings object for the stack matching the specified stack name, as read from the
+// Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *Workspace) StackSettings(ctx context.Context, stackName string) (*workspace.ProjectStack, error) {
+	path := w.stackSettingsPath(stackName)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("unable to find stack settings in workspace for %s", stackName)
+	}
+	ps, err := workspace.LoadProjectStack(path)
+	if err != nil {
+		return nil, fmt.Errorf("found stack settings, but failed to load: %w", err)
+	}
+	return ps, nil
+}
+
+// SaveStackSettings overwrites the settings object for the stack matching the specified stack name, and writes it to
+// the Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (w *Workspace) SaveStackSettings(ctx context.Context, stackName string, settings *workspace.ProjectStack) error {
+	ps, path, err := w.loadStackSettings(stackName)
+	if err != nil {
+		ps = &workspace.ProjectStack{}
+	}
+	// The settings are loaded from a cache that is shared by the whole process, so update them in place for later
+	// loads to see them.
+	*ps = *settings
+	if err = ps.Save(path); err != nil {
+		return fmt.Errorf("failed to save stack settings for %s: %w", stackName, err)
+	}
+	return nil
+}
+
+// SerializeArgsForOp is a hook to provide additional args to every CLI command. Workspace doesn't run the CLI, so
+// this always returns nil.
+func (w *Workspace) SerializeArgsForOp(ctx context.Context, stackName string) ([]string, error) {
+	return nil, nil
+}
+
+// PostCommandCallback is a hook executed after every CLI command. Workspace doesn't run the CLI, so this does
+// nothing.
+func (w *Workspace) PostCommandCallback(ctx context.Context, stackName string) error {
+	return nil
+}
+
+// GetConfig returns the value associated with the specified stack name and key. Keys without a namespace are in the
+// namespace of the project.
+func (w *Workspace) GetConfig(ctx context.Context, stackName string, key string) (auto.ConfigValue, error) {
+	var val auto.ConfigValue
+	k, err := w.parseConfigKey(key)
+	if err != nil {
+		return val, err
+	}
+	ps, _, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return val, err
+	}
+	v, ok, err := ps.Config.Get(k, false /*path*/)
+	if err != nil {
+		return val, err
+	}
+	if !ok {
+		return val, fmt.Errorf("configuration key '%s' not found for stack '%s'", key, stackName)
+	}
+
+	var dec config.Decrypter = config.NewPanicCrypter()
+	if v.Secure() {
+		if dec, err = w.decrypter(ctx, stackName); err != nil {
+			return val, err
+		}
+	}
+	if val.Value, err = v.Value(dec); err != nil {
+		return val, fmt.Errorf("could not decrypt configuration value: %w", err)
+	}
+	val.Secret = v.Secure()
+	return val, nil
+}
+
+// GetAllConfig returns the config map for the specified stack name, with secrets decrypted. Keys are qualified with
+// their namespace.
+func (w *Workspace) GetAllConfig(ctx context.Context, stackName string) (auto.ConfigMap, error) {
+	ps, _, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	var dec config.Decrypter = config.NewPanicCrypter()
+	if ps.Config.HasSecureValue() {
+		if dec, err = w.decrypter(ctx, stackName); err != nil {
+			return nil, err
+		}
+	}
+	cfg := make(auto.ConfigMap)
+	for k, v := range ps.Config {
+		value, err := v.Value(dec)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt configuration value: %w", err)
+		}
+		cfg[k.String()] = auto.ConfigValue{Value: value, Secret: v.Secure()}
+	}
+	return cfg, nil
+}
+
+// SetConfig sets the specified key-value pair on the provided stack name, encrypting it if it is a secret.
+func (w *Workspace) SetConfig(ctx context.Context, stackName string, key string, val auto.ConfigValue) error {
+	return w.SetAllConfig(ctx, stackName, auto.ConfigMap{key: val})
+}
+
+// SetAllConfig sets all values in the provided config map for the specified stack name.
+func (w *Workspace) SetAllConfig(ctx context.Context, stackName string, cfg auto.ConfigMap) error {
+	ps, path, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return err
+	}
+
+	var enc config.Encrypter
+	for key, val := range cfg {
+		k, err := w.parseConfigKey(key)
+		if err != nil {
+			return err
+		}
+		// The no-op decrypter returns the "ciphertext" of a secure value as is, so secrets are checked using their
+		// plaintext.
+		v := config.NewValue(val.Value)
+		if val.Secret {
+			v = config.NewSecureValue(val.Value)
+		}
+		if err = w.project.ValidateConfigValue(k, v, config.NopDecrypter); err != nil {
+			return err
+		}
+		if val.Secret {
+			if enc == nil {
+				if enc, err = w.encrypter(ctx, stackName); err != nil {
+					return err
+				}
+			}
+			ciphertext, err := enc.EncryptValue(val.Value)
+			if err != nil {
+				return fmt.Errorf("could not encrypt configuration value: %w", err)
+			}
+			v = config.NewSecureValue(ciphertext)
+		}
+		if err = ps.Config.Set(k, v, false /*path*/); err != nil {
+			return err
+		}
+	}
+	if err = ps.Save(path); err != nil {
+		return fmt.Errorf("unable to set config: %w", err)
+	}
+	return nil
+}
+
+// RemoveConfig removes the specified key-value pair on the provided stack name.
+func (w *Workspace) RemoveConfig(ctx context.Context, stackName string, key string) error {
+	return w.RemoveAllConfig(ctx, stackName, []string{key})
+}
+
+// RemoveAllConfig removes all values in the provided key list for the specified stack name.
+func (w *Workspace) RemoveAllConfig(ctx context.Context, stackName string, keys []string) error {
+	ps, path, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		k, err := w.parseConfigKey(key)
+		if err != nil {
+			return err
+		}
+		if err = ps.Config.Remove(k, false /*path*/); err != nil {
+			return err
+		}
+	}
+	if err = ps.Save(path); err != nil {
+		return fmt.Errorf("could not remove config: %w", err)
+	}
+	return nil
+}
+
+// RefreshConfig gets and sets the config map used with the last update of the stack matching the stack name.
+// It overwrites all of the configuration in the stack's settings.
+func (w *Workspace) RefreshConfig(ctx context.Context, stackName string) (auto.ConfigMap, error) {
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.Backend().GetLatestConfiguration(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("could not refresh config: %w", err)
+	}
+	ps, path, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return nil, err
+	}
+	ps.Config = latest
+	if err = ps.Save(path); err != nil {
+		return nil, fmt.Errorf("could not refresh config: %w", err)
+	}
+	return w.GetAllConfig(ctx, stackName)
+}
+
+// GetEnvVars returns the environment values that were set through the workspace.
+func (w *Workspace) GetEnvVars() map[string]string {
+	return w.envvars
+}
+
+// SetEnvVars sets the specified map of environment values on the process. They apply to every Workspace in the
+// process, not just this one.
+func (w *Workspace) SetEnvVars(envvars map[string]string) error {
+	if envvars == nil {
+		return errors.New("unable to set nil environment values")
+	}
+	for k, v := range envvars {
+		w.SetEnvVar(k, v)
+	}
+	return nil
+}
+
+// SetEnvVar sets the specified environment value on the process. It applies to every Workspace in the process, not just
+// this one.
+func (w *Workspace) SetEnvVar(key, value string) {
+	if w.envvars == nil {
+		w.envvars = map[string]string{}
+	}
+	w.envvars[key] = value
+	contract.IgnoreError(os.Setenv(key, value))
+}
+
+// UnsetEnvVar unsets the specified environment value on the process.
+func (w *Workspace) UnsetEnvVar(key string) {
+	delete(w.envvars, key)
+	contract.IgnoreError(os.Unsetenv(key))
+}
+
+// WorkDir returns the working directory of the workspace.
+func (w *Workspace) WorkDir() string {
+	return w.workDir
+}
+
+// PulumiHome returns the directory override for Pulumi metadata, if any.
+func (w *Workspace) PulumiHome() string {
+	return w.pulumiHome
+}
+
+// PulumiVersion returns the version of the Pulumi engine that the workspace runs.
+func (w *Workspace) PulumiVersion() string {
+	return strings.TrimPrefix(version.Version, "v")
+}
+
+// WhoAmI returns the currently authenticated user.
+func (w *Workspace) WhoAmI(ctx context.Context) (string, error) {
+	b, err := w.backend(ctx)
+	if err != nil {
+		return "", err
+	}
+	user, _, err := b.CurrentUser()
+	if err != nil {
+		return "", fmt.Errorf("could not determine authenticated user: %w", err)
+	}
+	return user, nil
+}
+
+// Stack returns a summary of the currently selected stack, if any.
+func (w *Workspace) Stack(ctx context.Context) (*auto.StackSummary, error) {
+	if w.current == "" {
+		return nil, nil
+	}
+	stacks, err := w.ListStacks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine selected stack: %w", err)
+	}
+	for _, s := range stacks {
+		if s.Current {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateStack creates and selects a new stack with the stack name, failing if one already exists.
+func (w *Workspace) CreateStack(ctx context.Context, stackName string) error {
+	b, err := w.backend(ctx)
+	if err != nil {
+		return err
+	}
+	ref, err := w.parseStackReference(b, stackName)
+	if err != nil {
+		return err
+	}
+	s, err := b.CreateStack(ctx, ref, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create stack: %w", err)
+	}
+	ps, path, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return err
+	}
+	if _, err = newSecretsManager(s, ps, path, w.secretsProvider, false /*rotate*/); err != nil {
+		return fmt.Errorf("failed to create stack: %w", err)
+	}
+	w.current = stackName
+	return nil
+}
+
+// SelectStack selects an existing stack, which later operations of the workspace default to.
+func (w *Workspace) SelectStack(ctx context.Context, stackName string) error {
+	if _, err := w.getStack(ctx, stackName); err != nil {
+		return fmt.Errorf("failed to select stack: %w", err)
+	}
+	w.current = stackName
+	return nil
+}
+
+// RemoveStack deletes the stack and all associated configuration and history.
+func (w *Workspace) RemoveStack(ctx context.Context, stackName string, opts ...optremove.Option) error {
+	removeOpts := &optremove.Options{}
+	for _, o := range opts {
+		o.ApplyOption(removeOpts)
+	}
+
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	hasResources, err := s.Remove(ctx, removeOpts.Force)
+	if err != nil {
+		if hasResources {
+			return fmt.Errorf("'%s' still has resources; removal rejected", s.Ref())
+		}
+		return fmt.Errorf("failed to remove stack: %w", err)
+	}
+
+	ps, path, err := w.loadStackSettings(stackName)
+	if err == nil {
+		*ps = workspace.ProjectStack{Config: make(config.Map)}
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if w.current == stackName {
+		w.current = ""
+	}
+	return nil
+}
+
+// ListStacks returns all the stacks of the workspace's project.
+// This queries the backend and may return stacks not present in the Workspace (as Pulumi.<stack>.yaml files).
+func (w *Workspace) ListStacks(ctx context.Context) ([]auto.StackSummary, error) {
+	b, err := w.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var current string
+	if w.current != "" {
+		if ref, err := w.parseStackReference(b, w.current); err == nil {
+			current = ref.String()
+		}
+	}
+
+	project := string(w.project.Name)
+	filter := backend.ListStacksFilter{Project: &project}
+	var stacks []auto.StackSummary
+	var token backend.ContinuationToken
+	for {
+		summaries, next, err := b.ListStacks(ctx, filter, token)
+		if err != nil {
+			return nil, fmt.Errorf("could not list stacks: %w", err)
+		}
+		for _, summary := range summaries {
+			ref := summary.Name()
+			s := auto.StackSummary{
+				Name:          ref.String(),
+				Current:       ref.String() == current,
+				ResourceCount: summary.ResourceCount(),
+			}
+			if lastUpdate := summary.LastUpdate(); lastUpdate != nil {
+				if lastUpdate.Unix() == 0 {
+					s.UpdateInProgress = true
+				} else {
+					s.LastUpdate = lastUpdate.UTC().Format(timeFormat)
+				}
+			}
+			if hb, ok := b.(httpstate.Backend); ok {
+				if url, err := hb.StackConsoleURL(ref); err == nil {
+					s.URL = url
+				}
+			}
+			stacks = append(stacks, s)
+		}
+		if next == nil {
+			return stacks, nil
+		}
+		token = next
+	}
+}
+
+// InstallPlugin downloads and installs the resource plugin matching the specified name and version.
+func (w *Workspace) InstallPlugin(ctx context.Context, name string, version string) error {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return fmt.Errorf("invalid plugin semver: %w", err)
+	}
+	spec := workspace.PluginInfo{Kind: workspace.ResourcePlugin, Name: name, Version: &v}
+	tarball, _, err := spec.Download()
+	if err != nil {
+		return fmt.Errorf("failed to install plugin: %w", err)
+	}
+	if err = spec.InstallWithContext(ctx, tarball, false /*reinstall*/); err != nil {
+		return fmt.Errorf("failed to install plugin: %w", err)
+	}
+	return nil
+}
+
+// RemovePlugin deletes the resource plugins matching the specified name and version range. An empty version
+// removes all of the versions of the plugin.
+func (w *Workspace) RemovePlugin(ctx context.Context, name string, version string) error {
+	var versionRange semver.Range
+	if version != "" {
+		r, err := semver.ParseRange(version)
+		if err != nil {
+			return fmt.Errorf("invalid plugin semver range: %w", err)
+		}
+		versionRange = r
+	}
+
+	plugins, err := workspace.GetPlugins()
+	if err != nil {
+		return fmt.Errorf("failed to remove plugin: %w", err)
+	}
+	for _, plugin := range plugins {
+		if plugin.Kind != workspace.ResourcePlugin || plugin.Name != name {
+			continue
+		}
+		if versionRange != nil && (plugin.Version == nil || !versionRange(*plugin.Version)) {
+			continue
+		}
+		if err := plugin.Delete(); err != nil {
+			return fmt.Errorf("failed to remove plugin: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListPlugins lists all installed plugins.
+func (w *Workspace) ListPlugins(ctx context.Context) ([]workspace.PluginInfo, error) {
+	plugins, err := workspace.GetPluginsWithMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("could not list plugins: %w", err)
+	}
+	return plugins, nil
+}
+
+// Program returns the program `pulumi.RunFunc` to be used for Preview/Update if any.
+// If none is specified, the stack will refer to ProjectSettings for this information.
+func (w *Workspace) Program() pulumi.RunFunc {
+	return w.program
+}
+
+// SetProgram sets the program associated with the Workspace to the specified `pulumi.RunFunc`.
+func (w *Workspace) SetProgram(fn pulumi.RunFunc) {
+	w.program = fn
+}
+
+// ExportStack exports the deployment state of the stack matching the given name, with its secrets in plaintext.
+// This can be combined with ImportStack to edit a stack's state (such as recovery from failed deployments).
+func (w *Workspace) ExportStack(ctx context.Context, stackName string) (apitype.UntypedDeployment, error) {
+	var state apitype.UntypedDeployment
+
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return state, err
+	}
+	deployment, err := s.ExportDeployment(ctx)
+	if err != nil {
+		return state, fmt.Errorf("could not export stack: %w", err)
+	}
+	snap, err := stack.DeserializeUntypedDeployment(deployment, stack.DefaultSecretsProvider)
+	if err != nil {
+		return state, fmt.Errorf("could not export stack: %w", err)
+	}
+	serialized, err := stack.SerializeDeployment(snap, snap.SecretsManager, true /*showSecrets*/)
+	if err != nil {
+		return state, fmt.Errorf("could not export stack: %w", err)
+	}
+	bytes, err := json.Marshal(serialized)
+	if err != nil {
+		return state, fmt.Errorf("could not export stack: %w", err)
+	}
+	state.Version, state.Deployment = apitype.DeploymentSchemaVersionCurrent, bytes
+	return state, nil
+}
+
+// ImportStack imports the specified deployment state into the stack matching the given name, after checking that it
+// only contains resources of that stack and removing its pending operations.
+// This can be combined with ExportStack to edit a stack's state (such as recovery from failed deployments).
+func (w *Workspace) ImportStack(ctx context.Context, stackName string, state apitype.UntypedDeployment) error {
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	snap, err := stack.DeserializeUntypedDeployment(&state, stack.DefaultSecretsProvider)
+	if err != nil {
+		return fmt.Errorf("could not import stack: %w", err)
+	}
+	for _, res := range snap.Resources {
+		if res.URN.Stack() != s.Ref().Name().Q() {
+			return fmt.Errorf("could not import stack: resource '%s' is from a different stack (%s != %s)",
+				res.URN, res.URN.Stack(), s.Ref().Name())
+		}
+	}
+	if err := snap.VerifyIntegrity(); err != nil {
+		return fmt.Errorf("could not import stack: state file contains errors: %w", err)
+	}
+	snap.PendingOperations = nil
+
+	serialized, err := stack.SerializeDeployment(snap, snap.SecretsManager, false /*showSecrets*/)
+	if err != nil {
+		return fmt.Errorf("could not import stack: %w", err)
+	}
+	bytes, err := json.Marshal(serialized)
+	if err != nil {
+		return fmt.Errorf("could not import stack: %w", err)
+	}
+	deployment := apitype.UntypedDeployment{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Deployment: bytes,
+	}
+	if err = s.ImportDeployment(ctx, &deployment); err != nil {
+		return fmt.Errorf("could not import stack: %w", err)
+	}
+	return nil
+}
+
+// StackOutputs gets the current set of Stack outputs from the last Stack.Up().
+func (w *Workspace) StackOutputs(ctx context.Context, stackName string) (auto.OutputMap, error) {
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := s.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get outputs: %w", err)
+	}
+	res, err := stack.GetRootStackResource(snap)
+	if err != nil {
+		return nil, fmt.Errorf("could not get outputs: %w", err)
+	}
+
+	outputs := make(auto.OutputMap)
+	if res == nil {
+		return outputs, nil
+	}
+	// The secrets are revealed before the outputs are serialized, so it is safe to pass a panic crypter.
+	values, err := stack.SerializeProperties(display.MassageSecrets(res.Outputs, true /*showSecrets*/),
+		config.NewPanicCrypter(), true /*showSecrets*/)
+	if err != nil {
+		return nil, fmt.Errorf("could not get outputs: %w", err)
+	}
+	for k, v := range res.Outputs {
+		outputs[string(k)] = auto.OutputValue{
+			Value:  values[string(k)],
+			Secret: v.ContainsSecrets(),
+		}
+	}
+	return outputs, nil
+}
+
+// GetTag returns the value of the tag with the given name on the stack matching the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (w *Workspace) GetTag(ctx context.Context, stackName string, key string) (string, error) {
+	tags, err := w.ListTags(ctx, stackName)
+	if err != nil {
+		return "", err
+	}
+	value, ok := tags[key]
+	if !ok {
+		return "", fmt.Errorf("stack tag %q not found for stack %q", key, stackName)
+	}
+	return value, nil
+}
+
+// SetTag sets the tag with the given name on the stack matching the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (w *Workspace) SetTag(ctx context.Context, stackName string, key string, value string) error {
+	return w.updateTags(ctx, stackName, func(tags map[apitype.StackTagName]string) {
+		tags[key] = value
+	})
+}
+
+// RemoveTag removes the tag with the given name from the stack matching the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (w *Workspace) RemoveTag(ctx context.Context, stackName string, key string) error {
+	return w.updateTags(ctx, stackName, func(tags map[apitype.StackTagName]string) {
+		delete(tags, key)
+	})
+}
+
+// ListTags returns the tags of the stack matching the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (w *Workspace) ListTags(ctx context.Context, stackName string) (map[string]string, error) {
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	for k, v := range s.Tags() {
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+func (w *Workspace) updateTags(ctx context.Context, stackName string,
+	update func(tags map[apitype.StackTagName]string)) error {
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	tags := make(map[apitype.StackTagName]string)
+	for k, v := range s.Tags() {
+		tags[k] = v
+	}
+	update(tags)
+	if err = s.Backend().UpdateStackTags(ctx, s, tags); err != nil {
+		return fmt.Errorf("failed to update stack tags: %w", err)
+	}
+	return nil
+}
+
+// RenameStack renames the stack matching the given name, along with its settings file. If the stack was selected,
+// the stack is selected under its new name.
+func (w *Workspace) RenameStack(ctx context.Context, stackName string, newName string) error {
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	if _, err = s.Rename(ctx, tokens.QName(newName)); err != nil {
+		return fmt.Errorf("failed to rename stack: %w", err)
+	}
+
+	ps, oldPath, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return err
+	}
+	if _, statErr := os.Stat(oldPath); statErr == nil {
+		if err = w.SaveStackSettings(ctx, newName, ps); err != nil {
+			return err
+		}
+		*ps = workspace.ProjectStack{Config: make(config.Map)}
+		if err = os.Remove(oldPath); err != nil {
+			return err
+		}
+	}
+	if w.current == stackName {
+		w.current = newName
+	}
+	return nil
+}
+
+// ChangeSecretsProvider changes the secrets provider of the stack matching the given name, re-encrypting the secrets
+// in its configuration and state with the new provider. Passphrase providers read the new passphrase from
+// PULUMI_CONFIG_PASSPHRASE or PULUMI_CONFIG_PASSPHRASE_FILE.
+func (w *Workspace) ChangeSecretsProvider(ctx context.Context, stackName string, secretsProvider string) error {
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	ps, path, err := w.loadStackSettings(stackName)
+	if err != nil {
+		return err
+	}
+
+	// Decrypt the configuration and state with the current secrets provider.
+	var dec config.Decrypter = config.NewPanicCrypter()
+	if ps.Config.HasSecureValue() {
+		if dec, err = w.decrypter(ctx, stackName); err != nil {
+			return err
+		}
+	}
+	deployment, err := s.ExportDeployment(ctx)
+	if err != nil {
+		return err
+	}
+	snap, err := stack.DeserializeUntypedDeployment(deployment, stack.DefaultSecretsProvider)
+	if err != nil {
+		return err
+	}
+
+	// Then re-encrypt them with the new one.
+	sm, err := newSecretsManager(s, ps, path, secretsProvider, secretsProvider == "passphrase" /*rotate*/)
+	if err != nil {
+		return fmt.Errorf("failed to change secrets provider: %w", err)
+	}
+	enc, err := sm.Encrypter()
+	if err != nil {
+		return err
+	}
+	if ps.Config, err = ps.Config.Copy(dec, enc); err != nil {
+		return err
+	}
+	if err = ps.Save(path); err != nil {
+		return err
+	}
+
+	serialized, err := stack.SerializeDeployment(snap, sm, false /*showSecrets*/)
+	if err != nil {
+		return err
+	}
+	bytes, err := json.Marshal(serialized)
+	if err != nil {
+		return err
+	}
+	return s.ImportDeployment(ctx, &apitype.UntypedDeployment{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Deployment: bytes,
+	})
+}
+
+// EnablePolicyPack enables the given version of a Policy Pack ("<org-name>/<policy-pack-name>") for a Pulumi
+// organization. The version "latest" enables the latest version of the Policy Pack.
+// Policy Packs are only supported by the Pulumi Service backend.
+func (w *Workspace) EnablePolicyPack(ctx context.Context, policyPack string, version string,
+	opts ...optpolicy.Option) error {
+	policyOpts := &optpolicy.Options{}
+	for _, o := range opts {
+		o.ApplyOption(policyOpts)
+	}
+
+	pack, err := w.policyPack(ctx, policyPack)
+	if err != nil {
+		return err
+	}
+	op := backend.PolicyPackOperation{Scopes: cancellationScopeSource{ctx: ctx}}
+	if version != "latest" {
+		op.VersionTag = &version
+	}
+	if policyOpts.Config != "" {
+		if op.Config, err = loadPolicyConfig(policyOpts.Config); err != nil {
+			return err
+		}
+	}
+	if err = pack.Enable(ctx, policyOpts.PolicyGroup, op); err != nil {
+		return fmt.Errorf("failed to enable policy pack: %w", err)
+	}
+	return nil
+}
+
+// DisablePolicyPack disables a Policy Pack ("<org-name>/<policy-pack-name>") for a Pulumi organization.
+// Policy Packs are only supported by the Pulumi Service backend.
+func (w *Workspace) DisablePolicyPack(ctx context.Context, policyPack string, opts ...optpolicy.Option) error {
+	policyOpts := &optpolicy.Options{}
+	for _, o := range opts {
+		o.ApplyOption(policyOpts)
+	}
+
+	pack, err := w.policyPack(ctx, policyPack)
+	if err != nil {
+		return err
+	}
+	op := backend.PolicyPackOperation{VersionTag: &policyOpts.Version, Scopes: cancellationScopeSource{ctx: ctx}}
+	if err = pack.Disable(ctx, policyOpts.PolicyGroup, op); err != nil {
+		return fmt.Errorf("failed to disable policy pack: %w", err)
+	}
+	return nil
+}
+
+func (w *Workspace) policyPack(ctx context.Context, policyPack string) (backend.PolicyPack, error) {
+	b, err := w.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := b.(httpstate.Backend); !ok {
+		return nil, errors.New("policy packs are only supported by the Pulumi Service backend")
+	}
+	return b.GetPolicyPack(ctx, policyPack, w.sink)
+}
+
+// loadPolicyConfig loads the configuration of a Policy Pack, in the format that the Pulumi Service expects.
+func loadPolicyConfig(path string) (map[string]*json.RawMessage, error) {
+	policyConfig, err := resourceanalyzer.LoadPolicyPackConfigFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := make(map[string]*json.RawMessage)
+	for name, c := range policyConfig {
+		m := make(map[string]interface{})
+		for k, v := range c.Properties {
+			m[k] = v
+		}
+		if c.EnforcementLevel != "" {
+			m["enforcementLevel"] = c.EnforcementLevel
+		}
+		bytes, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		raw := json.RawMessage(bytes)
+		cfg[name] = &raw
+	}
+	return cfg, nil
+}
+
+// About returns information about the environment of the workspace and the stack matching the given name, or the
+// selected stack if the name is empty. Workspace doesn't run a language runtime, so the information about the
+// project's runtime and dependencies is left empty.
+func (w *Workspace) About(ctx context.Context, stackName string) (auto.AboutInfo, error) {
+	about := auto.AboutInfo{
+		Host: &auto.AboutHost{OS: runtime.GOOS, Arch: runtime.GOARCH},
+		CLI: &auto.AboutCLI{
+			Version:    w.PulumiVersion(),
+			GoVersion:  runtime.Version(),
+			GoCompiler: runtime.Compiler,
+		},
+		Runtime: map[string]string{"name": w.project.Runtime.Name()},
+	}
+	addError := func(err error) {
+		about.Errors = append(about.Errors, err.Error())
+	}
+
+	if plugins, err := w.ListPlugins(ctx); err != nil {
+		addError(err)
+	} else {
+		for _, p := range plugins {
+			var v string
+			if p.Version != nil {
+				v = p.Version.String()
+			}
+			about.Plugins = append(about.Plugins, auto.AboutPlugin{Name: p.Name, Version: v})
+		}
+	}
+
+	b, err := w.backend(ctx)
+	if err != nil {
+		addError(err)
+		return about, nil
+	}
+	about.Backend = &auto.AboutBackend{Name: b.Name(), URL: b.URL()}
+	if user, orgs, err := b.CurrentUser(); err != nil {
+		addError(err)
+	} else {
+		about.Backend.User, about.Backend.Organizations = user, orgs
+	}
+
+	if stackName == "" {
+		stackName = w.current
+	}
+	if stackName == "" {
+		return about, nil
+	}
+	s, err := w.getStack(ctx, stackName)
+	if err != nil {
+		addError(err)
+		return about, nil
+	}
+	snap, err := s.Snapshot(ctx)
+	if err != nil {
+		addError(err)
+		return about, nil
+	}
+	about.CurrentStack = &auto.AboutStack{Name: s.Ref().String()}
+	if snap != nil {
+		for _, res := range snap.Resources {
+			about.CurrentStack.Resources = append(about.CurrentStack.Resources,
+				auto.AboutResource{Type: string(res.Type), URN: string(res.URN)})
+		}
+		for _, op := range snap.PendingOperations {
+			about.CurrentStack.PendingOps = append(about.CurrentStack.PendingOps,
+				auto.AboutResource{Type: string(op.Type), URN: string(op.Resource.URN)})
+		}
+	}
+	return about, nil
+}
+
+// backendURL returns the URL of the backend that the workspace uses: that of PULUMI_BACKEND_URL, the backend of the
+// project, or the backend that the user last logged in to.
+func (w *Workspace) backendURL() (string, error) {
+	if url := os.Getenv(workspace.PulumiBackendURLEnvVar); url != "" {
+		return url, nil
+	}
+	if w.project.Backend != nil && w.project.Backend.URL != "" {
+		return w.project.Backend.URL, nil
+	}
+	creds, err := workspace.GetStoredCredentials()
+	if err != nil {
+		return "", err
+	}
+	return creds.Current, nil
+}
+
+// backend returns the backend that the workspace uses, logging in to it if needed.
+func (w *Workspace) backend(ctx context.Context) (backend.Backend, error) {
+	url, err := w.backendURL()
+	if err != nil {
+		return nil, fmt.Errorf("could not get cloud url: %w", err)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if b, ok := w.backends[url]; ok {
+		return b, nil
+	}
+	var b backend.Backend
+	if filestate.IsFileStateBackendURL(url) {
+		b, err = filestate.New(w.sink, url)
+	} else {
+		b, err = httpstate.Login(ctx, w.sink, url, display.Options{Color: colors.Never})
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.backends[url] = b
+	return b, nil
+}
+
+// parseStackReference parses a stack name. The Pulumi Service backend infers the project of names that don't have
+// one from the current directory, so they are qualified with the workspace's project first.
+func (w *Workspace) parseStackReference(b backend.Backend, stackName string) (backend.StackReference, error) {
+	if _, ok := b.(httpstate.Backend); ok {
+		switch parts := strings.Split(stackName, "/"); len(parts) {
+		case 1:
+			owner, err := workspace.GetBackendConfigDefaultOrg()
+			if err != nil {
+				return nil, err
+			}
+			if owner == "" {
+				if owner, _, err = b.CurrentUser(); err != nil {
+					return nil, err
+				}
+			}
+			stackName = fmt.Sprintf("%s/%s/%s", owner, w.project.Name, stackName)
+		case 2:
+			stackName = fmt.Sprintf("%s/%s/%s", parts[0], w.project.Name, parts[1])
+		}
+	}
+	return b.ParseStackReference(stackName)
+}
+
+// getStack returns the stack with the given name, failing if it doesn't exist.
+func (w *Workspace) getStack(ctx context.Context, stackName string) (backend.Stack, error) {
+	b, err := w.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := w.parseStackReference(b, stackName)
+	if err != nil {
+		return nil, err
+	}
+	s, err := b.GetStack(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("no stack named '%s' found", stackName)
+	}
+	return s, nil
+}
+
+// stackSettingsPath returns the path of the settings file of the stack with the given name, which may not exist.
+func (w *Workspace) stackSettingsPath(stackName string) string {
+	parts := strings.Split(stackName, "/")
+	name := parts[len(parts)-1]
+	for _, ext := range settingsExtensions {
+		path := filepath.Join(w.workDir, fmt.Sprintf("Pulumi.%s%s", name, ext))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(w.workDir, fmt.Sprintf("Pulumi.%s.yaml", name))
+}
+
+// loadStackSettings loads the settings of the stack with the given name, which are empty if the stack has no
+// settings file, along with the path of the file.
+func (w *Workspace) loadStackSettings(stackName string) (*workspace.ProjectStack, string, error) {
+	path := w.stackSettingsPath(stackName)
+	ps, err := workspace.LoadProjectStack(path)
+	if err != nil {
+		return nil, path, fmt.Errorf("loading stack settings: %w", err)
+	}
+	return ps, path, nil
+}
+
+// parseConfigKey parses a configuration key, which is in the namespace of the project if it doesn't have one.
+func (w *Workspace) parseConfigKey(key string) (config.Key, error) {
+	if !strings.Contains(key, tokens.TokenDelimiter) {
+		key = fmt.Sprintf("%s:%s", w.project.Name, key)
+	}
+	return config.ParseKey(key)
+}
+
+// readProjectSettings reads the project settings in the given directory.
+func readProjectSettings(dir string) (*workspace.Project, error) {
+	for _, ext := range settingsExtensions {
+		path := filepath.Join(dir, fmt.Sprintf("Pulumi%s", ext))
+		if _, err := os.Stat(path); err == nil {
+			proj, err := workspace.LoadProject(path)
+			if err != nil {
+				return nil, fmt.Errorf("found project settings, but failed to load: %w", err)
+			}
+			return proj, nil
+		}
+	}
+	return nil, errors.New("unable to find project settings in workspace")
+}
@@ -88,13 +88,22 @@ func PreviewThenPrompt(ctx context.Context, kind apitype.UpdateKind, stack Stack
 	// Note that eventsChannel is not closed in a `defer`. It is generally unsafe to do so, since defers run during
 	// panics and we can't know whether or not we were in the middle of writing to this channel when the panic occurred.
 	//
-	// Instead of using a `defer`, we manually close `eventsChannel` on every exit of this function.
+	// Instead of using a `defer`, we manually close `eventsChannel` on every exit of this function, and wait for the
+	// goroutine below to finish forwarding the events to the caller.
 	eventsChannel := make(chan engine.Event)
+	eventsDone := make(chan bool)
+	closeEvents := func() {
+		close(eventsChannel)
+		<-eventsDone
+	}
 
 	var events []engine.Event
 	go func() {
 		// pull the events from the channel and store them locally
 		for e := range eventsChannel {
+			if op.Events != nil {
+				op.Events <- e
+			}
 			if e.Type == engine.ResourcePreEvent ||
 				e.Type == engine.ResourceOutputsEvent ||
 				e.Type == engine.SummaryEvent {
@@ -102,6 +111,7 @@ func PreviewThenPrompt(ctx context.Context, kind apitype.UpdateKind, stack Stack
 				events = append(events, e)
 			}
 		}
+		close(eventsDone)
 	}()
 
 	// Perform the update operations, passing true for dryRun, so that we get a preview.
@@ -115,19 +125,19 @@ func PreviewThenPrompt(ctx context.Context, kind apitype.UpdateKind, stack Stack
 
 	plan, changes, res := apply(ctx, kind, stack, op, opts, eventsChannel)
 	if res != nil {
-		close(eventsChannel)
+		closeEvents()
 		return plan, changes, res
 	}
 
 	// If there are no changes, or we're auto-approving or just previewing, we can skip the confirmation prompt.
 	if op.Opts.AutoApprove || kind == apitype.PreviewUpdate {
-		close(eventsChannel)
+		closeEvents()
 		return plan, changes, nil
 	}
 
 	// Otherwise, ensure the user wants to proceed.
+	closeEvents()
 	res = confirmBeforeUpdating(kind, stack, events, op.Opts)
-	close(eventsChannel)
 	return plan, changes, res
 }
 
@@ -221,12 +231,12 @@ func PreviewThenPromptThenExecute(ctx context.Context, kind apitype.UpdateKind,
 	}
 
 	// Perform the change (!DryRun) and show the cloud link to the result.
-	// We don't care about the events it issues, so just pass a nil channel along.
+	// We don't care about the events it issues, so just pass them along to the caller's channel, if any.
 	opts := ApplierOptions{
 		DryRun:   false,
 		ShowLink: true,
 	}
-	_, changes, res := apply(ctx, kind, stack, op, opts, nil /*events*/)
+	_, changes, res := apply(ctx, kind, stack, op, opts, op.Events)
 	return changes, res
 }
 
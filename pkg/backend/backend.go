@@ -219,6 +219,34 @@ type SpecificDeploymentExporter interface {
 	ExportDeploymentForVersion(ctx context.Context, stack Stack, version string) (*apitype.UntypedDeployment, error)
 }
 
+// HistoryRecordingDeploymentImporter is an interface defining an additional capability of a Backend, specifically
+// the ability to record the import of a stack's deployment in the stack's history, along with metadata describing
+// why it was imported. This isn't a requirement for all backends and should be checked for dynamically.
+type HistoryRecordingDeploymentImporter interface {
+	// ImportDeploymentWithMetadata imports the given deployment into the indicated stack, as ImportDeployment does,
+	// and records the import in the stack's history with the given metadata.
+	ImportDeploymentWithMetadata(ctx context.Context, stack Stack, deployment *apitype.UntypedDeployment,
+		m UpdateMetadata) error
+}
+
+// SnapshotPersistingBackend is an interface defining an additional capability of a Backend, specifically the ability
+// to persist a stack's snapshot outside of an update, as `pulumi state` edits do. This isn't a requirement for all
+// backends and should be checked for dynamically.
+type SnapshotPersistingBackend interface {
+	// SnapshotPersister returns a SnapshotPersister that saves snapshots of the indicated stack, encrypting their
+	// secrets with the given secrets manager.
+	SnapshotPersister(ctx context.Context, stack Stack, sm secrets.Manager) SnapshotPersister
+}
+
+// StackLockingBackend is an interface defining an additional capability of a Backend, specifically the ability to
+// hold a stack's lock while its state is read, edited and saved outside of an update, as `pulumi state` commands do.
+// This isn't a requirement for all backends and should be checked for dynamically.
+type StackLockingBackend interface {
+	// WithStackLock calls f while holding the lock of the indicated stack. While f runs, the backend's snapshot
+	// persisters and deployment imports for the stack don't try to take the lock again.
+	WithStackLock(ctx context.Context, stack Stack, f func() error) error
+}
+
 // UpdateOperation is a complete stack update operation (preview, update, import, refresh, or destroy).
 type UpdateOperation struct {
 	Proj               *workspace.Project
@@ -229,6 +257,9 @@ type UpdateOperation struct {
 	SecretsManager     secrets.Manager
 	StackConfiguration StackConfiguration
 	Scopes             CancellationScopeSource
+	// Events, if non-nil, receives the engine events of the operation, including those of its preview. The engine
+	// waits for each event to be received, so the channel must be drained until the operation completes.
+	Events chan<- engine.Event
 }
 
 // QueryOperation configures a query operation.
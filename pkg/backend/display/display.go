@@ -43,6 +43,11 @@ func ShowEvents(
 		events, done = startEventLogger(events, done, opts)
 	}
 
+	if opts.ReportFormat != "" {
+		ShowReport(action, stack, proj, events, done, opts, isPreview)
+		return
+	}
+
 	streamPreview := cmdutil.IsTruthy(os.Getenv("PULUMI_ENABLE_STREAMING_JSON_PREVIEW"))
 
 	if opts.JSONDisplay {
@@ -164,8 +169,9 @@ func shouldShow(step engine.StepEventMetadata, opts Options) bool {
 	}
 
 	// For logical replacement operations, only show them during progress-style updates (since this is integrated
-	// into the resource status update), or if it is requested explicitly (for diffs and JSON outputs).
-	if (opts.Type == DisplayDiff || opts.JSONDisplay) && !step.Logical && !opts.ShowReplacementSteps {
+	// into the resource status update), or if it is requested explicitly (for diffs, JSON outputs and reports).
+	machineReadable := opts.JSONDisplay || opts.ReportFormat != ""
+	if (opts.Type == DisplayDiff || machineReadable) && !step.Logical && !opts.ShowReplacementSteps {
 		return false
 	}
 
@@ -0,0 +1,309 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+// ReadEventLog reads the events of an event log, as written by `--event-log`.
+func ReadEventLog(r io.Reader) ([]apitype.EngineEvent, error) {
+	var events []apitype.EngineEvent
+	dec := json.NewDecoder(r)
+	for {
+		var event apitype.EngineEvent
+		if err := dec.Decode(&event); err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return nil, fmt.Errorf("decoding event %d: %w", len(events), err)
+		}
+		events = append(events, event)
+	}
+}
+
+// IsPreviewEventLog returns true if the given events were emitted by a preview.
+func IsPreviewEventLog(events []apitype.EngineEvent) bool {
+	for _, e := range events {
+		switch {
+		case e.ResourcePreEvent != nil:
+			return e.ResourcePreEvent.Planning
+		case e.ResOutputsEvent != nil:
+			return e.ResOutputsEvent.Planning
+		}
+	}
+	return false
+}
+
+// severityRanks orders diagnostic severities from least to most severe.
+var severityRanks = map[diag.Severity]int{
+	diag.Debug:   0,
+	diag.Info:    1,
+	diag.Infoerr: 2,
+	diag.Warning: 3,
+	diag.Error:   4,
+}
+
+// ParseSeverity parses the name of a diagnostic severity.
+func ParseSeverity(s string) (diag.Severity, error) {
+	sev := diag.Severity(strings.ToLower(s))
+	if _, ok := severityRanks[sev]; !ok {
+		return "", fmt.Errorf("unknown severity %q; expected one of debug, info, info#err, warning, error", s)
+	}
+	return sev, nil
+}
+
+// EventLogFilter selects the events of an event log that pertain to particular resources, steps or diagnostics. Events
+// that don't pertain to a resource, such as the prelude and summary, are always selected.
+type EventLogFilter struct {
+	// Targets selects the events of the resources that any of the targets select. A target is a URN or a target
+	// selector, as accepted by `--target`. If empty, the events of all resources are selected.
+	Targets []resource.URN
+	// Ops selects the events of the steps that perform any of the operations. If empty, all steps are selected.
+	Ops []apitype.OpType
+	// Severity is the least severe diagnostic to select. If empty, diagnostics of all severities are selected.
+	Severity diag.Severity
+}
+
+// IsZero returns true if the filter selects every event.
+func (f EventLogFilter) IsZero() bool {
+	return len(f.Targets) == 0 && len(f.Ops) == 0 && f.Severity == ""
+}
+
+// Apply returns the events that the filter selects.
+func (f EventLogFilter) Apply(events []apitype.EngineEvent) []apitype.EngineEvent {
+	if f.IsZero() {
+		return events
+	}
+
+	var matcher *deploy.TargetMatcher
+	if len(f.Targets) > 0 {
+		matcher = deploy.NewTargetMatcher(f.Targets)
+	}
+	ops := make(map[apitype.OpType]bool)
+	for _, op := range f.Ops {
+		ops[op] = true
+	}
+
+	// Resources are matched when their first step begins, which is the order in which they were registered, so that
+	// parents are seen before their children.
+	selected := make(map[string]bool)
+	selectResource := func(md apitype.StepEventMetadata) bool {
+		if matched, ok := selected[md.URN]; ok {
+			return matched
+		}
+		matched := true
+		if matcher != nil {
+			var parent string
+			if md.New != nil {
+				parent = md.New.Parent
+			} else if md.Old != nil {
+				parent = md.Old.Parent
+			}
+			matched = matcher.Matches(resource.URN(md.URN), tokens.Type(md.Type), resource.URN(parent))
+		}
+		selected[md.URN] = matched
+		return matched
+	}
+	// Diagnostics and policy violations that pertain to a resource are only selected if one of its steps is. Those that
+	// don't are only selected if no resources or steps are being filtered for.
+	shown := make(map[string]bool)
+	selectStep := func(md apitype.StepEventMetadata) bool {
+		if !selectResource(md) || len(ops) > 0 && !ops[md.Op] {
+			return false
+		}
+		shown[md.URN] = true
+		return true
+	}
+	selectURN := func(urn string) bool {
+		if urn == "" {
+			return matcher == nil && len(ops) == 0
+		}
+		return shown[urn]
+	}
+	minSeverity := severityRanks[f.Severity]
+
+	var filtered []apitype.EngineEvent
+	for _, e := range events {
+		var include bool
+		switch {
+		case e.ResourcePreEvent != nil:
+			include = selectStep(e.ResourcePreEvent.Metadata)
+		case e.ResOutputsEvent != nil:
+			include = selectStep(e.ResOutputsEvent.Metadata)
+		case e.ResOpFailedEvent != nil:
+			include = selectStep(e.ResOpFailedEvent.Metadata)
+		case e.DiagnosticEvent != nil:
+			severity := diag.Severity(e.DiagnosticEvent.Severity)
+			include = selectURN(e.DiagnosticEvent.URN) && severityRanks[severity] >= minSeverity
+		case e.PolicyEvent != nil:
+			include = selectURN(e.PolicyEvent.ResourceURN)
+		default:
+			include = true
+		}
+		if include {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// EventLogStats are statistics about the resource operations recorded in an event log. Event timestamps have a
+// resolution of one second, so durations are measured in seconds.
+type EventLogStats struct {
+	// DurationSeconds is the time between the first and last events.
+	DurationSeconds int `json:"durationSeconds"`
+	// Resources are the timings of the custom resources' steps, slowest first.
+	Resources []ResourceTiming `json:"resources"`
+	// Providers are the total timings of the steps performed by each provider, slowest first.
+	Providers []ProviderTiming `json:"providers"`
+	// Failures are the steps that failed, in the order in which they failed.
+	Failures []ResourceFailure `json:"failures"`
+}
+
+// ResourceTiming is how long a step took.
+type ResourceTiming struct {
+	URN             resource.URN   `json:"urn"`
+	Op              apitype.OpType `json:"op"`
+	Provider        string         `json:"provider"`
+	DurationSeconds int            `json:"durationSeconds"`
+	Failed          bool           `json:"failed,omitempty"`
+}
+
+// ProviderTiming is how long the steps performed by a provider took altogether.
+type ProviderTiming struct {
+	Provider        string `json:"provider"`
+	Steps           int    `json:"steps"`
+	DurationSeconds int    `json:"durationSeconds"`
+}
+
+// ResourceFailure is a step that failed, along with the errors reported for its resource.
+type ResourceFailure struct {
+	URN    resource.URN   `json:"urn"`
+	Op     apitype.OpType `json:"op"`
+	Errors []string       `json:"errors,omitempty"`
+}
+
+// providerName returns a short name for the provider with the given reference, such as "aws::default_5_4_0".
+func providerName(ref string) string {
+	if ref == "" {
+		return "(none)"
+	}
+	parsed, err := providers.ParseReference(ref)
+	if err != nil {
+		return ref
+	}
+	urn := parsed.URN()
+	return fmt.Sprintf("%s::%s", providers.GetProviderPackage(urn.Type()), urn.Name())
+}
+
+// ComputeEventLogStats computes statistics about the resource operations recorded in the given events.
+func ComputeEventLogStats(events []apitype.EngineEvent) EventLogStats {
+	stats := EventLogStats{
+		Resources: []ResourceTiming{},
+		Providers: []ProviderTiming{},
+		Failures:  []ResourceFailure{},
+	}
+	if len(events) == 0 {
+		return stats
+	}
+	stats.DurationSeconds = events[len(events)-1].Timestamp - events[0].Timestamp
+
+	type pendingStep struct {
+		md    apitype.StepEventMetadata
+		start int
+	}
+	pending := make(map[string]pendingStep)
+	errors := make(map[string][]string)
+	byProvider := make(map[string]*ProviderTiming)
+
+	finish := func(md apitype.StepEventMetadata, end int, failed bool) {
+		p, ok := pending[md.URN]
+		if !ok {
+			return
+		}
+		delete(pending, md.URN)
+
+		provider := providerName(p.md.Provider)
+		duration := end - p.start
+		stats.Resources = append(stats.Resources, ResourceTiming{
+			URN:             resource.URN(md.URN),
+			Op:              p.md.Op,
+			Provider:        provider,
+			DurationSeconds: duration,
+			Failed:          failed,
+		})
+		t, has := byProvider[provider]
+		if !has {
+			t = &ProviderTiming{Provider: provider}
+			byProvider[provider] = t
+		}
+		t.Steps++
+		t.DurationSeconds += duration
+	}
+
+	for _, e := range events {
+		switch {
+		case e.ResourcePreEvent != nil:
+			md := e.ResourcePreEvent.Metadata
+			// Component resources only report outputs once all of their children are done, so they aren't timed.
+			custom := (md.New != nil && md.New.Custom) || (md.Old != nil && md.Old.Custom)
+			if custom && md.Op != apitype.OpSame {
+				pending[md.URN] = pendingStep{md: md, start: e.Timestamp}
+			}
+		case e.ResOutputsEvent != nil:
+			finish(e.ResOutputsEvent.Metadata, e.Timestamp, false)
+		case e.ResOpFailedEvent != nil:
+			md := e.ResOpFailedEvent.Metadata
+			finish(md, e.Timestamp, true)
+			stats.Failures = append(stats.Failures, ResourceFailure{
+				URN:    resource.URN(md.URN),
+				Op:     md.Op,
+				Errors: errors[md.URN],
+			})
+		case e.DiagnosticEvent != nil:
+			d := e.DiagnosticEvent
+			if d.URN != "" && diag.Severity(d.Severity) == diag.Error {
+				errors[d.URN] = append(errors[d.URN], strings.TrimSpace(colors.Never.Colorize(d.Message)))
+			}
+		}
+	}
+
+	sort.SliceStable(stats.Resources, func(i, j int) bool {
+		return stats.Resources[i].DurationSeconds > stats.Resources[j].DurationSeconds
+	})
+	for _, t := range byProvider {
+		stats.Providers = append(stats.Providers, *t)
+	}
+	sort.Slice(stats.Providers, func(i, j int) bool {
+		if stats.Providers[i].DurationSeconds != stats.Providers[j].DurationSeconds {
+			return stats.Providers[i].DurationSeconds > stats.Providers[j].DurationSeconds
+		}
+		return stats.Providers[i].Provider < stats.Providers[j].Provider
+	})
+	return stats
+}
@@ -0,0 +1,116 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+func readTestEventLog(t *testing.T, path string) []apitype.EngineEvent {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer contract.IgnoreClose(f)
+
+	events, err := ReadEventLog(f)
+	require.NoError(t, err)
+	return events
+}
+
+func stepURNs(events []apitype.EngineEvent) []string {
+	var urns []string
+	for _, e := range events {
+		if e.ResourcePreEvent != nil {
+			urns = append(urns, e.ResourcePreEvent.Metadata.URN)
+		}
+	}
+	return urns
+}
+
+func TestEventLogFilter(t *testing.T) {
+	t.Parallel()
+
+	events := readTestEventLog(t, "testdata/up-3.json")
+	assert.False(t, IsPreviewEventLog(events))
+	assert.True(t, IsPreviewEventLog(readTestEventLog(t, "testdata/webserver-userdata.json")))
+
+	assert.Equal(t, events, EventLogFilter{}.Apply(events))
+
+	filtered := EventLogFilter{Targets: []resource.URN{"type:aws:iam**"}}.Apply(events)
+	assert.Equal(t, []string{
+		"urn:pulumi:dev::eks::aws:iam/role:Role::eks-role",
+		"urn:pulumi:dev::eks::aws:iam/rolePolicyAttachment:RolePolicyAttachment::eks-rpa-service-policy",
+		"urn:pulumi:dev::eks::aws:iam/rolePolicyAttachment:RolePolicyAttachment::eks-rpa-cluster-policy",
+	}, stepURNs(filtered))
+	// The summary is always selected.
+	hasSummary := false
+	for _, e := range filtered {
+		hasSummary = hasSummary || e.SummaryEvent != nil
+	}
+	assert.True(t, hasSummary)
+
+	filtered = EventLogFilter{Ops: []apitype.OpType{apitype.OpUpdate}}.Apply(events)
+	assert.Empty(t, stepURNs(filtered))
+
+	filtered = EventLogFilter{Severity: diag.Warning}.Apply(events)
+	for _, e := range filtered {
+		if e.DiagnosticEvent != nil {
+			assert.Contains(t, []string{"warning", "error"}, e.DiagnosticEvent.Severity)
+		}
+	}
+	assert.Equal(t, stepURNs(events), stepURNs(filtered))
+}
+
+func TestEventLogStats(t *testing.T) {
+	t.Parallel()
+
+	events := readTestEventLog(t, "testdata/up-3.json")
+	stats := ComputeEventLogStats(events)
+	assert.Equal(t, 502, stats.DurationSeconds)
+	require.Len(t, stats.Resources, 5)
+	assert.Equal(t, resource.URN("urn:pulumi:dev::eks::aws:eks/cluster:Cluster::eks-cluster"), stats.Resources[0].URN)
+	assert.Equal(t, 495, stats.Resources[0].DurationSeconds)
+	assert.Equal(t, []ProviderTiming{{Provider: "aws::default_4_36_0", Steps: 5, DurationSeconds: 503}}, stats.Providers)
+	assert.Empty(t, stats.Failures)
+
+	// Fail the creation of a resource.
+	md := apitype.StepEventMetadata{
+		Op:       apitype.OpCreate,
+		URN:      "urn:pulumi:dev::eks::aws:s3/bucket:Bucket::b",
+		Type:     "aws:s3/bucket:Bucket",
+		New:      &apitype.StepEventStateMetadata{Custom: true},
+		Provider: "urn:pulumi:dev::eks::pulumi:providers:aws::default_4_36_0::id",
+	}
+	events = []apitype.EngineEvent{
+		{Timestamp: 10, ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: md}},
+		{Timestamp: 12, DiagnosticEvent: &apitype.DiagnosticEvent{URN: md.URN, Message: "bucket exists", Severity: "error"}},
+		{Timestamp: 13, ResOpFailedEvent: &apitype.ResOpFailedEvent{Metadata: md}},
+	}
+	stats = ComputeEventLogStats(events)
+	assert.Equal(t, []ResourceTiming{{
+		URN: resource.URN(md.URN), Op: apitype.OpCreate, Provider: "aws::default_4_36_0", DurationSeconds: 3, Failed: true,
+	}}, stats.Resources)
+	assert.Equal(t, []ResourceFailure{{
+		URN: resource.URN(md.URN), Op: apitype.OpCreate, Errors: []string{"bucket exists"},
+	}}, stats.Failures)
+}
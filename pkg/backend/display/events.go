@@ -103,6 +103,7 @@ func ConvertEngineEvent(e engine.Event, showSecrets bool) (apitype.EngineEvent,
 			DurationSeconds: int(p.Duration.Seconds()),
 			ResourceChanges: changes,
 			PolicyPacks:     p.PolicyPacks,
+			StepTimings:     ConvertStepTimings(p.StepTimings),
 		}
 
 	case engine.ResourcePreEvent:
@@ -124,6 +125,10 @@ func ConvertEngineEvent(e engine.Event, showSecrets bool) (apitype.EngineEvent,
 			Metadata: convertStepEventMetadata(p.Metadata, showSecrets),
 			Planning: p.Planning,
 		}
+		if p.Timing != nil {
+			apiEvent.ResOutputsEvent.StartTime = p.Timing.Start.UnixMilli()
+			apiEvent.ResOutputsEvent.EndTime = p.Timing.End.UnixMilli()
+		}
 
 	case engine.ResourceOperationFailed:
 		p, ok := e.Payload().(engine.ResourceOperationFailedPayload)
@@ -135,6 +140,10 @@ func ConvertEngineEvent(e engine.Event, showSecrets bool) (apitype.EngineEvent,
 			Status:   int(p.Status),
 			Steps:    p.Steps,
 		}
+		if p.Timing != nil {
+			apiEvent.ResOpFailedEvent.StartTime = p.Timing.Start.UnixMilli()
+			apiEvent.ResOpFailedEvent.EndTime = p.Timing.End.UnixMilli()
+		}
 
 	default:
 		return apiEvent, fmt.Errorf("unknown event type %q", e.Type)
@@ -143,6 +152,24 @@ func ConvertEngineEvent(e engine.Event, showSecrets bool) (apitype.EngineEvent,
 	return apiEvent, nil
 }
 
+// ConvertStepTimings converts the step timings recorded by the engine into their API representation.
+func ConvertStepTimings(timings []engine.StepTiming) []apitype.StepTiming {
+	if len(timings) == 0 {
+		return nil
+	}
+	result := make([]apitype.StepTiming, len(timings))
+	for i, t := range timings {
+		result[i] = apitype.StepTiming{
+			URN:       string(t.URN),
+			Op:        apitype.OpType(t.Op),
+			StartTime: t.Start.UnixMilli(),
+			EndTime:   t.End.UnixMilli(),
+			Failed:    t.Failed,
+		}
+	}
+	return result
+}
+
 func convertStepEventMetadata(md engine.StepEventMetadata, showSecrets bool) apitype.StepEventMetadata {
 	keys := make([]string, len(md.Keys))
 	for i, v := range md.Keys {
@@ -293,6 +320,7 @@ func ConvertJSONEvent(apiEvent apitype.EngineEvent) (engine.Event, error) {
 			Duration:        time.Duration(p.DurationSeconds) * time.Second,
 			ResourceChanges: changes,
 			PolicyPacks:     p.PolicyPacks,
+			StepTimings:     convertJSONStepTimings(p.StepTimings),
 		})
 
 	case apiEvent.ResourcePreEvent != nil:
@@ -306,6 +334,7 @@ func ConvertJSONEvent(apiEvent apitype.EngineEvent) (engine.Event, error) {
 		p := apiEvent.ResOutputsEvent
 		event = engine.NewEvent(engine.ResourceOutputsEvent, engine.ResourceOutputsEventPayload{
 			Metadata: convertJSONStepEventMetadata(p.Metadata),
+			Timing:   convertJSONStepTiming(p.Metadata, p.StartTime, p.EndTime, false),
 			Planning: p.Planning,
 		})
 
@@ -315,6 +344,7 @@ func ConvertJSONEvent(apiEvent apitype.EngineEvent) (engine.Event, error) {
 			Metadata: convertJSONStepEventMetadata(p.Metadata),
 			Status:   resource.Status(p.Status),
 			Steps:    p.Steps,
+			Timing:   convertJSONStepTiming(p.Metadata, p.StartTime, p.EndTime, true),
 		})
 
 	default:
@@ -324,6 +354,37 @@ func ConvertJSONEvent(apiEvent apitype.EngineEvent) (engine.Event, error) {
 	return event, nil
 }
 
+func convertJSONStepTimings(timings []apitype.StepTiming) []engine.StepTiming {
+	if len(timings) == 0 {
+		return nil
+	}
+	result := make([]engine.StepTiming, len(timings))
+	for i, t := range timings {
+		result[i] = engine.StepTiming{
+			URN:    resource.URN(t.URN),
+			Op:     display.StepOp(t.Op),
+			Start:  time.UnixMilli(t.StartTime),
+			End:    time.UnixMilli(t.EndTime),
+			Failed: t.Failed,
+		}
+	}
+	return result
+}
+
+// convertJSONStepTiming returns the timing of the step described by the given metadata, or nil if it wasn't timed.
+func convertJSONStepTiming(md apitype.StepEventMetadata, start, end int64, failed bool) *engine.StepTiming {
+	if start == 0 {
+		return nil
+	}
+	return &engine.StepTiming{
+		URN:    resource.URN(md.URN),
+		Op:     display.StepOp(md.Op),
+		Start:  time.UnixMilli(start),
+		End:    time.UnixMilli(end),
+		Failed: failed,
+	}
+}
+
 func convertJSONStepEventMetadata(md apitype.StepEventMetadata) engine.StepEventMetadata {
 	keys := make([]resource.PropertyKey, len(md.Keys))
 	for i, v := range md.Keys {
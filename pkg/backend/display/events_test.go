@@ -0,0 +1,74 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestConvertStepTimings(t *testing.T) {
+	t.Parallel()
+
+	urn := resource.URN("urn:pulumi:dev::proj::test:index:Resource::a")
+	start := time.UnixMilli(1650000000123)
+	timing := engine.StepTiming{
+		URN:    urn,
+		Op:     display.StepOp(apitype.OpCreate),
+		Start:  start,
+		End:    start.Add(1500 * time.Millisecond),
+		Failed: true,
+	}
+
+	summary := engine.NewEvent(engine.SummaryEvent, engine.SummaryEventPayload{
+		Duration:    2 * time.Second,
+		StepTimings: []engine.StepTiming{timing},
+	})
+	apiEvent, err := ConvertEngineEvent(summary, false)
+	require.NoError(t, err)
+	assert.Equal(t, []apitype.StepTiming{{
+		URN:       string(urn),
+		Op:        apitype.OpCreate,
+		StartTime: 1650000000123,
+		EndTime:   1650000001623,
+		Failed:    true,
+	}}, apiEvent.SummaryEvent.StepTimings)
+
+	converted, err := ConvertJSONEvent(apiEvent)
+	require.NoError(t, err)
+	p := converted.Payload().(engine.SummaryEventPayload)
+	require.Len(t, p.StepTimings, 1)
+	assert.Equal(t, 1500*time.Millisecond, p.StepTimings[0].Duration())
+	assert.True(t, p.StepTimings[0].Failed)
+
+	// Events that weren't timed round trip without a timing.
+	apiEvent, err = ConvertEngineEvent(engine.NewEvent(engine.SummaryEvent, engine.SummaryEventPayload{}), false)
+	require.NoError(t, err)
+	assert.Nil(t, apiEvent.SummaryEvent.StepTimings)
+	failed := apitype.EngineEvent{ResOpFailedEvent: &apitype.ResOpFailedEvent{
+		Metadata: apitype.StepEventMetadata{URN: string(urn), Op: apitype.OpCreate},
+	}}
+	converted, err = ConvertJSONEvent(failed)
+	require.NoError(t, err)
+	assert.Nil(t, converted.Payload().(engine.ResourceOperationFailedPayload).Timing)
+}
@@ -0,0 +1,347 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/logging"
+)
+
+// ReportFormat is the format of a report that summarizes an operation once it completes.
+type ReportFormat string
+
+const (
+	// ReportJSON is a JSON document that follows a stable, versioned schema.
+	ReportJSON ReportFormat = "json"
+	// ReportMarkdown is a Markdown change summary, suitable for pull request comments.
+	ReportMarkdown ReportFormat = "markdown"
+	// ReportJUnit is a JUnit XML report of the operation's resource steps and policy violations.
+	ReportJUnit ReportFormat = "junit"
+)
+
+// ReportRenderer writes a report in a particular format.
+type ReportRenderer func(w io.Writer, report *Report) error
+
+var reportRenderers = map[ReportFormat]ReportRenderer{
+	ReportJSON:     renderJSONReport,
+	ReportMarkdown: renderMarkdownReport,
+	ReportJUnit:    renderJUnitReport,
+}
+
+// RegisterReportRenderer registers the renderer to use for reports in the given format, replacing any renderer
+// already registered for it.
+func RegisterReportRenderer(format ReportFormat, renderer ReportRenderer) {
+	contract.Require(format != "", "format")
+	contract.Require(renderer != nil, "renderer")
+	reportRenderers[format] = renderer
+}
+
+// ReportFormats returns the formats that reports can be rendered in, sorted by name.
+func ReportFormats() []ReportFormat {
+	formats := make([]ReportFormat, 0, len(reportRenderers))
+	for format := range reportRenderers {
+		formats = append(formats, format)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+	return formats
+}
+
+// ParseReportFormat parses the name of a report format, returning an error if no renderer exists for it.
+func ParseReportFormat(s string) (ReportFormat, error) {
+	format := ReportFormat(strings.ToLower(s))
+	if _, ok := reportRenderers[format]; ok {
+		return format, nil
+	}
+
+	names := make([]string, 0, len(reportRenderers))
+	for _, format := range ReportFormats() {
+		names = append(names, string(format))
+	}
+	return "", fmt.Errorf("unknown output format %q; expected one of %s", s, strings.Join(names, ", "))
+}
+
+// ReportSchemaVersion is the version of the schema of JSON reports. Fields may be added to the schema without
+// changing its version, but the version is incremented whenever an existing field is removed or changes meaning.
+const ReportSchemaVersion = 1
+
+// ReportResult is the outcome of the operation that a report describes.
+type ReportResult string
+
+const (
+	ReportSucceeded ReportResult = "succeeded"
+	ReportFailed    ReportResult = "failed"
+)
+
+// ReportStepStatus is the status of a resource step in a report.
+type ReportStepStatus string
+
+const (
+	// StepPlanned is the status of the steps of a preview.
+	StepPlanned ReportStepStatus = "planned"
+	// StepPending is the status of steps that were started but did not complete.
+	StepPending   ReportStepStatus = "pending"
+	StepSucceeded ReportStepStatus = "succeeded"
+	StepFailed    ReportStepStatus = "failed"
+)
+
+// Report summarizes the resource steps, policy violations and diagnostics of an operation.
+type Report struct {
+	// SchemaVersion is the version of the schema of this report. See ReportSchemaVersion.
+	SchemaVersion int `json:"schemaVersion"`
+	// Kind is the kind of operation, such as "update" or "destroy". It is "preview" for `pulumi preview`.
+	Kind apitype.UpdateKind `json:"kind"`
+	// Preview is true if the operation was a preview, in which case no resources were changed.
+	Preview bool `json:"preview"`
+	// Project is the name of the project.
+	Project string `json:"project"`
+	// Stack is the name of the stack.
+	Stack string `json:"stack"`
+	// Result is the outcome of the operation.
+	Result ReportResult `json:"result"`
+	// DurationSeconds is how long the operation took. It is zero for previews.
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	// ChangeSummary is the number of resources affected by each kind of operation.
+	ChangeSummary map[string]int `json:"changeSummary,omitempty"`
+	// PolicyPacks is the version of each policy pack that was run.
+	PolicyPacks map[string]string `json:"policyPacks,omitempty"`
+	// Steps are the resource steps that were planned or performed, in the order they were started.
+	Steps []*ReportStep `json:"steps"`
+	// PolicyViolations are the policy violations that were reported.
+	PolicyViolations []ReportPolicyViolation `json:"policyViolations"`
+	// Diagnostics are the errors, warnings and informational messages that were reported.
+	Diagnostics []ReportDiagnostic `json:"diagnostics"`
+
+	steps     map[reportStepKey]*ReportStep
+	lastSteps map[resource.URN]*ReportStep
+	complete  bool
+}
+
+// reportStepKey identifies a step in a report. A resource may have several steps, such as the create-replacement and
+// delete-replaced steps of a replacement, but only one of each kind.
+type reportStepKey struct {
+	urn resource.URN
+	op  display.StepOp
+}
+
+// ReportStep is a step planned or performed on a resource.
+type ReportStep struct {
+	Op             string           `json:"op"`
+	URN            resource.URN     `json:"urn"`
+	Type           tokens.Type      `json:"type"`
+	Name           tokens.QName     `json:"name"`
+	Provider       string           `json:"provider,omitempty"`
+	Diffs          []string         `json:"diffs,omitempty"`
+	ReplaceReasons []string         `json:"replaceReasons,omitempty"`
+	Status         ReportStepStatus `json:"status"`
+	// Errors are the error messages reported for the resource, if the step failed.
+	Errors []string `json:"errors,omitempty"`
+
+	custom bool
+}
+
+// ReportPolicyViolation is a policy violation reported by a policy pack.
+type ReportPolicyViolation struct {
+	PolicyPack        string                   `json:"policyPack"`
+	PolicyPackVersion string                   `json:"policyPackVersion,omitempty"`
+	Policy            string                   `json:"policy"`
+	EnforcementLevel  apitype.EnforcementLevel `json:"enforcementLevel"`
+	URN               resource.URN             `json:"urn,omitempty"`
+	Message           string                   `json:"message"`
+}
+
+// ReportDiagnostic is a message reported by the engine, a provider or the program.
+type ReportDiagnostic struct {
+	Severity diag.Severity `json:"severity"`
+	URN      resource.URN  `json:"urn,omitempty"`
+	Message  string        `json:"message"`
+}
+
+// NewReport creates an empty report of the given operation.
+func NewReport(kind apitype.UpdateKind, stack tokens.Name, proj tokens.PackageName, isPreview bool) *Report {
+	return &Report{
+		SchemaVersion:    ReportSchemaVersion,
+		Kind:             kind,
+		Preview:          isPreview,
+		Project:          string(proj),
+		Stack:            string(stack),
+		Result:           ReportSucceeded,
+		Steps:            []*ReportStep{},
+		PolicyViolations: []ReportPolicyViolation{},
+		Diagnostics:      []ReportDiagnostic{},
+		steps:            make(map[reportStepKey]*ReportStep),
+		lastSteps:        make(map[resource.URN]*ReportStep),
+	}
+}
+
+// AddEvent records the given engine event in the report.
+func (r *Report) AddEvent(e engine.Event, opts Options) {
+	switch e.Type {
+	case engine.DiagEvent:
+		p := e.Payload().(engine.DiagEventPayload)
+		if p.Ephemeral || p.Severity == diag.Debug {
+			return
+		}
+		msg := strings.TrimSpace(colors.Never.Colorize(p.Prefix + p.Message))
+		r.Diagnostics = append(r.Diagnostics, ReportDiagnostic{Severity: p.Severity, URN: p.URN, Message: msg})
+		if p.Severity == diag.Error {
+			r.fail()
+			if step, ok := r.lastSteps[p.URN]; ok {
+				step.Errors = append(step.Errors, strings.TrimSpace(colors.Never.Colorize(p.Message)))
+			}
+		}
+	case engine.PolicyViolationEvent:
+		p := e.Payload().(engine.PolicyViolationEventPayload)
+		r.PolicyViolations = append(r.PolicyViolations, ReportPolicyViolation{
+			PolicyPack:        p.PolicyPackName,
+			PolicyPackVersion: p.PolicyPackVersion,
+			Policy:            p.PolicyName,
+			EnforcementLevel:  p.EnforcementLevel,
+			URN:               p.ResourceURN,
+			Message:           strings.TrimSpace(colors.Never.Colorize(p.Message)),
+		})
+		if p.EnforcementLevel == apitype.Mandatory {
+			r.fail()
+		}
+	case engine.ResourcePreEvent:
+		m := e.Payload().(engine.ResourcePreEventPayload).Metadata
+		if !shouldShow(m, opts) {
+			return
+		}
+		step := &ReportStep{
+			Op:             string(m.Op),
+			URN:            m.URN,
+			Type:           m.URN.Type(),
+			Name:           m.URN.Name(),
+			Provider:       m.Provider,
+			Diffs:          propertyKeyStrings(m.Diffs),
+			ReplaceReasons: propertyKeyStrings(m.Keys),
+			Status:         StepPending,
+			custom:         m.Res != nil && m.Res.Custom,
+		}
+		if r.Preview {
+			step.Status = StepPlanned
+		}
+		r.Steps = append(r.Steps, step)
+		r.steps[reportStepKey{urn: m.URN, op: m.Op}] = step
+		r.lastSteps[m.URN] = step
+	case engine.ResourceOutputsEvent:
+		m := e.Payload().(engine.ResourceOutputsEventPayload).Metadata
+		if step, ok := r.steps[reportStepKey{urn: m.URN, op: m.Op}]; ok && step.Status == StepPending {
+			step.Status = StepSucceeded
+		}
+	case engine.ResourceOperationFailed:
+		m := e.Payload().(engine.ResourceOperationFailedPayload).Metadata
+		if step, ok := r.steps[reportStepKey{urn: m.URN, op: m.Op}]; ok {
+			step.Status = StepFailed
+		}
+		r.fail()
+	case engine.SummaryEvent:
+		p := e.Payload().(engine.SummaryEventPayload)
+		r.complete = true
+		r.DurationSeconds = p.Duration.Round(time.Millisecond).Seconds()
+		r.PolicyPacks = p.PolicyPacks
+		r.ChangeSummary = make(map[string]int)
+		for op, count := range p.ResourceChanges {
+			if count > 0 {
+				r.ChangeSummary[string(op)] = count
+			}
+		}
+		// Component resources don't report outputs when their steps complete, so assume that they succeeded.
+		for _, step := range r.Steps {
+			if step.Status == StepPending && !step.custom {
+				step.Status = StepSucceeded
+			}
+		}
+	}
+}
+
+func (r *Report) fail() {
+	if r.Result == ReportSucceeded {
+		r.Result = ReportFailed
+	}
+}
+
+func propertyKeyStrings(keys []resource.PropertyKey) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+	strs := make([]string, len(keys))
+	for i, k := range keys {
+		strs[i] = string(k)
+	}
+	return strs
+}
+
+// ShowReport collects engine events into a report, which is rendered in the format given by opts.ReportFormat once
+// the event stream is closed. The previews that run before an update or destroy are only rendered if they fail, as
+// the update itself is reported otherwise.
+func ShowReport(action apitype.UpdateKind, stack tokens.Name, proj tokens.PackageName,
+	events <-chan engine.Event, done chan<- bool, opts Options, isPreview bool) {
+
+	// Ensure we close the done channel before exiting.
+	defer func() { close(done) }()
+
+	render, ok := reportRenderers[opts.ReportFormat]
+	contract.Assertf(ok, "unknown report format '%s'", opts.ReportFormat)
+
+	report := NewReport(action, stack, proj, isPreview)
+	for e := range events {
+		report.AddEvent(e, opts)
+
+		// In the event of cancellation, break out of the loop immediately.
+		if e.Type == engine.CancelEvent {
+			break
+		}
+	}
+	// Operations that fail before they start, for instance because the program's language host can't be loaded,
+	// end without a summary.
+	if !report.complete {
+		report.fail()
+	}
+
+	if isPreview && action != apitype.PreviewUpdate && report.Result == ReportSucceeded {
+		return
+	}
+
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if err := render(stdout, report); err != nil {
+		logging.V(7).Infof("failed to render %s report: %v", opts.ReportFormat, err)
+	}
+}
+
+func renderJSONReport(w io.Writer, report *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(report)
+}
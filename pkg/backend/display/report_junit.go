@@ -0,0 +1,145 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (s *junitTestSuite) add(c junitTestCase) {
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	if c.Failure != nil {
+		s.Failures++
+	}
+}
+
+// renderJUnitReport renders a report as JUnit XML. Each resource step is a test case of the "resources" suite, which
+// fails if the step failed, and each policy violation is a test case of the "policies" suite, which fails if the
+// policy is mandatory. Errors that aren't reported for a particular resource, and failures that no step or policy
+// accounts for, fail the "operation" suite.
+func renderJUnitReport(w io.Writer, report *Report) error {
+	kind := string(report.Kind)
+	if report.Preview && report.Kind != apitype.PreviewUpdate {
+		kind += " preview"
+	}
+
+	resources := junitTestSuite{Name: "resources"}
+	for _, step := range report.Steps {
+		c := junitTestCase{
+			ClassName: string(step.Type),
+			Name:      fmt.Sprintf("%s %s", step.Op, step.URN),
+		}
+		if step.Status == StepFailed {
+			c.Failure = &junitFailure{
+				Type:    string(step.Status),
+				Message: fmt.Sprintf("failed to %s %s", step.Op, step.Name),
+				Text:    strings.Join(step.Errors, "\n"),
+			}
+		}
+		resources.add(c)
+	}
+
+	policies := junitTestSuite{Name: "policies"}
+	for _, v := range report.PolicyViolations {
+		c := junitTestCase{
+			ClassName: v.PolicyPack,
+			Name:      v.Policy,
+		}
+		if v.URN != "" {
+			c.Name += " " + string(v.URN)
+		}
+		if v.EnforcementLevel == apitype.Mandatory {
+			c.Failure = &junitFailure{Type: string(v.EnforcementLevel), Message: v.Policy, Text: v.Message}
+		} else {
+			c.SystemOut = v.Message
+		}
+		policies.add(c)
+	}
+
+	operation := junitTestSuite{Name: "operation"}
+	var errors []string
+	for _, d := range report.Diagnostics {
+		if d.Severity == diag.Error && (d.URN == "" || report.lastSteps[d.URN] == nil) {
+			errors = append(errors, d.Message)
+		}
+	}
+	opCase := junitTestCase{ClassName: "pulumi", Name: kind}
+	unexplained := report.Result == ReportFailed && resources.Failures == 0 && policies.Failures == 0
+	if len(errors) > 0 || unexplained {
+		opCase.Failure = &junitFailure{
+			Type:    string(report.Result),
+			Message: fmt.Sprintf("the %s %s", kind, report.Result),
+			Text:    strings.Join(errors, "\n"),
+		}
+	}
+	operation.add(opCase)
+
+	suites := junitTestSuites{
+		Name:   fmt.Sprintf("pulumi %s %s/%s", kind, report.Project, report.Stack),
+		Time:   report.DurationSeconds,
+		Suites: []junitTestSuite{operation, resources, policies},
+	}
+	for _, s := range suites.Suites {
+		suites.Tests += s.Tests
+		suites.Failures += s.Failures
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
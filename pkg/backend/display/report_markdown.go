@@ -0,0 +1,125 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+)
+
+// renderMarkdownReport renders a report as a Markdown change summary, suitable for pull request comments.
+func renderMarkdownReport(w io.Writer, report *Report) error {
+	out := &bytes.Buffer{}
+
+	title := string(report.Kind)
+	if report.Preview && report.Kind != apitype.PreviewUpdate {
+		title = string(report.Kind) + " preview"
+	}
+	fprintfIgnoreError(out, "### Pulumi %s of `%s/%s`: %s\n\n", title, report.Project, report.Stack, report.Result)
+
+	fprintfIgnoreError(out, "**Resources:** %s\n", markdownChangeSummary(report))
+	if report.DurationSeconds > 0 {
+		fprintfIgnoreError(out, "\n**Duration:** %gs\n", report.DurationSeconds)
+	}
+
+	if len(report.Steps) > 0 {
+		fprintIgnoreError(out, "\n#### Resources\n\n")
+		if report.Preview {
+			fprintIgnoreError(out, "| Operation | Name | Type | Changed properties |\n|---|---|---|---|\n")
+		} else {
+			fprintIgnoreError(out, "| Operation | Name | Type | Changed properties | Status |\n|---|---|---|---|---|\n")
+		}
+		for _, step := range report.Steps {
+			diffs := make([]string, 0, len(step.Diffs))
+			for _, k := range step.Diffs {
+				diffs = append(diffs, "`"+k+"`")
+			}
+			fprintfIgnoreError(out, "| %s | %s | `%s` | %s |",
+				step.Op, markdownCell(string(step.Name)), step.Type, strings.Join(diffs, ", "))
+			if !report.Preview {
+				fprintfIgnoreError(out, " %s |", step.Status)
+			}
+			fprintIgnoreError(out, "\n")
+		}
+	}
+
+	if len(report.PolicyViolations) > 0 {
+		fprintIgnoreError(out, "\n#### Policy violations\n\n")
+		fprintIgnoreError(out, "| Level | Policy | Resource | Message |\n|---|---|---|---|\n")
+		for _, v := range report.PolicyViolations {
+			var name string
+			if v.URN != "" {
+				name = string(v.URN.Name())
+			}
+			fprintfIgnoreError(out, "| %s | %s | %s | %s |\n", v.EnforcementLevel,
+				markdownCell(v.PolicyPack+"/"+v.Policy), markdownCell(name), markdownCell(v.Message))
+		}
+	}
+
+	// Only errors and warnings are worth the attention of a reviewer.
+	var diags []string
+	for _, d := range report.Diagnostics {
+		if d.Severity == diag.Error || d.Severity == diag.Warning {
+			diags = append(diags, d.Message)
+		}
+	}
+	if len(diags) > 0 {
+		fprintIgnoreError(out, "\n#### Diagnostics\n\n```\n")
+		for _, msg := range diags {
+			fprintfIgnoreError(out, "%s\n", msg)
+		}
+		fprintIgnoreError(out, "```\n")
+	}
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// markdownChangeSummary returns a one-line summary of the number of resources affected by each kind of operation.
+func markdownChangeSummary(report *Report) string {
+	var pieces []string
+	for _, op := range deploy.StepOps {
+		// Reads are not changes to the system, and sames are listed last.
+		if op == deploy.OpSame || op == deploy.OpRead || op == deploy.OpReadDiscard || op == deploy.OpReadReplacement {
+			continue
+		}
+		if c := report.ChangeSummary[string(op)]; c > 0 {
+			if report.Preview {
+				pieces = append(pieces, fmt.Sprintf("%d to %s", c, op))
+			} else {
+				pieces = append(pieces, fmt.Sprintf("%d %s", c, deploy.PastTense(op)))
+			}
+		}
+	}
+	if c := report.ChangeSummary[string(deploy.OpSame)]; c > 0 {
+		pieces = append(pieces, fmt.Sprintf("%d unchanged", c))
+	}
+	if len(pieces) == 0 {
+		return "no changes"
+	}
+	return strings.Join(pieces, ", ")
+}
+
+// markdownCell escapes text so that it can be used in a cell of a Markdown table.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+}
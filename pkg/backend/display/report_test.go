@@ -0,0 +1,221 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func reportTestEvents() []engine.Event {
+	bucket := resource.URN("urn:pulumi:dev::proj::aws:s3/bucket:Bucket::site")
+	table := resource.URN("urn:pulumi:dev::proj::aws:dynamodb/table:Table::users")
+	step := func(op display.StepOp, urn resource.URN, diffs ...resource.PropertyKey) engine.StepEventMetadata {
+		state := &engine.StepEventStateMetadata{URN: urn, Type: urn.Type(), Custom: true}
+		return engine.StepEventMetadata{Op: op, URN: urn, Type: urn.Type(), Old: state, New: state, Res: state,
+			Diffs: diffs, Logical: true}
+	}
+
+	return []engine.Event{
+		engine.NewEvent(engine.ResourcePreEvent, engine.ResourcePreEventPayload{
+			Metadata: step(deploy.OpCreate, bucket)}),
+		engine.NewEvent(engine.ResourcePreEvent, engine.ResourcePreEventPayload{
+			Metadata: step(deploy.OpUpdate, table, "billingMode", "tags")}),
+		engine.NewEvent(engine.ResourceOutputsEvent, engine.ResourceOutputsEventPayload{
+			Metadata: step(deploy.OpCreate, bucket)}),
+		engine.NewEvent(engine.DiagEvent, engine.DiagEventPayload{
+			URN: table, Severity: diag.Error, Prefix: "error: ", Message: "table is | busy\n"}),
+		engine.NewEvent(engine.ResourceOperationFailed, engine.ResourceOperationFailedPayload{
+			Metadata: step(deploy.OpUpdate, table)}),
+		engine.NewEvent(engine.DiagEvent, engine.DiagEventPayload{
+			Severity: diag.Info, Message: "ephemeral", Ephemeral: true}),
+		engine.NewEvent(engine.PolicyViolationEvent, engine.PolicyViolationEventPayload{
+			ResourceURN: bucket, PolicyPackName: "security", PolicyName: "no-public-buckets",
+			EnforcementLevel: apitype.Mandatory, Message: "buckets must not be public"}),
+		engine.NewEvent(engine.SummaryEvent, engine.SummaryEventPayload{
+			Duration: 3 * time.Second,
+			ResourceChanges: display.ResourceChanges{
+				deploy.OpCreate: 1,
+				deploy.OpSame:   2,
+			},
+		}),
+	}
+}
+
+func renderTestReport(t *testing.T, format ReportFormat, kind apitype.UpdateKind, isPreview bool) string {
+	events, done := make(chan engine.Event), make(chan bool)
+	var stdout bytes.Buffer
+	go ShowReport(kind, "dev", "proj", events, done, Options{ReportFormat: format, Stdout: &stdout}, isPreview)
+	for _, e := range reportTestEvents() {
+		events <- e
+	}
+	close(events)
+	<-done
+	return stdout.String()
+}
+
+func TestParseReportFormat(t *testing.T) {
+	t.Parallel()
+
+	format, err := ParseReportFormat("JUnit")
+	assert.NoError(t, err)
+	assert.Equal(t, ReportJUnit, format)
+
+	_, err = ParseReportFormat("yaml")
+	assert.EqualError(t, err, `unknown output format "yaml"; expected one of json, junit, markdown`)
+}
+
+func TestJSONReport(t *testing.T) {
+	t.Parallel()
+
+	var report Report
+	err := json.Unmarshal([]byte(renderTestReport(t, ReportJSON, apitype.UpdateUpdate, false)), &report)
+	require.NoError(t, err)
+
+	assert.Equal(t, ReportSchemaVersion, report.SchemaVersion)
+	assert.Equal(t, apitype.UpdateUpdate, report.Kind)
+	assert.Equal(t, ReportFailed, report.Result)
+	assert.Equal(t, 3.0, report.DurationSeconds)
+	assert.Equal(t, map[string]int{"create": 1, "same": 2}, report.ChangeSummary)
+
+	require.Len(t, report.Steps, 2)
+	assert.Equal(t, StepSucceeded, report.Steps[0].Status)
+	assert.Equal(t, StepFailed, report.Steps[1].Status)
+	assert.Equal(t, []string{"billingMode", "tags"}, report.Steps[1].Diffs)
+	assert.Equal(t, []string{"table is | busy"}, report.Steps[1].Errors)
+
+	require.Len(t, report.PolicyViolations, 1)
+	assert.Equal(t, "no-public-buckets", report.PolicyViolations[0].Policy)
+	// Ephemeral diagnostics are not reported.
+	require.Len(t, report.Diagnostics, 1)
+	assert.Equal(t, "error: table is | busy", report.Diagnostics[0].Message)
+}
+
+func TestReportReplacement(t *testing.T) {
+	t.Parallel()
+
+	// The steps of a replacement share a URN, but each keeps its own status.
+	urn := resource.URN("urn:pulumi:dev::proj::aws:s3/bucket:Bucket::site")
+	step := func(op display.StepOp) engine.StepEventMetadata {
+		state := &engine.StepEventStateMetadata{URN: urn, Type: urn.Type(), Custom: true}
+		return engine.StepEventMetadata{Op: op, URN: urn, Type: urn.Type(), Old: state, New: state, Res: state,
+			Logical: true}
+	}
+
+	r := NewReport(apitype.UpdateUpdate, "dev", "proj", false /*isPreview*/)
+	for _, e := range []engine.Event{
+		engine.NewEvent(engine.ResourcePreEvent, engine.ResourcePreEventPayload{
+			Metadata: step(deploy.OpCreateReplacement)}),
+		engine.NewEvent(engine.ResourcePreEvent, engine.ResourcePreEventPayload{
+			Metadata: step(deploy.OpDeleteReplaced)}),
+		engine.NewEvent(engine.ResourceOutputsEvent, engine.ResourceOutputsEventPayload{
+			Metadata: step(deploy.OpCreateReplacement)}),
+		engine.NewEvent(engine.DiagEvent, engine.DiagEventPayload{
+			URN: urn, Severity: diag.Error, Message: "bucket is not empty"}),
+		engine.NewEvent(engine.ResourceOperationFailed, engine.ResourceOperationFailedPayload{
+			Metadata: step(deploy.OpDeleteReplaced)}),
+	} {
+		r.AddEvent(e, Options{})
+	}
+
+	require.Len(t, r.Steps, 2)
+	assert.Equal(t, string(deploy.OpCreateReplacement), r.Steps[0].Op)
+	assert.Equal(t, StepSucceeded, r.Steps[0].Status)
+	assert.Empty(t, r.Steps[0].Errors)
+	assert.Equal(t, string(deploy.OpDeleteReplaced), r.Steps[1].Op)
+	assert.Equal(t, StepFailed, r.Steps[1].Status)
+	assert.Equal(t, []string{"bucket is not empty"}, r.Steps[1].Errors)
+}
+
+func TestMarkdownReport(t *testing.T) {
+	t.Parallel()
+
+	out := renderTestReport(t, ReportMarkdown, apitype.PreviewUpdate, true)
+	assert.Contains(t, out, "### Pulumi preview of `proj/dev`: failed\n")
+	assert.Contains(t, out, "**Resources:** 1 to create, 2 unchanged\n")
+	assert.Contains(t, out, "| update | users | `aws:dynamodb/table:Table` | `billingMode`, `tags` |\n")
+	assert.Contains(t, out, "| mandatory | security/no-public-buckets | site | buckets must not be public |\n")
+	assert.Contains(t, out, "```\nerror: table is | busy\n```\n")
+}
+
+func TestJUnitReport(t *testing.T) {
+	t.Parallel()
+
+	var suites junitTestSuites
+	err := xml.Unmarshal([]byte(renderTestReport(t, ReportJUnit, apitype.DestroyUpdate, false)), &suites)
+	require.NoError(t, err)
+
+	assert.Equal(t, "pulumi destroy proj/dev", suites.Name)
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 2, suites.Failures)
+	require.Len(t, suites.Suites, 3)
+
+	resources := suites.Suites[1]
+	assert.Equal(t, 2, resources.Tests)
+	assert.Nil(t, resources.Cases[0].Failure)
+	require.NotNil(t, resources.Cases[1].Failure)
+	assert.Equal(t, "table is | busy", resources.Cases[1].Failure.Text)
+	assert.Equal(t, 1, suites.Suites[2].Failures)
+}
+
+func TestPreviewReportBeforeUpdate(t *testing.T) {
+	t.Parallel()
+
+	// The preview that runs before an update is only reported if it fails.
+	out := renderTestReport(t, ReportJSON, apitype.UpdateUpdate, true)
+	assert.Contains(t, out, `"result": "failed"`)
+
+	events, done := make(chan engine.Event), make(chan bool)
+	var stdout bytes.Buffer
+	go ShowReport(apitype.UpdateUpdate, "dev", "proj", events, done,
+		Options{ReportFormat: ReportJSON, Stdout: &stdout}, true /*isPreview*/)
+	events <- reportTestEvents()[0]
+	events <- engine.NewEvent(engine.SummaryEvent, engine.SummaryEventPayload{IsPreview: true})
+	close(events)
+	<-done
+	assert.Empty(t, stdout.String())
+}
+
+func TestReportWithoutSummary(t *testing.T) {
+	t.Parallel()
+
+	// An operation that ends without a summary failed before it could start.
+	events, done := make(chan engine.Event), make(chan bool)
+	var stdout bytes.Buffer
+	go ShowReport(apitype.UpdateUpdate, "dev", "proj", events, done,
+		Options{ReportFormat: ReportJUnit, Stdout: &stdout}, false /*isPreview*/)
+	events <- engine.NewEvent(engine.CancelEvent, nil)
+	close(events)
+	<-done
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(stdout.Bytes(), &suites))
+	assert.Equal(t, 1, suites.Failures)
+	require.NotNil(t, suites.Suites[0].Cases[0].Failure)
+	assert.Equal(t, "the update failed", suites.Suites[0].Cases[0].Failure.Message)
+}
@@ -24,6 +24,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,10 +33,10 @@ import (
 
 	user "github.com/tweekmonster/luser"
 	"gocloud.dev/blob"
-	_ "gocloud.dev/blob/azureblob" // driver for azblob://
-	_ "gocloud.dev/blob/fileblob"  // driver for file://
-	"gocloud.dev/blob/gcsblob"     // driver for gs://
-	_ "gocloud.dev/blob/s3blob"    // driver for s3://
+	"gocloud.dev/blob/azureblob"  // driver for azblob://
+	_ "gocloud.dev/blob/fileblob" // driver for file://
+	"gocloud.dev/blob/gcsblob"    // driver for gs://
+	_ "gocloud.dev/blob/s3blob"   // driver for s3://
 	"gocloud.dev/gcerrors"
 
 	"github.com/pulumi/pulumi/pkg/v3/authhelpers"
@@ -68,6 +69,16 @@ const PulumiFilestateGzipEnvVar = "PULUMI_SELF_MANAGED_STATE_GZIP"
 type Backend interface {
 	backend.Backend
 	local() // at the moment, no local specific info, so just use a marker function.
+
+	// StackLocks returns the locks held on the given stack by other processes.
+	StackLocks(ctx context.Context, stackRef backend.StackReference) ([]StackLock, error)
+	// UnlockStack breaks the locks held on the given stack by other processes. Unless force is true, it refuses to
+	// break any lock whose holder may still be alive.
+	UnlockStack(ctx context.Context, stackRef backend.StackReference, force bool) error
+	// PruneHistory removes the history entries, checkpoints and backups of the given stack that the given policy
+	// doesn't retain. If dryRun is true, nothing is removed, but the result describes what would be.
+	PruneHistory(ctx context.Context, stackRef backend.StackReference,
+		retention HistoryRetention, dryRun bool) (HistoryPruneResult, error)
 }
 
 type localBackend struct {
@@ -83,6 +94,13 @@ type localBackend struct {
 	mutex  sync.Mutex
 
 	lockID string
+	// conditionalWrites is true if the bucket supports writes that fail if the key already exists.
+	conditionalWrites bool
+	// leases holds the heartbeat that renews the lease of each locked stack's lock.
+	leases     map[tokens.Name]*heartbeat
+	leaseMutex sync.Mutex
+	// held records the stacks whose lock is held by WithStackLock.
+	held map[tokens.Name]bool
 
 	gzip bool
 }
@@ -162,12 +180,15 @@ func New(d diag.Sink, originalURL string) (Backend, error) {
 	gzipCompression := cmdutil.IsTruthy(os.Getenv(PulumiFilestateGzipEnvVar))
 
 	return &localBackend{
-		d:           d,
-		originalURL: originalURL,
-		url:         u,
-		bucket:      &wrappedBucket{bucket: bucket},
-		lockID:      lockID.String(),
-		gzip:        gzipCompression,
+		d:                 d,
+		originalURL:       originalURL,
+		url:               u,
+		bucket:            &wrappedBucket{bucket: bucket},
+		lockID:            lockID.String(),
+		conditionalWrites: p.Scheme == gcsblob.Scheme || p.Scheme == azureblob.Scheme,
+		leases:            make(map[tokens.Name]*heartbeat),
+		held:              make(map[tokens.Name]bool),
+		gzip:              gzipCompression,
 	}, nil
 }
 
@@ -478,7 +499,7 @@ func (b *localBackend) Preview(ctx context.Context, stack backend.Stack,
 		DryRun:   true,
 		ShowLink: true,
 	}
-	return b.apply(ctx, apitype.PreviewUpdate, stack, op, opts, nil /*events*/)
+	return b.apply(ctx, apitype.PreviewUpdate, stack, op, opts, op.Events)
 }
 
 func (b *localBackend) Update(ctx context.Context, stack backend.Stack,
@@ -550,9 +571,15 @@ func (b *localBackend) apply(
 	stackName := stackRef.Name()
 	actionLabel := backend.ActionLabel(kind, opts.DryRun)
 
-	if !(op.Opts.Display.JSONDisplay || op.Opts.Display.Type == display.DisplayWatch) {
+	stdout := op.Opts.Display.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	if !(op.Opts.Display.JSONDisplay || op.Opts.Display.ReportFormat != "" ||
+		op.Opts.Display.Type == display.DisplayWatch) {
 		// Print a banner so it's clear this is a local deployment.
-		fmt.Printf(op.Opts.Display.Color.Colorize(
+		fmt.Fprintf(stdout, op.Opts.Display.Color.Colorize(
 			colors.SpecHeadline+"%s (%s):"+colors.Reset+"\n"), actionLabel, stackRef)
 	}
 
@@ -574,11 +601,17 @@ func (b *localBackend) apply(
 
 	scope := op.Scopes.NewScope(engineEvents, opts.DryRun)
 	eventsDone := make(chan bool)
+	var stepTimings []engine.StepTiming
 	go func() {
 		// Pull in all events from the engine and send them to the two listeners.
 		for e := range engineEvents {
 			displayEvents <- e
 
+			// Remember the step timings so that they can be saved with the update.
+			if p, ok := e.Payload().(engine.SummaryEventPayload); ok {
+				stepTimings = p.StepTimings
+			}
+
 			// If the caller also wants to see the events, stream them there also.
 			if events != nil {
 				events <- e
@@ -646,6 +679,7 @@ func (b *localBackend) apply(
 		//     rudely assume it knows where the checkpoint file is on disk as it makes a copy of it.  This isn't
 		//     trivial to achieve today given the event driven nature of plan-walking, however.
 		ResourceChanges: changes,
+		StepTimings:     display.ConvertStepTimings(stepTimings),
 	}
 
 	var saveErr error
@@ -653,6 +687,9 @@ func (b *localBackend) apply(
 	if !opts.DryRun {
 		saveErr = b.addToHistory(stackName, info)
 		backupErr = b.backupStack(stackName)
+		if saveErr == nil && backupErr == nil {
+			b.applyHistoryRetention(stackName)
+		}
 	}
 
 	if updateRes != nil {
@@ -670,7 +707,8 @@ func (b *localBackend) apply(
 	}
 
 	// Make sure to print a link to the stack's checkpoint before exiting.
-	if !op.Opts.Display.SuppressPermalink && opts.ShowLink && !op.Opts.Display.JSONDisplay {
+	if !op.Opts.Display.SuppressPermalink && opts.ShowLink && !op.Opts.Display.JSONDisplay &&
+		op.Opts.Display.ReportFormat == "" {
 		// Note we get a real signed link for aws/azure/gcp links.  But no such option exists for
 		// file:// links so we manually create the link ourselves.
 		var link string
@@ -696,7 +734,7 @@ func (b *localBackend) apply(
 		}
 
 		if link != "" {
-			fmt.Printf(op.Opts.Display.Color.Colorize(
+			fmt.Fprintf(stdout, op.Opts.Display.Color.Colorize(
 				colors.SpecHeadline+"Permalink: "+
 					colors.Underline+colors.BrightBlue+"%s"+colors.Reset+"\n"), link)
 		}
@@ -784,7 +822,41 @@ func (b *localBackend) ExportDeployment(ctx context.Context,
 	}
 
 	return &apitype.UntypedDeployment{
-		Version:    3,
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Deployment: json.RawMessage(data),
+	}, nil
+}
+
+// ExportDeploymentForVersion exports the deployment saved by the update with the given version, as numbered by the
+// stack's history. (The first update being version "1", the second "2", and so on.)
+func (b *localBackend) ExportDeploymentForVersion(ctx context.Context, stk backend.Stack,
+	version string) (*apitype.UntypedDeployment, error) {
+
+	v, err := strconv.Atoi(version)
+	if err != nil || v < 1 {
+		return nil, fmt.Errorf("%q is not a valid version; versions are positive numbers", version)
+	}
+
+	chk, err := b.getHistoricalCheckpoint(stk.Ref().Name(), v)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment := chk.Latest
+	if deployment == nil {
+		// The stack had no resources at that version.
+		deployment, err = stack.SerializeDeployment(deploy.NewSnapshot(deploy.Manifest{}, nil, nil, nil), nil, false)
+		if err != nil {
+			return nil, fmt.Errorf("serializing deployment: %w", err)
+		}
+	}
+	data, err := json.Marshal(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apitype.UntypedDeployment{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
 		Deployment: json.RawMessage(data),
 	}, nil
 }
@@ -792,14 +864,52 @@ func (b *localBackend) ExportDeployment(ctx context.Context,
 func (b *localBackend) ImportDeployment(ctx context.Context, stk backend.Stack,
 	deployment *apitype.UntypedDeployment) error {
 
-	err := b.Lock(ctx, stk.Ref())
+	unlock, err := b.lockStack(ctx, stk.Ref())
 	if err != nil {
 		return err
 	}
-	defer b.Unlock(ctx, stk.Ref())
+	defer unlock()
+
+	return b.importDeployment(stk.Ref().Name(), deployment)
+}
+
+func (b *localBackend) ImportDeploymentWithMetadata(ctx context.Context, stk backend.Stack,
+	deployment *apitype.UntypedDeployment, m backend.UpdateMetadata) error {
+
+	unlock, err := b.lockStack(ctx, stk.Ref())
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	stackName := stk.Ref().Name()
-	_, _, err = b.getStack(stackName)
+	start := time.Now().Unix()
+	if err := b.importDeployment(stackName, deployment); err != nil {
+		return err
+	}
+
+	info := backend.UpdateInfo{
+		Kind:        apitype.StackImportUpdate,
+		StartTime:   start,
+		Message:     m.Message,
+		Environment: m.Environment,
+		Result:      backend.SucceededResult,
+		EndTime:     time.Now().Unix(),
+	}
+	if err := b.addToHistory(stackName, info); err != nil {
+		return fmt.Errorf("saving update info: %w", err)
+	}
+	if err := b.backupStack(stackName); err != nil {
+		return fmt.Errorf("saving backup: %w", err)
+	}
+	b.applyHistoryRetention(stackName)
+	return nil
+}
+
+// importDeployment replaces the checkpoint of the given stack with the given deployment. The caller must hold the
+// stack's lock.
+func (b *localBackend) importDeployment(stackName tokens.Name, deployment *apitype.UntypedDeployment) error {
+	_, _, err := b.getStack(stackName)
 	if err != nil {
 		return err
 	}
@@ -877,30 +987,7 @@ func (b *localBackend) UpdateStackTags(ctx context.Context,
 }
 
 func (b *localBackend) CancelCurrentUpdate(ctx context.Context, stackRef backend.StackReference) error {
-	// Try to delete ALL the lock files
-	allFiles, err := listBucket(b.bucket, stackLockDir(stackRef.Name()))
-	if err != nil {
-		// Don't error if it just wasn't found
-		if gcerrors.Code(err) == gcerrors.NotFound {
-			return nil
-		}
-		return err
-	}
-
-	for _, file := range allFiles {
-		if file.IsDir {
-			continue
-		}
-
-		err := b.bucket.Delete(ctx, file.Key)
-		if err != nil {
-			// Race condition, don't error if the file was delete between us calling list and now
-			if gcerrors.Code(err) == gcerrors.NotFound {
-				return nil
-			}
-			return err
-		}
-	}
-
-	return nil
+	// An update against a self-managed backend can't be canceled remotely, but we can break the locks left behind by
+	// updates that are no longer running.
+	return b.UnlockStack(ctx, stackRef, false /*force*/)
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"math"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	user "github.com/tweekmonster/luser"
@@ -249,16 +251,14 @@ func TestCancel(t *testing.T) {
 	assert.True(t, ok)
 	assert.NotNil(t, lb)
 
-	// Lock the stack and check CancelCurrentUpdate deletes the lock file
+	// Lock the stack and check that Unlock deletes the lock file
 	err = lb.Lock(ctx, aStackRef)
 	assert.NoError(t, err)
 	// check the lock file exists
 	lockExists, err := lb.bucket.Exists(ctx, lb.lockPath(aStackRef.Name()))
 	assert.NoError(t, err)
 	assert.True(t, lockExists)
-	// Call CancelCurrentUpdate
-	err = lb.CancelCurrentUpdate(ctx, aStackRef)
-	assert.NoError(t, err)
+	lb.Unlock(ctx, aStackRef)
 	// Now check the lock file no longer exists
 	lockExists, err = lb.bucket.Exists(ctx, lb.lockPath(aStackRef.Name()))
 	assert.NoError(t, err)
@@ -276,11 +276,162 @@ func TestCancel(t *testing.T) {
 	assert.NoError(t, err)
 	err = lb.checkForLock(ctx, aStackRef)
 	assert.Error(t, err)
-	// Now call CancelCurrentUpdate and check that checkForLocks no longer errors
+	// The lock is held by a live process, so CancelCurrentUpdate must not break it
 	err = lb.CancelCurrentUpdate(ctx, aStackRef)
+	assert.Error(t, err)
+	err = lb.checkForLock(ctx, aStackRef)
+	assert.Error(t, err)
+	// Now force unlock the stack and check that checkForLocks no longer errors
+	err = lb.UnlockStack(ctx, aStackRef, true /*force*/)
 	assert.NoError(t, err)
 	err = lb.checkForLock(ctx, aStackRef)
 	assert.NoError(t, err)
+	otherBackend.stopHeartbeat(aStackRef.Name())
+}
+
+func TestStaleLocks(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "filestatebackend")
+	assert.NoError(t, err)
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(tmpDir))
+	assert.NoError(t, err)
+	lb := b.(*localBackend)
+	ctx := context.Background()
+
+	stackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	_, err = b.CreateStack(ctx, stackRef, nil)
+	assert.NoError(t, err)
+
+	writeLock := func(name string, l *lockContent) {
+		content, err := json.Marshal(l)
+		assert.NoError(t, err)
+		err = lb.bucket.WriteAll(ctx, path.Join(stackLockDir(stackRef.Name()), name), content, nil)
+		assert.NoError(t, err)
+	}
+
+	// A lock whose lease has expired.
+	expired, err := newLockContent("expired")
+	assert.NoError(t, err)
+	expires := time.Now().Add(-time.Minute)
+	expired.Expires = &expires
+	writeLock("expired.json", expired)
+
+	// A lock written by an older version on this machine, whose process no longer exists.
+	dead, err := newLockContent("")
+	assert.NoError(t, err)
+	dead.Pid = math.MaxInt32
+	dead.Expires = nil
+	writeLock("dead.json", dead)
+
+	// A lock held by a live process on another machine.
+	live, err := newLockContent("live")
+	assert.NoError(t, err)
+	live.Hostname = "some-other-host"
+	writeLock("live.json", live)
+
+	locks, err := lb.StackLocks(ctx, stackRef)
+	assert.NoError(t, err)
+	assert.Len(t, locks, 3)
+	reasons := make(map[string]string)
+	for _, l := range locks {
+		reasons[path.Base(l.Path)] = l.StaleReason
+	}
+	assert.Contains(t, reasons["expired.json"], "lease expired")
+	assert.Contains(t, reasons["dead.json"], "no longer exists")
+	assert.Equal(t, "", reasons["live.json"])
+
+	// Unlocking without force refuses to break the live lock, and doesn't break anything.
+	err = lb.UnlockStack(ctx, stackRef, false /*force*/)
+	assert.Error(t, err)
+	locks, err = lb.StackLocks(ctx, stackRef)
+	assert.NoError(t, err)
+	assert.Len(t, locks, 3)
+
+	// Checking for locks breaks the stale locks, but still reports the live lock.
+	err = lb.checkForLock(ctx, stackRef)
+	assert.Error(t, err)
+	locks, err = lb.StackLocks(ctx, stackRef)
+	assert.NoError(t, err)
+	assert.Len(t, locks, 1)
+	assert.Equal(t, "live.json", path.Base(locks[0].Path))
+
+	err = lb.UnlockStack(ctx, stackRef, true /*force*/)
+	assert.NoError(t, err)
+	err = lb.Lock(ctx, stackRef)
+	assert.NoError(t, err)
+	lb.Unlock(ctx, stackRef)
+}
+
+func TestRenewLease(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "filestatebackend")
+	assert.NoError(t, err)
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(tmpDir))
+	assert.NoError(t, err)
+	lb := b.(*localBackend)
+	ctx := context.Background()
+
+	stackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	err = lb.Lock(ctx, stackRef)
+	assert.NoError(t, err)
+	defer lb.Unlock(ctx, stackRef)
+
+	readLock := func() *lockContent {
+		content, err := lb.bucket.ReadAll(ctx, lb.lockPath(stackRef.Name()))
+		assert.NoError(t, err)
+		l := &lockContent{}
+		assert.NoError(t, json.Unmarshal(content, &l))
+		return l
+	}
+
+	l := readLock()
+	assert.NotNil(t, l.Expires)
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, lb.renewLease(stackRef.Name(), l))
+	renewed := readLock()
+	assert.True(t, renewed.Expires.After(*l.Expires) || renewed.Expires.Equal(*l.Expires))
+	assert.True(t, renewed.Timestamp.Equal(l.Timestamp))
+
+	// Once the lock has been broken, the lease is no longer renewed.
+	err = lb.bucket.Delete(ctx, lb.lockPath(stackRef.Name()))
+	assert.NoError(t, err)
+	assert.False(t, lb.renewLease(stackRef.Name(), l))
+}
+
+func TestUnlockStopsHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(t.TempDir()))
+	assert.NoError(t, err)
+	lb := b.(*localBackend)
+	ctx := context.Background()
+
+	stackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	err = lb.Lock(ctx, stackRef)
+	assert.NoError(t, err)
+
+	lb.leaseMutex.Lock()
+	hb := lb.leases[stackRef.Name()]
+	lb.leaseMutex.Unlock()
+	if !assert.NotNil(t, hb) {
+		return
+	}
+
+	// Unlock only deletes the lock once the heartbeat can no longer renew, and so recreate, it.
+	lb.Unlock(ctx, stackRef)
+	select {
+	case <-hb.stopped:
+	default:
+		t.Fatal("the heartbeat was still running after the stack was unlocked")
+	}
+	exists, err := lb.bucket.Exists(ctx, lb.lockPath(stackRef.Name()))
+	assert.NoError(t, err)
+	assert.False(t, exists)
 }
 
 func TestRemoveMakesBackups(t *testing.T) {
@@ -395,3 +546,72 @@ func TestRenameWorks(t *testing.T) {
 	assert.Len(t, history, 1)
 	assert.Equal(t, apitype.DestroyUpdate, history[0].Kind)
 }
+
+func TestImportDeploymentWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(tmpDir))
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	aStackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	aStack, err := b.CreateStack(ctx, aStackRef, nil)
+	assert.NoError(t, err)
+
+	deployment, err := b.ExportDeployment(ctx, aStack)
+	assert.NoError(t, err)
+
+	importer, ok := b.(backend.HistoryRecordingDeploymentImporter)
+	assert.True(t, ok)
+	err = importer.ImportDeploymentWithMetadata(ctx, aStack, deployment, backend.UpdateMetadata{
+		Message:     "rotated secrets",
+		Environment: map[string]string{"exec.kind": "cli"},
+	})
+	assert.NoError(t, err)
+
+	history, err := b.GetHistory(ctx, aStackRef, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, apitype.StackImportUpdate, history[0].Kind)
+	assert.Equal(t, "rotated secrets", history[0].Message)
+	assert.Equal(t, backend.SucceededResult, history[0].Result)
+	assert.Equal(t, "cli", history[0].Environment["exec.kind"])
+}
+
+func TestWithStackLock(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(tmpDir))
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	aStackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	aStack, err := b.CreateStack(ctx, aStackRef, nil)
+	assert.NoError(t, err)
+
+	ob, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(tmpDir))
+	assert.NoError(t, err)
+	otherBackend := ob.(*localBackend)
+
+	locker, ok := b.(backend.StackLockingBackend)
+	assert.True(t, ok)
+	err = locker.WithStackLock(ctx, aStack, func() error {
+		// The lock is held across the whole edit, so other processes can't take it...
+		assert.Error(t, otherBackend.Lock(ctx, aStackRef))
+
+		// ...but the stack can still be saved without taking it again.
+		deployment, err := b.ExportDeployment(ctx, aStack)
+		assert.NoError(t, err)
+		assert.NoError(t, b.ImportDeployment(ctx, aStack, deployment))
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// Once the edit is done, the lock is released.
+	assert.NoError(t, otherBackend.Lock(ctx, aStackRef))
+	otherBackend.Unlock(ctx, aStackRef)
+}
@@ -0,0 +1,330 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gocloud.dev/gcerrors"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/encoding"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+// PulumiFilestateHistoryKeepEnvVar is an env var that, when set to a positive number, limits the number of updates
+// whose history and checkpoints are retained by the filestate backend.
+const PulumiFilestateHistoryKeepEnvVar = "PULUMI_SELF_MANAGED_STATE_HISTORY_KEEP"
+
+// PulumiFilestateHistoryKeepDaysEnvVar is an env var that, when set to a positive number, makes the filestate backend
+// remove the history and checkpoints of updates that are older than that many days.
+const PulumiFilestateHistoryKeepDaysEnvVar = "PULUMI_SELF_MANAGED_STATE_HISTORY_KEEP_DAYS"
+
+// HistoryRetention is a policy for how much of a stack's history to retain. The most recent update is always retained.
+type HistoryRetention struct {
+	// KeepUpdates is the number of most recent updates to retain, or zero to retain any number of updates.
+	KeepUpdates int
+	// MaxAge is the age beyond which updates are removed, or zero to retain updates of any age.
+	MaxAge time.Duration
+}
+
+// IsZero returns true if the policy retains the entire history.
+func (r HistoryRetention) IsZero() bool {
+	return r.KeepUpdates <= 0 && r.MaxAge <= 0
+}
+
+// HistoryRetentionFromEnv returns the retention policy configured by the PULUMI_SELF_MANAGED_STATE_HISTORY_KEEP and
+// PULUMI_SELF_MANAGED_STATE_HISTORY_KEEP_DAYS environment variables.
+func HistoryRetentionFromEnv() (HistoryRetention, error) {
+	var r HistoryRetention
+	if v := os.Getenv(PulumiFilestateHistoryKeepEnvVar); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return HistoryRetention{}, fmt.Errorf("%s must be a non-negative number, got %q",
+				PulumiFilestateHistoryKeepEnvVar, v)
+		}
+		r.KeepUpdates = n
+	}
+	if v := os.Getenv(PulumiFilestateHistoryKeepDaysEnvVar); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return HistoryRetention{}, fmt.Errorf("%s must be a non-negative number, got %q",
+				PulumiFilestateHistoryKeepDaysEnvVar, v)
+		}
+		r.MaxAge = time.Duration(n) * 24 * time.Hour
+	}
+	return r, nil
+}
+
+// HistoryPruneResult describes what was removed from a stack's history.
+type HistoryPruneResult struct {
+	// Updates is the number of updates whose history entries and checkpoints were removed.
+	Updates int
+	// Backups is the number of checkpoint backups that were removed.
+	Backups int
+}
+
+// historyGroup is a set of files that belong to the same point in a stack's history, such as an update's history entry
+// and its checkpoint.
+type historyGroup struct {
+	time time.Time
+	keys []string
+}
+
+// groupByTime groups the given keys by the timestamp returned by timeOf, sorted from most to least recent. Keys without
+// a timestamp are not included.
+func groupByTime(keys []string, timeOf func(name string) (int64, bool)) []*historyGroup {
+	groups := make(map[int64]*historyGroup)
+	for _, key := range keys {
+		nanos, ok := timeOf(key[strings.LastIndex(key, "/")+1:])
+		if !ok {
+			continue
+		}
+		g, has := groups[nanos]
+		if !has {
+			g = &historyGroup{time: time.Unix(0, nanos)}
+			groups[nanos] = g
+		}
+		g.keys = append(g.keys, key)
+	}
+
+	sorted := make([]*historyGroup, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].time.After(sorted[j].time)
+	})
+	return sorted
+}
+
+// historyFileTime returns the timestamp of a file in a stack's history directory, which is named
+// <stack-name>-<timestamp>.[checkpoint|history].json[.gz].
+func historyFileTime(name string) (int64, bool) {
+	dash := strings.LastIndex(name, "-")
+	if dash == -1 {
+		return 0, false
+	}
+	rest := name[dash+1:]
+	dot := strings.Index(rest, ".")
+	if dot == -1 {
+		return 0, false
+	}
+	nanos, err := strconv.ParseInt(rest[:dot], 10, 64)
+	return nanos, err == nil
+}
+
+// backupFileTime returns the timestamp of a file in a stack's backup directory, which is named
+// <stack-name>.<timestamp>.json[.gz].
+func backupFileTime(name string) (int64, bool) {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".json")
+	dot := strings.LastIndex(name, ".")
+	if dot == -1 {
+		return 0, false
+	}
+	nanos, err := strconv.ParseInt(name[dot+1:], 10, 64)
+	return nanos, err == nil
+}
+
+// expiredGroups returns the groups, sorted from most to least recent, that the policy does not retain.
+func (r HistoryRetention) expiredGroups(groups []*historyGroup, now time.Time) []*historyGroup {
+	var expired []*historyGroup
+	for i, g := range groups {
+		if i == 0 {
+			// Always retain the most recent update.
+			continue
+		}
+		if (r.KeepUpdates > 0 && i >= r.KeepUpdates) || (r.MaxAge > 0 && now.Sub(g.time) > r.MaxAge) {
+			expired = append(expired, g)
+		}
+	}
+	return expired
+}
+
+// historyGroupVersions returns the versions of the updates whose history entries are in the given group. Entries
+// written by older versions don't record their version and are not included.
+func (b *localBackend) historyGroupVersions(g *historyGroup) ([]int, error) {
+	var versions []int
+	for _, key := range g.keys {
+		if !strings.Contains(key, ".history.") {
+			continue
+		}
+		update, err := b.readUpdateInfo(key)
+		if err != nil {
+			return nil, err
+		}
+		if update.Version != 0 {
+			versions = append(versions, update.Version)
+		}
+	}
+	return versions, nil
+}
+
+// recordLegacyHistoryVersions writes the version of each of the given stack's history entries that don't record it,
+// which older versions didn't, into the entry. These entries are otherwise numbered by their position in the history.
+func (b *localBackend) recordLegacyHistoryVersions(ctx context.Context, name tokens.Name) error {
+	entries, err := b.historyEntries(name)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		update, err := b.readUpdateInfo(entry.Key)
+		if err != nil {
+			return err
+		}
+		if update.Version != 0 {
+			continue
+		}
+		update.Version = len(entries) - i
+
+		m := encoding.JSON
+		if strings.HasSuffix(entry.Key, ".gz") {
+			m = encoding.Gzip(m)
+		}
+		byts, err := m.Marshal(&update)
+		if err != nil {
+			return err
+		}
+		if err := b.bucket.WriteAll(ctx, entry.Key, byts, nil); err != nil {
+			return fmt.Errorf("numbering history entry %s: %w", entry.Key, err)
+		}
+	}
+	return nil
+}
+
+// listKeys returns the keys of the files in the given directory, or nothing if the directory doesn't exist.
+func (b *localBackend) listKeys(dir string) ([]string, error) {
+	files, err := listBucket(b.bucket, dir)
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var keys []string
+	for _, file := range files {
+		if !file.IsDir {
+			keys = append(keys, file.Key)
+		}
+	}
+	return keys, nil
+}
+
+// PruneHistory removes the history entries, checkpoints and backups of the given stack that the given policy doesn't
+// retain. If dryRun is true, nothing is removed, but the result describes what would be.
+func (b *localBackend) PruneHistory(ctx context.Context, stackRef backend.StackReference,
+	retention HistoryRetention, dryRun bool) (HistoryPruneResult, error) {
+
+	if !dryRun {
+		if err := b.Lock(ctx, stackRef); err != nil {
+			return HistoryPruneResult{}, err
+		}
+		defer b.Unlock(ctx, stackRef)
+	}
+	return b.pruneHistory(ctx, stackRef.Name(), retention, dryRun)
+}
+
+// applyHistoryRetention prunes the given stack's history according to the retention policy configured in the
+// environment, if any. The stack must be locked. Failures are reported as warnings, as they don't affect the update.
+func (b *localBackend) applyHistoryRetention(name tokens.Name) {
+	retention, err := HistoryRetentionFromEnv()
+	if err == nil {
+		_, err = b.pruneHistory(context.TODO(), name, retention, false /*dryRun*/)
+	}
+	if err != nil {
+		b.d.Warningf(diag.Message("", "could not prune the history of stack %s: %v"), name, err)
+	}
+}
+
+func (b *localBackend) pruneHistory(ctx context.Context, name tokens.Name,
+	retention HistoryRetention, dryRun bool) (HistoryPruneResult, error) {
+
+	var result HistoryPruneResult
+	if retention.IsZero() {
+		return result, nil
+	}
+	now := time.Now()
+
+	historyKeys, err := b.listKeys(b.historyDirectory(name))
+	if err != nil {
+		return result, err
+	}
+	backupKeys, err := b.listKeys(b.backupDirectory(name))
+	if err != nil {
+		return result, err
+	}
+
+	history := retention.expiredGroups(groupByTime(historyKeys, historyFileTime), now)
+	backups := retention.expiredGroups(groupByTime(backupKeys, backupFileTime), now)
+	if dryRun {
+		return HistoryPruneResult{Updates: len(history), Backups: len(backups)}, nil
+	}
+
+	// Entries written by older versions are numbered by their position in the history, which removing older entries
+	// would shift, so record their numbers first.
+	if len(history) > 0 {
+		if err := b.recordLegacyHistoryVersions(ctx, name); err != nil {
+			return result, err
+		}
+	}
+
+	remove := func(g *historyGroup) error {
+		// Remove history entries before checkpoints, so that an interrupted prune never leaves behind an update
+		// without its checkpoint.
+		sort.SliceStable(g.keys, func(i, j int) bool {
+			return strings.Contains(g.keys[i], ".history.") && !strings.Contains(g.keys[j], ".history.")
+		})
+		for _, key := range g.keys {
+			if err := b.bucket.Delete(ctx, key); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+				return fmt.Errorf("removing %s: %w", key, err)
+			}
+		}
+		return nil
+	}
+
+	// Remove the oldest entries first, so that an interrupted prune leaves a contiguous history.
+	for i := len(history) - 1; i >= 0; i-- {
+		versions, err := b.historyGroupVersions(history[i])
+		if err != nil {
+			return result, err
+		}
+		if err := remove(history[i]); err != nil {
+			return result, err
+		}
+		for _, version := range versions {
+			key := b.historyVersionPath(name, version)
+			if err := b.bucket.Delete(ctx, key); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+				return result, fmt.Errorf("removing %s: %w", key, err)
+			}
+		}
+		result.Updates++
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		if err := remove(backups[i]); err != nil {
+			return result, err
+		}
+		result.Backups++
+	}
+	return result, nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestate
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+func TestHistoryFileTimes(t *testing.T) {
+	t.Parallel()
+
+	nanos, ok := historyFileTime("my-stack-1655900000000000000.history.json.gz")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1655900000000000000), nanos)
+	_, ok = historyFileTime("notes.txt")
+	assert.False(t, ok)
+
+	nanos, ok = backupFileTime("my.stack.1655900000000000000.json")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1655900000000000000), nanos)
+	_, ok = backupFileTime("my-stack.json")
+	assert.False(t, ok)
+}
+
+func TestPruneHistory(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "filestatebackend")
+	assert.NoError(t, err)
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(tmpDir))
+	assert.NoError(t, err)
+	lb := b.(*localBackend)
+	ctx := context.Background()
+
+	stackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	_, err = b.CreateStack(ctx, stackRef, nil)
+	assert.NoError(t, err)
+
+	// Write the history of five updates, one per day, the most recent of which happened today.
+	now := time.Now()
+	historyDir := lb.historyDirectory(stackRef.Name())
+	backupDir := lb.backupDirectory(stackRef.Name())
+	for i := 0; i < 5; i++ {
+		nanos := now.Add(-time.Duration(i)*24*time.Hour - time.Hour).UnixNano()
+		for _, kind := range []string{"history", "checkpoint"} {
+			key := path.Join(historyDir, fmt.Sprintf("a-%d.%s.json", nanos, kind))
+			assert.NoError(t, lb.bucket.WriteAll(ctx, key, []byte("{}"), nil))
+		}
+		key := path.Join(backupDir, fmt.Sprintf("a.%d.json", nanos))
+		assert.NoError(t, lb.bucket.WriteAll(ctx, key, []byte("{}"), nil))
+	}
+	// Files that don't follow the naming scheme are left alone.
+	assert.NoError(t, lb.bucket.WriteAll(ctx, path.Join(historyDir, "notes.txt"), []byte("{}"), nil))
+
+	countFiles := func(dir string) int {
+		keys, err := lb.listKeys(dir)
+		assert.NoError(t, err)
+		return len(keys)
+	}
+
+	// A dry run reports what would be removed without removing it.
+	res, err := lb.PruneHistory(ctx, stackRef, HistoryRetention{KeepUpdates: 3}, true /*dryRun*/)
+	assert.NoError(t, err)
+	assert.Equal(t, HistoryPruneResult{Updates: 2, Backups: 2}, res)
+	assert.Equal(t, 11, countFiles(historyDir))
+	assert.Equal(t, 5, countFiles(backupDir))
+
+	res, err = lb.PruneHistory(ctx, stackRef, HistoryRetention{KeepUpdates: 3}, false /*dryRun*/)
+	assert.NoError(t, err)
+	assert.Equal(t, HistoryPruneResult{Updates: 2, Backups: 2}, res)
+	assert.Equal(t, 7, countFiles(historyDir))
+	assert.Equal(t, 3, countFiles(backupDir))
+
+	// The stack must be unlocked once pruning is done.
+	assert.NoError(t, lb.checkForLock(ctx, stackRef))
+	locks, err := lb.listKeys(stackLockDir(stackRef.Name()))
+	assert.NoError(t, err)
+	assert.Empty(t, locks)
+
+	res, err = lb.PruneHistory(ctx, stackRef, HistoryRetention{MaxAge: 36 * time.Hour}, false /*dryRun*/)
+	assert.NoError(t, err)
+	assert.Equal(t, HistoryPruneResult{Updates: 1, Backups: 1}, res)
+
+	// The most recent update is always retained.
+	res, err = lb.PruneHistory(ctx, stackRef, HistoryRetention{MaxAge: time.Minute}, false /*dryRun*/)
+	assert.NoError(t, err)
+	assert.Equal(t, HistoryPruneResult{Updates: 1, Backups: 1}, res)
+
+	keys, err := lb.listKeys(historyDir)
+	assert.NoError(t, err)
+	sort.Strings(keys)
+	assert.Len(t, keys, 3)
+	assert.Equal(t, path.Join(historyDir, "notes.txt"), keys[2])
+	assert.Equal(t, 1, countFiles(backupDir))
+}
+
+func TestExportDeploymentForVersion(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "filestatebackend")
+	assert.NoError(t, err)
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(tmpDir))
+	assert.NoError(t, err)
+	lb := b.(*localBackend)
+	ctx := context.Background()
+
+	stackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	s, err := b.CreateStack(ctx, stackRef, nil)
+	assert.NoError(t, err)
+
+	// Record three updates, then prune the oldest one. Versions must not change when the history is pruned.
+	for i := 0; i < 3; i++ {
+		err = lb.addToHistory("a", backend.UpdateInfo{Kind: apitype.UpdateUpdate})
+		assert.NoError(t, err)
+	}
+	_, err = lb.PruneHistory(ctx, stackRef, HistoryRetention{KeepUpdates: 2}, false /*dryRun*/)
+	assert.NoError(t, err)
+
+	updates, err := b.GetHistory(ctx, stackRef, 0 /*pageSize*/, 0 /*page*/)
+	assert.NoError(t, err)
+	assert.Len(t, updates, 2)
+	assert.Equal(t, 3, updates[0].Version)
+	assert.Equal(t, 2, updates[1].Version)
+
+	deployment, err := lb.ExportDeploymentForVersion(ctx, s, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, apitype.DeploymentSchemaVersionCurrent, deployment.Version)
+
+	// Pruning removes the records of the pruned versions, but not those of the retained ones.
+	v, err := lb.readHistoryVersion("a", 1)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+	v, err = lb.readHistoryVersion("a", 2)
+	assert.NoError(t, err)
+	assert.NotNil(t, v)
+	latest, err := lb.readHistoryVersion("a", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, latest.Version)
+	assert.Equal(t, 1, latest.FirstIndexed)
+
+	_, err = lb.ExportDeploymentForVersion(ctx, s, "1")
+	assert.Error(t, err)
+	_, err = lb.ExportDeploymentForVersion(ctx, s, "latest")
+	assert.Error(t, err)
+}
+
+func TestHistoryVersionsOfOlderHistories(t *testing.T) {
+	t.Parallel()
+
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(t.TempDir()))
+	assert.NoError(t, err)
+	lb := b.(*localBackend)
+	ctx := context.Background()
+
+	stackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	s, err := b.CreateStack(ctx, stackRef, nil)
+	assert.NoError(t, err)
+
+	// Write the history of two updates the way older versions did, without version numbers or version records.
+	historyDir := lb.historyDirectory("a")
+	for i := 0; i < 2; i++ {
+		prefix := path.Join(historyDir, fmt.Sprintf("a-%d", time.Now().Add(time.Duration(i-2)*time.Hour).UnixNano()))
+		assert.NoError(t, lb.bucket.WriteAll(ctx, prefix+".history.json", []byte(`{"kind": "update"}`), nil))
+		assert.NoError(t, lb.bucket.Copy(ctx, prefix+".checkpoint.json", lb.stackPath("a"), nil))
+	}
+
+	// New updates are numbered after the older ones and recorded from then on.
+	err = lb.addToHistory("a", backend.UpdateInfo{Kind: apitype.UpdateUpdate})
+	assert.NoError(t, err)
+	latest, err := lb.readHistoryVersion("a", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, latest.Version)
+	assert.Equal(t, 3, latest.FirstIndexed)
+
+	// Both older and newer versions can be exported.
+	for _, version := range []string{"1", "2", "3"} {
+		_, err = lb.ExportDeploymentForVersion(ctx, s, version)
+		assert.NoError(t, err, "version %s", version)
+	}
+	_, err = lb.ExportDeploymentForVersion(ctx, s, "4")
+	assert.Error(t, err)
+}
+
+func TestPruneHistoryKeepsVersionsOfOlderHistories(t *testing.T) {
+	t.Parallel()
+
+	b, err := New(cmdutil.Diag(), "file://"+filepath.ToSlash(t.TempDir()))
+	assert.NoError(t, err)
+	lb := b.(*localBackend)
+	ctx := context.Background()
+
+	stackRef, err := b.ParseStackReference("a")
+	assert.NoError(t, err)
+	s, err := b.CreateStack(ctx, stackRef, nil)
+	assert.NoError(t, err)
+
+	// Write the history of three updates the way older versions did, without version numbers.
+	historyDir := lb.historyDirectory("a")
+	for i := 0; i < 3; i++ {
+		prefix := path.Join(historyDir, fmt.Sprintf("a-%d", time.Now().Add(time.Duration(i-3)*time.Hour).UnixNano()))
+		assert.NoError(t, lb.bucket.WriteAll(ctx, prefix+".history.json", []byte(`{"kind": "update"}`), nil))
+		assert.NoError(t, lb.bucket.Copy(ctx, prefix+".checkpoint.json", lb.stackPath("a"), nil))
+	}
+
+	// Pruning the oldest update doesn't renumber the remaining ones.
+	res, err := lb.PruneHistory(ctx, stackRef, HistoryRetention{KeepUpdates: 2}, false /*dryRun*/)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Updates)
+
+	history, err := b.GetHistory(ctx, stackRef, 10, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, 3, history[0].Version)
+		assert.Equal(t, 2, history[1].Version)
+	}
+	_, err = lb.ExportDeploymentForVersion(ctx, s, "1")
+	assert.Error(t, err)
+	_, err = lb.ExportDeploymentForVersion(ctx, s, "2")
+	assert.NoError(t, err)
+
+	// New updates are still numbered after the most recent one.
+	err = lb.addToHistory("a", backend.UpdateInfo{Kind: apitype.UpdateUpdate})
+	assert.NoError(t, err)
+	latest, err := lb.readHistoryVersion("a", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, latest.Version)
+}
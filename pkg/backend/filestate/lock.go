@@ -24,6 +24,11 @@ import (
 	"path"
 	"time"
 
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+
 	"github.com/pulumi/pulumi/pkg/v3/backend"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
@@ -32,14 +37,31 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
 )
 
+var (
+	// lockLeaseDuration is how long a lock remains valid unless its holder renews it. Once a lock's lease expires, the
+	// lock is considered stale and may be broken by other processes.
+	lockLeaseDuration = 5 * time.Minute
+	// lockHeartbeatInterval is how often the holder of a lock renews its lease.
+	lockHeartbeatInterval = time.Minute
+)
+
+// sharedLockName is the name of the lock file used by every process on blob stores that support conditional writes.
+// Creating the file only if it doesn't exist makes acquiring the lock atomic.
+const sharedLockName = "lock.json"
+
 type lockContent struct {
 	Pid       int       `json:"pid"`
 	Username  string    `json:"username"`
 	Hostname  string    `json:"hostname"`
 	Timestamp time.Time `json:"timestamp"`
+	// LockID identifies the backend instance that holds the lock. It is empty for locks written by older versions.
+	LockID string `json:"lockID,omitempty"`
+	// Expires is the time at which the lock's lease expires unless it is renewed. It is nil for locks written by older
+	// versions, which have no lease.
+	Expires *time.Time `json:"expires,omitempty"`
 }
 
-func newLockContent() (*lockContent, error) {
+func newLockContent(lockID string) (*lockContent, error) {
 	u, err := user.Current()
 	if err != nil {
 		return nil, err
@@ -48,53 +70,208 @@ func newLockContent() (*lockContent, error) {
 	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
+	expires := now.Add(lockLeaseDuration)
 	return &lockContent{
 		Pid:       os.Getpid(),
 		Username:  u.Username,
 		Hostname:  hostname,
-		Timestamp: time.Now(),
+		Timestamp: now,
+		LockID:    lockID,
+		Expires:   &expires,
 	}, nil
 }
 
-// checkForLock looks for any existing locks for this stack, and returns a helpful diagnostic if there is one.
-func (b *localBackend) checkForLock(ctx context.Context, stackRef backend.StackReference) error {
-	allFiles, err := listBucket(b.bucket, stackLockDir(stackRef.Name()))
+// staleReason returns the reason why the holder of the lock is known to be gone, or the empty string if the holder may
+// still be alive.
+func (l *lockContent) staleReason(now time.Time) string {
+	if l.Expires != nil && now.After(*l.Expires) {
+		return fmt.Sprintf("its lease expired at %v", l.Expires.Format(time.RFC3339))
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname == l.Hostname && l.Pid > 0 && !processExists(l.Pid) {
+		return fmt.Sprintf("process %v no longer exists", l.Pid)
+	}
+	return ""
+}
+
+// StackLock describes a lock held on a stack.
+type StackLock struct {
+	// Path is the location of the lock file.
+	Path string
+	// Username is the name of the user that holds the lock.
+	Username string
+	// Hostname is the name of the machine on which the lock was acquired.
+	Hostname string
+	// Pid is the process ID of the process that holds the lock.
+	Pid int
+	// Timestamp is the time at which the lock was acquired.
+	Timestamp time.Time
+	// Expires is the time at which the lock's lease expires unless it is renewed. It is nil for locks without a lease.
+	Expires *time.Time
+	// StaleReason explains why the holder of the lock is known to be gone. It is empty if the holder may still be alive.
+	StaleReason string
+}
+
+// lockFile is a lock file and its content.
+type lockFile struct {
+	key     string
+	content *lockContent
+}
+
+// stackLocks returns the lock files for the given stack that are not held by this backend.
+func (b *localBackend) stackLocks(ctx context.Context, stack tokens.Name) ([]lockFile, error) {
+	allFiles, err := listBucket(b.bucket, stackLockDir(stack))
 	if err != nil {
-		return err
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	var lockKeys []string
+	var locks []lockFile
 	for _, file := range allFiles {
 		if file.IsDir {
 			continue
 		}
-		if file.Key != b.lockPath(stackRef.Name()) {
-			lockKeys = append(lockKeys, file.Key)
+
+		content, err := b.bucket.ReadAll(ctx, file.Key)
+		if err != nil {
+			// The lock may have been released between listing and reading it.
+			if gcerrors.Code(err) == gcerrors.NotFound {
+				continue
+			}
+			return nil, err
+		}
+		l := &lockContent{}
+		if err = json.Unmarshal(content, &l); err != nil {
+			return nil, fmt.Errorf("reading lock %v: %w", b.url+"/"+file.Key, err)
+		}
+		if l.LockID == b.lockID {
+			continue
+		}
+		locks = append(locks, lockFile{key: file.Key, content: l})
+	}
+	return locks, nil
+}
+
+// StackLocks returns the locks held on the given stack by other processes.
+func (b *localBackend) StackLocks(ctx context.Context, stackRef backend.StackReference) ([]StackLock, error) {
+	locks, err := b.stackLocks(ctx, stackRef.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]StackLock, len(locks))
+	for i, l := range locks {
+		result[i] = StackLock{
+			Path:        b.url + "/" + l.key,
+			Username:    l.content.Username,
+			Hostname:    l.content.Hostname,
+			Pid:         l.content.Pid,
+			Timestamp:   l.content.Timestamp,
+			Expires:     l.content.Expires,
+			StaleReason: l.content.staleReason(now),
 		}
 	}
+	return result, nil
+}
 
-	if len(lockKeys) > 0 {
-		errorString := fmt.Sprintf("the stack is currently locked by %v lock(s). Either wait for the other "+
-			"process(es) to end or manually delete the lock file(s).", len(lockKeys))
+// UnlockStack breaks the locks held on the given stack by other processes. Unless force is true, it refuses to break
+// any lock whose holder may still be alive.
+func (b *localBackend) UnlockStack(ctx context.Context, stackRef backend.StackReference, force bool) error {
+	locks, err := b.stackLocks(ctx, stackRef.Name())
+	if err != nil {
+		return err
+	}
 
-		for _, lock := range lockKeys {
-			content, err := b.bucket.ReadAll(ctx, lock)
-			if err != nil {
-				return err
+	if !force {
+		now := time.Now()
+		var live []lockFile
+		for _, l := range locks {
+			if l.content.staleReason(now) == "" {
+				live = append(live, l)
 			}
-			l := &lockContent{}
-			err = json.Unmarshal(content, &l)
-			if err != nil {
-				return err
+		}
+		if len(live) > 0 {
+			errorString := fmt.Sprintf("the stack is locked by %v lock(s) whose holder may still be running. "+
+				"Breaking a lock that is in use can corrupt the stack's state; run `pulumi stack unlock --force` to "+
+				"break them anyway.",
+				len(live))
+			for _, l := range live {
+				errorString += fmt.Sprintf("\n  %v: created by %v", b.url+"/"+l.key, describeLock(l.content))
 			}
+			return errors.New(errorString)
+		}
+	}
+
+	for _, l := range locks {
+		if err := b.breakLock(ctx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// breakLock deletes the given lock file, unless it has been replaced by another lock since it was read.
+func (b *localBackend) breakLock(ctx context.Context, l lockFile) error {
+	content, err := b.bucket.ReadAll(ctx, l.key)
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil
+		}
+		return err
+	}
+	current := &lockContent{}
+	if err = json.Unmarshal(content, &current); err != nil {
+		return err
+	}
+	renewed := current.Expires != nil && (l.content.Expires == nil || !current.Expires.Equal(*l.content.Expires))
+	if current.LockID != l.content.LockID || !current.Timestamp.Equal(l.content.Timestamp) || renewed {
+		return nil
+	}
 
-			errorString += fmt.Sprintf("\n  %v: created by %v@%v (pid %v) at %v",
-				b.url+"/"+lock,
-				l.Username,
-				l.Hostname,
-				l.Pid,
-				l.Timestamp.Format(time.RFC3339),
-			)
+	err = b.bucket.Delete(ctx, l.key)
+	if err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		return err
+	}
+	return nil
+}
+
+func describeLock(l *lockContent) string {
+	return fmt.Sprintf("%v@%v (pid %v) at %v", l.Username, l.Hostname, l.Pid, l.Timestamp.Format(time.RFC3339))
+}
+
+// checkForLock looks for any existing locks for this stack, and returns a helpful diagnostic if there is one. Stale
+// locks, whose holder is known to be gone, are broken.
+func (b *localBackend) checkForLock(ctx context.Context, stackRef backend.StackReference) error {
+	locks, err := b.stackLocks(ctx, stackRef.Name())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var live []lockFile
+	for _, l := range locks {
+		reason := l.content.staleReason(now)
+		if reason == "" {
+			live = append(live, l)
+			continue
+		}
+
+		b.d.Warningf(diag.Message("", "breaking stale lock %v created by %v: %v"),
+			b.url+"/"+l.key, describeLock(l.content), reason)
+		if err := b.breakLock(ctx, l); err != nil {
+			return err
+		}
+	}
+
+	if len(live) > 0 {
+		errorString := fmt.Sprintf("the stack is currently locked by %v lock(s). Either wait for the other "+
+			"process(es) to end or, if they are no longer running, run `pulumi stack unlock`.", len(live))
+
+		for _, l := range live {
+			errorString += fmt.Sprintf("\n  %v: created by %v", b.url+"/"+l.key, describeLock(l.content))
 		}
 
 		return errors.New(errorString)
@@ -103,12 +280,11 @@ func (b *localBackend) checkForLock(ctx context.Context, stackRef backend.StackR
 }
 
 func (b *localBackend) Lock(ctx context.Context, stackRef backend.StackReference) error {
-	//
 	err := b.checkForLock(ctx, stackRef)
 	if err != nil {
 		return err
 	}
-	lockContent, err := newLockContent()
+	lockContent, err := newLockContent(b.lockID)
 	if err != nil {
 		return err
 	}
@@ -116,8 +292,17 @@ func (b *localBackend) Lock(ctx context.Context, stackRef backend.StackReference
 	if err != nil {
 		return err
 	}
-	err = b.bucket.WriteAll(ctx, b.lockPath(stackRef.Name()), content, nil)
+
+	var opts *blob.WriterOptions
+	if b.conditionalWrites {
+		opts = createIfNotExists()
+	}
+	err = b.bucket.WriteAll(ctx, b.lockPath(stackRef.Name()), content, opts)
 	if err != nil {
+		// If another process acquired the lock first, report who holds it.
+		if lockErr := b.checkForLock(ctx, stackRef); lockErr != nil {
+			return lockErr
+		}
 		return err
 	}
 	err = b.checkForLock(ctx, stackRef)
@@ -125,19 +310,213 @@ func (b *localBackend) Lock(ctx context.Context, stackRef backend.StackReference
 		b.Unlock(ctx, stackRef)
 		return err
 	}
+
+	b.startHeartbeat(stackRef.Name(), lockContent)
 	return nil
 }
 
 func (b *localBackend) Unlock(ctx context.Context, stackRef backend.StackReference) {
-	err := b.bucket.Delete(ctx, b.lockPath(stackRef.Name()))
+	b.stopHeartbeat(stackRef.Name())
+
+	lockPath := b.lockPath(stackRef.Name())
+	if b.conditionalWrites {
+		// The lock file is shared by every process, so make sure that it is still ours before deleting it.
+		if content, err := b.bucket.ReadAll(ctx, lockPath); err == nil {
+			l := &lockContent{}
+			if err = json.Unmarshal(content, &l); err == nil && l.LockID != b.lockID {
+				return
+			}
+		}
+	}
+
+	err := b.bucket.Delete(ctx, lockPath)
 	if err != nil {
 		b.d.Errorf(
 			diag.Message("", "there was a problem deleting the lock at %v, manual clean up may be required: %v"),
-			path.Join(b.url, b.lockPath(stackRef.Name())),
+			path.Join(b.url, lockPath),
 			err)
 	}
 }
 
+// WithStackLock calls f while holding the lock of the given stack.
+func (b *localBackend) WithStackLock(ctx context.Context, stk backend.Stack, f func() error) error {
+	stackRef := stk.Ref()
+	if err := b.Lock(ctx, stackRef); err != nil {
+		return err
+	}
+	defer b.Unlock(ctx, stackRef)
+
+	b.leaseMutex.Lock()
+	b.held[stackRef.Name()] = true
+	b.leaseMutex.Unlock()
+	defer func() {
+		b.leaseMutex.Lock()
+		delete(b.held, stackRef.Name())
+		b.leaseMutex.Unlock()
+	}()
+
+	return f()
+}
+
+// lockStack locks the given stack, unless its lock is already held by WithStackLock, and returns a function that
+// releases the lock if it was taken.
+func (b *localBackend) lockStack(ctx context.Context, stackRef backend.StackReference) (func(), error) {
+	b.leaseMutex.Lock()
+	held := b.held[stackRef.Name()]
+	b.leaseMutex.Unlock()
+	if held {
+		return func() {}, nil
+	}
+
+	if err := b.Lock(ctx, stackRef); err != nil {
+		return nil, err
+	}
+	return func() { b.Unlock(ctx, stackRef) }, nil
+}
+
+// heartbeat renews the lease of a lock held by this backend until it is stopped.
+type heartbeat struct {
+	done    chan struct{} // closed to stop renewing the lease.
+	stopped chan struct{} // closed once the lease is no longer being renewed.
+}
+
+// startHeartbeat periodically renews the lease of the given lock until the stack is unlocked.
+func (b *localBackend) startHeartbeat(stack tokens.Name, l *lockContent) {
+	hb := &heartbeat{done: make(chan struct{}), stopped: make(chan struct{})}
+
+	b.leaseMutex.Lock()
+	if previous, has := b.leases[stack]; has {
+		close(previous.done)
+	}
+	b.leases[stack] = hb
+	b.leaseMutex.Unlock()
+
+	go func() {
+		defer close(hb.stopped)
+
+		ticker := time.NewTicker(lockHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hb.done:
+				return
+			case <-ticker.C:
+				if !b.renewLease(stack, l) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// stopHeartbeat stops renewing the lease of the lock held on the given stack, if any. It waits for a renewal that is
+// already underway to finish, so that the lock can't be rewritten once it has been deleted.
+func (b *localBackend) stopHeartbeat(stack tokens.Name) {
+	b.leaseMutex.Lock()
+	hb, has := b.leases[stack]
+	if has {
+		close(hb.done)
+		delete(b.leases, stack)
+	}
+	b.leaseMutex.Unlock()
+
+	if has {
+		<-hb.stopped
+	}
+}
+
+// renewLease extends the lease of the given lock. It returns false if the lock no longer belongs to this backend, in
+// which case the lease must no longer be renewed.
+func (b *localBackend) renewLease(stack tokens.Name, l *lockContent) bool {
+	ctx := context.Background()
+	lockPath := b.lockPath(stack)
+
+	// Make sure that the lock hasn't been broken before renewing it, as that would recreate it. Where the bucket
+	// supports it, the lock is only rewritten if it hasn't changed since it was read.
+	var opts *blob.WriterOptions
+	attrs, err := b.bucket.Attributes(ctx, lockPath)
+	if err == nil && b.conditionalWrites {
+		opts = ifUnchanged(attrs)
+	}
+	var content []byte
+	if err == nil {
+		content, err = b.bucket.ReadAll(ctx, lockPath)
+	}
+	if err == nil {
+		current := &lockContent{}
+		if err = json.Unmarshal(content, &current); err == nil && current.LockID != l.LockID {
+			err = errors.New("the lock is held by another process")
+		}
+	}
+	if err != nil {
+		b.d.Warningf(diag.Message("", "the lock at %v was broken by another process: %v"),
+			path.Join(b.url, lockPath), err)
+		return false
+	}
+
+	expires := time.Now().Add(lockLeaseDuration)
+	l.Expires = &expires
+	content, err = json.Marshal(l)
+	contract.AssertNoError(err)
+	if err = b.bucket.WriteAll(ctx, lockPath, content, opts); err != nil {
+		// A conditional write fails if the lock changed after it was read, in which case it may have been broken.
+		if opts != nil && !b.ownsLock(ctx, lockPath, l) {
+			b.d.Warningf(diag.Message("", "the lock at %v was broken by another process"), path.Join(b.url, lockPath))
+			return false
+		}
+		b.d.Warningf(diag.Message("", "there was a problem renewing the lock at %v: %v"),
+			path.Join(b.url, lockPath), err)
+	}
+	return true
+}
+
+// createIfNotExists returns writer options that make the write fail if the key already exists, on the blob stores that
+// support conditional writes.
+func createIfNotExists() *blob.WriterOptions {
+	return &blob.WriterOptions{
+		BeforeWrite: func(as func(interface{}) bool) error {
+			var obj **storage.ObjectHandle
+			if as(&obj) {
+				*obj = (*obj).If(storage.Conditions{DoesNotExist: true})
+			}
+			var opts *azblob.UploadStreamToBlockBlobOptions
+			if as(&opts) {
+				opts.AccessConditions.ModifiedAccessConditions.IfNoneMatch = azblob.ETagAny
+			}
+			return nil
+		},
+	}
+}
+
+// ownsLock returns true if the lock at the given path exists and is the given lock.
+func (b *localBackend) ownsLock(ctx context.Context, lockPath string, l *lockContent) bool {
+	content, err := b.bucket.ReadAll(ctx, lockPath)
+	if err != nil {
+		return false
+	}
+	current := &lockContent{}
+	return json.Unmarshal(content, &current) == nil && current.LockID == l.LockID
+}
+
+// ifUnchanged returns writer options that make the write fail if the key has changed since the given attributes were
+// read, on the blob stores that support conditional writes.
+func ifUnchanged(attrs *blob.Attributes) *blob.WriterOptions {
+	return &blob.WriterOptions{
+		BeforeWrite: func(as func(interface{}) bool) error {
+			var obj **storage.ObjectHandle
+			var objAttrs storage.ObjectAttrs
+			if as(&obj) && attrs.As(&objAttrs) {
+				*obj = (*obj).If(storage.Conditions{GenerationMatch: objAttrs.Generation})
+			}
+			var opts *azblob.UploadStreamToBlockBlobOptions
+			if as(&opts) {
+				opts.AccessConditions.ModifiedAccessConditions.IfMatch = azblob.ETag(attrs.ETag)
+			}
+			return nil
+		},
+	}
+}
+
 func lockDir() string {
 	return path.Join(workspace.BookkeepingDir, workspace.LockDir)
 }
@@ -149,5 +528,8 @@ func stackLockDir(stack tokens.Name) string {
 
 func (b *localBackend) lockPath(stack tokens.Name) string {
 	contract.Require(stack != "", "stack")
+	if b.conditionalWrites {
+		return path.Join(stackLockDir(stack), sharedLockName)
+	}
 	return path.Join(stackLockDir(stack), b.lockID+".json")
 }
@@ -15,6 +15,9 @@
 package filestate
 
 import (
+	"context"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/v3/secrets"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
@@ -41,3 +44,34 @@ func (sp *localSnapshotPersister) Save(snapshot *deploy.Snapshot) error {
 func (b *localBackend) newSnapshotPersister(stackName tokens.Name, sm secrets.Manager) *localSnapshotPersister {
 	return &localSnapshotPersister{name: stackName, backend: b, sm: sm}
 }
+
+// lockingSnapshotPersister persists snapshots of a stack outside of an update, holding the stack's lock while it
+// does so.
+type lockingSnapshotPersister struct {
+	ctx       context.Context
+	stackRef  backend.StackReference
+	persister *localSnapshotPersister
+}
+
+func (sp *lockingSnapshotPersister) SecretsManager() secrets.Manager {
+	return sp.persister.SecretsManager()
+}
+
+func (sp *lockingSnapshotPersister) Save(snapshot *deploy.Snapshot) error {
+	unlock, err := sp.persister.backend.lockStack(sp.ctx, sp.stackRef)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return sp.persister.Save(snapshot)
+}
+
+func (b *localBackend) SnapshotPersister(ctx context.Context, stk backend.Stack,
+	sm secrets.Manager) backend.SnapshotPersister {
+	return &lockingSnapshotPersister{
+		ctx:       ctx,
+		stackRef:  stk.Ref(),
+		persister: b.newSnapshotPersister(stk.Ref().Name(), sm),
+	}
+}
@@ -392,9 +392,8 @@ func (b *localBackend) backupDirectory(stack tokens.Name) string {
 	return filepath.Join(b.StateDir(), workspace.BackupDir, fsutil.NamePath(stack))
 }
 
-// getHistory returns locally stored update history. The first element of the result will be
-// the most recent update record.
-func (b *localBackend) getHistory(name tokens.Name, pageSize int, page int) ([]backend.UpdateInfo, error) {
+// historyEntries returns the history entries of the given stack, most recent first.
+func (b *localBackend) historyEntries(name tokens.Name) ([]*blob.ListObject, error) {
 	contract.Require(name != "", "name")
 
 	dir := b.historyDirectory(name)
@@ -426,6 +425,50 @@ func (b *localBackend) getHistory(name tokens.Name, pageSize int, page int) ([]b
 
 		historyEntries = append(historyEntries, file)
 	}
+	return historyEntries, nil
+}
+
+// readHistoryEntry reads the update recorded by the history entry at the given index of the given list of entries,
+// which must be sorted most recent first.
+func (b *localBackend) readHistoryEntry(entries []*blob.ListObject, i int) (backend.UpdateInfo, error) {
+	update, err := b.readUpdateInfo(entries[i].Key)
+	if err != nil {
+		return update, err
+	}
+
+	// Entries written by older versions don't record their version until the history is first pruned, so number them
+	// by their position in the history.
+	if update.Version == 0 {
+		update.Version = len(entries) - i
+	}
+	return update, nil
+}
+
+// readUpdateInfo reads the update recorded by the history entry at the given path.
+func (b *localBackend) readUpdateInfo(filepath string) (backend.UpdateInfo, error) {
+	var update backend.UpdateInfo
+	byts, err := b.bucket.ReadAll(context.TODO(), filepath)
+	if err != nil {
+		return update, fmt.Errorf("reading history file %s: %w", filepath, err)
+	}
+	m := encoding.JSON
+	if encoding.IsCompressed(byts) {
+		m = encoding.Gzip(m)
+	}
+	err = m.Unmarshal(byts, &update)
+	if err != nil {
+		return update, fmt.Errorf("reading history file %s: %w", filepath, err)
+	}
+	return update, nil
+}
+
+// getHistory returns locally stored update history. The first element of the result will be
+// the most recent update record.
+func (b *localBackend) getHistory(name tokens.Name, pageSize int, page int) ([]backend.UpdateInfo, error) {
+	historyEntries, err := b.historyEntries(name)
+	if err != nil {
+		return nil, err
+	}
 
 	start := 0
 	end := len(historyEntries) - 1
@@ -443,27 +486,129 @@ func (b *localBackend) getHistory(name tokens.Name, pageSize int, page int) ([]b
 	var updates []backend.UpdateInfo
 
 	for i := start; i <= end; i++ {
-		file := historyEntries[i]
-		filepath := file.Key
-
-		var update backend.UpdateInfo
-		b, err := b.bucket.ReadAll(context.TODO(), filepath)
+		update, err := b.readHistoryEntry(historyEntries, i)
 		if err != nil {
-			return nil, fmt.Errorf("reading history file %s: %w", filepath, err)
+			return nil, err
 		}
-		m := encoding.JSON
-		if encoding.IsCompressed(b) {
-			m = encoding.Gzip(m)
+		updates = append(updates, update)
+	}
+
+	return updates, nil
+}
+
+// historyVersion records where the history entry and checkpoint of a numbered update are stored, so that they can be
+// found without listing the stack's history. It is stored in the history directory as
+// <stack-name>-v<version>.version.json, and the most recent update is also recorded as
+// <stack-name>-latest.version.json.
+type historyVersion struct {
+	// Version is the update's version.
+	Version int `json:"version"`
+	// Timestamp is the timestamp in the names of the update's history entry and checkpoint.
+	Timestamp int64 `json:"timestamp"`
+	// Ext is the extension of the update's history entry and checkpoint.
+	Ext string `json:"ext"`
+	// FirstIndexed is the first version recorded this way. Older versions predate these records and can only be found
+	// by reading the history entries. Only set in the record of the most recent update.
+	FirstIndexed int `json:"firstIndexed,omitempty"`
+}
+
+// historyVersionPath returns the path of the record of the given version of the given stack, or of its most recent
+// update if version is zero.
+func (b *localBackend) historyVersionPath(name tokens.Name, version int) string {
+	label := "latest"
+	if version != 0 {
+		label = fmt.Sprintf("v%d", version)
+	}
+	return path.Join(b.historyDirectory(name), fmt.Sprintf("%s-%s.version.json", name, label))
+}
+
+// readHistoryVersion reads the record of the given version of the given stack, or of its most recent update if version
+// is zero. It returns nil if there is no such record.
+func (b *localBackend) readHistoryVersion(name tokens.Name, version int) (*historyVersion, error) {
+	byts, err := b.bucket.ReadAll(context.TODO(), b.historyVersionPath(name, version))
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, nil
 		}
-		err = m.Unmarshal(b, &update)
+		return nil, err
+	}
+	var v historyVersion
+	if err := encoding.JSON.Unmarshal(byts, &v); err != nil {
+		return nil, fmt.Errorf("reading history version of stack %s: %w", name, err)
+	}
+	return &v, nil
+}
+
+// writeHistoryVersion writes the record of the given version of the given stack.
+func (b *localBackend) writeHistoryVersion(name tokens.Name, version int, v historyVersion) error {
+	byts, err := encoding.JSON.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.bucket.WriteAll(context.TODO(), b.historyVersionPath(name, version), byts, nil)
+}
+
+// getHistoricalCheckpoint returns the checkpoint saved by the update with the given version.
+func (b *localBackend) getHistoricalCheckpoint(name tokens.Name, version int) (*apitype.CheckpointV3, error) {
+	latest, err := b.readHistoryVersion(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil || version < latest.FirstIndexed {
+		return b.findHistoricalCheckpoint(name, version)
+	}
+
+	v, err := b.readHistoryVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("version %d of stack %s was not found in its history", version, name)
+	}
+	chkpath := path.Join(b.historyDirectory(name), fmt.Sprintf("%s-%d.checkpoint.%s", name, v.Timestamp, v.Ext))
+	return b.readHistoricalCheckpoint(name, version, chkpath)
+}
+
+// findHistoricalCheckpoint returns the checkpoint saved by the update with the given version by reading the stack's
+// history entries. It is only needed for updates that predate the history's version records.
+func (b *localBackend) findHistoricalCheckpoint(name tokens.Name, version int) (*apitype.CheckpointV3, error) {
+	historyEntries, err := b.historyEntries(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range historyEntries {
+		update, err := b.readHistoryEntry(historyEntries, i)
 		if err != nil {
-			return nil, fmt.Errorf("reading history file %s: %w", filepath, err)
+			return nil, err
+		}
+		if update.Version != version {
+			continue
 		}
 
-		updates = append(updates, update)
+		chkpath := strings.Replace(historyEntries[i].Key, ".history.", ".checkpoint.", 1)
+		return b.readHistoricalCheckpoint(name, version, chkpath)
 	}
 
-	return updates, nil
+	return nil, fmt.Errorf("version %d of stack %s was not found in its history", version, name)
+}
+
+// readHistoricalCheckpoint reads the checkpoint of the given version of the given stack from the given path.
+func (b *localBackend) readHistoricalCheckpoint(name tokens.Name, version int,
+	chkpath string) (*apitype.CheckpointV3, error) {
+
+	byts, err := b.bucket.ReadAll(context.TODO(), chkpath)
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, fmt.Errorf("the checkpoint of version %d of stack %s no longer exists", version, name)
+		}
+		return nil, err
+	}
+	m := encoding.JSON
+	if encoding.IsCompressed(byts) {
+		m = encoding.Gzip(m)
+	}
+	return stack.UnmarshalVersionedCheckpointToLatestCheckpoint(m, byts)
 }
 
 func (b *localBackend) renameHistory(oldName tokens.Name, newName tokens.Name) error {
@@ -516,7 +661,8 @@ func (b *localBackend) addToHistory(name tokens.Name, update backend.UpdateInfo)
 	dir := b.historyDirectory(name)
 
 	// Prefix for the update and checkpoint files.
-	pathPrefix := path.Join(dir, fmt.Sprintf("%s-%d", name, time.Now().UnixNano()))
+	timestamp := time.Now().UnixNano()
+	pathPrefix := path.Join(dir, fmt.Sprintf("%s-%d", name, timestamp))
 
 	m, ext := encoding.JSON, "json"
 	if b.gzip {
@@ -524,6 +670,25 @@ func (b *localBackend) addToHistory(name tokens.Name, update backend.UpdateInfo)
 		ext += ".gz"
 	}
 
+	// Number the update after the most recent one in the history. Histories written before the most recent update was
+	// recorded need to be read once to find it.
+	latest, err := b.readHistoryVersion(name, 0)
+	if err != nil {
+		return err
+	}
+	if latest == nil {
+		updates, err := b.getHistory(name, 1 /*pageSize*/, 1 /*page*/)
+		if err != nil {
+			return err
+		}
+		latest = &historyVersion{}
+		if len(updates) > 0 {
+			latest.Version = updates[0].Version
+		}
+		latest.FirstIndexed = latest.Version + 1
+	}
+	update.Version = latest.Version + 1
+
 	// Save the history file.
 	byts, err := m.Marshal(&update)
 	if err != nil {
@@ -537,5 +702,15 @@ func (b *localBackend) addToHistory(name tokens.Name, update backend.UpdateInfo)
 
 	// Make a copy of the checkpoint file. (Assuming it already exists.)
 	checkpointFile := fmt.Sprintf("%s.checkpoint.%s", pathPrefix, ext)
-	return b.bucket.Copy(context.TODO(), checkpointFile, b.stackPath(name), nil)
+	if err = b.bucket.Copy(context.TODO(), checkpointFile, b.stackPath(name), nil); err != nil {
+		return err
+	}
+
+	// Record where the update is stored, then record it as the most recent update.
+	v := historyVersion{Version: update.Version, Timestamp: timestamp, Ext: ext}
+	if err = b.writeHistoryVersion(name, update.Version, v); err != nil {
+		return err
+	}
+	v.FirstIndexed = latest.FirstIndexed
+	return b.writeHistoryVersion(name, 0, v)
 }
@@ -861,7 +861,7 @@ func (b *cloudBackend) Preview(ctx context.Context, stack backend.Stack,
 		ShowLink: true,
 	}
 	return b.apply(
-		ctx, apitype.PreviewUpdate, stack, op, opts, nil /*events*/)
+		ctx, apitype.PreviewUpdate, stack, op, opts, op.Events)
 }
 
 func (b *cloudBackend) Update(ctx context.Context, stack backend.Stack,
@@ -953,6 +953,14 @@ func (b *cloudBackend) createAndStartUpdate(
 	return update, version, token, nil
 }
 
+// displayStdout returns the writer that the display with the given options writes to.
+func displayStdout(opts display.Options) io.Writer {
+	if opts.Stdout != nil {
+		return opts.Stdout
+	}
+	return os.Stdout
+}
+
 // apply actually performs the provided type of update on a stack hosted in the Pulumi Cloud.
 func (b *cloudBackend) apply(
 	ctx context.Context, kind apitype.UpdateKind, stack backend.Stack,
@@ -961,9 +969,10 @@ func (b *cloudBackend) apply(
 
 	actionLabel := backend.ActionLabel(kind, opts.DryRun)
 
-	if !(op.Opts.Display.JSONDisplay || op.Opts.Display.Type == display.DisplayWatch) {
+	if !(op.Opts.Display.JSONDisplay || op.Opts.Display.ReportFormat != "" ||
+		op.Opts.Display.Type == display.DisplayWatch) {
 		// Print a banner so it's clear this is going to the cloud.
-		fmt.Printf(op.Opts.Display.Color.Colorize(
+		fmt.Fprintf(displayStdout(op.Opts.Display), op.Opts.Display.Color.Colorize(
 			colors.SpecHeadline+"%s (%s)"+colors.Reset+"\n\n"), actionLabel, stack.Ref())
 	}
 
@@ -974,7 +983,8 @@ func (b *cloudBackend) apply(
 		return nil, nil, result.FromError(err)
 	}
 
-	if !op.Opts.Display.SuppressPermalink && opts.ShowLink && !op.Opts.Display.JSONDisplay {
+	if !op.Opts.Display.SuppressPermalink && opts.ShowLink && !op.Opts.Display.JSONDisplay &&
+		op.Opts.Display.ReportFormat == "" {
 		// Print a URL at the beginning of the update pointing to the Pulumi Service.
 		b.printLink(op, opts, update, version)
 	}
@@ -994,7 +1004,7 @@ func (b *cloudBackend) printLink(
 		link = b.CloudConsoleURL(base, "previews", update.UpdateID)
 	}
 	if link != "" {
-		fmt.Printf(op.Opts.Display.Color.Colorize(
+		fmt.Fprintf(displayStdout(op.Opts.Display), op.Opts.Display.Color.Colorize(
 			colors.SpecHeadline+"View Live: "+
 				colors.Underline+colors.BrightBlue+"%s"+colors.Reset+"\n\n"), link)
 	}
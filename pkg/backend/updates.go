@@ -113,4 +113,6 @@ type UpdateInfo struct {
 	Result          UpdateResult            `json:"result"`
 	EndTime         int64                   `json:"endTime"`
 	ResourceChanges display.ResourceChanges `json:"resourceChanges,omitempty"`
+	// StepTimings records when each step that changed a resource started and finished.
+	StepTimings []apitype.StepTiming `json:"stepTimings,omitempty"`
 }
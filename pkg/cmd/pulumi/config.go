@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -31,7 +32,9 @@ import (
 
 	"github.com/pulumi/pulumi/pkg/v3/backend"
 	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
 	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
@@ -41,6 +44,7 @@ import (
 func newConfigCmd() *cobra.Command {
 	var stack string
 	var showSecrets bool
+	var showOrigin bool
 	var jsonOut bool
 
 	cmd := &cobra.Command{
@@ -60,13 +64,16 @@ func newConfigCmd() *cobra.Command {
 				return err
 			}
 
-			return listConfig(stack, showSecrets, jsonOut)
+			return listConfig(stack, showSecrets, showOrigin, jsonOut)
 		}),
 	}
 
 	cmd.Flags().BoolVar(
 		&showSecrets, "show-secrets", false,
 		"Show secret values when listing config instead of displaying blinded values")
+	cmd.Flags().BoolVar(
+		&showOrigin, "show-origin", false,
+		"Show the stack settings file that each config value came from, including the files that the stack extends")
 	cmd.Flags().BoolVarP(
 		&jsonOut, "json", "j", false,
 		"Emit output as JSON")
@@ -531,6 +538,11 @@ func newConfigSetCmd(stack *string) *cobra.Command {
 				}
 			}
 
+			proj, _, err := readProject()
+			if err != nil {
+				return err
+			}
+
 			ps, err := loadProjectStack(s)
 			if err != nil {
 				return err
@@ -540,6 +552,9 @@ func newConfigSetCmd(stack *string) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if err = validateConfigValue(proj, ps.Config, key, value, secret, path); err != nil {
+				return err
+			}
 
 			return saveProjectStack(s, ps)
 		}),
@@ -589,6 +604,11 @@ func newConfigSetAllCmd(stack *string) *cobra.Command {
 				return err
 			}
 
+			proj, _, err := readProject()
+			if err != nil {
+				return err
+			}
+
 			ps, err := loadProjectStack(s)
 			if err != nil {
 				return err
@@ -605,6 +625,9 @@ func newConfigSetAllCmd(stack *string) *cobra.Command {
 				if err != nil {
 					return err
 				}
+				if err = validateConfigValue(proj, ps.Config, key, value, false /*secret*/, path); err != nil {
+					return err
+				}
 			}
 
 			for _, sArg := range secretArgs {
@@ -626,6 +649,9 @@ func newConfigSetAllCmd(stack *string) *cobra.Command {
 				if err != nil {
 					return err
 				}
+				if err = validateConfigValue(proj, ps.Config, key, value, true /*secret*/, path); err != nil {
+					return err
+				}
 			}
 
 			return saveProjectStack(s, ps)
@@ -645,6 +671,43 @@ func newConfigSetAllCmd(stack *string) *cobra.Command {
 	return setCmd
 }
 
+// validateConfigValue checks a config value that has been set in m against the config schema of the project. value is
+// the plaintext that was set. If the key is a path, the value of its top-level key is checked instead, without
+// checking the types of secrets.
+func validateConfigValue(proj *workspace.Project, m config.Map, key config.Key, value string, secret, path bool) error {
+	if !path {
+		// The no-op decrypter returns the "ciphertext" of a secure value as is, so secrets are checked using their
+		// plaintext.
+		v := config.NewValue(value)
+		if secret {
+			v = config.NewSecureValue(value)
+		}
+		return proj.ValidateConfigValue(key, v, config.NopDecrypter)
+	}
+
+	rootKey, err := configRootKey(key)
+	if err != nil {
+		return err
+	}
+	return proj.ValidateConfigValue(rootKey, m[rootKey], nil /*dec*/)
+}
+
+// configRootKey returns the top-level key of a config key that is a path to a value inside a map or list.
+func configRootKey(key config.Key) (config.Key, error) {
+	p, err := resource.ParsePropertyPath(key.Name())
+	if err != nil {
+		return config.Key{}, err
+	}
+	if len(p) == 0 {
+		return config.Key{}, errors.New("empty config key path")
+	}
+	name, ok := p[0].(string)
+	if !ok {
+		return config.Key{}, errors.New("first path segment of config key must be a string")
+	}
+	return config.MustMakeKey(key.Namespace(), name), nil
+}
+
 func parseKeyValuePair(pair string) (config.Key, string, error) {
 	// Split the arg on the first '=' to separate key and value.
 	splitArg := strings.SplitN(pair, "=", 2)
@@ -691,6 +754,82 @@ func saveProjectStack(stack backend.Stack, ps *workspace.ProjectStack) error {
 	return ps.Save(stackConfigFile)
 }
 
+// loadStackConfig loads the config of a stack, layered from its stack settings file and the files that it extends,
+// along with the path of the file that each value came from. If sm is not nil, the secure values from the files that
+// the stack extends are reencrypted by it; otherwise they are left encrypted by the secrets providers of their files.
+func loadStackConfig(stack backend.Stack, sm secrets.Manager) (config.Map, map[config.Key]string, error) {
+	path, err := getProjectStackPath(stack)
+	if err != nil {
+		return nil, nil, err
+	}
+	layers, err := workspace.LoadProjectStackLayers(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reencrypt func(workspace.ProjectStackLayer, config.Value) (config.Value, error)
+	if sm != nil {
+		decrypters := newConfigDecrypters(stack, path)
+		reencrypt = func(layer workspace.ProjectStackLayer, v config.Value) (config.Value, error) {
+			dec, err := decrypters.decrypter(layer.Path)
+			if err != nil {
+				return config.Value{}, err
+			}
+			enc, err := sm.Encrypter()
+			if err != nil {
+				return config.Value{}, err
+			}
+			return v.Copy(dec, enc)
+		}
+	}
+	return workspace.MergeConfigLayers(layers, reencrypt)
+}
+
+// configDecrypters creates and caches the decrypters for the secure values of a stack's config, by the path of the
+// stack settings file that they came from.
+type configDecrypters struct {
+	stack      backend.Stack
+	stackPath  string
+	decrypters map[string]config.Decrypter
+}
+
+func newConfigDecrypters(stack backend.Stack, stackPath string) *configDecrypters {
+	return &configDecrypters{stack: stack, stackPath: stackPath, decrypters: make(map[string]config.Decrypter)}
+}
+
+// decrypter returns the decrypter for the secure values that came from the stack settings file at path.
+func (d *configDecrypters) decrypter(path string) (config.Decrypter, error) {
+	if dec, ok := d.decrypters[path]; ok {
+		return dec, nil
+	}
+	var dec config.Decrypter
+	var err error
+	if path == d.stackPath {
+		dec, err = getStackDecrypter(d.stack)
+	} else {
+		var ps *workspace.ProjectStack
+		if ps, err = workspace.LoadProjectStack(path); err == nil {
+			dec, err = stack.NewConfigLayerDecrypter(workspace.ProjectStackLayer{Path: path, Stack: ps})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.decrypters[path] = dec
+	return dec, nil
+}
+
+// prettyOrigin returns the path of the stack settings file that a config value came from, relative to the current
+// directory if possible.
+func prettyOrigin(path string) string {
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			return rel
+		}
+	}
+	return path
+}
+
 func parseConfigKey(key string) (config.Key, error) {
 	// As a convenience, we'll treat any key with no delimiter as if:
 	// <program-name>:<key> had been written instead
@@ -731,24 +870,28 @@ type configValueJSON struct {
 	Value       *string     `json:"value,omitempty"`
 	ObjectValue interface{} `json:"objectValue,omitempty"`
 	Secret      bool        `json:"secret"`
+	// Origin is the stack settings file that the value came from, if --show-origin was passed.
+	Origin string `json:"origin,omitempty"`
 }
 
-func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool) error {
-	ps, err := loadProjectStack(stack)
+func listConfig(stack backend.Stack, showSecrets, showOrigin, jsonOut bool) error {
+	cfg, origins, err := loadStackConfig(stack, nil /*sm*/)
+	if err != nil {
+		return err
+	}
+	stackPath, err := getProjectStackPath(stack)
 	if err != nil {
 		return err
 	}
 
-	cfg := ps.Config
-
-	// By default, we will use a blinding decrypter to show "[secret]". If requested, display secrets in plaintext.
-	decrypter := config.NewBlindingDecrypter()
-	if cfg.HasSecureValue() && showSecrets {
-		dec, decerr := getStackDecrypter(stack)
-		if decerr != nil {
-			return decerr
+	// By default, we will use a blinding decrypter to show "[secret]". If requested, display secrets in plaintext,
+	// decrypting each with the secrets provider of the file that it came from.
+	decrypters := newConfigDecrypters(stack, stackPath)
+	decrypter := func(key config.Key) (config.Decrypter, error) {
+		if !showSecrets || !cfg[key].Secure() {
+			return config.NewBlindingDecrypter(), nil
 		}
-		decrypter = dec
+		return decrypters.decrypter(origins[key])
 	}
 
 	var keys config.KeyArray
@@ -765,8 +908,15 @@ func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool) error {
 			entry := configValueJSON{
 				Secret: cfg[key].Secure(),
 			}
+			if showOrigin {
+				entry.Origin = prettyOrigin(origins[key])
+			}
 
-			decrypted, err := cfg[key].Value(decrypter)
+			dec, err := decrypter(key)
+			if err != nil {
+				return err
+			}
+			decrypted, err := cfg[key].Value(dec)
 			if err != nil {
 				return fmt.Errorf("could not decrypt configuration value: %w", err)
 			}
@@ -795,18 +945,30 @@ func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool) error {
 			return err
 		}
 	} else {
+		headers := []string{"KEY", "VALUE"}
+		if showOrigin {
+			headers = append(headers, "ORIGIN")
+		}
 		rows := []cmdutil.TableRow{}
 		for _, key := range keys {
-			decrypted, err := cfg[key].Value(decrypter)
+			dec, err := decrypter(key)
+			if err != nil {
+				return err
+			}
+			decrypted, err := cfg[key].Value(dec)
 			if err != nil {
 				return fmt.Errorf("could not decrypt configuration value: %w", err)
 			}
 
-			rows = append(rows, cmdutil.TableRow{Columns: []string{prettyKey(key), decrypted}})
+			columns := []string{prettyKey(key), decrypted}
+			if showOrigin {
+				columns = append(columns, prettyOrigin(origins[key]))
+			}
+			rows = append(rows, cmdutil.TableRow{Columns: columns})
 		}
 
 		cmdutil.PrintTable(cmdutil.Table{
-			Headers: []string{"KEY", "VALUE"},
+			Headers: headers,
 			Rows:    rows,
 		})
 	}
@@ -819,12 +981,14 @@ func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool) error {
 }
 
 func getConfig(stack backend.Stack, key config.Key, path, jsonOut bool) error {
-	ps, err := loadProjectStack(stack)
+	cfg, origins, err := loadStackConfig(stack, nil /*sm*/)
+	if err != nil {
+		return err
+	}
+	stackPath, err := getProjectStackPath(stack)
 	if err != nil {
 		return err
 	}
-
-	cfg := ps.Config
 
 	v, ok, err := cfg.Get(key, path)
 	if err != nil {
@@ -833,8 +997,14 @@ func getConfig(stack backend.Stack, key config.Key, path, jsonOut bool) error {
 	if ok {
 		var d config.Decrypter
 		if v.Secure() {
-			var err error
-			if d, err = getStackDecrypter(stack); err != nil {
+			// Decrypt the value with the secrets provider of the file that it came from.
+			rootKey := key
+			if path {
+				if rootKey, err = configRootKey(key); err != nil {
+					return err
+				}
+			}
+			if d, err = newConfigDecrypters(stack, stackPath).decrypter(origins[rootKey]); err != nil {
 				return fmt.Errorf("could not create a decrypter: %w", err)
 			}
 		} else {
@@ -910,10 +1080,12 @@ func looksLikeSecret(k config.Key, v string) bool {
 		(info.Entropy >= (entropyThreshold/2) && entropyPerChar >= entropyPerCharThreshold)
 }
 
-// getStackConfiguration loads configuration information for a given stack. If stackConfigFile is non empty,
-// it is uses instead of the default configuration file for the stack
-func getStackConfiguration(stack backend.Stack, sm secrets.Manager) (backend.StackConfiguration, error) {
-	workspaceStack, err := loadProjectStack(stack)
+// getStackConfiguration loads configuration information for a given stack, validated against the config schema of
+// the project and merged over its defaults. If stackConfigFile is non empty, it is uses instead of the default
+// configuration file for the stack
+func getStackConfiguration(stack backend.Stack, proj *workspace.Project,
+	sm secrets.Manager, validate bool) (backend.StackConfiguration, error) {
+	stackConfig, _, err := loadStackConfig(stack, sm)
 	if err != nil {
 		return backend.StackConfiguration{}, fmt.Errorf("loading stack configuration: %w", err)
 	}
@@ -921,20 +1093,28 @@ func getStackConfiguration(stack backend.Stack, sm secrets.Manager) (backend.Sta
 	// If there are no secrets in the configuration, we should never use the decrypter, so it is safe to return
 	// one which panics if it is used. This provides for some nice UX in the common case (since, for example, building
 	// the correct decrypter for the local backend would involve prompting for a passphrase)
-	if !workspaceStack.Config.HasSecureValue() {
-		return backend.StackConfiguration{
-			Config:    workspaceStack.Config,
-			Decrypter: config.NewPanicCrypter(),
-		}, nil
+	var crypter config.Decrypter = config.NewPanicCrypter()
+	var dec config.Decrypter
+	if stackConfig.HasSecureValue() {
+		if crypter, err = sm.Decrypter(); err != nil {
+			return backend.StackConfiguration{}, fmt.Errorf("getting configuration decrypter: %w", err)
+		}
+		dec = crypter
 	}
 
-	crypter, err := sm.Decrypter()
-	if err != nil {
-		return backend.StackConfiguration{}, fmt.Errorf("getting configuration decrypter: %w", err)
+	// Only the operations that run the program enforce the project's config schema, so that stacks whose config no
+	// longer matches it can still be destroyed or refreshed.
+	var cfg config.Map
+	if validate {
+		cfg, err = proj.ApplyConfig(stackConfig, dec)
+		if err != nil {
+			return backend.StackConfiguration{}, fmt.Errorf("validating stack configuration: %w", err)
+		}
+	} else if cfg, err = proj.MergeConfigDefaults(stackConfig); err != nil {
+		return backend.StackConfiguration{}, fmt.Errorf("loading stack configuration: %w", err)
 	}
-
 	return backend.StackConfiguration{
-		Config:    workspaceStack.Config,
+		Config:    cfg,
 		Decrypter: crypter,
 	}, nil
 }
@@ -0,0 +1,69 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/base64"
+
+	"github.com/pulumi/pulumi/pkg/v3/secrets/age"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+// newAgeSecretsManager returns the age secrets manager of a stack. If the stack doesn't use the age secrets provider
+// yet, or secretsProvider names its recipients, a new data key is generated and encrypted to the recipients.
+func newAgeSecretsManager(stackName tokens.Name, configFile, secretsProvider string) (*age.Manager, error) {
+	contract.Assertf(stackName != "", "stackName %s", "!= \"\"")
+
+	if configFile == "" {
+		f, err := workspace.DetectProjectStackPath(stackName.Q())
+		if err != nil {
+			return nil, err
+		}
+		configFile = f
+	}
+
+	info, err := workspace.LoadProjectStack(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only a passphrase provider has an encryption salt.
+	info.EncryptionSalt = ""
+
+	if info.SecretsProvider != age.Type || info.EncryptedKey == "" || secretsProvider != age.Type {
+		recipients, err := age.ParseSecretsProvider(secretsProvider)
+		if err != nil {
+			return nil, err
+		}
+		dataKey, err := age.GenerateNewDataKey(recipients)
+		if err != nil {
+			return nil, err
+		}
+		info.SecretsRecipients = recipients
+		info.EncryptedKey = base64.StdEncoding.EncodeToString(dataKey)
+	}
+	info.SecretsProvider = age.Type
+	if err = info.Save(configFile); err != nil {
+		return nil, err
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(info.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	return age.NewAgeSecretsManager(info.SecretsRecipients, dataKey)
+}
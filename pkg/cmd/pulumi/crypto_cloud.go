@@ -46,6 +46,8 @@ func newCloudSecretsManager(stackName tokens.Name, configFile, secretsProvider s
 	if info.EncryptionSalt != "" {
 		info.EncryptionSalt = ""
 	}
+	// Likewise, only an age provider has recipients.
+	info.SecretsRecipients = nil
 
 	var secretsManager *cloud.Manager
 
@@ -82,5 +82,9 @@ func changeProjectStackSecretDetails(info *workspace.ProjectStack) bool {
 		info.EncryptionSalt = ""
 		requiresSave = true
 	}
+	if len(info.SecretsRecipients) != 0 {
+		info.SecretsRecipients = nil
+		requiresSave = true
+	}
 	return requiresSave
 }
@@ -44,10 +44,11 @@ func newPassphraseSecretsManager(stackName tokens.Name, configFile string,
 	}
 
 	// If there are any other secrets providers set in the config, remove them, as the passphrase
-	// provider deals only with EncryptionSalt, not EncryptedKey or SecretsProvider.
-	if info.EncryptedKey != "" || info.SecretsProvider != "" {
+	// provider deals only with EncryptionSalt, not EncryptedKey, SecretsProvider or SecretsRecipients.
+	if info.EncryptedKey != "" || info.SecretsProvider != "" || len(info.SecretsRecipients) != 0 {
 		info.EncryptedKey = ""
 		info.SecretsProvider = ""
+		info.SecretsRecipients = nil
 	}
 
 	// If we have a salt, we can just use it.
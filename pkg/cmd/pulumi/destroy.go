@@ -41,6 +41,7 @@ func newDestroyCmd() *cobra.Command {
 
 	// Flags for engine.UpdateOptions.
 	var jsonDisplay bool
+	var outputFormat string
 	var diffDisplay bool
 	var eventLogPath string
 	var parallel int
@@ -54,6 +55,8 @@ func newDestroyCmd() *cobra.Command {
 	var yes bool
 	var targets *[]string
 	var targetDependents bool
+	var excludes []string
+	var excludeDependents bool
 	var excludeProtected bool
 
 	var cmd = &cobra.Command{
@@ -84,6 +87,14 @@ func newDestroyCmd() *cobra.Command {
 				return result.FromError(err)
 			}
 
+			reportFormat, err := parseOutputFormat(outputFormat, jsonDisplay)
+			if err != nil {
+				return result.FromError(err)
+			}
+			if reportFormat != "" && !yes {
+				return result.FromError(errors.New("--yes or --skip-preview must be passed in with --output-format"))
+			}
+
 			var displayType = display.DisplayProgress
 			if diffDisplay {
 				displayType = display.DisplayDiff
@@ -100,6 +111,7 @@ func newDestroyCmd() *cobra.Command {
 				EventLogPath:         eventLogPath,
 				Debug:                debug,
 				JSONDisplay:          jsonDisplay,
+				ReportFormat:         reportFormat,
 			}
 
 			// we only suppress permalinks if the user passes true. the default is an empty string
@@ -142,7 +154,7 @@ func newDestroyCmd() *cobra.Command {
 				return result.FromError(fmt.Errorf("getting secrets manager: %w", err))
 			}
 
-			cfg, err := getStackConfiguration(s, sm)
+			cfg, err := getStackConfiguration(s, proj, sm, false /*validate*/)
 			if err != nil {
 				return result.FromError(fmt.Errorf("getting stack configuration: %w", err))
 			}
@@ -151,16 +163,14 @@ func newDestroyCmd() *cobra.Command {
 			if err != nil {
 				return result.FromError(err)
 			}
-			targetUrns := []resource.URN{}
-			for _, t := range *targets {
-				targetUrns = append(targetUrns, snap.GlobUrn(resource.URN(t))...)
-			}
-			if len(targetUrns) == 0 && len(*targets) > 0 {
-				if !jsonDisplay {
-					fmt.Printf("There were no resources matching the wildcards provided.\n")
+			targetUrns, ok := parseTargetURNs(snap, *targets)
+			if !ok {
+				if !jsonDisplay && reportFormat == "" {
+					fmt.Println(noMatchingTargetsMessage)
 				}
 				return nil
 			}
+			excludeURNs := toURNs(excludes)
 
 			refreshOption, err := getRefreshOption(proj, refresh)
 			if err != nil {
@@ -178,7 +188,7 @@ func newDestroyCmd() *cobra.Command {
 				if err != nil {
 					return result.FromError(err)
 				} else if protectedCount > 0 && len(targetUrns) == 0 {
-					if !jsonDisplay {
+					if !jsonDisplay && reportFormat == "" {
 						fmt.Printf("There were no unprotected resources to destroy. There are still %d"+
 							" protected resources associated with this stack.\n", protectedCount)
 					}
@@ -195,6 +205,8 @@ func newDestroyCmd() *cobra.Command {
 				Refresh:                   refreshOption,
 				DestroyTargets:            targetUrns,
 				TargetDependents:          targetDependents,
+				ExcludeTargets:            excludeURNs,
+				ExcludeDependents:         excludeDependents,
 				UseLegacyDiff:             useLegacyDiff(),
 				DisableProviderPreview:    disableProviderPreview(),
 				DisableResourceReferences: disableResourceReferences(),
@@ -210,10 +222,10 @@ func newDestroyCmd() *cobra.Command {
 				SecretsManager:     sm,
 				Scopes:             cancellationScopes,
 			})
-			if res == nil && protectedCount > 0 && !jsonDisplay {
+			if res == nil && protectedCount > 0 && !jsonDisplay && reportFormat == "" {
 				fmt.Printf("All unprotected resources were destroyed. There are still %d protected resources"+
 					" associated with this stack.\n", protectedCount)
-			} else if res == nil && len(*targets) == 0 && !jsonDisplay {
+			} else if res == nil && len(*targets) == 0 && len(excludeURNs) == 0 && !jsonDisplay && reportFormat == "" {
 				fmt.Printf("The resources in the stack have been deleted, but the history and configuration "+
 					"associated with the stack are still maintained. \nIf you want to remove the stack "+
 					"completely, run 'pulumi stack rm %s'.\n", s.Ref())
@@ -241,10 +253,18 @@ func newDestroyCmd() *cobra.Command {
 		"target", "t", []string{},
 		"Specify a single resource URN to destroy. All resources necessary to destroy this target will also be destroyed."+
 			" Multiple resources can be specified using: --target urn1 --target urn2."+
-			" Wildcards (*, **) are also supported")
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
 	cmd.PersistentFlags().BoolVar(
 		&targetDependents, "target-dependents", false,
 		"Allows destroying of dependent targets discovered but not specified in --target list")
+	cmd.PersistentFlags().StringArrayVar(
+		&excludes, "exclude", []string{},
+		"Specify a resource URN to ignore. These resources will not be destroyed, nor will anything they depend on."+
+			" Multiple resources can be specified using --exclude urn1 --exclude urn2."+
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
+	cmd.PersistentFlags().BoolVar(
+		&excludeDependents, "exclude-dependents", false,
+		"Allows ignoring of dependent targets discovered but not specified in --exclude list")
 	cmd.PersistentFlags().BoolVar(&excludeProtected, "exclude-protected", false, "Do not destroy protected resources."+
 		" Destroy all other resources.")
 
@@ -255,6 +275,8 @@ func newDestroyCmd() *cobra.Command {
 	cmd.Flags().BoolVarP(
 		&jsonDisplay, "json", "j", false,
 		"Serialize the destroy diffs, operations, and overall output as JSON")
+	cmd.Flags().StringVar(
+		&outputFormat, "output-format", "", outputFormatUsage)
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
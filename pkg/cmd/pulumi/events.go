@@ -0,0 +1,38 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+func newEventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Inspect saved engine event logs",
+		Long: "Inspect saved engine event logs\n" +
+			"\n" +
+			"These commands work with the event logs written by the `--event-log` flag of `pulumi preview`,\n" +
+			"`pulumi up`, `pulumi refresh` and `pulumi destroy`, for instance to investigate an update that\n" +
+			"ran in CI after the fact.",
+		Args: cmdutil.NoArgs,
+	}
+
+	cmd.AddCommand(newEventsRenderCmd())
+
+	return cmd
+}
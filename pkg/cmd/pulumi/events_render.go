@@ -0,0 +1,282 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+func newEventsRenderCmd() *cobra.Command {
+	var kind string
+	var preview bool
+	var urns []string
+	var ops []string
+	var severity string
+	var stats bool
+	var top int
+
+	var jsonDisplay bool
+	var outputFormat string
+	var diffDisplay bool
+	var showConfig bool
+	var showReplacementSteps bool
+	var showSames bool
+	var showReads bool
+	var suppressOutputs bool
+	var debug bool
+
+	var cmd = &cobra.Command{
+		Use:   "render <event-log>",
+		Args:  cmdutil.ExactArgs(1),
+		Short: "Render a saved event log",
+		Long: "Render a saved event log\n" +
+			"\n" +
+			"This command reconstructs the output of the operation that wrote the given event log, using the\n" +
+			"progress view, the diff view (--diff), JSON (--json) or a report (--output-format). Pass --kind\n" +
+			"if the log was written by a refresh, destroy or import. Logs written by previews are detected\n" +
+			"automatically.\n" +
+			"\n" +
+			"Use --urn, --op and --severity to only render the events of some resources, steps or diagnostics.\n" +
+			"--urn accepts the same target selectors as `pulumi up --target`. The summary of the operation is\n" +
+			"always rendered as it was recorded.\n" +
+			"\n" +
+			"Pass --stats to show statistics instead: the slowest resource operations, the time spent in each\n" +
+			"provider, and the operations that failed. Event logs record time to the second, so durations are\n" +
+			"measured in seconds.",
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			events, err := readEventLog(args[0])
+			if err != nil {
+				return fmt.Errorf("error reading events: %w", err)
+			}
+
+			filter := display.EventLogFilter{}
+			for _, urn := range urns {
+				filter.Targets = append(filter.Targets, resource.URN(urn))
+			}
+			for _, op := range ops {
+				if !isStepOp(op) {
+					return fmt.Errorf("unknown step operation %q", op)
+				}
+				filter.Ops = append(filter.Ops, apitype.OpType(op))
+			}
+			if severity != "" {
+				if filter.Severity, err = display.ParseSeverity(severity); err != nil {
+					return err
+				}
+			}
+			isPreview := preview || display.IsPreviewEventLog(events)
+			events = filter.Apply(events)
+
+			if stats {
+				s := display.ComputeEventLogStats(events)
+				if jsonDisplay {
+					return printJSON(s)
+				}
+				printEventLogStats(s, top)
+				return nil
+			}
+
+			action, err := parseUpdateKind(kind, isPreview)
+			if err != nil {
+				return err
+			}
+			reportFormat, err := parseOutputFormat(outputFormat, jsonDisplay)
+			if err != nil {
+				return err
+			}
+
+			var displayType = display.DisplayProgress
+			if diffDisplay {
+				displayType = display.DisplayDiff
+			}
+
+			displayOpts := display.Options{
+				Color:                cmdutil.GetGlobalColorization(),
+				ShowConfig:           showConfig,
+				ShowReplacementSteps: showReplacementSteps,
+				ShowSameResources:    showSames,
+				ShowReads:            showReads,
+				SuppressOutputs:      suppressOutputs,
+				IsInteractive:        cmdutil.Interactive(),
+				Type:                 displayType,
+				JSONDisplay:          jsonDisplay,
+				ReportFormat:         reportFormat,
+				Debug:                debug,
+			}
+
+			engineEvents, err := convertEvents(events)
+			if err != nil {
+				return fmt.Errorf("error reading events: %w", err)
+			}
+			stack, proj := eventLogStack(events)
+
+			eventChannel, doneChannel := make(chan engine.Event), make(chan bool)
+			go display.ShowEvents(
+				strings.ToLower(string(action)), action, stack, proj,
+				eventChannel, doneChannel, displayOpts, isPreview)
+
+			for _, e := range engineEvents {
+				eventChannel <- e
+			}
+			<-doneChannel
+
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVar(
+		&kind, "kind", "update",
+		"The kind of operation that wrote the event log: update, refresh, destroy or import")
+	cmd.PersistentFlags().BoolVar(
+		&preview, "preview", false,
+		"Render the event log as that of a preview, even if it isn't detected as one")
+	cmd.PersistentFlags().StringArrayVar(
+		&urns, "urn", nil,
+		"Only render the events of the resources selected by this URN or target selector. "+
+			"Multiple resources can be selected using --urn urn1 --urn urn2")
+	cmd.PersistentFlags().StringArrayVar(
+		&ops, "op", nil,
+		"Only render the events of steps that perform this operation, such as create, update or replace. "+
+			"Multiple operations can be selected using --op op1 --op op2")
+	cmd.PersistentFlags().StringVar(
+		&severity, "severity", "",
+		"Only render diagnostics that are at least this severe: debug, info, info#err, warning or error")
+	cmd.PersistentFlags().BoolVar(
+		&stats, "stats", false,
+		"Show statistics about the resource operations instead of rendering the events")
+	cmd.PersistentFlags().IntVar(
+		&top, "top", 10,
+		"The number of slowest resource operations to show with --stats, or 0 to show all of them")
+
+	cmd.PersistentFlags().BoolVarP(
+		&debug, "debug", "d", false,
+		"Print detailed debugging output during resource operations")
+	cmd.PersistentFlags().BoolVar(
+		&diffDisplay, "diff", false,
+		"Display operation as a rich diff showing the overall change")
+	cmd.Flags().BoolVarP(
+		&jsonDisplay, "json", "j", false,
+		"Serialize the diffs, operations, and overall output as JSON, or the statistics if --stats is passed")
+	cmd.Flags().StringVar(
+		&outputFormat, "output-format", "", outputFormatUsage)
+	cmd.PersistentFlags().BoolVar(
+		&showConfig, "show-config", false,
+		"Show configuration keys and variables")
+	cmd.PersistentFlags().BoolVar(
+		&showReplacementSteps, "show-replacement-steps", false,
+		"Show detailed resource replacement creates and deletes instead of a single step")
+	cmd.PersistentFlags().BoolVar(
+		&showSames, "show-sames", false,
+		"Show resources that needn't be updated because they haven't changed, alongside those that do")
+	cmd.PersistentFlags().BoolVar(
+		&showReads, "show-reads", false,
+		"Show resources that are being read in, alongside those being managed directly in the stack")
+	cmd.PersistentFlags().BoolVar(
+		&suppressOutputs, "suppress-outputs", false,
+		"Suppress display of stack outputs (in case they contain sensitive values)")
+
+	return cmd
+}
+
+// isStepOp returns true if the given string names a step operation.
+func isStepOp(op string) bool {
+	for _, o := range deploy.StepOps {
+		if string(o) == op {
+			return true
+		}
+	}
+	return false
+}
+
+// eventLogStack returns the names of the stack and project whose resources the given events pertain to.
+func eventLogStack(events []apitype.EngineEvent) (tokens.Name, tokens.PackageName) {
+	for _, e := range events {
+		if e.ResourcePreEvent != nil {
+			urn := resource.URN(e.ResourcePreEvent.Metadata.URN)
+			return tokens.Name(urn.Stack()), urn.Project()
+		}
+	}
+	return "unknown", "unknown"
+}
+
+// printEventLogStats prints the statistics of an event log, showing the given number of slowest resource operations.
+func printEventLogStats(s display.EventLogStats, top int) {
+	fmt.Printf("Duration: %ds\n", s.DurationSeconds)
+
+	resources := s.Resources
+	if top > 0 && len(resources) > top {
+		resources = resources[:top]
+	}
+	fmt.Printf("\nSlowest resource operations:\n")
+	if len(resources) == 0 {
+		fmt.Printf("    none\n")
+	} else {
+		rows := []cmdutil.TableRow{}
+		for _, r := range resources {
+			status := "succeeded"
+			if r.Failed {
+				status = "failed"
+			}
+			rows = append(rows, cmdutil.TableRow{
+				Columns: []string{strconv.Itoa(r.DurationSeconds) + "s", string(r.Op), status, string(r.URN)},
+			})
+		}
+		cmdutil.PrintTable(cmdutil.Table{
+			Headers: []string{"DURATION", "OP", "STATUS", "URN"},
+			Rows:    rows,
+			Prefix:  "    ",
+		})
+	}
+
+	fmt.Printf("\nTime spent per provider:\n")
+	if len(s.Providers) == 0 {
+		fmt.Printf("    none\n")
+	} else {
+		rows := []cmdutil.TableRow{}
+		for _, p := range s.Providers {
+			rows = append(rows, cmdutil.TableRow{
+				Columns: []string{p.Provider, strconv.Itoa(p.Steps), strconv.Itoa(p.DurationSeconds) + "s"},
+			})
+		}
+		cmdutil.PrintTable(cmdutil.Table{
+			Headers: []string{"PROVIDER", "OPERATIONS", "DURATION"},
+			Rows:    rows,
+			Prefix:  "    ",
+		})
+	}
+
+	fmt.Printf("\nFailures:\n")
+	if len(s.Failures) == 0 {
+		fmt.Printf("    none\n")
+	}
+	for _, f := range s.Failures {
+		fmt.Printf("    %s %s\n", f.Op, f.URN)
+		for _, msg := range f.Errors {
+			fmt.Printf("        %s\n", strings.ReplaceAll(msg, "\n", "\n        "))
+		}
+	}
+}
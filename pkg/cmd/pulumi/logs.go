@@ -61,12 +61,17 @@ func newLogsCmd() *cobra.Command {
 				return err
 			}
 
+			proj, _, err := readProject()
+			if err != nil {
+				return err
+			}
+
 			sm, err := getStackSecretsManager(s)
 			if err != nil {
 				return fmt.Errorf("getting secrets manager: %w", err)
 			}
 
-			cfg, err := getStackConfiguration(s, sm)
+			cfg, err := getStackConfiguration(s, proj, sm, false /*validate*/)
 			if err != nil {
 				return fmt.Errorf("getting stack configuration: %w", err)
 			}
@@ -25,7 +25,6 @@ import (
 	"github.com/pulumi/pulumi/pkg/v3/backend/display"
 	"github.com/pulumi/pulumi/pkg/v3/engine"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
-	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
@@ -46,6 +45,7 @@ func newPreviewCmd() *cobra.Command {
 
 	// Flags for engine.UpdateOptions.
 	var jsonDisplay bool
+	var outputFormat string
 	var policyPackPaths []string
 	var policyPackConfigPaths []string
 	var diffDisplay bool
@@ -62,6 +62,8 @@ func newPreviewCmd() *cobra.Command {
 	var replaces []string
 	var targetReplaces []string
 	var targetDependents bool
+	var excludes []string
+	var excludeDependents bool
 
 	var cmd = &cobra.Command{
 		Use:        "preview",
@@ -86,6 +88,11 @@ func newPreviewCmd() *cobra.Command {
 				displayType = display.DisplayDiff
 			}
 
+			reportFormat, err := parseOutputFormat(outputFormat, jsonDisplay)
+			if err != nil {
+				return result.FromError(err)
+			}
+
 			displayOpts := display.Options{
 				Color:                cmdutil.GetGlobalColorization(),
 				ShowConfig:           showConfig,
@@ -96,6 +103,7 @@ func newPreviewCmd() *cobra.Command {
 				IsInteractive:        cmdutil.Interactive(),
 				Type:                 displayType,
 				JSONDisplay:          jsonDisplay,
+				ReportFormat:         reportFormat,
 				EventLogPath:         eventLogPath,
 				Debug:                debug,
 			}
@@ -147,25 +155,16 @@ func newPreviewCmd() *cobra.Command {
 				return result.FromError(fmt.Errorf("getting secrets manager: %w", err))
 			}
 
-			cfg, err := getStackConfiguration(s, sm)
+			cfg, err := getStackConfiguration(s, proj, sm, true /*validate*/)
 			if err != nil {
 				return result.FromError(fmt.Errorf("getting stack configuration: %w", err))
 			}
 
-			targetURNs := []resource.URN{}
-			for _, t := range targets {
-				targetURNs = append(targetURNs, resource.URN(t))
-			}
-
-			replaceURNs := []resource.URN{}
-			for _, r := range replaces {
-				replaceURNs = append(replaceURNs, resource.URN(r))
-			}
-
-			for _, tr := range targetReplaces {
-				targetURNs = append(targetURNs, resource.URN(tr))
-				replaceURNs = append(replaceURNs, resource.URN(tr))
-			}
+			// Targets are resolved by the engine, since selectors that match no existing resource may still match
+			// resources that the program registers.
+			targetURNs := toURNs(append(targets, targetReplaces...))
+			replaceURNs := append(toURNs(replaces), toURNs(targetReplaces)...)
+			excludeURNs := toURNs(excludes)
 
 			refreshOption, err := getRefreshOption(proj, refresh)
 			if err != nil {
@@ -185,6 +184,8 @@ func newPreviewCmd() *cobra.Command {
 					DisableOutputValues:       disableOutputValues(),
 					UpdateTargets:             targetURNs,
 					TargetDependents:          targetDependents,
+					ExcludeTargets:            excludeURNs,
+					ExcludeDependents:         excludeDependents,
 					ExperimentalPlans:         hasExperimentalCommands() || planFilePath != "",
 				},
 				Display: displayOpts,
@@ -267,10 +268,12 @@ func newPreviewCmd() *cobra.Command {
 	cmd.PersistentFlags().StringArrayVarP(
 		&targets, "target", "t", []string{},
 		"Specify a single resource URN to update. Other resources will not be updated."+
-			" Multiple resources can be specified using --target urn1 --target urn2")
+			" Multiple resources can be specified using --target urn1 --target urn2."+
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
 	cmd.PersistentFlags().StringArrayVar(
 		&replaces, "replace", []string{},
-		"Specify resources to replace. Multiple resources can be specified using --replace urn1 --replace urn2")
+		"Specify resources to replace. Multiple resources can be specified using --replace urn1 --replace urn2."+
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
 	cmd.PersistentFlags().StringArrayVar(
 		&targetReplaces, "target-replace", []string{},
 		"Specify a single resource URN to replace. Other resources will not be updated."+
@@ -278,6 +281,14 @@ func newPreviewCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(
 		&targetDependents, "target-dependents", false,
 		"Allows updating of dependent targets discovered but not specified in --target list")
+	cmd.PersistentFlags().StringArrayVar(
+		&excludes, "exclude", []string{},
+		"Specify a resource URN to ignore. These resources will not be updated."+
+			" Multiple resources can be specified using --exclude urn1 --exclude urn2."+
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
+	cmd.PersistentFlags().BoolVar(
+		&excludeDependents, "exclude-dependents", false,
+		"Allows ignoring of dependent targets discovered but not specified in --exclude list")
 
 	// Flags for engine.UpdateOptions.
 	cmd.PersistentFlags().StringSliceVar(
@@ -292,6 +303,8 @@ func newPreviewCmd() *cobra.Command {
 	cmd.Flags().BoolVarP(
 		&jsonDisplay, "json", "j", false,
 		"Serialize the preview diffs, operations, and overall output as JSON")
+	cmd.Flags().StringVar(
+		&outputFormat, "output-format", "", outputFormatUsage)
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
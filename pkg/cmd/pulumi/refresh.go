@@ -24,7 +24,6 @@ import (
 	"github.com/pulumi/pulumi/pkg/v3/backend"
 	"github.com/pulumi/pulumi/pkg/v3/backend/display"
 	"github.com/pulumi/pulumi/pkg/v3/engine"
-	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
 )
@@ -50,6 +49,8 @@ func newRefreshCmd() *cobra.Command {
 	var suppressPermalink string
 	var yes bool
 	var targets *[]string
+	var excludes []string
+	var excludeDependents bool
 
 	var cmd = &cobra.Command{
 		Use:   "refresh",
@@ -134,15 +135,23 @@ func newRefreshCmd() *cobra.Command {
 				return result.FromError(fmt.Errorf("getting secrets manager: %w", err))
 			}
 
-			cfg, err := getStackConfiguration(s, sm)
+			cfg, err := getStackConfiguration(s, proj, sm, false /*validate*/)
 			if err != nil {
 				return result.FromError(fmt.Errorf("getting stack configuration: %w", err))
 			}
 
-			targetUrns := []resource.URN{}
-			for _, t := range *targets {
-				targetUrns = append(targetUrns, resource.URN(t))
+			snap, err := s.Snapshot(commandContext())
+			if err != nil {
+				return result.FromError(err)
 			}
+			targetUrns, ok := parseTargetURNs(snap, *targets)
+			if !ok {
+				if !jsonDisplay {
+					fmt.Println(noMatchingTargetsMessage)
+				}
+				return nil
+			}
+			excludeURNs := toURNs(excludes)
 
 			opts.Engine = engine.UpdateOptions{
 				Parallel:                  parallel,
@@ -152,6 +161,8 @@ func newRefreshCmd() *cobra.Command {
 				DisableResourceReferences: disableResourceReferences(),
 				DisableOutputValues:       disableOutputValues(),
 				RefreshTargets:            targetUrns,
+				ExcludeTargets:            excludeURNs,
+				ExcludeDependents:         excludeDependents,
 			}
 
 			changes, res := s.Refresh(commandContext(), backend.UpdateOperation{
@@ -196,7 +207,16 @@ func newRefreshCmd() *cobra.Command {
 
 	targets = cmd.PersistentFlags().StringArrayP(
 		"target", "t", []string{},
-		"Specify a single resource URN to refresh. Multiple resource can be specified using: --target urn1 --target urn2")
+		"Specify a single resource URN to refresh. Multiple resource can be specified using: --target urn1 --target urn2."+
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
+	cmd.PersistentFlags().StringArrayVar(
+		&excludes, "exclude", []string{},
+		"Specify a resource URN to ignore. These resources will not be refreshed."+
+			" Multiple resources can be specified using --exclude urn1 --exclude urn2."+
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
+	cmd.PersistentFlags().BoolVar(
+		&excludeDependents, "exclude-dependents", false,
+		"Allows ignoring of dependent targets discovered but not specified in --exclude list")
 
 	// Flags for engine.UpdateOptions.
 	cmd.PersistentFlags().BoolVar(
@@ -15,9 +15,7 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"time"
 
@@ -57,22 +55,9 @@ func newReplayEventsCmd() *cobra.Command {
 		Args:   cmdutil.ExactArgs(2),
 		Hidden: !hasDebugCommands(),
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
-			var action apitype.UpdateKind
-			switch args[0] {
-			case "update":
-				if preview {
-					action = apitype.PreviewUpdate
-				} else {
-					action = apitype.UpdateUpdate
-				}
-			case "refresh":
-				action = apitype.RefreshUpdate
-			case "destroy":
-				action = apitype.DestroyUpdate
-			case "import":
-				action = apitype.ResourceImportUpdate
-			default:
-				return fmt.Errorf("unrecognized update kind '%v'", args[0])
+			action, err := parseUpdateKind(args[0], preview)
+			if err != nil {
+				return err
 			}
 
 			var displayType = display.DisplayProgress
@@ -152,24 +137,48 @@ func newReplayEventsCmd() *cobra.Command {
 	return cmd
 }
 
-func loadEvents(path string) ([]engine.Event, error) {
+// parseUpdateKind parses the kind of update named by the kind argument of the commands that replay event logs.
+func parseUpdateKind(kind string, preview bool) (apitype.UpdateKind, error) {
+	switch kind {
+	case "update":
+		if preview {
+			return apitype.PreviewUpdate, nil
+		}
+		return apitype.UpdateUpdate, nil
+	case "refresh":
+		return apitype.RefreshUpdate, nil
+	case "destroy":
+		return apitype.DestroyUpdate, nil
+	case "import":
+		return apitype.ResourceImportUpdate, nil
+	default:
+		return "", fmt.Errorf("unrecognized update kind '%v'", kind)
+	}
+}
+
+// readEventLog reads the events of the event log at the given path.
+func readEventLog(path string) ([]apitype.EngineEvent, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("opening '%v': %w", path, err)
 	}
 	defer contract.IgnoreClose(f)
 
-	var events []engine.Event
-	dec := json.NewDecoder(f)
-	for {
-		var jsonEvent apitype.EngineEvent
-		if err = dec.Decode(&jsonEvent); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, fmt.Errorf("decoding event: %w", err)
-		}
+	return display.ReadEventLog(f)
+}
+
+func loadEvents(path string) ([]engine.Event, error) {
+	jsonEvents, err := readEventLog(path)
+	if err != nil {
+		return nil, err
+	}
+	return convertEvents(jsonEvents)
+}
 
+// convertEvents converts the events of an event log to engine events.
+func convertEvents(jsonEvents []apitype.EngineEvent) ([]engine.Event, error) {
+	var events []engine.Event
+	for _, jsonEvent := range jsonEvents {
 		event, err := display.ConvertJSONEvent(jsonEvent)
 		if err != nil {
 			return nil, fmt.Errorf("decoding event: %w", err)
@@ -29,12 +29,15 @@ import (
 )
 
 func newStackChangeSecretsProviderCmd() *cobra.Command {
+	var stack string
+
 	var cmd = &cobra.Command{
 		Use:   "change-secrets-provider <new-secrets-provider>",
 		Args:  cmdutil.ExactArgs(1),
 		Short: "Change the secrets provider for the current stack",
 		Long: "Change the secrets provider for the current stack. " +
-			"Valid secret providers types are `default`, `passphrase`, `awskms`, `azurekeyvault`, `gcpkms`, `hashivault`.\n\n" +
+			"Valid secret providers types are `default`, `passphrase`, `age`, `awskms`, `azurekeyvault`, `gcpkms`, " +
+			"`hashivault`.\n\n" +
 			"To change to using the Pulumi Default Secrets Provider, use the following:\n" +
 			"\n" +
 			"pulumi stack change-secrets-provider default" +
@@ -50,7 +53,13 @@ func newStackChangeSecretsProviderCmd() *cobra.Command {
 			"\"azurekeyvault://mykeyvaultname.vault.azure.net/keys/mykeyname\"`\n" +
 			"* `pulumi stack change-secrets-provider " +
 			"\"gcpkms://projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>\"`\n" +
-			"* `pulumi stack change-secrets-provider \"hashivault://mykey\"`",
+			"* `pulumi stack change-secrets-provider \"hashivault://mykey\"`\n" +
+			"\n" +
+			"To change the stack to encrypt its secrets to the age public keys of its users, use one of the following:\n" +
+			"\n" +
+			"* `pulumi stack change-secrets-provider age`, for the key of the identity in PULUMI_AGE_KEY or " +
+			"PULUMI_AGE_KEY_FILE\n" +
+			"* `pulumi stack change-secrets-provider \"age://<recipient>,<recipient>\"`",
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			opts := display.Options{
 				Color: cmdutil.GetGlobalColorization(),
@@ -67,8 +76,8 @@ func newStackChangeSecretsProviderCmd() *cobra.Command {
 				return err
 			}
 
-			// Get the current stack and its project
-			currentStack, err := requireStack("", false, opts, true /*setCurrent*/)
+			// Get the current stack and its project. The stack is only selected if it was chosen interactively.
+			currentStack, err := requireStack(stack, false, opts, stack == "" /*setCurrent*/)
 			if err != nil {
 				return err
 			}
@@ -104,6 +113,10 @@ func newStackChangeSecretsProviderCmd() *cobra.Command {
 		}),
 	}
 
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+
 	return cmd
 }
 
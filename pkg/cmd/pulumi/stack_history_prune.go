@@ -0,0 +1,127 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStackHistoryPruneCmd() *cobra.Command {
+	var stack string
+	var keep int
+	var keepDays int
+	var dryRun bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Args:  cmdutil.NoArgs,
+		Short: "Remove old updates from a stack's history",
+		Long: "Remove old updates from a stack's history\n" +
+			"\n" +
+			"This command removes the history entries, checkpoints and backups of a stack's older updates\n" +
+			"from a self-managed backend. Use --keep to retain a number of the most recent updates, and\n" +
+			"--keep-days to retain the updates of a number of days. The most recent update is always retained.\n" +
+			"\n" +
+			"If neither flag is given, the retention policy configured by the " + filestate.PulumiFilestateHistoryKeepEnvVar +
+			"\nand " + filestate.PulumiFilestateHistoryKeepDaysEnvVar + " environment variables is used. When set, " +
+			"this policy\nis also applied automatically after each update.",
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			yes = yes || skipConfirmations()
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			retention := filestate.HistoryRetention{
+				KeepUpdates: keep,
+				MaxAge:      time.Duration(keepDays) * 24 * time.Hour,
+			}
+			if keep < 0 || keepDays < 0 {
+				return result.Error("--keep and --keep-days must not be negative")
+			}
+			if retention.IsZero() {
+				var err error
+				if retention, err = filestate.HistoryRetentionFromEnv(); err != nil {
+					return result.FromError(err)
+				}
+				if retention.IsZero() {
+					return result.Error("specify the updates to retain using --keep or --keep-days")
+				}
+			}
+
+			s, err := requireStack(stack, false /*offerNew */, opts, false /*setCurrent*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+			b, ok := s.Backend().(filestate.Backend)
+			if !ok {
+				return result.Errorf("the current backend (%s) does not support pruning history", s.Backend().Name())
+			}
+
+			res, err := b.PruneHistory(commandContext(), s.Ref(), retention, true /*dryRun*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+			if res.Updates == 0 && res.Backups == 0 {
+				fmt.Println("There is nothing to prune")
+				return nil
+			}
+
+			fmt.Printf("This will remove %d update(s) and %d backup(s) from the history of '%s'\n",
+				res.Updates, res.Backups, s.Ref())
+			if dryRun {
+				return nil
+			}
+
+			prompt := fmt.Sprintf("This will permanently remove old updates from the history of '%s'!", s.Ref())
+			if !yes && !confirmPrompt(prompt, s.Ref().String(), opts) {
+				fmt.Println("confirmation declined")
+				return result.Bail()
+			}
+
+			res, err = b.PruneHistory(commandContext(), s.Ref(), retention, false /*dryRun*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			msg := fmt.Sprintf("%sRemoved %d update(s) and %d backup(s) from the history of '%s'%s",
+				colors.SpecAttention, res.Updates, res.Backups, s.Ref(), colors.Reset)
+			fmt.Println(opts.Color.Colorize(msg))
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"Choose a stack other than the currently selected one")
+	cmd.PersistentFlags().IntVar(
+		&keep, "keep", 0, "The number of most recent updates to retain")
+	cmd.PersistentFlags().IntVar(
+		&keepDays, "keep-days", 0, "Retain the updates of this many most recent days")
+	cmd.PersistentFlags().BoolVar(
+		&dryRun, "dry-run", false, "Show what would be removed without removing anything")
+	cmd.PersistentFlags().BoolVarP(
+		&yes, "yes", "y", false, "Skip confirmation prompts, and proceed with pruning anyway")
+	return cmd
+}
@@ -0,0 +1,218 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStackHistoryRestoreCmd() *cobra.Command {
+	var stackName string
+	var refresh bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "restore <version>",
+		Args:  cmdutil.ExactArgs(1),
+		Short: "Restore a stack's state to the checkpoint saved by a previous update",
+		Long: "Restore a stack's state to the checkpoint saved by a previous update\n" +
+			"\n" +
+			"This command replaces the stack's current state with the checkpoint saved by the update with\n" +
+			"the given version, as shown by `pulumi stack history`. The checkpoint is validated before it\n" +
+			"is restored. No resources are changed, so the restored state may no longer match reality;\n" +
+			"pass --refresh to run `pulumi refresh` once the state is restored to reconcile them.",
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			yes = yes || skipConfirmations()
+			version := args[0]
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(stackName, false /*offerNew */, opts, false /*setCurrent*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+			// A restore doesn't need a project, so the Git metadata of the restore comes from the project's directory
+			// if there is one, and the working directory otherwise.
+			root, err := os.Getwd()
+			if err != nil {
+				return result.FromError(err)
+			}
+			if _, projRoot, err := readProject(); err == nil {
+				root = projRoot
+			}
+			m, err := getUpdateMetadata(fmt.Sprintf("Restored version %s", version), root, "", "")
+			if err != nil {
+				return result.FromError(fmt.Errorf("gathering environment metadata: %w", err))
+			}
+			be, ok := s.Backend().(backend.SpecificDeploymentExporter)
+			if !ok {
+				return result.Errorf("the current backend (%s) does not provide the ability to export previous deployments",
+					s.Backend().Name())
+			}
+
+			deployment, err := be.ExportDeploymentForVersion(commandContext(), s, version)
+			if err != nil {
+				return result.FromError(err)
+			}
+			snap, err := stack.DeserializeUntypedDeployment(deployment, stack.DefaultSecretsProvider)
+			if err != nil {
+				return result.FromError(checkDeploymentVersionError(err, string(s.Ref().Name())))
+			}
+			if err := snap.VerifyIntegrity(); err != nil {
+				return result.Errorf("the checkpoint of version %s is invalid: %v", version, err)
+			}
+
+			current, err := s.Snapshot(commandContext())
+			if err != nil {
+				return result.FromError(err)
+			}
+			currentCount := 0
+			if current != nil {
+				currentCount = len(current.Resources)
+			}
+			fmt.Printf("The state of '%s' will be restored to version %s, which has %d resource(s). "+
+				"It currently has %d resource(s).\n", s.Ref(), version, len(snap.Resources), currentCount)
+
+			prompt := fmt.Sprintf("This will replace the current state of '%s'!", s.Ref())
+			if !yes && !confirmPrompt(prompt, s.Ref().String(), opts) {
+				fmt.Println("confirmation declined")
+				return result.Bail()
+			}
+
+			if err := restoreSnapshot(commandContext(), s, snap, *m); err != nil {
+				return result.FromError(fmt.Errorf("restoring version %s: %w", version, err))
+			}
+			msg := fmt.Sprintf("%sThe state of '%s' has been restored to version %s%s",
+				colors.SpecAttention, s.Ref(), version, colors.Reset)
+			fmt.Println(opts.Color.Colorize(msg))
+
+			if !refresh {
+				return nil
+			}
+			return refreshRestoredStack(s, yes, opts)
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"Choose a stack other than the currently selected one")
+	cmd.PersistentFlags().BoolVar(
+		&refresh, "refresh", false,
+		"Refresh the stack once its state is restored, to reconcile it with the actual resources")
+	cmd.PersistentFlags().BoolVarP(
+		&yes, "yes", "y", false,
+		"Skip confirmation prompts, and proceed with the restore and refresh anyway")
+	return cmd
+}
+
+// restoreSnapshot replaces the state of a stack with the given snapshot, and records the restore in the stack's
+// history. The snapshot's secrets are encrypted with the stack's current secrets manager rather than the one the
+// snapshot was saved with, which may have been rotated out since.
+func restoreSnapshot(ctx context.Context, s backend.Stack, snap *deploy.Snapshot, m backend.UpdateMetadata) error {
+	sm, err := getStackSecretsManager(s)
+	if err != nil {
+		return fmt.Errorf("getting secrets manager: %w", err)
+	}
+	sdep, err := stack.SerializeDeployment(snap, sm, false /*showSecrets*/)
+	if err != nil {
+		return fmt.Errorf("serializing deployment: %w", err)
+	}
+	bytes, err := json.Marshal(sdep)
+	if err != nil {
+		return err
+	}
+	deployment := &apitype.UntypedDeployment{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Deployment: bytes,
+	}
+
+	if importer, ok := s.Backend().(backend.HistoryRecordingDeploymentImporter); ok {
+		return importer.ImportDeploymentWithMetadata(ctx, s, deployment, m)
+	}
+	return s.ImportDeployment(ctx, deployment)
+}
+
+// refreshRestoredStack refreshes a stack whose state has just been restored, using the project in the current
+// directory.
+func refreshRestoredStack(s backend.Stack, yes bool, displayOpts display.Options) result.Result {
+	interactive := cmdutil.Interactive()
+	opts, err := updateFlagsToOptions(interactive, false /*skipPreview*/, yes)
+	if err != nil {
+		return result.FromError(err)
+	}
+	displayOpts.IsInteractive = interactive
+	displayOpts.Type = display.DisplayProgress
+	opts.Display = displayOpts
+
+	proj, root, err := readProject()
+	if err != nil {
+		return result.FromError(fmt.Errorf("refreshing the restored state: %w", err))
+	}
+	m, err := getUpdateMetadata("", root, "", "")
+	if err != nil {
+		return result.FromError(fmt.Errorf("gathering environment metadata: %w", err))
+	}
+	sm, err := getStackSecretsManager(s)
+	if err != nil {
+		return result.FromError(fmt.Errorf("getting secrets manager: %w", err))
+	}
+	cfg, err := getStackConfiguration(s, proj, sm, false /*validate*/)
+	if err != nil {
+		return result.FromError(fmt.Errorf("getting stack configuration: %w", err))
+	}
+
+	opts.Engine = engine.UpdateOptions{
+		Parallel:                  defaultParallel,
+		UseLegacyDiff:             useLegacyDiff(),
+		DisableProviderPreview:    disableProviderPreview(),
+		DisableResourceReferences: disableResourceReferences(),
+		DisableOutputValues:       disableOutputValues(),
+	}
+
+	_, res := s.Refresh(commandContext(), backend.UpdateOperation{
+		Proj:               proj,
+		Root:               root,
+		M:                  m,
+		Opts:               opts,
+		StackConfiguration: cfg,
+		SecretsManager:     sm,
+		Scopes:             cancellationScopes,
+	})
+	switch {
+	case res != nil && res.Error() == context.Canceled:
+		return result.FromError(errors.New("refresh cancelled"))
+	case res != nil:
+		return PrintEngineResult(res)
+	default:
+		return nil
+	}
+}
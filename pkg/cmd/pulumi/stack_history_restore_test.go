@@ -0,0 +1,51 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+//nolint:paralleltest // sets environment variables and the stack config file
+func TestRestoreSnapshotUsesCurrentSecretsManager(t *testing.T) {
+	t.Setenv("PULUMI_CONFIG_PASSPHRASE", "correct horse battery staple")
+	ctx := context.Background()
+
+	s, oldSM := newRotateTestStack(t, func(path string) {})
+	old, err := s.ExportDeployment(ctx)
+	require.NoError(t, err)
+	require.NoError(t, rotateStackSecrets(ctx, s, backend.UpdateMetadata{}))
+
+	// Restoring a checkpoint saved before the rotation doesn't bring back the rotated-out key.
+	snap, err := stack.DeserializeUntypedDeployment(old, stack.DefaultSecretsProvider)
+	require.NoError(t, err)
+	require.NoError(t, restoreSnapshot(ctx, s, snap, backend.UpdateMetadata{Message: "Restored version 1"}))
+	assertRotated(t, s, oldSM)
+
+	// The restore is recorded in the stack's history.
+	history, err := s.Backend().GetHistory(ctx, s.Ref(), 1, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, apitype.StackImportUpdate, history[0].Kind)
+	assert.Equal(t, "Restored version 1", history[0].Message)
+}
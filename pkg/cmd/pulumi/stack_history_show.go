@@ -0,0 +1,254 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/graph"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+func newStackHistoryShowCmd() *cobra.Command {
+	var stackName string
+	var timings bool
+	var criticalPath bool
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:   "show <version>",
+		Args:  cmdutil.ExactArgs(1),
+		Short: "Show the details of a previous update",
+		Long: "Show the details of a previous update\n" +
+			"\n" +
+			"This command shows the update with the given version, as shown by `pulumi stack history`.\n" +
+			"\n" +
+			"Pass --timings to show how long each of the update's resource operations took, slowest first.\n" +
+			"Pass --critical-path to show the chain of dependent resource operations that took the longest,\n" +
+			"according to the dependencies recorded in the checkpoint saved by the update. However many\n" +
+			"operations run in parallel, an update can't take less time than its critical path. Timings are\n" +
+			"only recorded by updates run by this version of the CLI or later.",
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+			version, err := strconv.Atoi(args[0])
+			if err != nil || version < 1 {
+				return fmt.Errorf("%q is not a valid version; versions are positive numbers", args[0])
+			}
+
+			s, err := requireStack(stackName, false /*offerNew */, opts, false /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+			updates, err := s.Backend().GetHistory(commandContext(), s.Ref(), 0, 0)
+			if err != nil {
+				return fmt.Errorf("getting history: %w", err)
+			}
+			var update *backend.UpdateInfo
+			for i := range updates {
+				if updates[i].Version == version {
+					update = &updates[i]
+					break
+				}
+			}
+			if update == nil {
+				return fmt.Errorf("stack '%s' has no update with version %d", s.Ref(), version)
+			}
+
+			if (timings || criticalPath) && len(update.StepTimings) == 0 {
+				return fmt.Errorf("no timings were recorded for version %d; timings are only recorded for updates "+
+					"that change resources, by recent versions of the CLI", version)
+			}
+
+			var path []criticalPathStep
+			var pathDuration time.Duration
+			if criticalPath {
+				path, pathDuration, err = computeCriticalPath(s, *update)
+				if err != nil {
+					return err
+				}
+			}
+
+			if jsonOut {
+				out := updateDetailsJSON{
+					Version:         update.Version,
+					Kind:            string(update.Kind),
+					Result:          string(update.Result),
+					Message:         update.Message,
+					StartTime:       time.Unix(update.StartTime, 0).UTC().Format(timeFormat),
+					EndTime:         time.Unix(update.EndTime, 0).UTC().Format(timeFormat),
+					ResourceChanges: make(map[string]int),
+				}
+				for op, count := range update.ResourceChanges {
+					out.ResourceChanges[string(op)] = count
+				}
+				if timings {
+					out.Timings = sortedStepTimings(update.StepTimings)
+				}
+				if criticalPath {
+					out.CriticalPath = path
+					out.CriticalPathMilliseconds = pathDuration.Milliseconds()
+				}
+				return printJSON(out)
+			}
+
+			fmt.Printf("Version: %d\n", update.Version)
+			fmt.Printf("UpdateKind: %v\n", update.Kind)
+			fmt.Printf("Status: %v\n", update.Result)
+			fmt.Printf("Message: %v\n", update.Message)
+			start, end := time.Unix(update.StartTime, 0), time.Unix(update.EndTime, 0)
+			fmt.Printf("Started: %s\n", start)
+			fmt.Printf("Duration: %s\n", end.Sub(start))
+
+			if timings {
+				fmt.Printf("\nResource operations:\n")
+				rows := []cmdutil.TableRow{}
+				for _, t := range sortedStepTimings(update.StepTimings) {
+					status := "succeeded"
+					if t.Failed {
+						status = "failed"
+					}
+					rows = append(rows, cmdutil.TableRow{
+						Columns: []string{stepTimingDuration(t).String(), string(t.Op), status, t.URN},
+					})
+				}
+				cmdutil.PrintTable(cmdutil.Table{
+					Headers: []string{"DURATION", "OP", "STATUS", "URN"},
+					Rows:    rows,
+					Prefix:  "    ",
+				})
+			}
+
+			if criticalPath {
+				fmt.Printf("\nCritical path (%s):\n", pathDuration)
+				if len(path) == 0 {
+					fmt.Printf("    none\n")
+				} else {
+					rows := []cmdutil.TableRow{}
+					for _, step := range path {
+						duration := time.Duration(step.Milliseconds) * time.Millisecond
+						rows = append(rows, cmdutil.TableRow{
+							Columns: []string{duration.String(), string(step.URN)},
+						})
+					}
+					cmdutil.PrintTable(cmdutil.Table{
+						Headers: []string{"DURATION", "URN"},
+						Rows:    rows,
+						Prefix:  "    ",
+					})
+				}
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"Choose a stack other than the currently selected one")
+	cmd.PersistentFlags().BoolVar(
+		&timings, "timings", false,
+		"Show how long each resource operation took")
+	cmd.PersistentFlags().BoolVar(
+		&criticalPath, "critical-path", false,
+		"Show the chain of dependent resource operations that took the longest")
+	cmd.PersistentFlags().BoolVarP(
+		&jsonOut, "json", "j", false, "Emit output as JSON")
+	return cmd
+}
+
+// updateDetailsJSON is the shape of the --json output of `pulumi stack history show`.
+type updateDetailsJSON struct {
+	Version                  int                  `json:"version"`
+	Kind                     string               `json:"kind"`
+	Result                   string               `json:"result"`
+	Message                  string               `json:"message"`
+	StartTime                string               `json:"startTime"`
+	EndTime                  string               `json:"endTime"`
+	ResourceChanges          map[string]int       `json:"resourceChanges,omitempty"`
+	Timings                  []apitype.StepTiming `json:"timings,omitempty"`
+	CriticalPath             []criticalPathStep   `json:"criticalPath,omitempty"`
+	CriticalPathMilliseconds int64                `json:"criticalPathMilliseconds,omitempty"`
+}
+
+// criticalPathStep is a resource on the critical path of an update, along with how long its operations took.
+type criticalPathStep struct {
+	URN          resource.URN `json:"urn"`
+	Milliseconds int64        `json:"milliseconds"`
+}
+
+func stepTimingDuration(t apitype.StepTiming) time.Duration {
+	return time.Duration(t.EndTime-t.StartTime) * time.Millisecond
+}
+
+// sortedStepTimings returns the given step timings, slowest first.
+func sortedStepTimings(timings []apitype.StepTiming) []apitype.StepTiming {
+	sorted := append([]apitype.StepTiming(nil), timings...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return stepTimingDuration(sorted[i]) > stepTimingDuration(sorted[j])
+	})
+	return sorted
+}
+
+// computeCriticalPath computes the critical path of an update from its step timings and the dependencies recorded in
+// the checkpoint that it saved.
+func computeCriticalPath(s backend.Stack, update backend.UpdateInfo) ([]criticalPathStep, time.Duration, error) {
+	be, ok := s.Backend().(backend.SpecificDeploymentExporter)
+	if !ok {
+		return nil, 0, fmt.Errorf("the current backend (%s) does not provide the ability to export previous "+
+			"deployments, which is needed to compute the critical path", s.Backend().Name())
+	}
+	version := strconv.Itoa(update.Version)
+	deployment, err := be.ExportDeploymentForVersion(commandContext(), s, version)
+	if err != nil {
+		return nil, 0, err
+	}
+	snap, err := stack.DeserializeUntypedDeployment(deployment, stack.DefaultSecretsProvider)
+	if err != nil {
+		return nil, 0, checkDeploymentVersionError(err, string(s.Ref().Name()))
+	}
+	if snap == nil {
+		return nil, 0, errors.New("the update did not save a checkpoint")
+	}
+
+	// Deletions happen once the resources depending on the deleted resource have been updated, in the reverse of the
+	// dependency order, so they aren't part of the dependency chains that the checkpoint records.
+	durations := make(map[resource.URN]time.Duration)
+	for _, t := range update.StepTimings {
+		switch t.Op {
+		case apitype.OpDelete, apitype.OpDeleteReplaced, apitype.OpDiscardReplaced, apitype.OpReadDiscard:
+			continue
+		}
+		durations[resource.URN(t.URN)] += stepTimingDuration(t)
+	}
+
+	resources, total := graph.NewDependencyGraph(snap.Resources).CriticalPath(durations)
+	path := make([]criticalPathStep, len(resources))
+	for i, res := range resources {
+		path[i] = criticalPathStep{URN: res.URN, Milliseconds: durations[res.URN].Milliseconds()}
+	}
+	return path, total, nil
+}
@@ -26,7 +26,7 @@ import (
 
 const (
 	possibleSecretsProviderChoices = "The type of the provider that should be used to encrypt and decrypt secrets\n" +
-		"(possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault)"
+		"(possible choices: default, passphrase, age, awskms, azurekeyvault, gcpkms, hashivault)"
 )
 
 func newStackInitCmd() *cobra.Command {
@@ -63,6 +63,20 @@ func newStackInitCmd() *cobra.Command {
 			"* `pulumi stack init --secrets-provider=\"gcpkms://projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>\"`\n" +
 			"* `pulumi stack init --secrets-provider=\"hashivault://mykey\"\n`" +
 			"\n" +
+			"A `hashivault` secrets provider logs in to the Vault server at VAULT_ADDR with the token in\n" +
+			"VAULT_TOKEN by default. The URL's `auth` parameter selects `approle` auth, with the credentials in\n" +
+			"VAULT_ROLE_ID and VAULT_SECRET_ID, or `kubernetes` auth, with the role in the `role` parameter.\n" +
+			"The `namespace`, `engine`, `authMount` and `keyVersion` parameters select the Vault namespace, the\n" +
+			"transit engine's mount path, the auth method's mount path and the version of the key to encrypt with:\n" +
+			"\n" +
+			"* `pulumi stack init --secrets-provider=\"hashivault://mykey?auth=approle&namespace=team-a\"`\n" +
+			"\n" +
+			"To encrypt the stack's secrets to the age public keys of its users, use one of the following:\n" +
+			"\n" +
+			"* `pulumi stack init --secrets-provider=age`, for the key of the identity in PULUMI_AGE_KEY or\n" +
+			"  PULUMI_AGE_KEY_FILE\n" +
+			"* `pulumi stack init --secrets-provider=\"age://<recipient>,<recipient>\"`\n" +
+			"\n" +
 			"A stack can be created based on the configuration of an existing stack by passing the\n" +
 			"`--copy-config-from` flag.\n" +
 			"* `pulumi stack init --copy-config-from dev`",
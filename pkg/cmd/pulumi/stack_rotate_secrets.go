@@ -0,0 +1,221 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/age"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/cloud"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/passphrase"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+func newStackRotateSecretsCmd() *cobra.Command {
+	var stackName string
+	var message string
+
+	var cmd = &cobra.Command{
+		Use:   "rotate-secrets",
+		Args:  cmdutil.NoArgs,
+		Short: "Rotate the key that encrypts the secrets of the current stack",
+		Long: "Rotate the key that encrypts the secrets of the current stack.\n" +
+			"\n" +
+			"The stack keeps its secrets provider, but its secrets are encrypted with a new key: a cloud secrets " +
+			"provider or the age secrets provider generates a new data key, and the passphrase secrets provider uses " +
+			"a new salt along with a new passphrase, which is prompted for when running interactively. When not " +
+			"running interactively, the passphrase from PULUMI_CONFIG_PASSPHRASE or PULUMI_CONFIG_PASSPHRASE_FILE is " +
+			"kept and only the salt changes.\n" +
+			"\n" +
+			"Every secret in the stack's configuration and checkpoint is re-encrypted with the new key, and the " +
+			"rotation is recorded in the stack's history. Secrets in the stack settings files that the stack's " +
+			"configuration extends are encrypted by their own secrets providers and are not rotated.\n" +
+			"\n" +
+			"Stacks whose secrets are encrypted by the Pulumi Service can't be rotated with this command, since the " +
+			"service manages their keys.",
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			_, root, err := readProject()
+			if err != nil {
+				return err
+			}
+
+			s, err := requireStack(stackName, false, opts, stackName == "" /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+
+			m, err := getUpdateMetadata(message, root, "", "")
+			if err != nil {
+				return fmt.Errorf("gathering environment metadata: %w", err)
+			}
+
+			if err := rotateStackSecrets(commandContext(), s, *m); err != nil {
+				return err
+			}
+			fmt.Printf("Rotated the secrets key of stack '%s'\n", s.Ref())
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.PersistentFlags().StringVarP(
+		&message, "message", "m", "Rotated the secrets key",
+		"A message to record with the rotation in the stack's history")
+
+	return cmd
+}
+
+// rotateStackSecrets re-encrypts the config and checkpoint of a stack with a new key from its secrets provider.
+func rotateStackSecrets(ctx context.Context, s backend.Stack, m backend.UpdateMetadata) error {
+	// Load the current secrets manager before the stack's settings, since it may need to initialize them.
+	oldSM, err := getStackSecretsManager(s)
+	if err != nil {
+		return err
+	}
+	ps, err := loadProjectStack(s)
+	if err != nil {
+		return err
+	}
+
+	newSM, err := newRotatedSecretsManager(s, ps)
+	if err != nil {
+		return err
+	}
+
+	// Re-encrypt the stack's own config. Config inherited from the files it extends is left as it is.
+	dec, err := oldSM.Decrypter()
+	if err != nil {
+		return err
+	}
+	enc, err := newSM.Encrypter()
+	if err != nil {
+		return err
+	}
+	newConfig, err := ps.Config.Copy(dec, enc)
+	if err != nil {
+		return fmt.Errorf("re-encrypting config: %w", err)
+	}
+	ps.Config = newConfig
+
+	return saveStackSecrets(ctx, s, ps, newSM, m)
+}
+
+// saveStackSecrets re-encrypts the checkpoint of a stack with the given secrets manager, and saves it along with the
+// stack's settings, whose config must already be encrypted by the secrets manager. The checkpoint is imported first,
+// and if the stack's settings can't then be saved, the old checkpoint is restored, so that the stack's config and
+// checkpoint are never left encrypted with different keys.
+func saveStackSecrets(ctx context.Context, s backend.Stack, ps *workspace.ProjectStack, sm secrets.Manager,
+	m backend.UpdateMetadata) error {
+	checkpoint, err := s.ExportDeployment(ctx)
+	if err != nil {
+		return err
+	}
+	snap, err := stack.DeserializeUntypedDeployment(checkpoint, stack.DefaultSecretsProvider)
+	if err != nil {
+		return checkDeploymentVersionError(err, s.Ref().Name().String())
+	}
+	deployment, err := stack.SerializeDeployment(snap, sm, false /*showSecrets*/)
+	if err != nil {
+		return fmt.Errorf("re-encrypting checkpoint: %w", err)
+	}
+	bytes, err := json.Marshal(deployment)
+	if err != nil {
+		return err
+	}
+	rotated := &apitype.UntypedDeployment{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Deployment: bytes,
+	}
+
+	if importer, ok := s.Backend().(backend.HistoryRecordingDeploymentImporter); ok {
+		err = importer.ImportDeploymentWithMetadata(ctx, s, rotated, m)
+	} else {
+		err = s.ImportDeployment(ctx, rotated)
+	}
+	if err != nil {
+		return fmt.Errorf("saving re-encrypted checkpoint: %w", err)
+	}
+
+	if err := saveProjectStack(s, ps); err != nil {
+		if restoreErr := s.ImportDeployment(ctx, checkpoint); restoreErr != nil {
+			return fmt.Errorf("saving re-encrypted config: %w; restoring the previous checkpoint also failed: %v",
+				err, restoreErr)
+		}
+		return fmt.Errorf("saving re-encrypted config: %w", err)
+	}
+	return nil
+}
+
+// newRotatedSecretsManager returns a secrets manager for the stack's secrets provider with a new key, and records
+// the new key's state in the stack's settings, which the caller saves.
+func newRotatedSecretsManager(s backend.Stack, ps *workspace.ProjectStack) (secrets.Manager, error) {
+	switch {
+	case ps.SecretsProvider == age.Type:
+		dataKey, err := age.GenerateNewDataKey(ps.SecretsRecipients)
+		if err != nil {
+			return nil, err
+		}
+		sm, err := age.NewAgeSecretsManager(ps.SecretsRecipients, dataKey)
+		if err != nil {
+			return nil, err
+		}
+		ps.EncryptedKey = base64.StdEncoding.EncodeToString(dataKey)
+		return sm, nil
+	case ps.SecretsProvider != "" && ps.SecretsProvider != passphrase.Type && ps.SecretsProvider != "default":
+		dataKey, err := cloud.GenerateNewDataKey(ps.SecretsProvider)
+		if err != nil {
+			return nil, err
+		}
+		sm, err := cloud.NewCloudSecretsManager(ps.SecretsProvider, dataKey)
+		if err != nil {
+			return nil, err
+		}
+		ps.EncryptedKey = base64.StdEncoding.EncodeToString(dataKey)
+		return sm, nil
+	case ps.EncryptionSalt != "" || isFilestateStack(s):
+		salt, sm, err := passphrase.PromptForNewPassphrase(cmdutil.Interactive() /*rotate*/)
+		if err != nil {
+			return nil, err
+		}
+		ps.EncryptionSalt = salt
+		return sm, nil
+	default:
+		return nil, fmt.Errorf("the secrets of stack '%s' are encrypted by the Pulumi Service, which manages their "+
+			"keys; only passphrase, age and cloud secrets providers can be rotated", s.Ref())
+	}
+}
+
+func isFilestateStack(s backend.Stack) bool {
+	_, ok := s.(filestate.Stack)
+	return ok
+}
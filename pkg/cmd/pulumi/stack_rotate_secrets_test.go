@@ -0,0 +1,212 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agelib "filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/age"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+var rotateTestKey = config.MustMakeKey("proj", "password")
+
+// newRotateTestStack creates a stack in a filestate backend whose settings are written by writeSettings, and gives it
+// a secret config value and a checkpoint with a secret output, both encrypted by the stack's secrets manager, which
+// is returned along with the stack.
+func newRotateTestStack(t *testing.T,
+	writeSettings func(path string)) (backend.Stack, secrets.Manager) {
+	dir, stateDir := t.TempDir(), t.TempDir()
+	path := filepath.Join(dir, "Pulumi.dev.yaml")
+	writeSettings(path)
+	stackConfigFile = path
+	t.Cleanup(func() { stackConfigFile = "" })
+
+	ctx := context.Background()
+	b, err := filestate.New(cmdutil.Diag(), "file://"+filepath.ToSlash(stateDir))
+	require.NoError(t, err)
+	ref, err := b.ParseStackReference("dev")
+	require.NoError(t, err)
+	s, err := b.CreateStack(ctx, ref, nil)
+	require.NoError(t, err)
+
+	sm, err := getStackSecretsManager(s)
+	require.NoError(t, err)
+	enc, err := sm.Encrypter()
+	require.NoError(t, err)
+	ciphertext, err := enc.EncryptValue("hunter2")
+	require.NoError(t, err)
+	ps, err := loadProjectStack(s)
+	require.NoError(t, err)
+	ps.Config = config.Map{rotateTestKey: config.NewSecureValue(ciphertext)}
+	require.NoError(t, saveProjectStack(s, ps))
+
+	root := &resource.State{
+		URN:  resource.NewURN("dev", "proj", "", resource.RootStackType, "proj-dev"),
+		Type: resource.RootStackType,
+		Outputs: resource.PropertyMap{
+			"token": resource.MakeSecret(resource.NewStringProperty("s3cr3t")),
+		},
+	}
+	snap := deploy.NewSnapshot(deploy.Manifest{}, sm, []*resource.State{root}, nil)
+	require.NoError(t, saveSnapshot(s, snap))
+	return s, sm
+}
+
+// rotateTestCiphertexts returns the ciphertexts of the stack's secret config value and checkpoint output.
+func rotateTestCiphertexts(t *testing.T, s backend.Stack) (string, string) {
+	ps, err := loadProjectStack(s)
+	require.NoError(t, err)
+	configCiphertext, err := ps.Config[rotateTestKey].Value(config.NopDecrypter)
+	require.NoError(t, err)
+
+	untyped, err := s.ExportDeployment(context.Background())
+	require.NoError(t, err)
+	var deployment apitype.DeploymentV3
+	require.NoError(t, json.Unmarshal(untyped.Deployment, &deployment))
+	require.Len(t, deployment.Resources, 1)
+	token, ok := deployment.Resources[0].Outputs["token"].(map[string]interface{})
+	require.True(t, ok)
+	checkpointCiphertext, ok := token["ciphertext"].(string)
+	require.True(t, ok)
+	return configCiphertext, checkpointCiphertext
+}
+
+// assertRotated checks that the stack's config and checkpoint secrets are encrypted by its current secrets manager,
+// and can no longer be decrypted by the given old one.
+func assertRotated(t *testing.T, s backend.Stack, oldSM secrets.Manager) {
+	configCiphertext, checkpointCiphertext := rotateTestCiphertexts(t, s)
+
+	newSM, err := getStackSecretsManager(s)
+	require.NoError(t, err)
+	newDec, err := newSM.Decrypter()
+	require.NoError(t, err)
+	plaintext, err := newDec.DecryptValue(configCiphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", plaintext)
+	plaintext, err = newDec.DecryptValue(checkpointCiphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, `"s3cr3t"`, plaintext)
+
+	oldDec, err := oldSM.Decrypter()
+	require.NoError(t, err)
+	_, err = oldDec.DecryptValue(configCiphertext)
+	assert.Error(t, err)
+	_, err = oldDec.DecryptValue(checkpointCiphertext)
+	assert.Error(t, err)
+}
+
+//nolint:paralleltest // sets environment variables and the stack config file
+func TestRotateStackSecretsPassphrase(t *testing.T) {
+	t.Setenv("PULUMI_CONFIG_PASSPHRASE", "correct horse battery staple")
+
+	s, oldSM := newRotateTestStack(t, func(path string) {})
+	ps, err := loadProjectStack(s)
+	require.NoError(t, err)
+	oldSalt := ps.EncryptionSalt
+
+	require.NoError(t, rotateStackSecrets(context.Background(), s, backend.UpdateMetadata{}))
+
+	ps, err = loadProjectStack(s)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldSalt, ps.EncryptionSalt)
+	assertRotated(t, s, oldSM)
+}
+
+//nolint:paralleltest // sets environment variables and the stack config file
+func TestRotateStackSecretsAge(t *testing.T) {
+	id, err := agelib.GenerateX25519Identity()
+	require.NoError(t, err)
+	t.Setenv(age.KeyEnvVar, id.String())
+
+	recipients := []string{id.Recipient().String()}
+	s, oldSM := newRotateTestStack(t, func(path string) {
+		dataKey, err := age.GenerateNewDataKey(recipients)
+		require.NoError(t, err)
+		require.NoError(t, (&workspace.ProjectStack{
+			SecretsProvider:   age.Type,
+			SecretsRecipients: recipients,
+			EncryptedKey:      base64.StdEncoding.EncodeToString(dataKey),
+		}).Save(path))
+	})
+	ps, err := loadProjectStack(s)
+	require.NoError(t, err)
+	oldKey := ps.EncryptedKey
+
+	require.NoError(t, rotateStackSecrets(context.Background(), s, backend.UpdateMetadata{}))
+
+	ps, err = loadProjectStack(s)
+	require.NoError(t, err)
+	assert.Equal(t, age.Type, ps.SecretsProvider)
+	assert.Equal(t, recipients, ps.SecretsRecipients)
+	assert.NotEqual(t, oldKey, ps.EncryptedKey)
+	assertRotated(t, s, oldSM)
+}
+
+//nolint:paralleltest // sets environment variables and the stack config file
+func TestRotateStackSecretsRestoresCheckpoint(t *testing.T) {
+	t.Setenv("PULUMI_CONFIG_PASSPHRASE", "correct horse battery staple")
+
+	s, oldSM := newRotateTestStack(t, func(path string) {})
+	settings, err := os.ReadFile(stackConfigFile)
+	require.NoError(t, err)
+
+	ps, err := loadProjectStack(s)
+	require.NoError(t, err)
+	newSM, err := newRotatedSecretsManager(s, ps)
+	require.NoError(t, err)
+
+	// Saving the settings fails, since the parent of the stack config file is not a directory.
+	path := stackConfigFile
+	notADir := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(notADir, nil, 0o600))
+	stackConfigFile = filepath.Join(notADir, "Pulumi.dev.yaml")
+	err = saveStackSecrets(context.Background(), s, ps, newSM, backend.UpdateMetadata{})
+	assert.ErrorContains(t, err, "saving re-encrypted config")
+	stackConfigFile = path
+
+	// The settings were not changed, and the checkpoint is still encrypted with the old key.
+	newSettings, err := os.ReadFile(stackConfigFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(settings), string(newSettings))
+
+	_, checkpointCiphertext := rotateTestCiphertexts(t, s)
+	oldDec, err := oldSM.Decrypter()
+	require.NoError(t, err)
+	plaintext, err := oldDec.DecryptValue(checkpointCiphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, `"s3cr3t"`, plaintext)
+	newDec, err := newSM.Decrypter()
+	require.NoError(t, err)
+	_, err = newDec.DecryptValue(checkpointCiphertext)
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/age"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+func newStackSecretsRecipientsCmd() *cobra.Command {
+	var stack string
+
+	cmd := &cobra.Command{
+		Use:   "secrets-recipients",
+		Short: "Manage the recipients of a stack's secrets",
+		Long: "Manage the recipients of a stack's secrets\n" +
+			"\n" +
+			"A stack that uses the `age` secrets provider encrypts its data key to the age public keys\n" +
+			"of its recipients, any of whom can decrypt the stack's secrets with the age identity in\n" +
+			"PULUMI_AGE_KEY or PULUMI_AGE_KEY_FILE. The `ls`, `add`, and `rm` commands can be used to\n" +
+			"manage the recipients without re-encrypting the stack's configuration.\n" +
+			"\n" +
+			"A recipient that is removed may have kept a copy of the data key. To revoke its access to\n" +
+			"the stack's secrets, also run `pulumi stack rotate-secrets`.\n",
+		Args: cmdutil.NoArgs,
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "", "The name of the stack to operate on. Defaults to the current stack")
+
+	cmd.AddCommand(newStackSecretsRecipientsLsCmd(&stack))
+	cmd.AddCommand(newStackSecretsRecipientsAddCmd(&stack))
+	cmd.AddCommand(newStackSecretsRecipientsRmCmd(&stack))
+
+	return cmd
+}
+
+func newStackSecretsRecipientsLsCmd(stack *string) *cobra.Command {
+	var jsonOut bool
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List the recipients of the stack's secrets",
+		Args:  cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(*stack, false, opts, false /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+			ps, err := loadAgeProjectStack(s)
+			if err != nil {
+				return err
+			}
+
+			if jsonOut {
+				return printJSON(ps.SecretsRecipients)
+			}
+			for _, r := range ps.SecretsRecipients {
+				fmt.Println(r)
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().BoolVarP(
+		&jsonOut, "json", "j", false, "Emit output as JSON")
+
+	return cmd
+}
+
+func newStackSecretsRecipientsAddCmd(stack *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <recipient>...",
+		Short: "Add recipients of the stack's secrets",
+		Args:  cmdutil.MinimumNArgs(1),
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			return changeStackSecretsRecipients(*stack, "Added secrets recipients", func(current []string) ([]string, error) {
+				return append(current, args...), nil
+			})
+		}),
+	}
+}
+
+func newStackSecretsRecipientsRmCmd(stack *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm <recipient>...",
+		Short: "Remove recipients of the stack's secrets",
+		Args:  cmdutil.MinimumNArgs(1),
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			return changeStackSecretsRecipients(*stack, "Removed secrets recipients", func(current []string) ([]string, error) {
+				removed := make(map[string]bool)
+				for _, r := range args {
+					removed[r] = true
+				}
+				var recipients []string
+				for _, r := range current {
+					if removed[r] {
+						delete(removed, r)
+					} else {
+						recipients = append(recipients, r)
+					}
+				}
+				for r := range removed {
+					return nil, fmt.Errorf("%s is not a recipient of the stack's secrets", r)
+				}
+				return recipients, nil
+			})
+		}),
+	}
+}
+
+// changeStackSecretsRecipients encrypts the data key of a stack that uses the age secrets provider to a new set of
+// recipients, as returned by change from the current set, and records the change in the stack's history.
+func changeStackSecretsRecipients(stackName, message string,
+	change func(current []string) ([]string, error)) error {
+	opts := display.Options{
+		Color: cmdutil.GetGlobalColorization(),
+	}
+
+	_, root, err := readProject()
+	if err != nil {
+		return err
+	}
+	s, err := requireStack(stackName, false, opts, false /*setCurrent*/)
+	if err != nil {
+		return err
+	}
+	ps, err := loadAgeProjectStack(s)
+	if err != nil {
+		return err
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(ps.EncryptedKey)
+	if err != nil {
+		return err
+	}
+	current, err := age.NewAgeSecretsManager(ps.SecretsRecipients, dataKey)
+	if err != nil {
+		return err
+	}
+	recipients, err := change(append([]string(nil), ps.SecretsRecipients...))
+	if err != nil {
+		return err
+	}
+	sm, err := current.WithRecipients(recipients)
+	if err != nil {
+		return err
+	}
+	ps.SecretsRecipients = sm.Recipients()
+	ps.EncryptedKey = base64.StdEncoding.EncodeToString(sm.EncryptedKey())
+
+	m, err := getUpdateMetadata(message, root, "", "")
+	if err != nil {
+		return fmt.Errorf("gathering environment metadata: %w", err)
+	}
+	// The config is still encrypted by the same data key, so only the checkpoint needs to record the new recipients.
+	if err := saveStackSecrets(commandContext(), s, ps, sm, *m); err != nil {
+		return err
+	}
+
+	fmt.Printf("The secrets of stack '%s' are now encrypted to %d recipient(s)\n", s.Ref(), len(ps.SecretsRecipients))
+	return nil
+}
+
+// loadAgeProjectStack loads the settings of a stack that uses the age secrets provider.
+func loadAgeProjectStack(s backend.Stack) (*workspace.ProjectStack, error) {
+	ps, err := loadProjectStack(s)
+	if err != nil {
+		return nil, err
+	}
+	if ps.SecretsProvider != age.Type {
+		return nil, fmt.Errorf("stack '%s' does not use the age secrets provider; "+
+			"use `pulumi stack change-secrets-provider age` to switch to it", s.Ref())
+	}
+	return ps, nil
+}
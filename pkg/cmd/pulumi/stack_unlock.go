@@ -0,0 +1,132 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStackUnlockCmd() *cobra.Command {
+	var stack string
+	var yes bool
+	var force bool
+	var cmd = &cobra.Command{
+		Use:   "unlock [<stack-name>]",
+		Args:  cmdutil.MaximumNArgs(1),
+		Short: "Break the locks held on a stack by other processes",
+		Long: "Break the locks held on a stack by other processes\n" +
+			"\n" +
+			"Self-managed backends lock a stack while it is being updated. Locks whose holder has crashed\n" +
+			"are normally broken automatically once their lease expires, or immediately if the holder ran\n" +
+			"on this machine. This command shows who holds the stack's locks and breaks them.\n" +
+			"\n" +
+			"Locks whose holder may still be running are only broken if --force is passed. Breaking a lock\n" +
+			"that is in use can corrupt the stack's state.",
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			yes = yes || skipConfirmations()
+			// Use the stack provided or, if missing, default to the current one.
+			if len(args) > 0 {
+				if stack != "" {
+					return result.Error("only one of --stack or argument stack name may be specified, not both")
+				}
+				stack = args[0]
+			}
+
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(stack, false, opts, false /*setCurrent*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+			b, ok := s.Backend().(filestate.Backend)
+			if !ok {
+				return result.Errorf("stack locks are only used by self-managed backends; " +
+					"use `pulumi cancel` to cancel an update in progress")
+			}
+
+			locks, err := b.StackLocks(commandContext(), s.Ref())
+			if err != nil {
+				return result.FromError(err)
+			}
+			if len(locks) == 0 {
+				fmt.Printf("The stack '%s' is not locked\n", s.Ref())
+				return nil
+			}
+
+			live := 0
+			fmt.Printf("The stack '%s' is locked by:\n", s.Ref())
+			for _, l := range locks {
+				fmt.Printf("  %v@%v (pid %v) since %v\n",
+					l.Username, l.Hostname, l.Pid, l.Timestamp.Format(time.RFC3339))
+				switch {
+				case l.StaleReason != "":
+					fmt.Printf("    stale: %s\n", l.StaleReason)
+				case l.Expires != nil:
+					live++
+					fmt.Printf("    lease expires at %v\n", l.Expires.Format(time.RFC3339))
+				default:
+					live++
+					fmt.Println("    the lock has no lease, so its holder may still be running")
+				}
+				fmt.Printf("    %s\n", l.Path)
+			}
+			fmt.Println()
+
+			if live > 0 {
+				if !force {
+					return result.Errorf("refusing to break %d lock(s) whose holder may still be running; "+
+						"pass --force to break them anyway", live)
+				}
+
+				prompt := fmt.Sprintf("This will break %d lock(s) on '%s' that may still be in use!", live, s.Ref())
+				if !yes && !confirmPrompt(prompt, s.Ref().String(), opts) {
+					fmt.Println("confirmation declined")
+					return result.Bail()
+				}
+			}
+
+			if err := b.UnlockStack(commandContext(), s.Ref(), force); err != nil {
+				return result.FromError(err)
+			}
+
+			msg := fmt.Sprintf("%sStack '%s' has been unlocked%s", colors.SpecAttention, s.Ref(), colors.Reset)
+			fmt.Println(opts.Color.Colorize(msg))
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().BoolVarP(
+		&force, "force", "f", false,
+		"Break locks whose holder may still be running")
+	cmd.PersistentFlags().BoolVarP(
+		&yes, "yes", "y", false,
+		"Skip confirmation prompts, and proceed with unlocking anyway")
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+
+	return cmd
+}
@@ -18,7 +18,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/pulumi/pulumi/pkg/v3/backend"
 	"github.com/pulumi/pulumi/pkg/v3/backend/display"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
@@ -46,8 +49,12 @@ troubleshooting a stack or when performing specific edits that otherwise would r
 	}
 
 	cmd.AddCommand(newStateDeleteCommand())
+	cmd.AddCommand(newStateProtectCommand())
 	cmd.AddCommand(newStateUnprotectCommand())
 	cmd.AddCommand(newStateRenameCommand())
+	cmd.AddCommand(newStateMoveCommand())
+	cmd.AddCommand(newStateEditCommand())
+	cmd.AddCommand(newStateRepairCommand())
 	return cmd
 }
 
@@ -123,6 +130,63 @@ func runStateEdit(stackName string, showPrompt bool, urn resource.URN, operation
 	})
 }
 
+// errStateEditDeclined is returned by state edit operations when the user declines to confirm the edit.
+var errStateEditDeclined = errors.New("confirmation declined")
+
+// runSelectedStateEdit runs the given state edit function on every resource in the given stack that is selected by
+// the given targets, which may contain wildcards or be `type:` and `children-of:` selectors. The selected resources
+// are listed before the user is prompted for confirmation.
+func runSelectedStateEdit(
+	stackName string, showPrompt bool, targets []resource.URN, operation edit.OperationFunc) result.Result {
+	return runTotalStateEdit(stackName, false, func(opts display.Options, snap *deploy.Snapshot) error {
+		if snap == nil {
+			return errors.New("no resources found in the current state")
+		}
+
+		// Selectors may match nothing, but a URN that is given exactly must name a resource.
+		var missing []string
+		for _, t := range targets {
+			if !deploy.IsTargetSelector(t) && len(edit.LocateResource(snap, t)) == 0 {
+				missing = append(missing, strconv.Quote(string(t)))
+			}
+		}
+		if len(missing) != 0 {
+			return fmt.Errorf("no resource named %s exists in the current state", strings.Join(missing, ", "))
+		}
+
+		selected := snap.SelectResources(targets)
+		if len(selected) == 0 {
+			return errors.New("no resources in the current state match the given URNs and types")
+		}
+
+		fmt.Println("The following resources will be edited:")
+		for _, res := range selected {
+			fmt.Printf(" * %s\n", res.URN)
+		}
+		fmt.Println()
+
+		if showPrompt && cmdutil.Interactive() && !confirmStateEdit(opts) {
+			return errStateEditDeclined
+		}
+
+		for _, res := range selected {
+			if err := operation(snap, res); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// stateEditTargets returns the targets selected by the given URN arguments and `--type` flags.
+func stateEditTargets(urns []string, types []string) []resource.URN {
+	targets := toURNs(urns)
+	for _, t := range types {
+		targets = append(targets, resource.URN(deploy.TypeSelectorPrefix+t))
+	}
+	return targets
+}
+
 // runTotalStateEdit runs a snapshot-mutating function on the entirety of the given stack's snapshot.
 // Before mutating, the user may be prompted to for confirmation if the current session is interactive.
 func runTotalStateEdit(
@@ -135,53 +199,113 @@ func runTotalStateEdit(
 	if err != nil {
 		return result.FromError(err)
 	}
-	snap, err := s.Snapshot(commandContext())
-	if err != nil {
-		return result.FromError(err)
-	}
 
-	if showPrompt && cmdutil.Interactive() {
-		confirm := false
-		surveycore.DisableColor = true
-		surveycore.QuestionIcon = ""
-		surveycore.SelectFocusIcon = opts.Color.Colorize(colors.BrightGreen + ">" + colors.Reset)
-		prompt := opts.Color.Colorize(colors.Yellow + "warning" + colors.Reset + ": ")
-		prompt += "This command will edit your stack's state directly. Confirm?"
-		cmdutil.EndKeypadTransmitMode()
-		if err = survey.AskOne(&survey.Confirm{
-			Message: prompt,
-		}, &confirm, nil); err != nil || !confirm {
-			fmt.Println("confirmation declined")
-			return result.Bail()
+	return withStackLock(s, func() result.Result {
+		snap, err := currentSnapshot(s)
+		if err != nil {
+			return result.FromError(err)
+		}
+
+		if showPrompt && cmdutil.Interactive() {
+			if !confirmStateEdit(opts) {
+				fmt.Println("confirmation declined")
+				return result.Bail()
+			}
+		}
+
+		// The `operation` callback will mutate `snap` in-place. In order to validate the correctness of the
+		// transformation that we are doing here, we verify the integrity of the snapshot before the mutation. If the
+		// snapshot was valid before we mutated it, we'll assert that we didn't make it invalid by mutating it.
+		stackIsAlreadyHosed := snap.VerifyIntegrity() != nil
+		if err = operation(opts, snap); err != nil {
+			if errors.Is(err, errStateEditDeclined) {
+				fmt.Println("confirmation declined")
+				return result.Bail()
+			}
+			return result.FromError(err)
 		}
+
+		// If the stack is already broken, don't bother verifying the integrity here.
+		if !stackIsAlreadyHosed {
+			contract.AssertNoErrorf(snap.VerifyIntegrity(), "state edit produced an invalid snapshot")
+		}
+
+		return result.WrapIfNonNil(persistSnapshot(s, snap))
+	})
+}
+
+// confirmStateEdit prompts the user to confirm that they want to edit their stack's state directly.
+func confirmStateEdit(opts display.Options) bool {
+	confirm := false
+	surveycore.DisableColor = true
+	surveycore.QuestionIcon = ""
+	surveycore.SelectFocusIcon = opts.Color.Colorize(colors.BrightGreen + ">" + colors.Reset)
+	prompt := opts.Color.Colorize(colors.Yellow + "warning" + colors.Reset + ": ")
+	prompt += "This command will edit your stack's state directly. Confirm?"
+	cmdutil.EndKeypadTransmitMode()
+	if err := survey.AskOne(&survey.Confirm{
+		Message: prompt,
+	}, &confirm, nil); err != nil {
+		return false
+	}
+	return confirm
+}
+
+// withStackLock runs f while holding the lock of the given stack, if its backend supports holding the lock across a
+// read-modify-write of the stack's state. Every `pulumi state` command reads, edits and saves the state within f.
+func withStackLock(s backend.Stack, f func() result.Result) result.Result {
+	lb, ok := s.Backend().(backend.StackLockingBackend)
+	if !ok {
+		return f()
 	}
 
-	// The `operation` callback will mutate `snap` in-place. In order to validate the correctness of the transformation
-	// that we are doing here, we verify the integrity of the snapshot before the mutation. If the snapshot was valid
-	// before we mutated it, we'll assert that we didn't make it invalid by mutating it.
-	stackIsAlreadyHosed := snap.VerifyIntegrity() != nil
-	if err = operation(opts, snap); err != nil {
+	var res result.Result
+	if err := lb.WithStackLock(commandContext(), s, func() error {
+		res = f()
+		return nil
+	}); err != nil {
 		return result.FromError(err)
 	}
+	return res
+}
+
+// currentSnapshot loads the current snapshot of the given stack. Stacks may cache the snapshot they were loaded with,
+// so the stack is loaded again, which is needed to read its state once its lock is held.
+func currentSnapshot(s backend.Stack) (*deploy.Snapshot, error) {
+	current, err := s.Backend().GetStack(commandContext(), s.Ref())
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("no stack named '%s' found", s.Ref())
+	}
+	return current.Snapshot(commandContext())
+}
 
-	// If the stack is already broken, don't bother verifying the integrity here.
-	if !stackIsAlreadyHosed {
-		contract.AssertNoErrorf(snap.VerifyIntegrity(), "state edit produced an invalid snapshot")
+// persistSnapshot saves the given snapshot to the given stack using the backend's SnapshotPersister, falling back to
+// importing the snapshot as a deployment if the backend cannot persist snapshots directly.
+func persistSnapshot(s backend.Stack, snap *deploy.Snapshot) error {
+	spb, ok := s.Backend().(backend.SnapshotPersistingBackend)
+	if !ok {
+		return saveSnapshot(s, snap)
 	}
+	return spb.SnapshotPersister(commandContext(), s, snap.SecretsManager).Save(snap)
+}
 
+// saveSnapshot serializes the given snapshot and imports it back into the given stack so that it is persisted.
+func saveSnapshot(s backend.Stack, snap *deploy.Snapshot) error {
 	sdep, err := stack.SerializeDeployment(snap, snap.SecretsManager, false /* showSecrets */)
 	if err != nil {
-		return result.FromError(fmt.Errorf("serializing deployment: %w", err))
+		return fmt.Errorf("serializing deployment: %w", err)
 	}
 
-	// Once we've mutated the snapshot, import it back into the backend so that it can be persisted.
 	bytes, err := json.Marshal(sdep)
 	if err != nil {
-		return result.FromError(err)
+		return err
 	}
 	dep := apitype.UntypedDeployment{
 		Version:    apitype.DeploymentSchemaVersionCurrent,
 		Deployment: bytes,
 	}
-	return result.WrapIfNonNil(s.ImportDeployment(commandContext(), &dep))
+	return s.ImportDeployment(commandContext(), &dep)
 }
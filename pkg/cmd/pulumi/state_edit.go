@@ -0,0 +1,299 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	survey "gopkg.in/AlecAivazis/survey.v1"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	sdkDisplay "github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStateEditCommand() *cobra.Command {
+	var stackName string
+	var showSecrets bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the current stack's state in your editor",
+		Long: `Edit the current stack's state in your editor
+
+This command opens the stack's state in the editor given by the VISUAL or EDITOR environment variables. Once the
+editor exits, the edited state is validated and the changes are displayed before they are saved.
+
+Edits that leave the state invalid, such as resources that refer to missing parents, dependencies or providers, are
+refused. If the session is interactive, the state can be edited again to fix such problems.`,
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			yes = yes || skipConfirmations()
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(stackName, false, opts, false /*setCurrent*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+			return withStackLock(s, func() result.Result {
+				snap, err := currentSnapshot(s)
+				if err != nil {
+					return result.FromError(err)
+				}
+				if snap == nil {
+					return result.Errorf("the stack %s has no state to edit", s.Ref())
+				}
+
+				sdep, err := stack.SerializeDeployment(snap, snap.SecretsManager, showSecrets)
+				if err != nil {
+					return result.FromError(fmt.Errorf("serializing deployment: %w", err))
+				}
+				original, err := json.MarshalIndent(sdep, "", "    ")
+				if err != nil {
+					return result.FromError(err)
+				}
+
+				newSnap, err := editState(original)
+				if err != nil {
+					return result.FromError(err)
+				}
+				if newSnap == nil {
+					fmt.Println("No changes were made to the state")
+					return nil
+				}
+
+				fmt.Println(opts.Color.Colorize(colors.SpecHeadline + "Changes:" + colors.Reset))
+				fmt.Print(opts.Color.Colorize(renderStateDiff(snap.Resources, newSnap.Resources)))
+				fmt.Println()
+
+				if !yes && cmdutil.Interactive() && !confirmStateEdit(opts) {
+					fmt.Println("confirmation declined")
+					return result.Bail()
+				}
+
+				if err := persistSnapshot(s, newSnap); err != nil {
+					return result.FromError(err)
+				}
+				fmt.Println("State saved")
+				return nil
+			})
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().BoolVar(
+		&showSecrets, "show-secrets", false, "Show secret values in plaintext while editing the state")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	return cmd
+}
+
+// editState opens the given serialized deployment in the user's editor until they either make a valid edit or give
+// up. It returns the snapshot described by the edited deployment, or nil if nothing was changed.
+func editState(original []byte) (*deploy.Snapshot, error) {
+	f, err := ioutil.TempFile("", "pulumi-state-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(original)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := runEditor(f.Name()); err != nil {
+			return nil, err
+		}
+
+		edited, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(bytes.TrimSpace(edited), bytes.TrimSpace(original)) {
+			return nil, nil
+		}
+
+		snap, err := parseEditedState(edited)
+		if err == nil {
+			return snap, nil
+		}
+
+		// Offer to fix the problem if we can, otherwise refuse the edit.
+		if !cmdutil.Interactive() {
+			return nil, fmt.Errorf("the edited state is invalid: %w", err)
+		}
+		fmt.Printf("The edited state is invalid: %v\n", err)
+
+		retry := false
+		cmdutil.EndKeypadTransmitMode()
+		if err := survey.AskOne(&survey.Confirm{
+			Message: "Edit the state again?",
+			Default: true,
+		}, &retry, nil); err != nil || !retry {
+			return nil, errors.New("the edited state is invalid; no changes were saved")
+		}
+	}
+}
+
+// parseEditedState deserializes and validates an edited deployment.
+func parseEditedState(edited []byte) (*deploy.Snapshot, error) {
+	var dep apitype.DeploymentV3
+	if err := json.Unmarshal(edited, &dep); err != nil {
+		return nil, fmt.Errorf("could not parse the state: %w", err)
+	}
+	snap, err := stack.DeserializeDeploymentV3(dep, stack.DefaultSecretsProvider)
+	if err != nil {
+		return nil, err
+	}
+	if err := snap.VerifyIntegrity(); err != nil {
+		return nil, err
+	}
+
+	// VerifyIntegrity does not check property dependencies, so check that they refer to known resources here.
+	urns := make(map[resource.URN]bool)
+	for _, res := range snap.Resources {
+		urns[res.URN] = true
+	}
+	for _, res := range snap.Resources {
+		for key, deps := range res.PropertyDependencies {
+			for _, dep := range deps {
+				if !urns[dep] {
+					return nil, fmt.Errorf("resource %s property %s dependency %s refers to missing resource",
+						res.URN, key, dep)
+				}
+			}
+		}
+	}
+	return snap, nil
+}
+
+// runEditor opens the given file in the user's editor and waits for it to exit.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The editor may include arguments, e.g. `code --wait`.
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...) //nolint:gosec
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running editor %q: %w", editor, err)
+	}
+	return nil
+}
+
+// renderStateDiff renders the differences between two lists of resources using the same notation as the property
+// diffs shown during previews and updates.
+func renderStateDiff(olds, news []*resource.State) string {
+	type key struct {
+		urn    resource.URN
+		delete bool
+	}
+	keyOf := func(res *resource.State) key {
+		return key{urn: res.URN, delete: res.Delete}
+	}
+
+	oldMap := make(map[key]*resource.State)
+	for _, res := range olds {
+		oldMap[keyOf(res)] = res
+	}
+	newMap := make(map[key]*resource.State)
+	for _, res := range news {
+		newMap[keyOf(res)] = res
+	}
+
+	var b bytes.Buffer
+	writeHeader := func(op sdkDisplay.StepOp, urn resource.URN) {
+		b.WriteString(fmt.Sprintf("%s%s%s\n", deploy.Prefix(op, true /*done*/), urn, colors.Reset))
+	}
+	for _, old := range olds {
+		if _, has := newMap[keyOf(old)]; !has {
+			writeHeader(deploy.OpDelete, old.URN)
+		}
+	}
+	for _, new := range news {
+		old, has := oldMap[keyOf(new)]
+		if !has {
+			writeHeader(deploy.OpCreate, new.URN)
+			display.PrintObject(&b, new.Outputs, false, 2, deploy.OpCreate, true, false)
+			continue
+		}
+
+		var details bytes.Buffer
+		writeField := func(name string, oldValue, newValue interface{}) {
+			oldString, newString := fmt.Sprintf("%v", oldValue), fmt.Sprintf("%v", newValue)
+			if oldString != newString {
+				details.WriteString(fmt.Sprintf("%s    [%s: %s => %s]\n%s",
+					deploy.Color(deploy.OpUpdate), name, oldString, newString, colors.Reset))
+			}
+		}
+		writeField("id", old.ID, new.ID)
+		writeField("type", old.Type, new.Type)
+		writeField("parent", old.Parent, new.Parent)
+		writeField("provider", old.Provider, new.Provider)
+		writeField("protect", old.Protect, new.Protect)
+		writeField("dependencies", old.Dependencies, new.Dependencies)
+		writeField("propertyDependencies", old.PropertyDependencies, new.PropertyDependencies)
+		writeField("external", old.External, new.External)
+		writeField("retainOnDelete", old.RetainOnDelete, new.RetainOnDelete)
+		if diff := old.Inputs.Diff(new.Inputs); diff != nil {
+			details.WriteString("    inputs:\n")
+			display.PrintObjectDiff(&details, *diff, nil, false, 2, false, false)
+		}
+		if diff := old.Outputs.Diff(new.Outputs); diff != nil {
+			details.WriteString("    outputs:\n")
+			display.PrintObjectDiff(&details, *diff, nil, false, 2, false, false)
+		}
+
+		if details.Len() > 0 {
+			writeHeader(deploy.OpUpdate, new.URN)
+			b.Write(details.Bytes())
+		}
+	}
+	return b.String()
+}
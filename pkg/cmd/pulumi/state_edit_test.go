@@ -0,0 +1,205 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+const (
+	stateEditStackURN  = "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev"
+	stateEditBucketURN = "urn:pulumi:dev::proj::pulumi:pulumi:Stack$aws:s3/bucket:Bucket::b"
+)
+
+// marshalEditedState serializes the given resources as an edited deployment.
+func marshalEditedState(t *testing.T, resources ...apitype.ResourceV3) []byte {
+	t.Helper()
+	bytes, err := json.Marshal(apitype.DeploymentV3{Resources: resources})
+	require.NoError(t, err)
+	return bytes
+}
+
+func TestParseEditedState(t *testing.T) {
+	t.Parallel()
+
+	edited := marshalEditedState(t,
+		apitype.ResourceV3{URN: stateEditStackURN, Type: "pulumi:pulumi:Stack", Custom: false},
+		apitype.ResourceV3{
+			URN:     stateEditBucketURN,
+			Type:    "aws:s3/bucket:Bucket",
+			Parent:  stateEditStackURN,
+			Outputs: map[string]interface{}{"bucket": "b-1234"},
+		})
+
+	snap, err := parseEditedState(edited)
+	require.NoError(t, err)
+	require.Len(t, snap.Resources, 2)
+	assert.Equal(t, resource.URN(stateEditBucketURN), snap.Resources[1].URN)
+	assert.Equal(t, resource.URN(stateEditStackURN), snap.Resources[1].Parent)
+	assert.Equal(t, "b-1234", snap.Resources[1].Outputs["bucket"].StringValue())
+}
+
+func TestParseEditedStateInvalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		edited   func(t *testing.T) []byte
+		expected string
+	}{
+		{
+			name:     "malformed JSON",
+			edited:   func(t *testing.T) []byte { return []byte(`{"resources": [`) },
+			expected: "could not parse the state",
+		},
+		{
+			name: "missing parent",
+			edited: func(t *testing.T) []byte {
+				return marshalEditedState(t, apitype.ResourceV3{
+					URN:    stateEditBucketURN,
+					Type:   "aws:s3/bucket:Bucket",
+					Parent: stateEditStackURN,
+				})
+			},
+			expected: "refers to missing parent",
+		},
+		{
+			name: "missing dependency",
+			edited: func(t *testing.T) []byte {
+				return marshalEditedState(t, apitype.ResourceV3{
+					URN:          stateEditBucketURN,
+					Type:         "aws:s3/bucket:Bucket",
+					Dependencies: []resource.URN{stateEditStackURN},
+				})
+			},
+			expected: "refers to missing resource",
+		},
+		{
+			name: "missing property dependency",
+			edited: func(t *testing.T) []byte {
+				return marshalEditedState(t, apitype.ResourceV3{
+					URN:                  stateEditBucketURN,
+					Type:                 "aws:s3/bucket:Bucket",
+					PropertyDependencies: map[resource.PropertyKey][]resource.URN{"acl": {stateEditStackURN}},
+				})
+			},
+			expected: "property acl dependency",
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseEditedState(c.edited(t))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), c.expected)
+		})
+	}
+}
+
+func TestRenderStateDiff(t *testing.T) {
+	t.Parallel()
+
+	stack := &resource.State{URN: stateEditStackURN, Type: "pulumi:pulumi:Stack"}
+	bucket := &resource.State{
+		URN:     stateEditBucketURN,
+		Type:    "aws:s3/bucket:Bucket",
+		ID:      "b-1234",
+		Outputs: resource.PropertyMap{"bucket": resource.NewStringProperty("b-1234")},
+	}
+	protected := *bucket
+	protected.Protect = true
+	protected.Outputs = resource.PropertyMap{"bucket": resource.NewStringProperty("b-5678")}
+
+	// Unchanged resources are not shown.
+	assert.Empty(t, renderStateDiff([]*resource.State{stack, bucket}, []*resource.State{stack, bucket}))
+
+	// Changed fields and outputs are shown under the resource they belong to.
+	diff := renderStateDiff([]*resource.State{stack, bucket}, []*resource.State{stack, &protected})
+	assert.Contains(t, diff, stateEditBucketURN)
+	assert.NotContains(t, diff, stateEditStackURN+"\n")
+	assert.Contains(t, diff, "[protect: false => true]")
+	assert.Contains(t, diff, "5678")
+
+	// Removed and added resources are shown as deletes and creates.
+	diff = renderStateDiff([]*resource.State{stack, bucket}, []*resource.State{stack})
+	assert.Contains(t, diff, "- "+stateEditBucketURN)
+	diff = renderStateDiff([]*resource.State{stack}, []*resource.State{stack, bucket})
+	assert.Contains(t, diff, "+ "+stateEditBucketURN)
+}
+
+func TestPersistSnapshotFilestate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b, err := filestate.New(cmdutil.Diag(), "file://"+filepath.ToSlash(t.TempDir()))
+	require.NoError(t, err)
+	ref, err := b.ParseStackReference("dev")
+	require.NoError(t, err)
+	s, err := b.CreateStack(ctx, ref, nil)
+	require.NoError(t, err)
+
+	snap, err := parseEditedState(marshalEditedState(t,
+		apitype.ResourceV3{URN: stateEditStackURN, Type: "pulumi:pulumi:Stack"},
+		apitype.ResourceV3{URN: stateEditBucketURN, Type: "aws:s3/bucket:Bucket", Parent: stateEditStackURN}))
+	require.NoError(t, err)
+	require.NoError(t, persistSnapshot(s, snap))
+
+	s, err = b.GetStack(ctx, ref)
+	require.NoError(t, err)
+	saved, err := s.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Len(t, saved.Resources, 2)
+	assert.Equal(t, resource.URN(stateEditBucketURN), saved.Resources[1].URN)
+}
+
+func TestWithStackLockFilestate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b, err := filestate.New(cmdutil.Diag(), "file://"+filepath.ToSlash(t.TempDir()))
+	require.NoError(t, err)
+	ref, err := b.ParseStackReference("dev")
+	require.NoError(t, err)
+	s, err := b.CreateStack(ctx, ref, nil)
+	require.NoError(t, err)
+
+	snap, err := parseEditedState(marshalEditedState(t,
+		apitype.ResourceV3{URN: stateEditStackURN, Type: "pulumi:pulumi:Stack"}))
+	require.NoError(t, err)
+
+	// Saving while the lock is held doesn't try to take it again, and the saved state is read back even though the
+	// stack was loaded before it was saved.
+	res := withStackLock(s, func() result.Result {
+		return result.WrapIfNonNil(persistSnapshot(s, snap))
+	})
+	require.Nil(t, res)
+	saved, err := currentSnapshot(s)
+	require.NoError(t, err)
+	require.Len(t, saved.Resources, 1)
+}
@@ -0,0 +1,252 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/pkg/v3/version"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+
+	"github.com/spf13/cobra"
+)
+
+func newStateMoveCommand() *cobra.Command {
+	var sourceStackName string
+	var destStackName string
+	var destBackendURL string
+	var destProject string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "move <resource URN>...",
+		Short: "Moves resources from one stack's state to another's",
+		Long: `Moves resources from one stack's state to another's
+
+This command moves one or more resources, along with all of their children, from the state of a source stack into
+the state of a destination stack. The resources are specified by their Pulumi URNs (use ` +
+			"`pulumi stack --show-urns`" + ` to get them).
+
+The providers used by the moved resources are copied to the destination stack, unless it already has a provider
+with the same name. Dependencies on resources that are not moved are dropped, and resources whose parent is not
+moved become children of the destination stack's root stack resource, which is created if the destination stack
+has none yet. Secrets are re-encrypted using the destination stack's secrets provider. Resources can't be moved if
+there exist other resources that depend on them and that are not moved too, or if they have pending operations.
+
+The destination stack may belong to a different project, or, using --dest-backend, to a different backend.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state move --source dev --dest prod 'urn:pulumi:dev::demo::aws:s3/bucket:Bucket::logs'
+`,
+		Args: cmdutil.MinimumNArgs(1),
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			yes = yes || skipConfirmations()
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			if destStackName == "" {
+				return result.Error("a destination stack must be specified using --dest")
+			}
+
+			var urns []resource.URN
+			for _, arg := range args {
+				urn := resource.URN(arg)
+				if !urn.IsValid() {
+					return result.Errorf("%q is not a valid URN", arg)
+				}
+				urns = append(urns, urn)
+			}
+
+			source, err := requireStack(sourceStackName, false, opts, false /*setCurrent*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+			dest, err := requireDestinationStack(destBackendURL, destStackName, opts)
+			if err != nil {
+				return result.FromError(err)
+			}
+			if sameStack(source, dest) {
+				return result.Error("the source and destination stacks must be different")
+			}
+
+			// Hold the locks of both stacks while their states are read, edited and saved, so that no update or other
+			// edit can interleave with the move.
+			return withStackLock(source, func() result.Result {
+				return withStackLock(dest, func() result.Result {
+					return moveResources(source, dest, urns, destProject, yes, opts)
+				})
+			})
+		}),
+	}
+
+	cmd.PersistentFlags().StringVar(
+		&sourceStackName, "source", "",
+		"The name of the stack to move resources from. Defaults to the current stack")
+	cmd.PersistentFlags().StringVar(
+		&destStackName, "dest", "",
+		"The name of the stack to move resources to")
+	cmd.PersistentFlags().StringVar(
+		&destBackendURL, "dest-backend", "",
+		"The URL of the backend of the destination stack. Defaults to the current backend")
+	cmd.PersistentFlags().StringVar(
+		&destProject, "dest-project", "",
+		"The name of the project of the destination stack. Defaults to the project of its existing resources, "+
+			"or to the project of the source stack if it has none")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	return cmd
+}
+
+// moveResources moves the resources with the given URNs from the source stack to the destination stack. The caller
+// must hold the locks of both stacks.
+func moveResources(source, dest backend.Stack, urns []resource.URN, destProject string, yes bool,
+	opts display.Options) result.Result {
+	sourceSnap, err := currentSnapshot(source)
+	if err != nil {
+		return result.FromError(err)
+	}
+	if sourceSnap == nil {
+		return result.Errorf("the stack %s has no resources to move", source.Ref())
+	}
+	destSnap, err := currentSnapshot(dest)
+	if err != nil {
+		return result.FromError(err)
+	}
+	if destSnap == nil {
+		sm, err := getStackSecretsManager(dest)
+		if err != nil {
+			return result.FromError(fmt.Errorf("getting secrets manager: %w", err))
+		}
+		destSnap = deploy.NewSnapshot(deploy.Manifest{
+			Time:    time.Now(),
+			Version: version.Version,
+		}, sm, nil, nil)
+	}
+
+	if err := sourceSnap.VerifyIntegrity(); err != nil {
+		return result.FromError(fmt.Errorf("the state of %s is invalid: %w", source.Ref(), err))
+	}
+	if err := destSnap.VerifyIntegrity(); err != nil {
+		return result.FromError(fmt.Errorf("the state of %s is invalid: %w", dest.Ref(), err))
+	}
+
+	project := tokens.PackageName(destProject)
+	if project == "" {
+		project = snapshotProject(destSnap)
+	}
+	if project == "" {
+		project = snapshotProject(sourceSnap)
+	}
+
+	moved, err := edit.MoveResources(sourceSnap, destSnap, urns, dest.Ref().Name(), project)
+	if err != nil {
+		if e, ok := err.(edit.ResourceHasDependentsError); ok {
+			message := fmt.Sprintf("The resource %q can't be safely moved because the following resources "+
+				"depend on it:\n", e.Moved.URN)
+			for _, dependentResource := range e.Dependents {
+				depUrn := dependentResource.URN
+				message += fmt.Sprintf(" * %-15q (%s)\n", depUrn.Name(), depUrn)
+			}
+
+			message += "\nMove those resources as well, or remove their dependency on this one first."
+			return result.Error(message)
+		}
+		return result.FromError(err)
+	}
+
+	fmt.Printf("The following resources will be moved from %s to %s:\n", source.Ref(), dest.Ref())
+	for _, res := range moved {
+		fmt.Printf(" * %s\n", res.URN)
+	}
+	fmt.Println()
+
+	if !yes && cmdutil.Interactive() && !confirmStateEdit(opts) {
+		fmt.Println("confirmation declined")
+		return result.Bail()
+	}
+
+	contract.AssertNoErrorf(sourceSnap.VerifyIntegrity(), "state move produced an invalid source snapshot")
+	contract.AssertNoErrorf(destSnap.VerifyIntegrity(), "state move produced an invalid destination snapshot")
+
+	// Persist the destination first, so that a failure to persist the source can't lose any resources. If the
+	// source then can't be persisted, the destination is restored so that the resources aren't left in both.
+	destCheckpoint, err := dest.ExportDeployment(commandContext())
+	if err != nil {
+		return result.FromError(fmt.Errorf("exporting the state of %s: %w", dest.Ref(), err))
+	}
+	if err := persistSnapshot(dest, destSnap); err != nil {
+		return result.FromError(fmt.Errorf("saving the state of %s: %w", dest.Ref(), err))
+	}
+	if err := persistSnapshot(source, sourceSnap); err != nil {
+		if restoreErr := dest.ImportDeployment(commandContext(), destCheckpoint); restoreErr != nil {
+			return result.FromError(fmt.Errorf("saving the state of %s: %w; restoring the state of %s also "+
+				"failed, so the moved resources are now in both stacks: %v", source.Ref(), err, dest.Ref(), restoreErr))
+		}
+		return result.FromError(fmt.Errorf("saving the state of %s: %w", source.Ref(), err))
+	}
+
+	fmt.Println("Resources moved")
+	return nil
+}
+
+// requireDestinationStack returns the stack with the given name in the backend at the given URL, or in the current
+// backend if the URL is empty.
+func requireDestinationStack(backendURL, stackName string, opts display.Options) (backend.Stack, error) {
+	if backendURL == "" {
+		return requireStack(stackName, false, opts, false /*setCurrent*/)
+	}
+
+	b, err := backendForURL(backendURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	stackRef, err := b.ParseStackReference(stackName)
+	if err != nil {
+		return nil, err
+	}
+	s, err := b.GetStack(commandContext(), stackRef)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("no stack named '%s' found in %s", stackName, backendURL)
+	}
+	return s, nil
+}
+
+// sameStack returns true if the given stacks are the same stack of the same backend.
+func sameStack(a, b backend.Stack) bool {
+	return a.Backend().URL() == b.Backend().URL() && a.Ref().String() == b.Ref().String()
+}
+
+// snapshotProject returns the name of the project that the resources in the given snapshot belong to, or the empty
+// string if it has no resources.
+func snapshotProject(snap *deploy.Snapshot) tokens.PackageName {
+	if len(snap.Resources) == 0 {
+		return ""
+	}
+	return snap.Resources[0].URN.Project()
+}
@@ -0,0 +1,58 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+)
+
+func TestSameStack(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	url := "file://" + filepath.ToSlash(t.TempDir())
+	getStack := func(url, name string, create bool) backend.Stack {
+		b, err := filestate.New(cmdutil.Diag(), url)
+		require.NoError(t, err)
+		ref, err := b.ParseStackReference(name)
+		require.NoError(t, err)
+		if create {
+			s, err := b.CreateStack(ctx, ref, nil)
+			require.NoError(t, err)
+			return s
+		}
+		s, err := b.GetStack(ctx, ref)
+		require.NoError(t, err)
+		return s
+	}
+
+	dev := getStack(url, "dev", true)
+	prod := getStack(url, "prod", true)
+	other := getStack("file://"+filepath.ToSlash(t.TempDir()), "dev", true)
+
+	// A stack loaded through a second backend for the same URL, as with --dest-backend, is the same stack.
+	assert.True(t, sameStack(dev, getStack(url, "dev", false)))
+	assert.False(t, sameStack(dev, prod))
+	assert.False(t, sameStack(dev, other))
+}
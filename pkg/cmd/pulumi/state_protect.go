@@ -0,0 +1,122 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+
+	"github.com/spf13/cobra"
+)
+
+func newStateProtectCommand() *cobra.Command {
+	var protectAll bool
+	var types []string
+	var stack string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "protect [resource URN...]",
+		Short: "Protect resources in a stack's state",
+		Long: `Protect resources in a stack's state
+
+This command sets the 'protect' bit on one or more resources, preventing those resources from being deleted.
+
+Note that the next update of the stack will set the 'protect' bit according to the program again, so protection
+should also be added to the program if it is meant to be permanent.
+
+Resources can be selected by URN, by URN pattern using wildcards (*, **), by type using --type, or all at once using
+--all. When more than one resource may be selected, the affected resources are listed before the state is changed.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state protect --type 'aws:rds/instance:Instance' 'urn:pulumi:prod::demo::aws:s3/bucket:Bucket::*'
+`,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			yes = yes || skipConfirmations()
+			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
+			showPrompt := !yes
+
+			if protectAll {
+				return protectAllResources(stack, showPrompt)
+			}
+
+			if len(args) == 0 && len(types) == 0 {
+				return result.Error("must provide a URN corresponding to a resource")
+			}
+
+			if len(args) == 1 && len(types) == 0 && !deploy.IsTargetSelector(resource.URN(args[0])) {
+				urn := resource.URN(args[0])
+				return protectResource(stack, urn, showPrompt)
+			}
+
+			res := runSelectedStateEdit(stack, showPrompt, stateEditTargets(args, types), edit.ProtectResource)
+			if res != nil {
+				return res
+			}
+			fmt.Println("Resources protected")
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().BoolVar(&protectAll, "all", false, "Protect all resources in the checkpoint")
+	cmd.Flags().StringArrayVar(&types, "type", []string{},
+		"Protect all resources of the given type. Wildcards (*, **) are also supported")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+
+	return cmd
+}
+
+func protectAllResources(stackName string, showPrompt bool) result.Result {
+	res := runTotalStateEdit(stackName, showPrompt, func(_ display.Options, snap *deploy.Snapshot) error {
+		// Protects against Panic when a user tries to protect non-existing resources
+		if snap == nil {
+			return fmt.Errorf("no resources found to protect")
+		}
+
+		for _, res := range snap.Resources {
+			err := edit.ProtectResource(snap, res)
+			contract.AssertNoError(err)
+		}
+
+		return nil
+	})
+
+	if res != nil {
+		return res
+	}
+	fmt.Println("All resources protected")
+	return nil
+}
+
+func protectResource(stackName string, urn resource.URN, showPrompt bool) result.Result {
+	res := runStateEdit(stackName, showPrompt, urn, edit.ProtectResource)
+	if res != nil {
+		return res
+	}
+	fmt.Println("Resource protected")
+	return nil
+}
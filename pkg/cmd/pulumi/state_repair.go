@@ -0,0 +1,138 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v3/backend"
+	"github.com/pulumi/pulumi/pkg/v3/backend/display"
+	"github.com/pulumi/pulumi/pkg/v3/backend/filestate"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/edit"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+)
+
+func newStateRepairCommand() *cobra.Command {
+	var stackName string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Repair an invalid stack state",
+		Long: `Repair an invalid stack state
+
+This command checks the current stack's state for the problems that make it invalid, such as resources that refer to
+missing parents, dependencies or providers, and proposes a fix for each of them:
+
+ - dependencies on missing resources are dropped;
+ - resources with a missing parent become children of the stack;
+ - references to unknown providers are replaced with a provider of the same name, or removed;
+ - duplicate resources are removed from the state, keeping the first of them;
+ - pending operations on missing resources are removed;
+ - resources are reordered so that they follow their parents, dependencies and providers.
+
+The fixes are applied once they are confirmed. Problems that can't be repaired automatically can be fixed using
+` + "`pulumi state edit`" + `.`,
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			yes = yes || skipConfirmations()
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			var s backend.Stack
+			err := withoutIntegrityChecking(func() (err error) {
+				s, err = requireStack(stackName, false, opts, false /*setCurrent*/)
+				return err
+			})
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			return withStackLock(s, func() result.Result {
+				var snap *deploy.Snapshot
+				err := withoutIntegrityChecking(func() (err error) {
+					snap, err = currentSnapshot(s)
+					return err
+				})
+				if err != nil {
+					return result.FromError(err)
+				}
+				if snap == nil {
+					fmt.Println("The stack has no state to repair")
+					return nil
+				}
+
+				repairs := edit.ProposeRepairs(snap)
+				if len(repairs) == 0 {
+					if err := snap.VerifyIntegrity(); err != nil {
+						return result.Errorf("the state can't be repaired automatically: %v\n"+
+							"Use `pulumi state edit` to fix it", err)
+					}
+					fmt.Println("No problems were found in the state")
+					return nil
+				}
+
+				fmt.Println(opts.Color.Colorize(colors.SpecHeadline + "Problems:" + colors.Reset))
+				for _, r := range repairs {
+					fmt.Printf(" * %s\n", r.Problem)
+					fmt.Println(opts.Color.Colorize(fmt.Sprintf("   %sfix: %s%s", colors.SpecInfo, r.Fix, colors.Reset)))
+				}
+				fmt.Println()
+
+				if !yes && cmdutil.Interactive() && !confirmStateEdit(opts) {
+					fmt.Println("confirmation declined")
+					return result.Bail()
+				}
+
+				for _, r := range repairs {
+					r.Apply()
+				}
+				if err := snap.VerifyIntegrity(); err != nil {
+					return result.Errorf("the state is still invalid after repair, so no changes were saved: %v\n"+
+						"Use `pulumi state edit` to fix it", err)
+				}
+
+				if err := persistSnapshot(s, snap); err != nil {
+					return result.FromError(err)
+				}
+				fmt.Printf("Repaired %d problem(s)\n", len(repairs))
+				return nil
+			})
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
+	return cmd
+}
+
+// withoutIntegrityChecking calls f with state integrity checking disabled. The self-managed backends refuse to load
+// invalid state unless integrity checking is disabled, so it is disabled while the stack to repair is loaded, but not
+// while the repaired snapshot is saved.
+func withoutIntegrityChecking(f func() error) error {
+	disabled := filestate.DisableIntegrityChecking
+	filestate.DisableIntegrityChecking = true
+	defer func() { filestate.DisableIntegrityChecking = disabled }()
+
+	return f()
+}
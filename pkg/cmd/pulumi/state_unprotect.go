@@ -30,16 +30,25 @@ import (
 
 func newStateUnprotectCommand() *cobra.Command {
 	var unprotectAll bool
+	var types []string
 	var stack string
 	var yes bool
 
 	cmd := &cobra.Command{
-		Use:   "unprotect <resource URN>",
+		Use:   "unprotect [resource URN...]",
 		Short: "Unprotect resources in a stack's state",
 		Long: `Unprotect resource in a stack's state
 
-This command clears the 'protect' bit on one or more resources, allowing those resources to be deleted.`,
-		Args: cmdutil.MaximumNArgs(1),
+This command clears the 'protect' bit on one or more resources, allowing those resources to be deleted.
+
+Resources can be selected by URN, by URN pattern using wildcards (*, **), by type using --type, or all at once using
+--all. When more than one resource may be selected, the affected resources are listed before the state is changed.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state unprotect --type 'aws:rds/instance:Instance' 'urn:pulumi:prod::demo::aws:s3/bucket:Bucket::*'
+`,
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
 			yes = yes || skipConfirmations()
 			// Show the confirmation prompt if the user didn't pass the --yes parameter to skip it.
@@ -49,12 +58,21 @@ This command clears the 'protect' bit on one or more resources, allowing those r
 				return unprotectAllResources(stack, showPrompt)
 			}
 
-			if len(args) != 1 {
+			if len(args) == 0 && len(types) == 0 {
 				return result.Error("must provide a URN corresponding to a resource")
 			}
 
-			urn := resource.URN(args[0])
-			return unprotectResource(stack, urn, showPrompt)
+			if len(args) == 1 && len(types) == 0 && !deploy.IsTargetSelector(resource.URN(args[0])) {
+				urn := resource.URN(args[0])
+				return unprotectResource(stack, urn, showPrompt)
+			}
+
+			res := runSelectedStateEdit(stack, showPrompt, stateEditTargets(args, types), edit.UnprotectResource)
+			if res != nil {
+				return res
+			}
+			fmt.Println("Resources unprotected")
+			return nil
 		}),
 	}
 
@@ -62,6 +80,8 @@ This command clears the 'protect' bit on one or more resources, allowing those r
 		&stack, "stack", "s", "",
 		"The name of the stack to operate on. Defaults to the current stack")
 	cmd.Flags().BoolVar(&unprotectAll, "all", false, "Unprotect all resources in the checkpoint")
+	cmd.Flags().StringArrayVar(&types, "type", []string{},
+		"Unprotect all resources of the given type. Wildcards (*, **) are also supported")
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompts")
 
 	return cmd
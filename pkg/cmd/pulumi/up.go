@@ -29,7 +29,6 @@ import (
 	"github.com/pulumi/pulumi/pkg/v3/engine"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
-	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
@@ -57,6 +56,7 @@ func newUpCmd() *cobra.Command {
 
 	// Flags for engine.UpdateOptions.
 	var jsonDisplay bool
+	var outputFormat string
 	var policyPackPaths []string
 	var policyPackConfigPaths []string
 	var diffDisplay bool
@@ -76,7 +76,10 @@ func newUpCmd() *cobra.Command {
 	var replaces []string
 	var targetReplaces []string
 	var targetDependents bool
+	var excludes []string
+	var excludeDependents bool
 	var planFilePath string
+	var continueOnError bool
 
 	// up implementation used when the source of the Pulumi program is in the current working directory.
 	upWorkingDirectory := func(opts backend.UpdateOptions) result.Result {
@@ -105,40 +108,16 @@ func newUpCmd() *cobra.Command {
 			return result.FromError(fmt.Errorf("getting secrets manager: %w", err))
 		}
 
-		cfg, err := getStackConfiguration(s, sm)
+		cfg, err := getStackConfiguration(s, proj, sm, true /*validate*/)
 		if err != nil {
 			return result.FromError(fmt.Errorf("getting stack configuration: %w", err))
 		}
 
-		targetURNs := []resource.URN{}
-		snap, err := s.Snapshot(commandContext())
-		if err != nil {
-			return result.FromError(err)
-		}
-		for _, t := range targets {
-			targetURNs = append(targetURNs, snap.GlobUrn(resource.URN(t))...)
-		}
-
-		replaceURNs := []resource.URN{}
-		for _, r := range replaces {
-			replaceURNs = append(replaceURNs, snap.GlobUrn(resource.URN(r))...)
-		}
-
-		for _, tr := range targetReplaces {
-			targetURNs = append(targetURNs, snap.GlobUrn(resource.URN(tr))...)
-			replaceURNs = append(replaceURNs, snap.GlobUrn(resource.URN(tr))...)
-		}
-
-		if len(targetURNs) == 0 && len(targets)+len(targetReplaces) > 0 {
-			// Wildcards were used, but they all evaluated to empty. We don't
-			// want a targeted update to turn into a general update, so we
-			// should abort.
-			if !jsonDisplay {
-				fmt.Printf("There were no resources matching the wildcards provided.\n")
-				fmt.Printf("Wildcards can only be used to target resources that already exist.\n")
-			}
-			return nil
-		}
+		// Targets are resolved by the engine, since selectors that match no existing resource may still match
+		// resources that the program registers.
+		targetURNs := toURNs(append(targets, targetReplaces...))
+		replaceURNs := append(toURNs(replaces), toURNs(targetReplaces)...)
+		excludeURNs := toURNs(excludes)
 
 		refreshOption, err := getRefreshOption(proj, refresh)
 		if err != nil {
@@ -157,7 +136,10 @@ func newUpCmd() *cobra.Command {
 			DisableOutputValues:       disableOutputValues(),
 			UpdateTargets:             targetURNs,
 			TargetDependents:          targetDependents,
+			ExcludeTargets:            excludeURNs,
+			ExcludeDependents:         excludeDependents,
 			ExperimentalPlans:         hasExperimentalCommands() || planFilePath != "",
+			ContinueOnError:           continueOnError,
 		}
 
 		if planFilePath != "" {
@@ -330,7 +312,7 @@ func newUpCmd() *cobra.Command {
 			return result.FromError(fmt.Errorf("getting secrets manager: %w", err))
 		}
 
-		cfg, err := getStackConfiguration(s, sm)
+		cfg, err := getStackConfiguration(s, proj, sm, true /*validate*/)
 		if err != nil {
 			return result.FromError(fmt.Errorf("getting stack configuration: %w", err))
 		}
@@ -346,6 +328,7 @@ func newUpCmd() *cobra.Command {
 			Debug:             debug,
 			Refresh:           refreshOption,
 			ExperimentalPlans: hasExperimentalCommands() || planFilePath != "",
+			ContinueOnError:   continueOnError,
 		}
 
 		// TODO for the URL case:
@@ -405,6 +388,14 @@ func newUpCmd() *cobra.Command {
 				return result.FromError(err)
 			}
 
+			reportFormat, err := parseOutputFormat(outputFormat, jsonDisplay)
+			if err != nil {
+				return result.FromError(err)
+			}
+			if reportFormat != "" && !yes {
+				return result.FromError(errors.New("--yes or --skip-preview must be passed in with --output-format"))
+			}
+
 			if err = validatePolicyPackConfig(policyPackPaths, policyPackConfigPaths); err != nil {
 				return result.FromError(err)
 			}
@@ -426,6 +417,7 @@ func newUpCmd() *cobra.Command {
 				EventLogPath:         eventLogPath,
 				Debug:                debug,
 				JSONDisplay:          jsonDisplay,
+				ReportFormat:         reportFormat,
 			}
 
 			// we only suppress permalinks if the user passes true. the default is an empty string
@@ -475,7 +467,7 @@ func newUpCmd() *cobra.Command {
 		"Config keys contain a path to a property in a map or list to set")
 	cmd.PersistentFlags().StringVar(
 		&secretsProvider, "secrets-provider", "default", "The type of the provider that should be used to encrypt and "+
-			"decrypt secrets (possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault). Only"+
+			"decrypt secrets (possible choices: default, passphrase, age, awskms, azurekeyvault, gcpkms, hashivault). Only"+
 			"used when creating a new stack from an existing template")
 
 	cmd.PersistentFlags().StringVar(
@@ -490,11 +482,11 @@ func newUpCmd() *cobra.Command {
 		&targets, "target", "t", []string{},
 		"Specify a single resource URN to update. Other resources will not be updated."+
 			" Multiple resources can be specified using --target urn1 --target urn2."+
-			" Wildcards (*, **) are also supported")
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
 	cmd.PersistentFlags().StringArrayVar(
 		&replaces, "replace", []string{},
 		"Specify resources to replace. Multiple resources can be specified using --replace urn1 --replace urn2."+
-			" Wildcards (*, **) are also supported")
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
 	cmd.PersistentFlags().StringArrayVar(
 		&targetReplaces, "target-replace", []string{},
 		"Specify a single resource URN to replace. Other resources will not be updated."+
@@ -502,6 +494,14 @@ func newUpCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(
 		&targetDependents, "target-dependents", false,
 		"Allows updating of dependent targets discovered but not specified in --target list")
+	cmd.PersistentFlags().StringArrayVar(
+		&excludes, "exclude", []string{},
+		"Specify a resource URN to ignore. These resources will not be updated."+
+			" Multiple resources can be specified using --exclude urn1 --exclude urn2."+
+			" Wildcards (*, **), type:<type> and children-of:<urn> selectors are also supported")
+	cmd.PersistentFlags().BoolVar(
+		&excludeDependents, "exclude-dependents", false,
+		"Allows ignoring of dependent targets discovered but not specified in --exclude list")
 
 	// Flags for engine.UpdateOptions.
 	cmd.PersistentFlags().StringSliceVar(
@@ -516,6 +516,8 @@ func newUpCmd() *cobra.Command {
 	cmd.Flags().BoolVarP(
 		&jsonDisplay, "json", "j", false,
 		"Serialize the update diffs, operations, and overall output as JSON")
+	cmd.Flags().StringVar(
+		&outputFormat, "output-format", "", outputFormatUsage)
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
@@ -537,6 +539,10 @@ func newUpCmd() *cobra.Command {
 		&showReads, "show-reads", false,
 		"Show resources that are being read in, alongside those being managed directly in the stack")
 
+	cmd.PersistentFlags().BoolVar(
+		&continueOnError, "continue-on-error", false,
+		"Continue updating resources that do not depend on a failed resource instead of stopping at the first failure")
+
 	cmd.PersistentFlags().BoolVarP(
 		&skipPreview, "skip-preview", "f", false,
 		"Do not perform a preview before performing the update")
@@ -44,12 +44,14 @@ import (
 	"github.com/pulumi/pulumi/pkg/v3/engine"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/v3/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/age"
 	"github.com/pulumi/pulumi/pkg/v3/secrets/passphrase"
 	"github.com/pulumi/pulumi/pkg/v3/util/cancel"
 	"github.com/pulumi/pulumi/pkg/v3/util/tracing"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/constant"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/ciutil"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
@@ -119,6 +121,11 @@ func currentBackend(opts display.Options) (backend.Backend, error) {
 		return nil, fmt.Errorf("could not get cloud url: %w", err)
 	}
 
+	return backendForURL(url, opts)
+}
+
+// backendForURL returns the backend at the given URL, which may be a filestate URL or the URL of a Pulumi service.
+func backendForURL(url string, opts display.Options) (backend.Backend, error) {
 	if filestate.IsFileStateBackendURL(url) {
 		return filestate.New(cmdutil.Diag(), url)
 	}
@@ -179,6 +186,10 @@ func createSecretsManager(b backend.Backend, stackRef backend.StackReference, se
 			rotatePassphraseSecretsProvider); pharseErr != nil {
 			return pharseErr
 		}
+	} else if age.IsSecretsProvider(secretsProvider) {
+		if _, ageErr := newAgeSecretsManager(stackRef.Name(), stackConfigFile, secretsProvider); ageErr != nil {
+			return ageErr
+		}
 	} else if !isDefaultSecretsProvider {
 		// All other non-default secrets providers are handled by the cloud secrets provider which
 		// uses a URL schema to identify the provider
@@ -834,6 +845,22 @@ func updateFlagsToOptions(interactive, skipPreview, yes bool) (backend.UpdateOpt
 	}, nil
 }
 
+// outputFormatUsage is the usage of the --output-format flag of the preview, up and destroy commands.
+const outputFormatUsage = "Emit a report in the given format instead of displaying the operation's progress: " +
+	"json (a stable, versioned schema), markdown (a change summary for pull request comments) or junit"
+
+// parseOutputFormat validates the --output-format flag, returning the format of the report to emit instead of the
+// display, if any.
+func parseOutputFormat(outputFormat string, jsonDisplay bool) (display.ReportFormat, error) {
+	if outputFormat == "" {
+		return "", nil
+	}
+	if jsonDisplay {
+		return "", errors.New("only one of --json or --output-format may be specified")
+	}
+	return display.ParseReportFormat(outputFormat)
+}
+
 func checkDeploymentVersionError(err error, stackName string) error {
 	switch err {
 	case stack.ErrDeploymentSchemaVersionTooOld:
@@ -846,6 +873,48 @@ func checkDeploymentVersionError(err error, stackName string) error {
 	return fmt.Errorf("could not deserialize deployment: %w", err)
 }
 
+// parseTargetURNs converts the given target arguments into URNs. An argument may be a URN, which may contain
+// wildcards, or a `type:` or `children-of:` selector; the engine resolves these against both the stack's current
+// snapshot and the resources registered by the program.
+//
+// If every argument is a selector and none of them matches a resource in the given snapshot, false is returned: the
+// targets can then only match resources that the program registers, so an operation that does not run the program
+// has nothing to target, and should stop after printing noMatchingTargetsMessage.
+func parseTargetURNs(snap *deploy.Snapshot, targets []string) ([]resource.URN, bool) {
+	urns := toURNs(targets)
+	if len(urns) == 0 {
+		return urns, true
+	}
+
+	for _, urn := range urns {
+		if !deploy.IsTargetSelector(urn) {
+			return urns, true
+		}
+	}
+	if snap != nil && len(snap.SelectResources(urns)) > 0 {
+		return urns, true
+	}
+	return urns, false
+}
+
+// noMatchingTargetsMessage is printed by operations that stop because none of their targets match an existing
+// resource.
+const noMatchingTargetsMessage = "There were no resources matching the wildcards provided.\n" +
+	"Wildcards can only be used to target resources that already exist or that the program registers."
+
+// toURNs converts the given target arguments into URNs without resolving them.
+func toURNs(targets []string) []resource.URN {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	urns := make([]resource.URN, len(targets))
+	for i, t := range targets {
+		urns[i] = resource.URN(t)
+	}
+	return urns
+}
+
 func getRefreshOption(proj *workspace.Project, refresh string) (bool, error) {
 	// we want to check for an explicit --refresh or a --refresh=true or --refresh=false
 	// refresh is assigned the empty string by default to distinguish the difference between
@@ -221,6 +221,7 @@ type runActions interface {
 
 	Changes() display.ResourceChanges
 	MaybeCorrupt() bool
+	StepTimings() []StepTiming
 }
 
 // run executes the deployment. It is primarily responsible for handling cancellation.
@@ -262,11 +263,14 @@ func (deployment *deployment) run(cancelCtx *Context, actions runActions, policy
 			DestroyTargets:            deployment.Options.DestroyTargets,
 			UpdateTargets:             deployment.Options.UpdateTargets,
 			TargetDependents:          deployment.Options.TargetDependents,
+			ExcludeTargets:            deployment.Options.ExcludeTargets,
+			ExcludeDependents:         deployment.Options.ExcludeDependents,
 			TrustDependencies:         deployment.Options.trustDependencies,
 			UseLegacyDiff:             deployment.Options.UseLegacyDiff,
 			DisableResourceReferences: deployment.Options.DisableResourceReferences,
 			DisableOutputValues:       deployment.Options.DisableOutputValues,
 			ExperimentalPlans:         deployment.Options.UpdateOptions.ExperimentalPlans,
+			ContinueOnError:           deployment.Options.UpdateOptions.ContinueOnError,
 		}
 		newPlan, walkResult = deployment.Deployment.Execute(ctx, opts, preview)
 		close(done)
@@ -297,7 +301,8 @@ func (deployment *deployment) run(cancelCtx *Context, actions runActions, policy
 	changes := actions.Changes()
 
 	// Emit a summary event.
-	deployment.Options.Events.summaryEvent(preview, actions.MaybeCorrupt(), duration, changes, policyPacks)
+	deployment.Options.Events.summaryEvent(preview, actions.MaybeCorrupt(), duration, changes, policyPacks,
+		actions.StepTimings())
 
 	return newPlan, changes, res
 }
@@ -132,16 +132,33 @@ type SummaryEventPayload struct {
 	Duration        time.Duration           // the duration of the entire update operation (zero values for previews)
 	ResourceChanges display.ResourceChanges // count of changed resources, useful for reporting
 	PolicyPacks     map[string]string       // {policy-pack: version} for each policy pack applied
+	StepTimings     []StepTiming            // the timings of the steps that changed resources, in order of completion
+}
+
+// StepTiming records when the engine started and finished performing a step.
+type StepTiming struct {
+	URN    resource.URN   // the URN of the resource the step was performed on.
+	Op     display.StepOp // the operation the step performed.
+	Start  time.Time      // when the step started.
+	End    time.Time      // when the step finished.
+	Failed bool           // true if the step failed.
+}
+
+// Duration returns how long the step took.
+func (t StepTiming) Duration() time.Duration {
+	return t.End.Sub(t.Start)
 }
 
 type ResourceOperationFailedPayload struct {
 	Metadata StepEventMetadata
 	Status   resource.Status
 	Steps    int
+	Timing   *StepTiming // when the failed step started and finished.
 }
 
 type ResourceOutputsEventPayload struct {
 	Metadata StepEventMetadata
+	Timing   *StepTiming // when the step that produced the outputs started and finished, if it changed resources.
 	Planning bool
 	Debug    bool
 }
@@ -350,7 +367,7 @@ func (e *eventEmitter) Close() {
 }
 
 func (e *eventEmitter) resourceOperationFailedEvent(
-	step deploy.Step, status resource.Status, steps int, debug bool) {
+	step deploy.Step, status resource.Status, steps int, timing *StepTiming, debug bool) {
 
 	contract.Requiref(e != nil, "e", "!= nil")
 
@@ -358,14 +375,18 @@ func (e *eventEmitter) resourceOperationFailedEvent(
 		Metadata: makeStepEventMetadata(step.Op(), step, debug),
 		Status:   status,
 		Steps:    steps,
+		Timing:   timing,
 	})
 }
 
-func (e *eventEmitter) resourceOutputsEvent(op display.StepOp, step deploy.Step, planning bool, debug bool) {
+func (e *eventEmitter) resourceOutputsEvent(op display.StepOp, step deploy.Step, timing *StepTiming,
+	planning bool, debug bool) {
+
 	contract.Requiref(e != nil, "e", "!= nil")
 
 	e.ch <- NewEvent(ResourceOutputsEvent, ResourceOutputsEventPayload{
 		Metadata: makeStepEventMetadata(op, step, debug),
+		Timing:   timing,
 		Planning: planning,
 		Debug:    debug,
 	})
@@ -401,7 +422,7 @@ func (e *eventEmitter) preludeEvent(isPreview bool, cfg config.Map) {
 }
 
 func (e *eventEmitter) summaryEvent(preview, maybeCorrupt bool, duration time.Duration,
-	resourceChanges display.ResourceChanges, policyPacks map[string]string) {
+	resourceChanges display.ResourceChanges, policyPacks map[string]string, stepTimings []StepTiming) {
 
 	contract.Requiref(e != nil, "e", "!= nil")
 
@@ -411,6 +432,7 @@ func (e *eventEmitter) summaryEvent(preview, maybeCorrupt bool, duration time.Du
 		Duration:        duration,
 		ResourceChanges: resourceChanges,
 		PolicyPacks:     policyPacks,
+		StepTimings:     stepTimings,
 	})
 }
 
@@ -5248,3 +5248,200 @@ func TestDefaultParents(t *testing.T) {
 	assert.Equal(t, snap.Resources[0].URN, snap.Resources[1].Parent)
 	assert.Equal(t, snap.Resources[0].URN, snap.Resources[2].Parent)
 }
+
+func TestContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	var createdLock sync.Mutex
+	created := map[string]bool{}
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN, news resource.PropertyMap, timeout float64,
+					preview bool) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					if urn.Name() == "resA" {
+						return "", nil, resource.StatusOK, errors.New("create failed")
+					}
+
+					createdLock.Lock()
+					defer createdLock.Unlock()
+					created[string(urn.Name())] = true
+					return resource.ID(urn.Name()), news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		urnA, _, _, errA := monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		assert.Error(t, errA)
+
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resB", true)
+		assert.NoError(t, err)
+
+		// Like the language SDKs, the program does not register resources whose dependencies failed.
+		if errA == nil {
+			_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resC", true, deploytest.ResourceOptions{
+				Dependencies: []resource.URN{urnA},
+			})
+			assert.NoError(t, err)
+		}
+		return errA
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host, ContinueOnError: true},
+	}
+
+	project := p.GetProject()
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	assert.NotNil(t, res)
+	assert.True(t, created["resB"])
+	assert.False(t, created["resC"])
+
+	assert.NotNil(t, snap)
+	var names []string
+	for _, r := range snap.Resources {
+		names = append(names, string(r.URN.Name()))
+	}
+	assert.Contains(t, names, "resB")
+	assert.NotContains(t, names, "resA")
+}
+
+func TestContinueOnErrorSkipsDeletesOfDependencies(t *testing.T) {
+	t.Parallel()
+
+	var deletedLock sync.Mutex
+	deleted := map[string]bool{}
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap,
+					timeout float64) (resource.Status, error) {
+
+					if urn.Name() == "resB" {
+						return resource.StatusOK, errors.New("delete failed")
+					}
+
+					deletedLock.Lock()
+					defer deletedLock.Unlock()
+					deleted[string(urn.Name())] = true
+					return resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	createResources := true
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		if !createResources {
+			return nil
+		}
+
+		urnA, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		assert.NoError(t, err)
+
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, deploytest.ResourceOptions{
+			Dependencies: []resource.URN{urnA},
+		})
+		assert.NoError(t, err)
+
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resC", true)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host, ContinueOnError: true},
+	}
+
+	project := p.GetProject()
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	assert.Nil(t, res)
+	assert.Len(t, snap.Resources, 4)
+
+	// Remove every resource. The delete of resB fails, so resA, which resB depends on, must be left alone while the
+	// independent resC is still deleted.
+	createResources = false
+	snap, res = TestOp(Update).Run(project, p.GetTarget(t, snap), p.Options, false, p.BackendClient, nil)
+	assert.NotNil(t, res)
+	assert.True(t, deleted["resC"])
+	assert.False(t, deleted["resA"])
+
+	var names []string
+	for _, r := range snap.Resources {
+		names = append(names, string(r.URN.Name()))
+	}
+	assert.Contains(t, names, "resA")
+	assert.Contains(t, names, "resB")
+	assert.NotContains(t, names, "resC")
+}
+
+func TestContinueOnErrorKeepsResourcesThatFailStepGeneration(t *testing.T) {
+	t.Parallel()
+
+	var deletedLock sync.Mutex
+	deleted := map[string]bool{}
+
+	failCheck := false
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CheckF: func(urn resource.URN, olds, news resource.PropertyMap,
+					sequenceNumber int) (resource.PropertyMap, []plugin.CheckFailure, error) {
+
+					if failCheck && urn.Name() == "resA" {
+						return nil, []plugin.CheckFailure{{Property: "foo", Reason: "invalid"}}, nil
+					}
+					return news, nil, nil
+				},
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap,
+					timeout float64) (resource.Status, error) {
+
+					deletedLock.Lock()
+					defer deletedLock.Unlock()
+					deleted[string(urn.Name())] = true
+					return resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		// The program ignores the failure to register resA and runs to completion, so the engine must not mistake
+		// resA for a resource that was removed from the program.
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true)
+		assert.Equal(t, failCheck, err != nil)
+
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Host: host, ContinueOnError: true},
+	}
+
+	project := p.GetProject()
+	snap, res := TestOp(Update).Run(project, p.GetTarget(t, nil), p.Options, false, p.BackendClient, nil)
+	assert.Nil(t, res)
+	assert.Len(t, snap.Resources, 3)
+
+	failCheck = true
+	snap, res = TestOp(Update).Run(project, p.GetTarget(t, snap), p.Options, false, p.BackendClient, nil)
+	assert.NotNil(t, res)
+	assert.False(t, deleted["resA"])
+
+	var names []string
+	for _, r := range snap.Resources {
+		names = append(names, string(r.URN.Name()))
+	}
+	assert.Contains(t, names, "resA")
+	assert.Contains(t, names, "resB")
+}
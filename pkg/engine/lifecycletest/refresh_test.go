@@ -2,9 +2,11 @@ package lifecycletest
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/blang/semver"
@@ -916,3 +918,93 @@ func TestRefreshUpdateWithDeletedResource(t *testing.T) {
 	snap := p.Run(t, old)
 	assert.Equal(t, 0, len(snap.Resources))
 }
+
+// Tests that a refresh that does not continue on error still refreshes resources whose parent failed to refresh.
+func TestRefreshDoesNotSkipDependentsOfFailures(t *testing.T) {
+	t.Parallel()
+
+	testRefreshDoesNotSkipDependentsOfFailures(t, false /*continueOnError*/)
+}
+
+// Tests that a refresh that continues on error never skips resources whose parent failed to refresh.
+func TestRefreshContinueOnErrorDoesNotSkipDependentsOfFailures(t *testing.T) {
+	t.Parallel()
+
+	testRefreshDoesNotSkipDependentsOfFailures(t, true /*continueOnError*/)
+}
+
+func testRefreshDoesNotSkipDependentsOfFailures(t *testing.T, continueOnError bool) {
+	p := &TestPlan{}
+
+	urnA := p.NewURN("pkgA:m:typA", "resA", "")
+	urnB := p.NewURN("pkgA:m:typA", "resB", "")
+
+	var readLock sync.Mutex
+	read := map[resource.URN]bool{}
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				ReadF: func(
+					urn resource.URN, id resource.ID, inputs, state resource.PropertyMap,
+				) (plugin.ReadResult, resource.Status, error) {
+					readLock.Lock()
+					read[urn] = true
+					readLock.Unlock()
+
+					if urn == urnA {
+						return plugin.ReadResult{}, resource.StatusUnknown, errors.New("read failed")
+					}
+					return plugin.ReadResult{Outputs: resource.PropertyMap{}}, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		return nil
+	})
+	p.Options.Host = deploytest.NewPluginHost(nil, nil, program, loaders...)
+	// A single worker refreshes resA before resB, so resA has always failed by the time resB is refreshed.
+	p.Options.Parallel = 1
+	p.Options.ContinueOnError = continueOnError
+
+	old := &deploy.Snapshot{
+		Resources: []*resource.State{
+			{
+				Type:    urnA.Type(),
+				URN:     urnA,
+				Custom:  true,
+				ID:      "0",
+				Inputs:  resource.PropertyMap{},
+				Outputs: resource.PropertyMap{},
+			},
+			{
+				Type:    urnB.Type(),
+				URN:     urnB,
+				Custom:  true,
+				ID:      "1",
+				Parent:  urnA,
+				Inputs:  resource.PropertyMap{},
+				Outputs: resource.PropertyMap{},
+			},
+		},
+	}
+
+	validate := func(project workspace.Project, target deploy.Target, entries JournalEntries,
+		events []Event, res result.Result) result.Result {
+
+		for _, evt := range events {
+			if evt.Type == DiagEvent {
+				e := evt.Payload().(DiagEventPayload)
+				assert.NotContains(t, e.Message, "skipped")
+			}
+		}
+		return res
+	}
+
+	_, res := TestOp(Refresh).Run(p.GetProject(), p.GetTarget(t, old), p.Options, false, p.BackendClient, validate)
+	assert.NotNil(t, res)
+	assert.True(t, read[urnA])
+	assert.True(t, read[urnB])
+}
@@ -10,6 +10,7 @@ import (
 	. "github.com/pulumi/pulumi/pkg/v3/engine"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
@@ -886,3 +887,275 @@ func newResource(urn, parent resource.URN, id resource.ID, provider string, depe
 		Parent:               parent,
 	}
 }
+
+func TestUpdateExclude(t *testing.T) {
+	t.Parallel()
+
+	//             A
+	//    _________|_________
+	//    B        C        D
+	//          ___|___  ___|___
+	//          E  F  G  H  I  J
+	//             |__|
+	//             K  L
+	//
+	// The program only registers the provider relationships of this graph (B on A, E on C and H on D), so those are
+	// the only dependents that are excluded along with their dependencies.
+	updateExcludes(t, []string{"C"}, false /*excludeDependents*/, []string{"C"})
+	updateExcludes(t, []string{"C"}, true /*excludeDependents*/, []string{"C", "E"})
+	updateExcludes(t, []string{"A", "D"}, true /*excludeDependents*/, []string{"A", "B", "D", "H"})
+}
+
+func updateExcludes(t *testing.T, excludes []string, excludeDependents bool, expectedSames []string) {
+	p := &TestPlan{}
+
+	urns, old, program := generateComplexTestDependencyGraph(t, p)
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap,
+					ignoreChanges []string) (plugin.DiffResult, error) {
+
+					// all resources will change.
+					return plugin.DiffResult{
+						Changes: plugin.DiffSome,
+					}, nil
+				},
+
+				UpdateF: func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap, timeout float64,
+					ignoreChanges []string, preview bool) (resource.PropertyMap, resource.Status, error) {
+
+					outputs := olds.Copy()
+
+					outputs["output_prop"] = resource.NewPropertyValue(42)
+					return outputs, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	p.Options.Host = deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p.Options.ExcludeDependents = excludeDependents
+	for _, exclude := range excludes {
+		p.Options.ExcludeTargets = append(p.Options.ExcludeTargets,
+			pickURN(t, urns, complexTestDependencyGraphNames, exclude))
+	}
+	t.Logf("Excluding: %v", p.Options.ExcludeTargets)
+
+	p.Steps = []TestStep{{
+		Op: Update,
+		Validate: func(project workspace.Project, target deploy.Target, entries JournalEntries,
+			evts []Event, res result.Result) result.Result {
+
+			assert.Nil(t, res)
+
+			sames := make(map[string]bool)
+			for _, entry := range entries {
+				switch entry.Step.Op() {
+				case deploy.OpSame:
+					sames[entry.Step.URN().Name().String()] = true
+				case deploy.OpUpdate:
+					assert.NotContains(t, expectedSames, entry.Step.URN().Name().String())
+				default:
+					assert.FailNowf(t, "", "Got a step that wasn't a same/update: %v", entry.Step.Op())
+				}
+			}
+
+			for _, name := range expectedSames {
+				assert.Contains(t, sames, name)
+			}
+			return res
+		},
+	}}
+	p.Run(t, old)
+}
+
+func TestDestroyExclude(t *testing.T) {
+	t.Parallel()
+
+	//             A
+	//    _________|_________
+	//    B        C        D
+	//          ___|___  ___|___
+	//          E  F  G  H  I  J
+	//             |__|
+	//             K  L
+
+	// Excluding F keeps F and everything it depends on.
+	destroyExcludes(t, []string{"F"}, false /*excludeDependents*/, []string{"A", "C", "F"})
+
+	// Excluding F and its dependents also keeps K and L, and therefore G.
+	destroyExcludes(t, []string{"F"}, true /*excludeDependents*/, []string{"A", "C", "F", "G", "K", "L"})
+}
+
+func destroyExcludes(t *testing.T, excludes []string, excludeDependents bool, expectedRetained []string) {
+	p := &TestPlan{}
+
+	urns, old, program := generateComplexTestDependencyGraph(t, p)
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	p.Options.Host = deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p.Options.ExcludeDependents = excludeDependents
+	for _, exclude := range excludes {
+		p.Options.ExcludeTargets = append(p.Options.ExcludeTargets,
+			pickURN(t, urns, complexTestDependencyGraphNames, exclude))
+	}
+	t.Logf("Excluding: %v", p.Options.ExcludeTargets)
+
+	p.Steps = []TestStep{{
+		Op: Destroy,
+		Validate: func(project workspace.Project, target deploy.Target, entries JournalEntries,
+			evts []Event, res result.Result) result.Result {
+
+			assert.Nil(t, res)
+
+			deleted := make(map[string]bool)
+			for _, entry := range entries {
+				assert.Equal(t, deploy.OpDelete, entry.Step.Op())
+				deleted[entry.Step.URN().Name().String()] = true
+			}
+
+			for _, name := range complexTestDependencyGraphNames {
+				if contains(expectedRetained, name) {
+					assert.NotContains(t, deleted, name)
+				} else {
+					assert.Contains(t, deleted, name)
+				}
+			}
+			return res
+		},
+	}}
+	p.Run(t, old)
+}
+
+func TestUpdateTargetSelectors(t *testing.T) {
+	t.Parallel()
+
+	// comp (Component)
+	// |-- a (Resource)
+	// |-- sub (Component)
+	//     |-- b (Resource)
+	//     |-- c (Other)
+	//     |-- e (Resource, not yet created)
+	// d (Resource)
+	updateTargetSelectors(t, []string{"children-of:**::comp"}, []string{"a", "b", "c"}, []string{"e"})
+	updateTargetSelectors(t, []string{"children-of:**::sub"}, []string{"b", "c"}, []string{"e"})
+	updateTargetSelectors(t, []string{"type:pkgA:index:Resource"}, []string{"a", "b", "d"}, []string{"e"})
+	updateTargetSelectors(t, []string{"type:pkgA:index:Other", "**::d"}, []string{"c", "d"}, nil)
+	updateTargetSelectors(t, []string{"**Component$pkgA:index:Resource::*"}, []string{"a", "b"}, []string{"e"})
+	updateTargetSelectors(t, []string{"**::z*"}, nil, nil)
+}
+
+func updateTargetSelectors(t *testing.T, targets, expectedUpdates, expectedCreates []string) {
+	p := &TestPlan{}
+
+	resTypeComponent := tokens.Type("pkgA:index:Component")
+	resTypeResource := tokens.Type("pkgA:index:Resource")
+	resTypeOther := tokens.Type("pkgA:index:Other")
+
+	newURN := func(typ tokens.Type, name string, parent resource.URN) resource.URN {
+		var pt tokens.Type
+		if parent != "" {
+			pt = parent.QualifiedType()
+		}
+		return resource.NewURN("test", "test", pt, typ, tokens.QName(name))
+	}
+	urnComp := newURN(resTypeComponent, "comp", "")
+	urnA := newURN(resTypeResource, "a", urnComp)
+	urnSub := newURN(resTypeComponent, "sub", urnComp)
+	urnB := newURN(resTypeResource, "b", urnSub)
+	urnC := newURN(resTypeOther, "c", urnSub)
+	urnD := newURN(resTypeResource, "d", "")
+
+	old := &deploy.Snapshot{
+		Resources: []*resource.State{
+			newResource(urnComp, "", "", "", nil, nil, nil, false),
+			newResource(urnA, urnComp, "0", "", nil, nil, nil, true),
+			newResource(urnSub, urnComp, "", "", nil, nil, nil, false),
+			newResource(urnB, urnSub, "1", "", nil, nil, nil, true),
+			newResource(urnC, urnSub, "2", "", nil, nil, nil, true),
+			newResource(urnD, "", "3", "", nil, nil, nil, true),
+		},
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		register := func(typ tokens.Type, name string, parent resource.URN) resource.URN {
+			urn, _, _, err := monitor.RegisterResource(typ, name, typ != resTypeComponent,
+				deploytest.ResourceOptions{Parent: parent})
+			assert.NoError(t, err)
+			return urn
+		}
+
+		comp := register(resTypeComponent, "comp", "")
+		register(resTypeResource, "a", comp)
+		sub := register(resTypeComponent, "sub", comp)
+		register(resTypeResource, "b", sub)
+		register(resTypeOther, "c", sub)
+		register(resTypeResource, "e", sub)
+		register(resTypeResource, "d", "")
+		return nil
+	})
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap,
+					ignoreChanges []string) (plugin.DiffResult, error) {
+
+					// all resources will change.
+					return plugin.DiffResult{
+						Changes: plugin.DiffSome,
+					}, nil
+				},
+			}, nil
+		}),
+	}
+
+	p.Options.Host = deploytest.NewPluginHost(nil, nil, program, loaders...)
+	for _, target := range targets {
+		p.Options.UpdateTargets = append(p.Options.UpdateTargets, resource.URN(target))
+	}
+	t.Logf("Updating targets: %v", p.Options.UpdateTargets)
+
+	p.Steps = []TestStep{{
+		Op: Update,
+		Validate: func(project workspace.Project, target deploy.Target, entries JournalEntries,
+			evts []Event, res result.Result) result.Result {
+
+			assert.Nil(t, res)
+
+			updates, creates := make(map[string]bool), make(map[string]bool)
+			for _, entry := range entries {
+				switch entry.Step.Op() {
+				case deploy.OpSame:
+				case deploy.OpCreate:
+					// The default provider is always created, as the snapshot has no provider resources.
+					if !providers.IsProviderType(entry.Step.URN().Type()) {
+						creates[entry.Step.URN().Name().String()] = true
+					}
+				case deploy.OpUpdate:
+					updates[entry.Step.URN().Name().String()] = true
+				default:
+					assert.FailNowf(t, "", "Got a step that wasn't a same/create/update: %v", entry.Step.Op())
+				}
+			}
+
+			assert.Len(t, updates, len(expectedUpdates))
+			for _, name := range expectedUpdates {
+				assert.Contains(t, updates, name)
+			}
+			assert.Len(t, creates, len(expectedCreates))
+			for _, name := range expectedCreates {
+				assert.Contains(t, creates, name)
+			}
+			return res
+		},
+	}}
+	p.Run(t, old)
+}
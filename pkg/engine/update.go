@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	resourceanalyzer "github.com/pulumi/pulumi/pkg/v3/resource/analyzer"
 	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
@@ -128,6 +129,12 @@ type UpdateOptions struct {
 	// XXXTargets lists.
 	TargetDependents bool
 
+	// Specific resources to exclude from an update, refresh or destroy operation.
+	ExcludeTargets []resource.URN
+
+	// true if the dependents of the resources in ExcludeTargets should be excluded as well.
+	ExcludeDependents bool
+
 	// true if the engine should use legacy diffing behavior during an update.
 	UseLegacyDiff bool
 
@@ -151,6 +158,10 @@ type UpdateOptions struct {
 
 	// true if experimental plans should be generated.
 	ExperimentalPlans bool
+
+	// true if the engine should continue executing steps that do not depend on a failed step instead of canceling
+	// the deployment when a step fails.
+	ContinueOnError bool
 }
 
 // HasChanges returns true if there are any non-same changes in the resulting summary.
@@ -492,22 +503,40 @@ type updateActions struct {
 	Opts    deploymentOptions
 
 	maybeCorrupt bool
+	stepStarts   map[deploy.Step]time.Time // when each step in progress started.
+	stepTimings  []StepTiming              // the timings of the finished steps that changed resources.
 }
 
 func newUpdateActions(context *Context, u UpdateInfo, opts deploymentOptions) *updateActions {
 	return &updateActions{
-		Context: context,
-		Ops:     make(map[display.StepOp]int),
-		Seen:    make(map[resource.URN]deploy.Step),
-		Update:  u,
-		Opts:    opts,
+		Context:    context,
+		Ops:        make(map[display.StepOp]int),
+		Seen:       make(map[resource.URN]deploy.Step),
+		Update:     u,
+		Opts:       opts,
+		stepStarts: make(map[deploy.Step]time.Time),
+	}
+}
+
+// finishStep records that the given step finished, returning its timing. Steps that leave their resource unchanged
+// are not timed, and nil is returned for them. MapLock must be held.
+func (acts *updateActions) finishStep(step deploy.Step, failed bool) *StepTiming {
+	start, ok := acts.stepStarts[step]
+	delete(acts.stepStarts, step)
+	if !ok || step.Op() == deploy.OpSame {
+		return nil
 	}
+
+	timing := StepTiming{URN: step.URN(), Op: step.Op(), Start: start, End: time.Now(), Failed: failed}
+	acts.stepTimings = append(acts.stepTimings, timing)
+	return &timing
 }
 
 func (acts *updateActions) OnResourceStepPre(step deploy.Step) (interface{}, error) {
 	// Ensure we've marked this step as observed.
 	acts.MapLock.Lock()
 	acts.Seen[step.URN()] = step
+	acts.stepStarts[step] = time.Now()
 	acts.MapLock.Unlock()
 
 	// Skip reporting if necessary.
@@ -525,6 +554,7 @@ func (acts *updateActions) OnResourceStepPost(
 
 	acts.MapLock.Lock()
 	assertSeen(acts.Seen, step)
+	timing := acts.finishStep(step, err != nil)
 	acts.MapLock.Unlock()
 
 	// If we've already been terminated, exit without writing the checkpoint. We explicitly want to leave the
@@ -549,7 +579,7 @@ func (acts *updateActions) OnResourceStepPost(
 		// Issue a true, bonafide error.
 		acts.Opts.Diag.Errorf(diag.GetResourceOperationFailedError(errorURN), err)
 		if reportStep {
-			acts.Opts.Events.resourceOperationFailedEvent(step, status, acts.Steps, acts.Opts.Debug)
+			acts.Opts.Events.resourceOperationFailedEvent(step, status, acts.Steps, timing, acts.Opts.Debug)
 		}
 	} else if reportStep {
 		op, record := step.Op(), step.Logical()
@@ -574,7 +604,7 @@ func (acts *updateActions) OnResourceStepPost(
 		// not show outputs for component resources at this point: any that exist must be from a previous execution of
 		// the Pulumi program, as component resources only report outputs via calls to RegisterResourceOutputs.
 		if step.Res().Custom || acts.Opts.Refresh && step.Op() == deploy.OpRefresh {
-			acts.Opts.Events.resourceOutputsEvent(op, step, false /*planning*/, acts.Opts.Debug)
+			acts.Opts.Events.resourceOutputsEvent(op, step, timing, false /*planning*/, acts.Opts.Debug)
 		}
 	}
 
@@ -616,7 +646,7 @@ func (acts *updateActions) OnResourceOutputs(step deploy.Step) error {
 
 	// Skip reporting if necessary.
 	if shouldReportStep(step, acts.Opts) {
-		acts.Opts.Events.resourceOutputsEvent(step.Op(), step, false /*planning*/, acts.Opts.Debug)
+		acts.Opts.Events.resourceOutputsEvent(step.Op(), step, nil /*timing*/, false /*planning*/, acts.Opts.Debug)
 	}
 
 	// There's a chance there are new outputs that weren't written out last time.
@@ -628,6 +658,12 @@ func (acts *updateActions) OnPolicyViolation(urn resource.URN, d plugin.AnalyzeD
 	acts.Opts.Events.policyViolationEvent(urn, d)
 }
 
+func (acts *updateActions) StepTimings() []StepTiming {
+	acts.MapLock.Lock()
+	defer acts.MapLock.Unlock()
+	return append([]StepTiming(nil), acts.stepTimings...)
+}
+
 func (acts *updateActions) MaybeCorrupt() bool {
 	return acts.maybeCorrupt
 }
@@ -719,7 +755,7 @@ func (acts *previewActions) OnResourceStepPost(ctx interface{},
 			acts.MapLock.Unlock()
 		}
 
-		acts.Opts.Events.resourceOutputsEvent(op, step, true /*planning*/, acts.Opts.Debug)
+		acts.Opts.Events.resourceOutputsEvent(op, step, nil /*timing*/, true /*planning*/, acts.Opts.Debug)
 	}
 
 	return nil
@@ -736,7 +772,7 @@ func (acts *previewActions) OnResourceOutputs(step deploy.Step) error {
 	}
 
 	// Print the resource outputs separately.
-	acts.Opts.Events.resourceOutputsEvent(step.Op(), step, true /*planning*/, acts.Opts.Debug)
+	acts.Opts.Events.resourceOutputsEvent(step.Op(), step, nil /*timing*/, true /*planning*/, acts.Opts.Debug)
 
 	return nil
 }
@@ -745,6 +781,10 @@ func (acts *previewActions) OnPolicyViolation(urn resource.URN, d plugin.Analyze
 	acts.Opts.Events.policyViolationEvent(urn, d)
 }
 
+func (acts *previewActions) StepTimings() []StepTiming {
+	return nil
+}
+
 func (acts *previewActions) MaybeCorrupt() bool {
 	return false
 }
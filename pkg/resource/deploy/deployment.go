@@ -59,11 +59,14 @@ type Options struct {
 	DestroyTargets            []resource.URN // Specific resources to destroy.
 	UpdateTargets             []resource.URN // Specific resources to update.
 	TargetDependents          bool           // true if we're allowing things to proceed, even with unspecified targets
+	ExcludeTargets            []resource.URN // Specific resources to exclude from the operation.
+	ExcludeDependents         bool           // true if the dependents of excluded resources are also excluded.
 	TrustDependencies         bool           // whether or not to trust the resource dependency graph.
 	UseLegacyDiff             bool           // whether or not to use legacy diffing behavior.
 	DisableResourceReferences bool           // true to disable resource reference support.
 	DisableOutputValues       bool           // true to disable output value support.
 	ExperimentalPlans         bool           // true to enable experimental plan support.
+	ContinueOnError           bool           // true to continue with independent steps after a step fails.
 }
 
 // DegreeOfParallelism returns the degree of parallelism that should be used during the
@@ -57,6 +57,27 @@ func createTargetMap(targets []resource.URN) map[resource.URN]bool {
 	return targetMap
 }
 
+// excludedResources returns the set of resources in the given snapshot that are excluded from an operation. If
+// `dependents` is true, this includes every resource that (transitively) depends on or is a child of an excluded
+// resource. The set is nil if there are no exclusions.
+func excludedResources(snap *Snapshot, excludesOpt map[resource.URN]bool, dependents bool) map[resource.URN]bool {
+	if excludesOpt == nil || !dependents || snap == nil {
+		return excludesOpt
+	}
+
+	dg := graph.NewDependencyGraph(snap.Resources)
+	excluded := make(map[resource.URN]bool)
+	for _, res := range snap.Resources {
+		if excludesOpt[res.URN] && !excluded[res.URN] {
+			excluded[res.URN] = true
+			for _, dep := range dg.DependingOn(res, nil, true) {
+				excluded[dep.URN] = true
+			}
+		}
+	}
+	return excluded
+}
+
 // checkTargets validates that all the targets passed in refer to existing resources.  Diagnostics
 // are generated for any target that cannot be found.  The target must either have existed in the stack
 // prior to running the operation, or it must be the urn for a resource that was created.
@@ -73,6 +94,11 @@ func (ex *deploymentExecutor) checkTargets(targets []resource.URN, op display.St
 
 	hasUnknownTarget := false
 	for _, target := range targets {
+		// Selectors may legitimately match nothing.
+		if IsTargetSelector(target) {
+			continue
+		}
+
 		hasOld := false
 		if _, has := olds[target]; has {
 			hasOld = true
@@ -186,10 +212,16 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 	// The set of -t targets provided on the command line.  'nil' means 'update everything'.
 	// Non-nil means 'update only in this set'.  We don't error if the user specifies a target
 	// during `update` that we don't know about because it might be the urn for a resource they
-	// want to create.
-	updateTargetsOpt := createTargetMap(opts.UpdateTargets)
-	replaceTargetsOpt := createTargetMap(opts.ReplaceTargets)
-	destroyTargetsOpt := createTargetMap(opts.DestroyTargets)
+	// want to create. Target selectors are resolved against the base snapshot here; the step generator
+	// resolves them against newly registered resources as they arrive.
+	var prevResources []*resource.State
+	if ex.deployment.prev != nil {
+		prevResources = ex.deployment.prev.Resources
+	}
+	updateTargetsOpt := resolveTargets(opts.UpdateTargets, prevResources)
+	replaceTargetsOpt := resolveTargets(opts.ReplaceTargets, prevResources)
+	destroyTargetsOpt := resolveTargets(opts.DestroyTargets, prevResources)
+	excludeTargetsOpt := resolveTargets(opts.ExcludeTargets, prevResources)
 	if res := ex.checkTargets(opts.ReplaceTargets, OpReplace); res != nil {
 		return nil, res
 	}
@@ -208,7 +240,7 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 	}
 
 	// Set up a step generator for this deployment.
-	ex.stepGen = newStepGenerator(ex.deployment, opts, updateTargetsOpt, replaceTargetsOpt)
+	ex.stepGen = newStepGenerator(ex.deployment, opts, updateTargetsOpt, replaceTargetsOpt, excludeTargetsOpt)
 
 	// Retire any pending deletes that are currently present in this deployment.
 	if res := ex.retirePendingDeletes(callerCtx, opts, preview); res != nil {
@@ -264,7 +296,14 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 					if !event.Result.IsBail() {
 						ex.reportError("", event.Result.Error())
 					}
-					cancel()
+					if opts.ContinueOnError {
+						// Let the steps that are already executing run to completion, but do not perform any
+						// deletes: the program did not run to completion, so a resource it did not register
+						// cannot be distinguished from one that was removed from the program.
+						ex.stepExec.SignalCompletion()
+					} else {
+						cancel()
+					}
 
 					// We reported any errors above.  So we can just bail now.
 					return false, result.Bail()
@@ -287,6 +326,9 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 						logging.V(4).Infof("deploymentExecutor.Execute(...): error handling event: %v", resErr)
 						ex.reportError(ex.deployment.generateEventURN(event.Event), resErr)
 					}
+					if opts.ContinueOnError && ex.failEvent(event.Event) {
+						continue
+					}
 					cancel()
 					return false, result.Bail()
 				}
@@ -303,6 +345,10 @@ func (ex *deploymentExecutor) Execute(callerCtx context.Context, opts Options, p
 	ex.stepExec.WaitForCompletion()
 	logging.V(4).Infof("deploymentExecutor.Execute(...): step executor has completed")
 
+	if opts.ContinueOnError {
+		ex.reportFailureSummary()
+	}
+
 	// Now that we've performed all steps in the deployment, ensure that the list of targets to update was
 	// valid.  We have to do this *after* performing the steps as the target list may have referred
 	// to a resource that was created in one of hte steps.
@@ -435,6 +481,48 @@ func (ex *deploymentExecutor) performDeletes(
 	return nil
 }
 
+// failEvent records the failure to generate steps for the given source event and signals that failure to the source,
+// allowing the deployment to continue with the resources that do not depend on it. It returns false if the event
+// cannot be failed independently of the rest of the deployment.
+func (ex *deploymentExecutor) failEvent(event SourceEvent) bool {
+	urn := ex.deployment.generateEventURN(event)
+	switch e := event.(type) {
+	case RegisterResourceEvent:
+		ex.stepGen.RecordFailure(urn, e.Goal().Aliases)
+		ex.stepExec.recordEventFailure(urn)
+		e.Done(&RegisterResult{State: &resource.State{URN: urn}, Failed: true})
+	case ReadResourceEvent:
+		ex.stepGen.RecordFailure(urn, nil)
+		ex.stepExec.recordEventFailure(urn)
+		e.Done(&ReadResult{State: &resource.State{URN: urn}, Failed: true})
+	default:
+		return false
+	}
+	return true
+}
+
+// reportFailureSummary reports a single diagnostic summarizing the resources whose steps failed or were skipped
+// during a deployment that continued on error.
+func (ex *deploymentExecutor) reportFailureSummary() {
+	failed, skipped := ex.stepExec.Failures()
+	if len(failed) == 0 && len(skipped) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d resource(s) failed", len(failed))
+	for _, urn := range failed {
+		fmt.Fprintf(&sb, "\n    %v", urn)
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&sb, "\n%d resource(s) were skipped because a dependency failed", len(skipped))
+		for _, urn := range skipped {
+			fmt.Fprintf(&sb, "\n    %v", urn)
+		}
+	}
+	ex.reportError("", errors.New(sb.String()))
+}
+
 // handleSingleEvent handles a single source event. For all incoming events, it produces a chain that needs
 // to be executed and schedules the chain for execution.
 func (ex *deploymentExecutor) handleSingleEvent(event SourceEvent) result.Result {
@@ -479,6 +567,8 @@ func (ex *deploymentExecutor) retirePendingDeletes(callerCtx context.Context, op
 	logging.V(4).Infof("deploymentExecutor.retirePendingDeletes(...): executing %d steps", len(steps))
 	ctx, cancel := context.WithCancel(callerCtx)
 
+	// Pending deletes are always retired in full: the first failure cancels the rest.
+	opts.ContinueOnError = false
 	stepExec := newStepExecutor(ctx, cancel, ex.deployment, opts, preview, false)
 	antichains := ex.stepGen.ScheduleDeletes(steps)
 	// Submit the deletes for execution and wait for them all to retire.
@@ -517,7 +607,9 @@ func (ex *deploymentExecutor) importResources(
 		return nil, nil
 	}
 
-	// Create an executor for this import.
+	// Create an executor for this import. Every import is attempted even if others fail, and since imports do not
+	// depend on each other, none of them is skipped because of another's failure.
+	opts.ContinueOnError = false
 	ctx, cancel := context.WithCancel(callerCtx)
 	stepExec := newStepExecutor(ctx, cancel, ex.deployment, opts, preview, true)
 
@@ -565,16 +657,19 @@ func (ex *deploymentExecutor) refresh(callerCtx context.Context, opts Options, p
 	// specific targets.
 	steps := []Step{}
 	resourceToStep := map[*resource.State]Step{}
-	targetMapOpt := createTargetMap(opts.RefreshTargets)
+	targetMapOpt := resolveTargets(opts.RefreshTargets, prev.Resources)
+	excludeMapOpt := excludedResources(prev, resolveTargets(opts.ExcludeTargets, prev.Resources), opts.ExcludeDependents)
 	for _, res := range prev.Resources {
-		if targetMapOpt == nil || targetMapOpt[res.URN] {
+		if (targetMapOpt == nil || targetMapOpt[res.URN]) && !excludeMapOpt[res.URN] {
 			step := NewRefreshStep(ex.deployment, res, nil)
 			steps = append(steps, step)
 			resourceToStep[res] = step
 		}
 	}
 
-	// Fire up a worker pool and issue each refresh in turn.
+	// Fire up a worker pool and issue each refresh in turn. Every resource is refreshed even if others fail, and
+	// since refreshes do not depend on each other, none of them is skipped because of another's failure.
+	opts.ContinueOnError = false
 	ctx, cancel := context.WithCancel(callerCtx)
 	stepExec := newStepExecutor(ctx, cancel, ex.deployment, opts, preview, true)
 	stepExec.ExecuteParallel(steps)
@@ -16,7 +16,6 @@ package deploy
 
 import (
 	"fmt"
-	"regexp"
 	"sort"
 	"strings"
 
@@ -186,17 +185,7 @@ func (snap *Snapshot) GlobUrn(urn resource.URN) []resource.URN {
 	if !strings.Contains(string(urn), "*") {
 		return []resource.URN{urn}
 	}
-	segmentGlob := strings.Split(string(urn), "**")
-	for i, v := range segmentGlob {
-		part := strings.Split(v, "*")
-		for i, v := range part {
-			part[i] = regexp.QuoteMeta(v)
-		}
-		segmentGlob[i] = strings.Join(part, "[^:]*")
-	}
-
-	// Because we have quoted all input, this is safe to compile.
-	glob := regexp.MustCompile("^" + strings.Join(segmentGlob, ".*") + "$")
+	glob := globRegexp(string(urn))
 
 	results := make(map[string]struct{})
 	for _, r := range snap.Resources {
@@ -384,6 +384,10 @@ func (d *defaultProviders) handleRequest(req providers.ProviderRequest) (provide
 		return providers.Reference{}, context.Canceled
 	}
 
+	if result.Failed {
+		return providers.Reference{}, fmt.Errorf("failed to register default provider for package %s", req)
+	}
+
 	logging.V(5).Infof("registered default provider for package %s: %s", req, result.State.URN)
 
 	id := result.State.ID
@@ -908,6 +912,10 @@ func (rm *resmon) ReadResource(ctx context.Context,
 	}
 
 	contract.Assert(result != nil)
+	if result.Failed {
+		return nil, rpcerror.New(codes.Aborted, fmt.Sprintf("failed to read resource %s", result.State.URN))
+	}
+
 	marshaled, err := plugin.MarshalProperties(result.State.Outputs, plugin.MarshalOptions{
 		Label:         label,
 		KeepUnknowns:  true,
@@ -1145,6 +1153,9 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 			logging.V(5).Infof("ResourceMonitor.RegisterResource operation canceled, name=%s", name)
 			return nil, rpcerror.New(codes.Unavailable, "resource monitor shut down while waiting on step's done channel")
 		}
+		if result.Failed {
+			return nil, rpcerror.New(codes.Aborted, fmt.Sprintf("failed to register resource %s", result.State.URN))
+		}
 	}
 
 	// Filter out partially-known values if the requestor does not support them.
@@ -1230,3 +1230,30 @@ func getProvider(s Step) (plugin.Provider, error) {
 	}
 	return provider, nil
 }
+
+// signalStepFailed informs the source event that produced the given step, if any, that the step did not complete
+// successfully. This unblocks the program that is waiting on the step when the deployment continues on error.
+func signalStepFailed(s Step) {
+	switch s := s.(type) {
+	case *SameStep:
+		if s.reg != nil {
+			s.reg.Done(&RegisterResult{State: s.new, Failed: true})
+		}
+	case *CreateStep:
+		if s.reg != nil {
+			s.reg.Done(&RegisterResult{State: s.new, Failed: true})
+		}
+	case *UpdateStep:
+		if s.reg != nil {
+			s.reg.Done(&RegisterResult{State: s.new, Failed: true})
+		}
+	case *ImportStep:
+		if s.reg != nil {
+			s.reg.Done(&RegisterResult{State: s.new, Failed: true})
+		}
+	case *ReadStep:
+		if s.event != nil {
+			s.event.Done(&ReadResult{State: s.new, Failed: true})
+		}
+	}
+}
@@ -18,9 +18,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
@@ -98,6 +100,11 @@ type stepExecutor struct {
 	ctx      context.Context    // cancellation context for the current deployment.
 	cancel   context.CancelFunc // CancelFunc that cancels the above context.
 	sawError atomic.Value       // atomic boolean indicating whether or not the step excecutor saw that there was an error.
+
+	failuresLock  sync.Mutex            // lock protecting the failure bookkeeping below.
+	failed        map[resource.URN]bool // resources whose steps failed, if continuing on error.
+	skipped       map[resource.URN]bool // resources whose steps were skipped due to a failed dependency.
+	blockedDelete map[resource.URN]bool // resources that may not be deleted because a dependent was not deleted.
 }
 
 //
@@ -206,7 +213,9 @@ func (se *stepExecutor) ExecuteRegisterResourceOutputs(e RegisterResourceOutputs
 			diagMsg := diag.RawMessage(reg.URN(), outErr.Error())
 			se.deployment.Diag().Errorf(diagMsg)
 			se.cancelDueToError()
-			return nil
+			if !se.opts.ContinueOnError {
+				return nil
+			}
 		}
 	}
 	e.Done()
@@ -241,7 +250,7 @@ func (se *stepExecutor) WaitForCompletion() {
 // executeChain executes a chain, one step at a time. If any step in the chain fails to execute, or if the
 // context is canceled, the chain stops execution.
 func (se *stepExecutor) executeChain(workerID int, chain chain) {
-	for _, step := range chain {
+	for i, step := range chain {
 		select {
 		case <-se.ctx.Done():
 			se.log(workerID, "step %v on %v canceled", step.Op(), step.URN())
@@ -249,9 +258,22 @@ func (se *stepExecutor) executeChain(workerID int, chain chain) {
 		default:
 		}
 
+		if se.opts.ContinueOnError {
+			if dep, ok := se.failedDependency(step); ok {
+				se.log(workerID, "step %v on %v skipped due to failed dependency %v", step.Op(), step.URN(), dep)
+				se.recordFailure(chain[i:], true /*skipped*/)
+				msg := fmt.Sprintf("skipped because its dependency %v was not updated successfully", dep)
+				se.deployment.Diag().Warningf(diag.RawMessage(step.URN(), msg))
+				return
+			}
+		}
+
 		if err := se.executeStep(workerID, step); err != nil {
 			se.log(workerID, "step %v on %v failed, signalling cancellation", step.Op(), step.URN())
 			se.cancelDueToError()
+			if se.opts.ContinueOnError {
+				se.recordFailure(chain[i:], false /*skipped*/)
+			}
 			if err != errStepApplyFailed {
 				// Step application errors are recorded by the OnResourceStepPost callback. This is confusing,
 				// but it means that at this level we shouldn't be logging any errors that came from there.
@@ -268,11 +290,105 @@ func (se *stepExecutor) executeChain(workerID int, chain chain) {
 
 func (se *stepExecutor) cancelDueToError() {
 	se.sawError.Store(true)
-	if !se.continueOnError {
+	if !se.continueOnError && !se.opts.ContinueOnError {
 		se.cancel()
 	}
 }
 
+// failedDependency returns the URN of a failed or skipped resource that prevents the given step from executing, if
+// any. Steps that create, update or read a resource are blocked by failures of the resources they depend on; steps that
+// delete a resource are blocked by failures to delete the resources that depend on it.
+func (se *stepExecutor) failedDependency(step Step) (resource.URN, bool) {
+	se.failuresLock.Lock()
+	defer se.failuresLock.Unlock()
+
+	if step.New() == nil {
+		if se.blockedDelete[step.URN()] {
+			return step.URN(), true
+		}
+		return "", false
+	}
+
+	for _, dep := range stepDependencies(step.New()) {
+		if se.failed[dep] || se.skipped[dep] {
+			return dep, true
+		}
+	}
+	return "", false
+}
+
+// recordFailure records the resources of the given steps as failed (or skipped) and signals their failure to the
+// source so that the program does not wait on them. The first step in the list is the one that failed; the rest are
+// the remainder of its chain, which will not execute.
+func (se *stepExecutor) recordFailure(steps []Step, skipped bool) {
+	se.failuresLock.Lock()
+	for i, step := range steps {
+		if skipped || i > 0 {
+			se.skipped[step.URN()] = true
+		} else {
+			se.failed[step.URN()] = true
+		}
+
+		// If a resource could not be deleted, the resources it depends on must not be deleted either.
+		if step.New() == nil && step.Old() != nil {
+			for _, dep := range stepDependencies(step.Old()) {
+				se.blockedDelete[dep] = true
+			}
+		}
+	}
+	se.failuresLock.Unlock()
+
+	for _, step := range steps {
+		signalStepFailed(step)
+	}
+}
+
+// recordEventFailure records the resource with the given URN as failed because no steps could be generated for it.
+func (se *stepExecutor) recordEventFailure(urn resource.URN) {
+	se.sawError.Store(true)
+
+	se.failuresLock.Lock()
+	defer se.failuresLock.Unlock()
+	se.failed[urn] = true
+}
+
+// Failures returns the sorted lists of resources whose steps failed and of resources whose steps were skipped because
+// one of their dependencies failed. Failures are only tracked if the deployment continues on error.
+func (se *stepExecutor) Failures() (failed []resource.URN, skipped []resource.URN) {
+	se.failuresLock.Lock()
+	defer se.failuresLock.Unlock()
+
+	for urn := range se.failed {
+		failed = append(failed, urn)
+	}
+	for urn := range se.skipped {
+		if !se.failed[urn] {
+			skipped = append(skipped, urn)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i] < failed[j] })
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i] < skipped[j] })
+	return failed, skipped
+}
+
+// stepDependencies returns the URNs of every resource the given resource depends on, including its parent, its
+// provider and the dependencies of each of its properties.
+func stepDependencies(res *resource.State) []resource.URN {
+	deps := append([]resource.URN{}, res.Dependencies...)
+	if res.Parent != "" {
+		deps = append(deps, res.Parent)
+	}
+	if res.Provider != "" {
+		if ref, err := providers.ParseReference(res.Provider); err == nil {
+			deps = append(deps, ref.URN())
+		}
+	}
+	for _, propDeps := range res.PropertyDependencies {
+		deps = append(deps, propDeps...)
+	}
+	return deps
+}
+
 //
 // The next few functions are responsible for executing individual steps. The basic flow of step
 // execution is
@@ -368,8 +484,9 @@ func (se *stepExecutor) executeStep(workerID int, step Step) error {
 	}
 
 	// Calling stepComplete allows steps that depend on this step to continue. OnResourceStepPost saved the results
-	// of the step in the snapshot, so we are ready to go.
-	if stepComplete != nil {
+	// of the step in the snapshot, so we are ready to go. If we are continuing on error, a failed step is never
+	// completed: its failure is signalled instead so that its dependents do not proceed.
+	if stepComplete != nil && (err == nil || !se.opts.ContinueOnError) {
 		se.log(workerID, "step %v on %v retired", step.Op(), step.URN())
 		stepComplete()
 	}
@@ -458,6 +575,9 @@ func newStepExecutor(ctx context.Context, cancel context.CancelFunc, deployment
 		incomingChains:  make(chan incomingChain),
 		ctx:             ctx,
 		cancel:          cancel,
+		failed:          make(map[resource.URN]bool),
+		skipped:         make(map[resource.URN]bool),
+		blockedDelete:   make(map[resource.URN]bool),
 	}
 
 	exec.sawError.Store(false)
@@ -39,6 +39,13 @@ type stepGenerator struct {
 
 	updateTargetsOpt  map[resource.URN]bool // the set of resources to update; resources not in this set will be same'd
 	replaceTargetsOpt map[resource.URN]bool // the set of resoures to replace
+	excludeTargetsOpt map[resource.URN]bool // the set of resources to exclude; these will be same'd
+
+	// matchers for the target selectors in the update, replace and exclude targets. These are used to add resources
+	// registered by the program to the corresponding target sets.
+	updateTargetsMatcher  *targetMatcher
+	replaceTargetsMatcher *targetMatcher
+	excludeTargetsMatcher *targetMatcher
 
 	// signals that one or more errors have been reported to the user, and the deployment should terminate
 	// in error. This primarily allows `preview` to aggregate many policy violation events and
@@ -52,6 +59,7 @@ type stepGenerator struct {
 	updates  map[resource.URN]bool // set of URNs updated in this deployment
 	creates  map[resource.URN]bool // set of URNs created in this deployment
 	sames    map[resource.URN]bool // set of URNs that were not changed in this deployment
+	failed   map[resource.URN]bool // set of URNs (and aliases) whose steps could not be generated
 
 	// set of URNs that would have been created, but were filtered out because the user didn't
 	// specify them with --target
@@ -69,13 +77,44 @@ type stepGenerator struct {
 }
 
 func (sg *stepGenerator) isTargetedUpdate() bool {
-	return sg.updateTargetsOpt != nil || sg.replaceTargetsOpt != nil
+	return sg.updateTargetsOpt != nil || sg.replaceTargetsOpt != nil || sg.excludeTargetsOpt != nil
+}
+
+// isExcluded returns if `res` is excluded from the deployment. The function accommodates
+// `--exclude-dependents`: if `res` depends on an excluded resource, it is itself recorded as excluded so that its own
+// dependents are excluded in turn.
+func (sg *stepGenerator) isExcluded(res *resource.State) bool {
+	if sg.excludeTargetsOpt == nil {
+		return false
+	} else if sg.excludeTargetsOpt[res.URN] {
+		return true
+	} else if !sg.opts.ExcludeDependents {
+		return false
+	}
+
+	excluded := res.Parent != "" && sg.excludeTargetsOpt[res.Parent]
+	if res.Provider != "" {
+		ref, err := providers.ParseReference(res.Provider)
+		contract.AssertNoError(err)
+		excluded = excluded || sg.excludeTargetsOpt[ref.URN()]
+	}
+	for _, dep := range res.Dependencies {
+		excluded = excluded || (dep != "" && sg.excludeTargetsOpt[dep])
+	}
+
+	if excluded {
+		sg.excludeTargetsOpt[res.URN] = true
+	}
+	return excluded
 }
 
 // isTargetedForUpdate returns if `res` is targeted for update. The function accommodates
 // `--target-dependents`. `targetDependentsForUpdate` should probably be called if this function
 // returns true.
 func (sg *stepGenerator) isTargetedForUpdate(res *resource.State) bool {
+	if sg.isExcluded(res) {
+		return false
+	}
 	if sg.updateTargetsOpt == nil || sg.updateTargetsOpt[res.URN] {
 		return true
 	} else if !sg.opts.TargetDependents {
@@ -101,6 +140,19 @@ func (sg *stepGenerator) isTargetedForUpdate(res *resource.State) bool {
 	return false
 }
 
+// matchTargets adds the given newly registered resource to each target set with a selector that matches it.
+func (sg *stepGenerator) matchTargets(new *resource.State) {
+	if sg.updateTargetsMatcher.Matches(new.URN, new.Type, new.Parent) {
+		sg.updateTargetsOpt[new.URN] = true
+	}
+	if sg.replaceTargetsMatcher.Matches(new.URN, new.Type, new.Parent) {
+		sg.replaceTargetsOpt[new.URN] = true
+	}
+	if sg.excludeTargetsMatcher.Matches(new.URN, new.Type, new.Parent) {
+		sg.excludeTargetsOpt[new.URN] = true
+	}
+}
+
 func (sg *stepGenerator) isTargetedReplace(urn resource.URN) bool {
 	return sg.replaceTargetsOpt != nil && sg.replaceTargetsOpt[urn]
 }
@@ -401,6 +453,7 @@ func (sg *stepGenerator) generateSteps(event RegisterResourceEvent) ([]Step, res
 	if hasOld {
 		new.SequenceNumber = old.SequenceNumber
 	}
+	sg.matchTargets(new)
 
 	// Mark the URN/resource as having been seen. So we can run analyzers on all resources seen, as well as
 	// lookup providers for calculating replacement of resources that use the provider.
@@ -934,6 +987,15 @@ func (sg *stepGenerator) generateStepsFromDiff(
 	return nil, nil
 }
 
+// RecordFailure records that no steps could be generated for the resource with the given URN and aliases, so that
+// GenerateDeletes does not mistake it for a resource that was removed from the program.
+func (sg *stepGenerator) RecordFailure(urn resource.URN, aliases []resource.URN) {
+	sg.failed[urn] = true
+	for _, alias := range aliases {
+		sg.failed[alias] = true
+	}
+}
+
 func (sg *stepGenerator) GenerateDeletes(targetsOpt map[resource.URN]bool) ([]Step, result.Result) {
 	// To compute the deletion list, we must walk the list of old resources *backwards*.  This is because the list is
 	// stored in dependency order, and earlier elements are possibly leaf nodes for later elements.  We must not delete
@@ -975,7 +1037,7 @@ func (sg *stepGenerator) GenerateDeletes(targetsOpt map[resource.URN]bool) ([]St
 				sg.deletes[res.URN] = true
 				dels = append(dels, NewDeleteReplacementStep(sg.deployment, res, false))
 			} else if _, aliased := sg.aliased[res.URN]; !sg.sames[res.URN] && !sg.updates[res.URN] && !sg.replaces[res.URN] &&
-				!sg.reads[res.URN] && !aliased {
+				!sg.reads[res.URN] && !aliased && !sg.failed[res.URN] {
 				// NOTE: we deliberately do not check sg.deletes here, as it is possible for us to issue multiple
 				// delete steps for the same URN if the old checkpoint contained pending deletes.
 				logging.V(7).Infof("Planner decided to delete '%v'", res.URN)
@@ -1044,6 +1106,22 @@ func (sg *stepGenerator) GenerateDeletes(targetsOpt map[resource.URN]bool) ([]St
 		dels = filtered
 	}
 
+	// If --exclude was provided, never delete an excluded resource or anything an excluded resource depends on.
+	// Deletions of resources that were replaced are unaffected, as the replacement takes their place.
+	if sg.excludeTargetsOpt != nil {
+		retained := sg.determineResourcesToRetainFromExcludes()
+		filtered := []Step{}
+		for _, step := range dels {
+			if step.Op() == OpDelete && retained[step.URN()] {
+				logging.V(7).Infof("Planner decided not to delete '%v' due to being excluded", step.URN())
+				continue
+			}
+			filtered = append(filtered, step)
+		}
+
+		dels = filtered
+	}
+
 	deletingUnspecifiedTarget := false
 	for _, step := range dels {
 		urn := step.URN()
@@ -1166,6 +1244,27 @@ func (sg *stepGenerator) determineAllowedResourcesToDeleteFromTargets(
 	return resourcesToDelete, nil
 }
 
+// determineResourcesToRetainFromExcludes computes the set of resources in the base snapshot that must not be deleted
+// because they are excluded, or because an excluded resource depends on them (transitively). If
+// `--exclude-dependents` was given, the dependents of the excluded resources are excluded as well.
+func (sg *stepGenerator) determineResourcesToRetainFromExcludes() map[resource.URN]bool {
+	excluded := excludedResources(sg.deployment.prev, sg.excludeTargetsOpt, sg.opts.ExcludeDependents)
+	dg := graph.NewDependencyGraph(sg.deployment.prev.Resources)
+	retained := make(map[resource.URN]bool)
+	for _, res := range sg.deployment.prev.Resources {
+		if !excluded[res.URN] {
+			continue
+		}
+
+		retained[res.URN] = true
+		for dep := range dg.TransitiveDependenciesOf(res) {
+			retained[dep.URN] = true
+		}
+	}
+
+	return retained
+}
+
 // GeneratePendingDeletes generates delete steps for all resources that are pending deletion. This function should be
 // called at the start of a deployment in order to find all resources that are pending deletion from the previous
 // deployment.
@@ -1811,25 +1910,30 @@ func (sg *stepGenerator) AnalyzeResources() result.Result {
 }
 
 // newStepGenerator creates a new step generator that operates on the given deployment.
-func newStepGenerator(
-	deployment *Deployment, opts Options, updateTargetsOpt, replaceTargetsOpt map[resource.URN]bool) *stepGenerator {
+func newStepGenerator(deployment *Deployment, opts Options,
+	updateTargetsOpt, replaceTargetsOpt, excludeTargetsOpt map[resource.URN]bool) *stepGenerator {
 
 	return &stepGenerator{
-		deployment:           deployment,
-		opts:                 opts,
-		updateTargetsOpt:     updateTargetsOpt,
-		replaceTargetsOpt:    replaceTargetsOpt,
-		urns:                 make(map[resource.URN]bool),
-		reads:                make(map[resource.URN]bool),
-		creates:              make(map[resource.URN]bool),
-		sames:                make(map[resource.URN]bool),
-		replaces:             make(map[resource.URN]bool),
-		updates:              make(map[resource.URN]bool),
-		deletes:              make(map[resource.URN]bool),
-		skippedCreates:       make(map[resource.URN]bool),
-		pendingDeletes:       make(map[*resource.State]bool),
-		providers:            make(map[resource.URN]*resource.State),
-		dependentReplaceKeys: make(map[resource.URN][]resource.PropertyKey),
-		aliased:              make(map[resource.URN]resource.URN),
+		deployment:            deployment,
+		opts:                  opts,
+		updateTargetsOpt:      updateTargetsOpt,
+		replaceTargetsOpt:     replaceTargetsOpt,
+		excludeTargetsOpt:     excludeTargetsOpt,
+		updateTargetsMatcher:  newTargetMatcher(opts.UpdateTargets),
+		replaceTargetsMatcher: newTargetMatcher(opts.ReplaceTargets),
+		excludeTargetsMatcher: newTargetMatcher(opts.ExcludeTargets),
+		urns:                  make(map[resource.URN]bool),
+		reads:                 make(map[resource.URN]bool),
+		creates:               make(map[resource.URN]bool),
+		sames:                 make(map[resource.URN]bool),
+		replaces:              make(map[resource.URN]bool),
+		updates:               make(map[resource.URN]bool),
+		deletes:               make(map[resource.URN]bool),
+		failed:                make(map[resource.URN]bool),
+		skippedCreates:        make(map[resource.URN]bool),
+		pendingDeletes:        make(map[*resource.State]bool),
+		providers:             make(map[resource.URN]*resource.State),
+		dependentReplaceKeys:  make(map[resource.URN][]resource.PropertyKey),
+		aliased:               make(map[resource.URN]resource.URN),
 	}
 }
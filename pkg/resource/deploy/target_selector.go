@@ -0,0 +1,185 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+// The targets given to a deployment (e.g. Options.UpdateTargets) are target selectors. A selector is one of:
+//
+//   - a URN, which may contain wildcards: `*` matches any run of characters other than `:` and `**` matches any run of
+//     characters;
+//   - `type:<type>`, which selects every resource of the given type. The type may contain wildcards;
+//   - `children-of:<urn>`, which selects every resource that is parented, directly or transitively, by a resource whose
+//     URN matches the given URN. The URN may contain wildcards.
+//
+// Selectors are resolved against both the resources in the base snapshot and the resources registered by the program.
+const (
+	// TypeSelectorPrefix is the prefix of a target selector that selects resources by type.
+	TypeSelectorPrefix = "type:"
+	// ChildrenOfSelectorPrefix is the prefix of a target selector that selects the descendants of a resource.
+	ChildrenOfSelectorPrefix = "children-of:"
+)
+
+// IsTargetSelector returns true if the given target may select resources other than the one with exactly that URN.
+func IsTargetSelector(target resource.URN) bool {
+	t := string(target)
+	return strings.Contains(t, "*") ||
+		strings.HasPrefix(t, TypeSelectorPrefix) ||
+		strings.HasPrefix(t, ChildrenOfSelectorPrefix)
+}
+
+// globRegexp compiles a glob, in which `*` matches any run of characters other than `:` and `**` matches any run of
+// characters, into a regular expression that matches the entire input.
+func globRegexp(glob string) *regexp.Regexp {
+	segmentGlob := strings.Split(glob, "**")
+	for i, v := range segmentGlob {
+		part := strings.Split(v, "*")
+		for i, v := range part {
+			part[i] = regexp.QuoteMeta(v)
+		}
+		segmentGlob[i] = strings.Join(part, "[^:]*")
+	}
+
+	// Because we have quoted all input, this is safe to compile.
+	return regexp.MustCompile("^" + strings.Join(segmentGlob, ".*") + "$")
+}
+
+// targetSelector is a single parsed target selector.
+type targetSelector struct {
+	urn        *regexp.Regexp // if non-nil, matches the URNs of the selected resources.
+	typ        *regexp.Regexp // if non-nil, matches the types of the selected resources.
+	childrenOf *regexp.Regexp // if non-nil, matches the URNs of the ancestors of the selected resources.
+}
+
+func parseTargetSelector(target resource.URN) targetSelector {
+	t := string(target)
+	switch {
+	case strings.HasPrefix(t, TypeSelectorPrefix):
+		return targetSelector{typ: globRegexp(strings.TrimPrefix(t, TypeSelectorPrefix))}
+	case strings.HasPrefix(t, ChildrenOfSelectorPrefix):
+		return targetSelector{childrenOf: globRegexp(strings.TrimPrefix(t, ChildrenOfSelectorPrefix))}
+	default:
+		return targetSelector{urn: globRegexp(t)}
+	}
+}
+
+// targetMatcher resolves a set of target selectors against resources. Resources must be presented to the matcher in
+// an order in which parents precede their children, which is the order of both snapshots and resource registrations.
+type targetMatcher struct {
+	selectors   []targetSelector
+	descendants map[resource.URN]bool // the resources selected by a children-of selector so far.
+}
+
+// newTargetMatcher creates a matcher for the given targets. It returns nil if none of the targets is a selector, in
+// which case each target refers to exactly one resource and no matching is needed.
+func newTargetMatcher(targets []resource.URN) *targetMatcher {
+	var selectors []targetSelector
+	for _, t := range targets {
+		if IsTargetSelector(t) {
+			selectors = append(selectors, parseTargetSelector(t))
+		}
+	}
+	if len(selectors) == 0 {
+		return nil
+	}
+
+	return &targetMatcher{
+		selectors:   selectors,
+		descendants: make(map[resource.URN]bool),
+	}
+}
+
+// Matches returns true if any of the matcher's selectors selects the resource with the given URN, type and parent.
+func (m *targetMatcher) Matches(urn resource.URN, typ tokens.Type, parent resource.URN) bool {
+	if m == nil {
+		return false
+	}
+
+	matched := false
+	for _, s := range m.selectors {
+		switch {
+		case s.urn != nil:
+			matched = matched || s.urn.MatchString(string(urn))
+		case s.typ != nil:
+			matched = matched || s.typ.MatchString(string(typ))
+		case s.childrenOf != nil && parent != "":
+			if m.descendants[parent] || s.childrenOf.MatchString(string(parent)) {
+				m.descendants[urn] = true
+				matched = true
+			}
+		}
+	}
+	return matched
+}
+
+// TargetMatcher matches resources against a set of targets, any of which may be a selector. Resources must be
+// presented to the matcher in an order in which parents precede their children.
+type TargetMatcher struct {
+	targets map[resource.URN]bool
+	matcher *targetMatcher
+}
+
+// NewTargetMatcher creates a matcher for the given targets.
+func NewTargetMatcher(targets []resource.URN) *TargetMatcher {
+	return &TargetMatcher{
+		targets: createTargetMap(targets),
+		matcher: newTargetMatcher(targets),
+	}
+}
+
+// Matches returns true if any of the targets selects the resource with the given URN, type and parent.
+func (m *TargetMatcher) Matches(urn resource.URN, typ tokens.Type, parent resource.URN) bool {
+	return m.targets[urn] || m.matcher.Matches(urn, typ, parent)
+}
+
+// resolveTargets returns the set of URNs selected by the given targets from the given resources. Targets that are not
+// selectors are included as-is, whether or not they refer to one of the resources. The set is nil if there are no
+// targets.
+func resolveTargets(targets []resource.URN, resources []*resource.State) map[resource.URN]bool {
+	targetMap := createTargetMap(targets)
+	if matcher := newTargetMatcher(targets); matcher != nil {
+		for _, t := range targets {
+			if IsTargetSelector(t) {
+				delete(targetMap, t)
+			}
+		}
+		for _, res := range resources {
+			if matcher.Matches(res.URN, res.Type, res.Parent) {
+				targetMap[res.URN] = true
+			}
+		}
+	}
+	return targetMap
+}
+
+// SelectResources returns the resources in the snapshot that are selected by any of the given targets, in snapshot
+// order.
+func (snap *Snapshot) SelectResources(targets []resource.URN) []*resource.State {
+	selected := resolveTargets(targets, snap.Resources)
+
+	var resources []*resource.State
+	for _, res := range snap.Resources {
+		if selected[res.URN] {
+			resources = append(resources, res)
+		}
+	}
+	return resources
+}
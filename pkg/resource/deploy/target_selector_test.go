@@ -0,0 +1,87 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestIsTargetSelector(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, IsTargetSelector("urn:pulumi:stack::proj::pkg:index:type::name"))
+	assert.True(t, IsTargetSelector("urn:pulumi:stack::proj::pkg:index:type::*"))
+	assert.True(t, IsTargetSelector("type:pkg:index:type"))
+	assert.True(t, IsTargetSelector("children-of:urn:pulumi:stack::proj::pkg:index:type::name"))
+}
+
+func TestResolveTargets(t *testing.T) {
+	t.Parallel()
+
+	bucket := &resource.State{
+		URN: "urn:pulumi:stack::proj::aws:s3/bucket:Bucket::logs", Type: "aws:s3/bucket:Bucket"}
+	component := &resource.State{
+		URN: "urn:pulumi:stack::proj::my:index:Component::comp", Type: "my:index:Component"}
+	child := &resource.State{
+		URN:    "urn:pulumi:stack::proj::my:index:Component$aws:s3/bucket:Bucket::data",
+		Type:   "aws:s3/bucket:Bucket",
+		Parent: component.URN,
+	}
+	grandchild := &resource.State{
+		URN:    "urn:pulumi:stack::proj::my:index:Component$aws:s3/bucket:Bucket$aws:s3/bucketPolicy:BucketPolicy::data",
+		Type:   "aws:s3/bucketPolicy:BucketPolicy",
+		Parent: child.URN,
+	}
+	resources := []*resource.State{bucket, component, child, grandchild}
+
+	cases := []struct {
+		targets  []resource.URN
+		expected map[resource.URN]bool
+	}{
+		{nil, nil},
+		{
+			[]resource.URN{"urn:pulumi:stack::proj::pkg:index:type::missing"},
+			map[resource.URN]bool{"urn:pulumi:stack::proj::pkg:index:type::missing": true},
+		},
+		{[]resource.URN{"**::aws:s3/bucket:Bucket::*"}, map[resource.URN]bool{bucket.URN: true}},
+		{[]resource.URN{"**aws:s3/bucket:Bucket::*"}, map[resource.URN]bool{bucket.URN: true, child.URN: true}},
+		{[]resource.URN{"type:aws:s3/*:*"}, map[resource.URN]bool{
+			bucket.URN: true, child.URN: true, grandchild.URN: true}},
+		{[]resource.URN{"children-of:" + component.URN}, map[resource.URN]bool{child.URN: true, grandchild.URN: true}},
+		{[]resource.URN{"children-of:**::comp", bucket.URN}, map[resource.URN]bool{
+			bucket.URN: true, child.URN: true, grandchild.URN: true}},
+		{[]resource.URN{"type:no:such:Type"}, map[resource.URN]bool{}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, resolveTargets(c.targets, resources), "targets: %v", c.targets)
+	}
+}
+
+func TestSelectResources(t *testing.T) {
+	t.Parallel()
+
+	a := &resource.State{URN: "urn:pulumi:stack::proj::aws:s3/bucket:Bucket::a", Type: "aws:s3/bucket:Bucket"}
+	b := &resource.State{URN: "urn:pulumi:stack::proj::aws:rds/instance:Instance::b", Type: "aws:rds/instance:Instance"}
+	c := &resource.State{URN: "urn:pulumi:stack::proj::aws:s3/bucket:Bucket::c", Type: "aws:s3/bucket:Bucket"}
+	snap := &Snapshot{Resources: []*resource.State{a, b, c}}
+
+	assert.Equal(t, []*resource.State{a, c}, snap.SelectResources([]resource.URN{"type:aws:s3/bucket:Bucket"}))
+	assert.Equal(t, []*resource.State{a, b}, snap.SelectResources([]resource.URN{b.URN, "**::a"}))
+	assert.Empty(t, snap.SelectResources([]resource.URN{"urn:pulumi:stack::proj::aws:s3/bucket:Bucket::d"}))
+}
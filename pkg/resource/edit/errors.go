@@ -39,3 +39,14 @@ type ResourceProtectedError struct {
 func (ResourceProtectedError) Error() string {
 	return "Can't delete protected resource"
 }
+
+// ResourceHasDependentsError is returned by MoveResources if a resource can't be moved due to the presence of
+// resources that depend upon it and that would remain behind.
+type ResourceHasDependentsError struct {
+	Moved      *resource.State
+	Dependents []*resource.State
+}
+
+func (r ResourceHasDependentsError) Error() string {
+	return fmt.Sprintf("Can't move resource %q due to dependent resources", r.Moved.URN)
+}
@@ -73,6 +73,12 @@ func DeleteResource(snapshot *deploy.Snapshot, condemnedRes *resource.State) err
 	return nil
 }
 
+// ProtectResource protects a resource.
+func ProtectResource(_ *deploy.Snapshot, res *resource.State) error {
+	res.Protect = true
+	return nil
+}
+
 // UnprotectResource unprotects a resource.
 func UnprotectResource(_ *deploy.Snapshot, res *resource.State) error {
 	res.Protect = false
@@ -120,43 +126,296 @@ func RenameStack(snap *deploy.Snapshot, newName tokens.Name, newProject tokens.P
 		contract.Assert(res != nil)
 
 		res.URN = rewriteUrn(res.URN)
+		rewriteReferences(res, rewriteUrn)
+	}
 
-		if res.Parent != "" {
-			res.Parent = rewriteUrn(res.Parent)
+	if err := snap.VerifyIntegrity(); err != nil {
+		return fmt.Errorf("checkpoint is invalid: %w", err)
+	}
+
+	for _, res := range snap.Resources {
+		rewriteState(res)
+	}
+
+	for _, ops := range snap.PendingOperations {
+		rewriteState(ops.Resource)
+	}
+
+	return nil
+}
+
+// rewriteReferences rewrites the URNs of the parent, dependencies and provider of the given resource in-place.
+func rewriteReferences(res *resource.State, rewriteUrn func(resource.URN) resource.URN) {
+	if res.Parent != "" {
+		res.Parent = rewriteUrn(res.Parent)
+	}
+
+	for depIdx, dep := range res.Dependencies {
+		res.Dependencies[depIdx] = rewriteUrn(dep)
+	}
+
+	for _, propDeps := range res.PropertyDependencies {
+		for depIdx, dep := range propDeps {
+			propDeps[depIdx] = rewriteUrn(dep)
 		}
+	}
+
+	if res.Provider != "" {
+		providerRef, err := providers.ParseReference(res.Provider)
+		contract.AssertNoErrorf(err, "failed to parse provider reference from validated checkpoint")
 
-		for depIdx, dep := range res.Dependencies {
-			res.Dependencies[depIdx] = rewriteUrn(dep)
+		providerRef, err = providers.NewReference(rewriteUrn(providerRef.URN()), providerRef.ID())
+		contract.AssertNoErrorf(err, "failed to generate provider reference from valid reference")
+
+		res.Provider = providerRef.String()
+	}
+}
+
+// MoveResources moves the resources with the given URNs from the source snapshot into the destination snapshot. The
+// children of each resource are moved along with it. The URNs of the moved resources are rewritten to belong to the
+// given stack and project, and the moved resources are appended to the destination snapshot. Both snapshots are
+// edited in-place and the moved resources are returned.
+//
+// The providers used by the moved resources are copied to the destination, unless it already holds a provider with
+// the same URN, in which case the moved resources are changed to use that provider instead. A provider is removed
+// from the source only if none of the resources that remain there use it. Dependencies on resources that remain in
+// the source are dropped, and resources whose parent remains in the source are parented to the destination's root
+// stack resource, which is created if the destination does not have one yet.
+//
+// Resources can't be moved if there exist resources remaining in the source that depend on them. If such a resource
+// does exist, MoveResources returns an error instance of `ResourceHasDependentsError`. Resources with pending
+// operations can't be moved either, since those operations can't be resolved once the resources have moved.
+func MoveResources(source, dest *deploy.Snapshot, urns []resource.URN,
+	stackName tokens.Name, project tokens.PackageName) ([]*resource.State, error) {
+	contract.Require(source != nil, "source")
+	contract.Require(dest != nil, "dest")
+
+	requested := make(map[resource.URN]bool)
+	for _, urn := range urns {
+		candidates := LocateResource(source, urn)
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no such resource %q exists in the source stack", urn)
+		}
+		if urn.Type() == resource.RootStackType {
+			return nil, fmt.Errorf("the root stack resource %q can't be moved", urn)
 		}
+		requested[urn] = true
+	}
 
-		for _, propDeps := range res.PropertyDependencies {
-			for depIdx, dep := range propDeps {
-				propDeps[depIdx] = rewriteUrn(dep)
-			}
+	// Find the resources to move. Snapshots order parents before their children, so a single pass suffices to find
+	// every descendant of the requested resources.
+	moving := make(map[resource.URN]bool)
+	var moved, remaining []*resource.State
+	for _, res := range source.Resources {
+		if requested[res.URN] || (res.Parent != "" && moving[res.Parent]) {
+			moving[res.URN] = true
+			moved = append(moved, res)
+		} else {
+			remaining = append(remaining, res)
 		}
+	}
 
+	// Make sure that nothing that remains in the source refers to a moved resource.
+	providersInUse := make(map[resource.URN]bool)
+	dependents := make(map[resource.URN][]*resource.State)
+	for _, res := range remaining {
+		var dependencies []resource.URN
+		dependencies = append(dependencies, res.Dependencies...)
+		for _, deps := range res.PropertyDependencies {
+			dependencies = append(dependencies, deps...)
+		}
 		if res.Provider != "" {
-			providerRef, err := providers.ParseReference(res.Provider)
-			contract.AssertNoErrorf(err, "failed to parse provider reference from validated checkpoint")
+			ref, err := providers.ParseReference(res.Provider)
+			if err != nil {
+				return nil, err
+			}
+			providersInUse[ref.URN()] = true
+			dependencies = append(dependencies, ref.URN())
+		}
 
-			providerRef, err = providers.NewReference(rewriteUrn(providerRef.URN()), providerRef.ID())
-			contract.AssertNoErrorf(err, "failed to generate provider reference from valid reference")
+		for _, dep := range dependencies {
+			if n := len(dependents[dep]); moving[dep] && (n == 0 || dependents[dep][n-1] != res) {
+				dependents[dep] = append(dependents[dep], res)
+			}
+		}
+	}
+	for _, res := range moved {
+		if len(dependents[res.URN]) != 0 {
+			return nil, ResourceHasDependentsError{Moved: res, Dependents: dependents[res.URN]}
+		}
+	}
 
-			res.Provider = providerRef.String()
+	// Moved resources are re-parented, so their URNs are rebuilt from their new parent chain, just as the engine
+	// generates them. Resources whose parent is not moved become children of the destination's root stack resource,
+	// whose type is not part of its children's URNs.
+	newURNs := make(map[resource.URN]resource.URN)
+	for _, res := range moved {
+		parentType := tokens.Type("")
+		if res.Parent != "" && moving[res.Parent] && res.Parent.Type() != resource.RootStackType {
+			parentType = newURNs[res.Parent].QualifiedType()
+		}
+		newURNs[res.URN] = resource.NewURN(stackName.Q(), project, parentType, res.URN.Type(), res.URN.Name())
+	}
+	rewriteUrn := func(u resource.URN) resource.URN {
+		if newURN, has := newURNs[u]; has {
+			return newURN
 		}
+		return resource.NewURN(stackName.Q(), project, "", u.Type(), u.Name())
 	}
 
-	if err := snap.VerifyIntegrity(); err != nil {
-		return fmt.Errorf("checkpoint is invalid: %w", err)
+	// Find the destination's root stack resource and providers.
+	var destRoot resource.URN
+	destProviders := make(map[resource.URN]*resource.State)
+	destURNs := make(map[resource.URN]bool)
+	for _, res := range dest.Resources {
+		if res.Type == resource.RootStackType && res.Parent == "" {
+			destRoot = res.URN
+		}
+		if providers.IsProviderType(res.Type) {
+			destProviders[res.URN] = res
+		}
+		destURNs[res.URN] = true
 	}
 
-	for _, res := range snap.Resources {
-		rewriteState(res)
+	// Copy or move the providers that the moved resources need but that are not moved themselves.
+	var copiedProviders []*resource.State
+	sourceProviders := make(map[resource.URN]bool)
+	movedProviders := make(map[resource.URN]bool)
+	for _, res := range moved {
+		if res.Provider == "" {
+			continue
+		}
+		ref, err := providers.ParseReference(res.Provider)
+		if err != nil {
+			return nil, err
+		}
+		urn := ref.URN()
+		if moving[urn] || sourceProviders[urn] {
+			continue
+		}
+		sourceProviders[urn] = true
+
+		if _, has := destProviders[rewriteUrn(urn)]; has {
+			continue
+		}
+
+		var provider *resource.State
+		for _, candidate := range LocateResource(source, urn) {
+			if candidate.ID == ref.ID() {
+				provider = candidate
+			}
+		}
+		if provider == nil {
+			return nil, fmt.Errorf("provider %q used by %q does not exist in the source stack", ref, res.URN)
+		}
+
+		if providersInUse[urn] {
+			provider = copyState(provider)
+		} else {
+			// Nothing else in the source uses this provider, so we can move it.
+			movedProviders[urn] = true
+			for i, r := range remaining {
+				if r == provider {
+					remaining = append(remaining[:i], remaining[i+1:]...)
+					break
+				}
+			}
+		}
+		copiedProviders = append(copiedProviders, provider)
 	}
 
-	for _, ops := range snap.PendingOperations {
-		rewriteState(ops.Resource)
+	// Rewrite the moved resources so that they belong to the destination.
+	toAppend := append(copiedProviders, moved...)
+	for _, res := range toAppend {
+		newURN := rewriteUrn(res.URN)
+		if destURNs[newURN] && !res.Delete {
+			return nil, fmt.Errorf("resource %q already exists in the destination stack", newURN)
+		}
+	}
+	for _, op := range source.PendingOperations {
+		if moving[op.Resource.URN] || movedProviders[op.Resource.URN] {
+			return nil, fmt.Errorf("resource %q has a pending %s operation; resolve it with `pulumi refresh` "+
+				"before moving the resource", op.Resource.URN, op.Type)
+		}
 	}
 
-	return nil
+	// Resources whose parent is not moved become children of the destination's root stack resource. If the
+	// destination does not have one yet, it is created, just as the engine would when the program first runs.
+	var root *resource.State
+	if destRoot == "" {
+		root = &resource.State{
+			Type:    resource.RootStackType,
+			URN:     resource.NewURN(stackName.Q(), project, "", resource.RootStackType, rootStackName(project, stackName)),
+			Inputs:  resource.PropertyMap{},
+			Outputs: resource.PropertyMap{},
+		}
+		destRoot = root.URN
+	}
+	createRoot := false
+
+	for _, res := range toAppend {
+		if res.Parent != "" && !moving[res.Parent] {
+			res.Parent = destRoot
+			createRoot = root != nil
+		}
+		res.Dependencies = keepMoving(res.Dependencies, moving)
+		for key, deps := range res.PropertyDependencies {
+			res.PropertyDependencies[key] = keepMoving(deps, moving)
+		}
+
+		res.URN = rewriteUrn(res.URN)
+		rewriteReferences(res, func(u resource.URN) resource.URN {
+			if u == destRoot {
+				return u
+			}
+			return rewriteUrn(u)
+		})
+
+		// Point the resource at the destination's own instance of its provider, if there is one.
+		if res.Provider != "" {
+			ref, err := providers.ParseReference(res.Provider)
+			contract.AssertNoErrorf(err, "failed to parse provider reference from validated checkpoint")
+			if provider, has := destProviders[ref.URN()]; has {
+				ref, err = providers.NewReference(provider.URN, provider.ID)
+				contract.AssertNoErrorf(err, "failed to generate provider reference from valid reference")
+				res.Provider = ref.String()
+			}
+		}
+	}
+
+	source.Resources = remaining
+	dest.Resources = append(dest.Resources, toAppend...)
+	if createRoot {
+		dest.Resources = append([]*resource.State{root}, dest.Resources...)
+	}
+	return moved, nil
+}
+
+// rootStackName returns the name of the root stack resource that the language SDKs register for the given stack.
+func rootStackName(project tokens.PackageName, stackName tokens.Name) tokens.QName {
+	return tokens.QName(project) + "-" + stackName.Q()
+}
+
+// keepMoving returns the URNs in the given list that refer to resources that are being moved.
+func keepMoving(urns []resource.URN, moving map[resource.URN]bool) []resource.URN {
+	var kept []resource.URN
+	for _, urn := range urns {
+		if moving[urn] {
+			kept = append(kept, urn)
+		}
+	}
+	return kept
+}
+
+// copyState returns a copy of the given resource that can be edited without affecting the original.
+func copyState(res *resource.State) *resource.State {
+	copied := *res
+	copied.Dependencies = append([]resource.URN(nil), res.Dependencies...)
+	if res.PropertyDependencies != nil {
+		copied.PropertyDependencies = make(map[resource.PropertyKey][]resource.URN, len(res.PropertyDependencies))
+		for key, deps := range res.PropertyDependencies {
+			copied.PropertyDependencies[key] = append([]resource.URN(nil), deps...)
+		}
+	}
+	return &copied
 }
@@ -217,6 +217,25 @@ func TestUnprotectResource(t *testing.T) {
 	assert.False(t, a.Protect)
 }
 
+func TestProtectResource(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	b := NewResource("b", pA)
+	snap := NewSnapshot([]*resource.State{
+		pA,
+		a,
+		b,
+	})
+
+	err := ProtectResource(snap, a)
+	assert.NoError(t, err)
+	assert.Equal(t, []*resource.State{pA, a, b}, snap.Resources)
+	assert.True(t, a.Protect)
+	assert.False(t, b.Protect)
+}
+
 func TestLocateResourceNotFound(t *testing.T) {
 	t.Parallel()
 
@@ -343,3 +362,177 @@ func TestRenameStack(t *testing.T) {
 		assert.Len(t, LocateResource(snap, updatedResourceURN), 1)
 	})
 }
+
+func TestMoveResources(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	pB := NewProviderResource("b", "p2", "1")
+	a := NewResource("a", pA)
+	b := NewResource("b", pA, a.URN)
+	c := NewResource("c", pB, b.URN)
+	c.Parent = b.URN
+	d := NewResource("d", pA)
+	source := NewSnapshot([]*resource.State{pA, pB, a, b, c, d})
+	dest := NewSnapshot(nil)
+
+	moved, err := MoveResources(source, dest, []resource.URN{b.URN}, "dest", "proj")
+	assert.NoError(t, err)
+	assert.Equal(t, []*resource.State{b, c}, moved)
+	assert.NoError(t, source.VerifyIntegrity())
+	assert.NoError(t, dest.VerifyIntegrity())
+
+	// pA is still used by a and d, so it is copied. pB is only used by c, so it is moved.
+	assert.Equal(t, []*resource.State{pA, a, d}, source.Resources)
+	assert.Len(t, dest.Resources, 4)
+	for _, res := range dest.Resources {
+		assert.EqualValues(t, "dest", res.URN.Stack())
+		assert.EqualValues(t, "proj", res.URN.Project())
+	}
+	assert.EqualValues(t, "test", pA.URN.Stack())
+	assert.Same(t, pB, dest.Resources[1])
+
+	// The dependency of b on a, which stays behind, is dropped. The edges between moved resources are rewritten.
+	assert.Empty(t, b.Dependencies)
+	assert.Equal(t, []resource.URN{b.URN}, c.Dependencies)
+	assert.Equal(t, b.URN, c.Parent)
+	ref, err := providers.ParseReference(c.Provider)
+	assert.NoError(t, err)
+	assert.Equal(t, pB.URN, ref.URN())
+}
+
+func TestMoveResourcesReusesDestinationProvider(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	source := NewSnapshot([]*resource.State{pA, a})
+
+	destProvider := NewProviderResource("a", "p1", "1")
+	destProvider.URN = resource.NewURN("dest", "test", "", destProvider.Type, "p1")
+	dest := NewSnapshot([]*resource.State{destProvider})
+
+	_, err := MoveResources(source, dest, []resource.URN{a.URN}, "dest", "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []*resource.State{destProvider, a}, dest.Resources)
+	assert.NoError(t, dest.VerifyIntegrity())
+
+	ref, err := providers.ParseReference(a.Provider)
+	assert.NoError(t, err)
+	assert.Equal(t, destProvider.URN, ref.URN())
+	assert.Equal(t, destProvider.ID, ref.ID())
+}
+
+func TestFailedMoveResourcesDependency(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	b := NewResource("b", pA, a.URN)
+	source := NewSnapshot([]*resource.State{pA, a, b})
+	dest := NewSnapshot(nil)
+
+	_, err := MoveResources(source, dest, []resource.URN{a.URN}, "dest", "test")
+	assert.Error(t, err)
+	depErr, ok := err.(ResourceHasDependentsError)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	assert.Equal(t, a, depErr.Moved)
+	assert.Equal(t, []*resource.State{b}, depErr.Dependents)
+	assert.Empty(t, dest.Resources)
+}
+
+func TestFailedMoveResourcesConflict(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	source := NewSnapshot([]*resource.State{pA, a})
+	dest := NewSnapshot([]*resource.State{NewResource("a", nil)})
+
+	_, err := MoveResources(source, dest, []resource.URN{a.URN}, "test", "test")
+	assert.Error(t, err)
+	assert.Len(t, source.Resources, 2)
+	assert.Len(t, dest.Resources, 1)
+}
+
+func TestMoveResourcesCreatesDestinationRoot(t *testing.T) {
+	t.Parallel()
+
+	root := &resource.State{
+		Type:    resource.RootStackType,
+		URN:     resource.NewURN("test", "test", "", resource.RootStackType, "test-test"),
+		Inputs:  resource.PropertyMap{},
+		Outputs: resource.PropertyMap{},
+	}
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	a.Parent = root.URN
+	source := NewSnapshot([]*resource.State{root, pA, a})
+	dest := NewSnapshot(nil)
+
+	_, err := MoveResources(source, dest, []resource.URN{a.URN}, "dest", "proj")
+	assert.NoError(t, err)
+	assert.NoError(t, dest.VerifyIntegrity())
+
+	// The destination had no root stack resource, so one is created for the moved resource to be parented to.
+	if assert.Len(t, dest.Resources, 3) {
+		destRoot := dest.Resources[0]
+		assert.Equal(t, resource.NewURN("dest", "proj", "", resource.RootStackType, "proj-dest"), destRoot.URN)
+		assert.Equal(t, destRoot.URN, a.Parent)
+	}
+}
+
+func TestMoveResourcesRebuildsQualifiedTypes(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	component := &resource.State{
+		Type:    "my:mod:Component",
+		URN:     resource.NewURN("test", "test", "", "my:mod:Component", "comp"),
+		Inputs:  resource.PropertyMap{},
+		Outputs: resource.PropertyMap{},
+	}
+	a := NewResource("a", pA)
+	a.URN = resource.NewURN("test", "test", component.URN.QualifiedType(), a.Type, "a")
+	a.Parent = component.URN
+	b := NewResource("b", pA)
+	b.URN = resource.NewURN("test", "test", a.URN.QualifiedType(), b.Type, "b")
+	b.Parent = a.URN
+	source := NewSnapshot([]*resource.State{pA, component, a, b})
+	dest := NewSnapshot(nil)
+
+	_, err := MoveResources(source, dest, []resource.URN{a.URN}, "dest", "test")
+	assert.NoError(t, err)
+	assert.NoError(t, dest.VerifyIntegrity())
+
+	// a is re-parented to the destination's root stack, so its URN loses the component's type. b stays a child of a,
+	// so its URN is qualified by a's new type.
+	assert.Equal(t, resource.NewURN("dest", "test", "", a.Type, "a"), a.URN)
+	assert.Equal(t, resource.NewURN("dest", "test", a.Type, b.Type, "b"), b.URN)
+	assert.Equal(t, a.URN, b.Parent)
+}
+
+func TestFailedMoveResourcesPendingOperation(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	source := NewSnapshot([]*resource.State{pA, a})
+	source.PendingOperations = []resource.Operation{resource.NewOperation(a, resource.OperationTypeUpdating)}
+	dest := NewSnapshot(nil)
+
+	_, err := MoveResources(source, dest, []resource.URN{a.URN}, "dest", "test")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "pending updating operation")
+	}
+	assert.Len(t, source.Resources, 2)
+	assert.Empty(t, dest.Resources)
+
+	// The provider is moved along with a, so a pending operation on it prevents the move too.
+	source.PendingOperations = []resource.Operation{resource.NewOperation(pA, resource.OperationTypeUpdating)}
+	_, err = MoveResources(source, dest, []resource.URN{a.URN}, "dest", "test")
+	assert.Error(t, err)
+	assert.Len(t, source.Resources, 2)
+}
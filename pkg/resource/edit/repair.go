@@ -0,0 +1,308 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/providers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// Repair is a proposed fix for a single problem that makes a snapshot fail `VerifyIntegrity`.
+type Repair struct {
+	// Problem describes what is wrong with the snapshot.
+	Problem string
+	// Fix describes how the problem will be repaired.
+	Fix string
+
+	apply func()
+}
+
+// Apply applies the repair to the snapshot it was proposed for. Repairs must be applied in the order in which they were
+// proposed.
+func (r Repair) Apply() {
+	r.apply()
+}
+
+// ProposeRepairs inspects the given snapshot for the problems that `VerifyIntegrity` detects and proposes a repair for
+// each of them. The snapshot is not modified until the repairs are applied. Applying every proposed repair, in order,
+// produces a snapshot that passes `VerifyIntegrity` unless it contains a provider that is not referenceable, which
+// can't be repaired automatically.
+func ProposeRepairs(snap *deploy.Snapshot) []Repair {
+	contract.Require(snap != nil, "snap")
+
+	var repairs []Repair
+
+	if snap.Manifest.Magic != snap.Manifest.NewMagic() {
+		repairs = append(repairs, Repair{
+			Problem: "the manifest's magic cookie does not match its contents",
+			Fix:     "recompute the magic cookie",
+			apply: func() {
+				snap.Manifest.Magic = snap.Manifest.NewMagic()
+			},
+		})
+	}
+
+	// Gather every URN, provider and the root stack resource up front, so that we can tell references to missing
+	// resources apart from references to resources that are merely out of order.
+	var root *resource.State
+	all := make(map[resource.URN]bool)
+	provs := make(map[resource.URN]*resource.State)
+	for _, res := range snap.Resources {
+		all[res.URN] = true
+		if res.Type == resource.RootStackType && res.Parent == "" && root == nil {
+			root = res
+		}
+		if providers.IsProviderType(res.Type) && !res.Delete {
+			provs[res.URN] = res
+		}
+	}
+
+	// Resources that share a URN are only legal if all but one are pending deletion. Keep the first such resource and
+	// remove the others from the snapshot. They must not be marked as pending deletion instead: duplicates usually share
+	// the cloud ID of the resource that is kept, so the next update would delete the live resource.
+	live := make(map[resource.URN]*resource.State)
+	for _, res := range snap.Resources {
+		if res.Delete {
+			continue
+		}
+		if kept, has := live[res.URN]; has {
+			res := res
+			fix := fmt.Sprintf("remove the duplicate entry for %s from the state", res.URN)
+			if res.ID != kept.ID {
+				fix = fmt.Sprintf("remove the resource %s with ID %q from the state, leaving it unmanaged", res.URN, res.ID)
+			}
+			repairs = append(repairs, Repair{
+				Problem: fmt.Sprintf("there are several resources named %s that are not pending deletion", res.URN),
+				Fix:     fix,
+				apply: func() {
+					snap.Resources = removeResource(snap.Resources, res)
+				},
+			})
+			continue
+		}
+		live[res.URN] = res
+	}
+
+	seen := make(map[resource.URN]bool)
+	seenProvs := make(map[providers.Reference]bool)
+	outOfOrder := false
+	for _, res := range snap.Resources {
+		res := res
+
+		if res.Provider != "" {
+			ref, err := providers.ParseReference(res.Provider)
+			switch {
+			case err != nil || provs[ref.URN()] == nil:
+				repairs = append(repairs, Repair{
+					Problem: fmt.Sprintf("resource %s refers to unknown provider %s", res.URN, res.Provider),
+					Fix:     "remove the provider reference, so that the default provider is used",
+					apply: func() {
+						res.Provider = ""
+					},
+				})
+			case ref.ID() != provs[ref.URN()].ID:
+				provider := provs[ref.URN()]
+				newRef, err := providers.NewReference(provider.URN, provider.ID)
+				if err != nil {
+					// The provider itself is not referenceable, which we can't repair.
+					break
+				}
+				repairs = append(repairs, Repair{
+					Problem: fmt.Sprintf("resource %s refers to unknown provider %s", res.URN, res.Provider),
+					Fix:     fmt.Sprintf("refer to the provider %s instead", newRef),
+					apply: func() {
+						res.Provider = newRef.String()
+					},
+				})
+			case !seenProvs[ref]:
+				outOfOrder = true
+			}
+		}
+
+		if res.Parent != "" {
+			switch {
+			case !all[res.Parent] || res.Parent == res.URN:
+				newParent := resource.URN("")
+				fix := "remove the parent"
+				if root != nil && root != res {
+					newParent = root.URN
+					fix = "parent the resource to the root stack resource"
+				}
+				repairs = append(repairs, Repair{
+					Problem: fmt.Sprintf("resource %s refers to missing parent %s", res.URN, res.Parent),
+					Fix:     fix,
+					apply: func() {
+						res.Parent = newParent
+					},
+				})
+			case !seen[res.Parent]:
+				outOfOrder = true
+			}
+		}
+
+		for _, dep := range res.Dependencies {
+			switch {
+			case !all[dep] || dep == res.URN:
+				dep := dep
+				repairs = append(repairs, Repair{
+					Problem: fmt.Sprintf("resource %s depends on missing resource %s", res.URN, dep),
+					Fix:     "drop the dependency",
+					apply: func() {
+						res.Dependencies = removeURN(res.Dependencies, dep)
+					},
+				})
+			case !seen[dep]:
+				outOfOrder = true
+			}
+		}
+
+		for key, deps := range res.PropertyDependencies {
+			for _, dep := range deps {
+				if !all[dep] {
+					key, dep := key, dep
+					repairs = append(repairs, Repair{
+						Problem: fmt.Sprintf("property %s of resource %s depends on missing resource %s", key, res.URN, dep),
+						Fix:     "drop the dependency",
+						apply: func() {
+							res.PropertyDependencies[key] = removeURN(res.PropertyDependencies[key], dep)
+						},
+					})
+				}
+			}
+		}
+
+		// The resource that is not pending deletion must precede those that are.
+		if seen[res.URN] && live[res.URN] == res {
+			outOfOrder = true
+		}
+
+		seen[res.URN] = true
+		if providers.IsProviderType(res.Type) {
+			if ref, err := providers.NewReference(res.URN, res.ID); err == nil {
+				seenProvs[ref] = true
+			}
+		}
+	}
+
+	for i, op := range snap.PendingOperations {
+		if op.Type == resource.OperationTypeCreating || op.Type == resource.OperationTypeImporting ||
+			op.Resource == nil || all[op.Resource.URN] {
+			continue
+		}
+
+		op := op
+		repairs = append(repairs, Repair{
+			Problem: fmt.Sprintf("pending operation %d (%s %s) refers to a resource that is not in the state",
+				i, op.Type, op.Resource.URN),
+			Fix: "remove the pending operation",
+			apply: func() {
+				for j, other := range snap.PendingOperations {
+					if other.Resource == op.Resource && other.Type == op.Type {
+						snap.PendingOperations = append(snap.PendingOperations[:j], snap.PendingOperations[j+1:]...)
+						break
+					}
+				}
+			},
+		})
+	}
+
+	// Reordering must come last, as the repairs above may change the references that determine the order.
+	if outOfOrder {
+		repairs = append(repairs, Repair{
+			Problem: "some resources come before their parents, dependencies or providers, or after pending deletions",
+			Fix:     "reorder the resources",
+			apply: func() {
+				snap.Resources = sortResources(snap.Resources)
+			},
+		})
+	}
+
+	return repairs
+}
+
+// removeResource returns the given list of resources without the given resource.
+func removeResource(resources []*resource.State, res *resource.State) []*resource.State {
+	kept := make([]*resource.State, 0, len(resources))
+	for _, r := range resources {
+		if r != res {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// removeURN returns the given list of URNs without any occurrence of the given URN.
+func removeURN(urns []resource.URN, urn resource.URN) []resource.URN {
+	var kept []resource.URN
+	for _, u := range urns {
+		if u != urn {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// sortResources orders the given resources so that every resource comes after its parent, dependencies and provider,
+// and after the resource with the same URN that is not pending deletion, while otherwise preserving their order.
+func sortResources(resources []*resource.State) []*resource.State {
+	byURN := make(map[resource.URN][]*resource.State)
+	for _, res := range resources {
+		byURN[res.URN] = append(byURN[res.URN], res)
+	}
+
+	visited := make(map[*resource.State]bool)
+	sorted := make([]*resource.State, 0, len(resources))
+	var visit func(res *resource.State)
+	visit = func(res *resource.State) {
+		if visited[res] {
+			return
+		}
+		visited[res] = true
+
+		var refs []resource.URN
+		if res.Parent != "" {
+			refs = append(refs, res.Parent)
+		}
+		refs = append(refs, res.Dependencies...)
+		if res.Provider != "" {
+			if ref, err := providers.ParseReference(res.Provider); err == nil {
+				refs = append(refs, ref.URN())
+			}
+		}
+		for _, urn := range refs {
+			for _, other := range byURN[urn] {
+				visit(other)
+			}
+		}
+		if res.Delete {
+			for _, other := range byURN[res.URN] {
+				if !other.Delete {
+					visit(other)
+				}
+			}
+		}
+
+		sorted = append(sorted, res)
+	}
+
+	for _, res := range resources {
+		visit(res)
+	}
+	return sorted
+}
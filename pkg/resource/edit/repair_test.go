@@ -0,0 +1,146 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edit
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func applyRepairs(repairs []Repair) {
+	for _, r := range repairs {
+		r.Apply()
+	}
+}
+
+func TestProposeRepairsValidSnapshot(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	b := NewResource("b", pA, a.URN)
+	snap := NewSnapshot([]*resource.State{pA, a, b})
+
+	assert.Empty(t, ProposeRepairs(snap))
+}
+
+func TestProposeRepairsDanglingReferences(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	missing := NewResource("missing", pA)
+	a := NewResource("a", pA)
+	b := NewResource("b", pA, a.URN, missing.URN)
+	b.Parent = missing.URN
+	b.PropertyDependencies = map[resource.PropertyKey][]resource.URN{
+		"foo": {a.URN, missing.URN},
+	}
+	c := NewResource("c", NewProviderResource("a", "p2", "1"))
+	snap := NewSnapshot([]*resource.State{pA, a, b, c})
+	snap.Manifest.Magic = "bad"
+
+	repairs := ProposeRepairs(snap)
+	assert.Len(t, repairs, 5)
+	assert.Error(t, snap.VerifyIntegrity())
+
+	applyRepairs(repairs)
+	assert.NoError(t, snap.VerifyIntegrity())
+	assert.Equal(t, resource.URN(""), b.Parent)
+	assert.Equal(t, []resource.URN{a.URN}, b.Dependencies)
+	assert.Equal(t, []resource.URN{a.URN}, b.PropertyDependencies["foo"])
+	assert.Equal(t, "", c.Provider)
+	assert.Empty(t, ProposeRepairs(snap))
+}
+
+func TestProposeRepairsReparentsToRootStack(t *testing.T) {
+	t.Parallel()
+
+	stack := NewResource("stack", nil)
+	stack.Type = resource.RootStackType
+	a := NewResource("a", nil)
+	a.Parent = "urn:pulumi:test::test::a:b:c::missing"
+	snap := NewSnapshot([]*resource.State{stack, a})
+
+	repairs := ProposeRepairs(snap)
+	assert.Len(t, repairs, 1)
+
+	applyRepairs(repairs)
+	assert.NoError(t, snap.VerifyIntegrity())
+	assert.Equal(t, stack.URN, a.Parent)
+}
+
+func TestProposeRepairsDuplicatesAndOrder(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	b := NewResource("b", pA, a.URN)
+	oldB := NewResource("b", pA)
+	oldB.ID = "old"
+	snap := NewSnapshot([]*resource.State{oldB, b, a, pA})
+
+	repairs := ProposeRepairs(snap)
+	assert.Len(t, repairs, 2)
+
+	applyRepairs(repairs)
+	assert.NoError(t, snap.VerifyIntegrity())
+	assert.False(t, oldB.Delete)
+	assert.Equal(t, []*resource.State{pA, oldB, a}, snap.Resources)
+}
+
+func TestProposeRepairsDuplicatesSharingID(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	dup := NewResource("a", pA)
+	dup.ID = a.ID
+	snap := NewSnapshot([]*resource.State{pA, a, dup})
+
+	repairs := ProposeRepairs(snap)
+	assert.Len(t, repairs, 1)
+
+	// The duplicate must be dropped rather than marked as pending deletion, which would delete the live resource.
+	applyRepairs(repairs)
+	assert.NoError(t, snap.VerifyIntegrity())
+	assert.Equal(t, []*resource.State{pA, a}, snap.Resources)
+	assert.False(t, a.Delete)
+	assert.False(t, dup.Delete)
+}
+
+func TestProposeRepairsPendingOperations(t *testing.T) {
+	t.Parallel()
+
+	a := NewResource("a", nil)
+	gone := NewResource("gone", nil)
+	created := NewResource("created", nil)
+	snap := NewSnapshot([]*resource.State{a})
+	snap.PendingOperations = []resource.Operation{
+		resource.NewOperation(a, resource.OperationTypeUpdating),
+		resource.NewOperation(gone, resource.OperationTypeDeleting),
+		resource.NewOperation(created, resource.OperationTypeCreating),
+	}
+
+	repairs := ProposeRepairs(snap)
+	assert.Len(t, repairs, 1)
+
+	applyRepairs(repairs)
+	assert.Len(t, snap.PendingOperations, 2)
+	assert.Equal(t, a, snap.PendingOperations[0].Resource)
+	assert.Equal(t, created, snap.PendingOperations[1].Resource)
+}
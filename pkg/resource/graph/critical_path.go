@@ -0,0 +1,70 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// CriticalPath returns the chain of dependencies whose durations add up to the longest time, along with that time.
+// Because a resource can't be operated on until all of its dependencies have been, this chain bounds how quickly the
+// resources in the graph can be deployed, however many operations run in parallel. The chain is returned in
+// dependency order, starting with the resource that doesn't depend on any of the others.
+//
+// durations maps the URNs of resources to how long they took. Resources that have no duration are treated as having
+// taken no time, and are left out of the returned chain.
+//
+// The time complexity of CriticalPath is quadratic with respect to the number of resources.
+func (dg *DependencyGraph) CriticalPath(durations map[resource.URN]time.Duration) ([]*resource.State, time.Duration) {
+	// Resources are in topological order, so the longest chain ending at each resource can be computed from those
+	// ending at its dependencies in a single pass.
+	finish := make([]time.Duration, len(dg.resources))
+	previous := make([]int, len(dg.resources))
+	last := -1
+	for i, res := range dg.resources {
+		previous[i] = -1
+		for dep := range dg.DependenciesOf(res) {
+			j := dg.index[dep]
+			// Break ties in favor of the earliest dependency so that the result is deterministic.
+			if previous[i] == -1 || finish[j] > finish[previous[i]] ||
+				finish[j] == finish[previous[i]] && j < previous[i] {
+				previous[i] = j
+			}
+		}
+		if previous[i] != -1 {
+			finish[i] = finish[previous[i]]
+		}
+		finish[i] += durations[res.URN]
+		if last == -1 || finish[i] > finish[last] {
+			last = i
+		}
+	}
+	if last == -1 {
+		return nil, 0
+	}
+
+	var path []*resource.State
+	for i := last; i != -1; i = previous[i] {
+		if _, ok := durations[dg.resources[i].URN]; ok {
+			path = append(path, dg.resources[i])
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, finish[last]
+}
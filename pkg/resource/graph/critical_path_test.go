@@ -0,0 +1,81 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestCriticalPath(t *testing.T) {
+	t.Parallel()
+
+	// pA <- a <- b <- d
+	//        ^
+	//        +-- c (slow, but only one hop)
+	pA := NewProviderResource("test", "pA", "0")
+	a := NewResource("a", pA)
+	b := NewResource("b", pA, a.URN)
+	c := NewResource("c", pA, a.URN)
+	d := NewResource("d", pA, b.URN)
+	dg := NewDependencyGraph([]*resource.State{pA, a, b, c, d})
+
+	path, total := dg.CriticalPath(map[resource.URN]time.Duration{
+		a.URN: 10 * time.Second,
+		b.URN: 20 * time.Second,
+		c.URN: 35 * time.Second,
+		d.URN: 20 * time.Second,
+	})
+	assert.Equal(t, []*resource.State{a, b, d}, path)
+	assert.Equal(t, 50*time.Second, total)
+
+	path, total = dg.CriticalPath(map[resource.URN]time.Duration{
+		a.URN: 10 * time.Second,
+		c.URN: 35 * time.Second,
+	})
+	assert.Equal(t, []*resource.State{a, c}, path)
+	assert.Equal(t, 45*time.Second, total)
+}
+
+func TestCriticalPathIncludesParents(t *testing.T) {
+	t.Parallel()
+
+	pA := NewProviderResource("test", "pA", "0")
+	parent := NewResource("parent", pA)
+	child := NewResource("child", pA)
+	child.Parent = parent.URN
+	other := NewResource("other", pA)
+	dg := NewDependencyGraph([]*resource.State{pA, parent, child, other})
+
+	path, total := dg.CriticalPath(map[resource.URN]time.Duration{
+		parent.URN: 5 * time.Second,
+		child.URN:  5 * time.Second,
+		other.URN:  8 * time.Second,
+	})
+	assert.Equal(t, []*resource.State{parent, child}, path)
+	assert.Equal(t, 10*time.Second, total)
+}
+
+func TestCriticalPathEmpty(t *testing.T) {
+	t.Parallel()
+
+	path, total := NewDependencyGraph(nil).CriticalPath(nil)
+	assert.Empty(t, path)
+	assert.Equal(t, time.Duration(0), total)
+}
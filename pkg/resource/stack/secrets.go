@@ -15,16 +15,19 @@
 package stack
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 
 	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/pkg/v3/secrets/age"
 	"github.com/pulumi/pulumi/pkg/v3/secrets/b64"
 	"github.com/pulumi/pulumi/pkg/v3/secrets/cloud"
 	"github.com/pulumi/pulumi/pkg/v3/secrets/passphrase"
 	"github.com/pulumi/pulumi/pkg/v3/secrets/service"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
 )
 
 // DefaultSecretsProvider is the default SecretsProvider to use when deserializing deployments.
@@ -55,6 +58,8 @@ func (defaultSecretsProvider) OfType(ty string, state json.RawMessage) (secrets.
 		sm, err = service.NewServiceSecretsManagerFromState(state)
 	case cloud.Type:
 		sm, err = cloud.NewCloudSecretsManagerFromState(state)
+	case age.Type:
+		sm, err = age.NewAgeSecretsManagerFromState(state)
 	default:
 		return nil, fmt.Errorf("no known secrets provider for type %q", ty)
 	}
@@ -229,3 +234,38 @@ func (c *mapDecrypter) BulkDecrypt(ciphertexts []string) (map[string]string, err
 
 	return secretMap, nil
 }
+
+// NewConfigLayerDecrypter returns a decrypter for the secure values in a stack settings file that a stack's config
+// extends. They are encrypted by the secrets provider of that file, which must be a passphrase, age or cloud secrets
+// provider, since the file's secrets can't be decrypted by the service on behalf of a stack that extends it.
+func NewConfigLayerDecrypter(layer workspace.ProjectStackLayer) (config.Decrypter, error) {
+	ps := layer.Stack
+	var sm secrets.Manager
+	var err error
+	switch {
+	case ps.SecretsProvider == age.Type:
+		dataKey, err := base64.StdEncoding.DecodeString(ps.EncryptedKey)
+		if err != nil {
+			return nil, fmt.Errorf("decoding the encrypted key of %s: %w", layer.Path, err)
+		}
+		if sm, err = age.NewAgeSecretsManager(ps.SecretsRecipients, dataKey); err != nil {
+			return nil, err
+		}
+	case ps.SecretsProvider != "" && ps.SecretsProvider != passphrase.Type && ps.SecretsProvider != "default":
+		dataKey, err := base64.StdEncoding.DecodeString(ps.EncryptedKey)
+		if err != nil {
+			return nil, fmt.Errorf("decoding the encrypted key of %s: %w", layer.Path, err)
+		}
+		if sm, err = cloud.NewCloudSecretsManager(ps.SecretsProvider, dataKey); err != nil {
+			return nil, err
+		}
+	case ps.EncryptionSalt != "":
+		if sm, err = passphrase.NewPromptingPassphraseSecretsManager(ps.EncryptionSalt); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s has secrets but no passphrase, age or cloud secrets provider to decrypt them",
+			layer.Path)
+	}
+	return sm.Decrypter()
+}
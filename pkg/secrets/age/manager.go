@@ -0,0 +1,257 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package age implements a secrets manager that encrypts a stack's data key to the public keys of a set of age
+// recipients, so that any of them can decrypt the stack's secrets with their own private key.
+package age
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	agelib "filippo.io/age"
+
+	"github.com/pulumi/pulumi/pkg/v3/secrets"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// Type is the type of secrets managed by this secrets provider.
+const Type = "age"
+
+// The environment variables from which the age identities that decrypt a stack's data key are read. Each holds the
+// content, or the path, of an identity file as written by age-keygen, which may hold several identities.
+const (
+	KeyEnvVar     = "PULUMI_AGE_KEY"
+	KeyFileEnvVar = "PULUMI_AGE_KEY_FILE"
+)
+
+type ageSecretsManagerState struct {
+	Recipients   []string `json:"recipients"`
+	EncryptedKey []byte   `json:"encryptedkey"`
+}
+
+// NewAgeSecretsManagerFromState deserializes configuration from state and returns a secrets manager that decrypts
+// the data key used for envelope encryption of secrets values with the age identities from the environment.
+func NewAgeSecretsManagerFromState(state json.RawMessage) (secrets.Manager, error) {
+	var s ageSecretsManagerState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, fmt.Errorf("unmarshalling state: %w", err)
+	}
+
+	return NewAgeSecretsManager(s.Recipients, s.EncryptedKey)
+}
+
+// IsSecretsProvider returns true if the given secrets provider is an age secrets provider, as accepted by
+// ParseSecretsProvider.
+func IsSecretsProvider(secretsProvider string) bool {
+	return secretsProvider == Type || strings.HasPrefix(secretsProvider, Type+"://")
+}
+
+// ParseSecretsProvider returns the recipients named by an age secrets provider, which is either `age://` followed by
+// a comma-separated list of recipients, or just `age`, which names the recipients of the identities from the
+// environment.
+func ParseSecretsProvider(secretsProvider string) ([]string, error) {
+	list := strings.TrimPrefix(strings.TrimPrefix(secretsProvider, Type), "://")
+	if list == "" {
+		return DefaultRecipients()
+	}
+	return normalizeRecipients(strings.Split(list, ","))
+}
+
+// DefaultRecipients returns the recipients of the age identities from the environment.
+func DefaultRecipients() ([]string, error) {
+	identities, err := loadIdentities()
+	if err != nil {
+		return nil, err
+	}
+	var recipients []string
+	for _, id := range identities {
+		if x25519, ok := id.(*agelib.X25519Identity); ok {
+			recipients = append(recipients, x25519.Recipient().String())
+		}
+	}
+	return normalizeRecipients(recipients)
+}
+
+// GenerateNewDataKey generates a new data key seeded by a fresh random 32-byte key and encrypted to the given
+// recipients.
+func GenerateNewDataKey(recipients []string) ([]byte, error) {
+	plaintextDataKey := make([]byte, 32)
+	if _, err := rand.Read(plaintextDataKey); err != nil {
+		return nil, err
+	}
+	return encryptDataKey(recipients, plaintextDataKey)
+}
+
+// NewAgeSecretsManager returns a secrets manager that decrypts a data key encrypted to the given recipients with the
+// age identities from the environment, and uses it for envelope encryption of secrets values. The data key is only
+// decrypted once the manager's encrypter or decrypter is first needed.
+func NewAgeSecretsManager(recipients []string, encryptedDataKey []byte) (*Manager, error) {
+	recipients, err := normalizeRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{
+		state: ageSecretsManagerState{
+			Recipients:   recipients,
+			EncryptedKey: encryptedDataKey,
+		},
+	}, nil
+}
+
+// Manager is the secrets.Manager implementation for age.
+type Manager struct {
+	state ageSecretsManagerState
+
+	once    sync.Once
+	crypter config.Crypter
+	err     error
+}
+
+func (m *Manager) Type() string         { return Type }
+func (m *Manager) State() interface{}   { return m.state }
+func (m *Manager) Recipients() []string { return m.state.Recipients }
+func (m *Manager) EncryptedKey() []byte { return m.state.EncryptedKey }
+
+func (m *Manager) Encrypter() (config.Encrypter, error) { return m.getCrypter() }
+func (m *Manager) Decrypter() (config.Decrypter, error) { return m.getCrypter() }
+
+// WithRecipients returns a secrets manager with the same data key as this one, encrypted to the given recipients
+// instead. Secrets encrypted by either manager can be decrypted by the other, so recipients can be added and removed
+// without reencrypting them. A recipient that is removed may have kept the data key, though, so to revoke its access
+// the data key must be replaced with a new one.
+func (m *Manager) WithRecipients(recipients []string) (*Manager, error) {
+	plaintextDataKey, err := m.decryptDataKey()
+	if err != nil {
+		return nil, err
+	}
+	recipients, err = normalizeRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+	encryptedDataKey, err := encryptDataKey(recipients, plaintextDataKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewAgeSecretsManager(recipients, encryptedDataKey)
+}
+
+func (m *Manager) getCrypter() (config.Crypter, error) {
+	m.once.Do(func() {
+		var plaintextDataKey []byte
+		plaintextDataKey, m.err = m.decryptDataKey()
+		if m.err == nil {
+			m.crypter = config.NewSymmetricCrypter(plaintextDataKey)
+		}
+	})
+	return m.crypter, m.err
+}
+
+func (m *Manager) decryptDataKey() ([]byte, error) {
+	identities, err := loadIdentities()
+	if err != nil {
+		return nil, err
+	}
+	r, err := agelib.Decrypt(bytes.NewReader(m.state.EncryptedKey), identities...)
+	if err != nil {
+		var noMatch *agelib.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, fmt.Errorf("none of the age identities in %s or %s is a recipient of the secrets (%s)",
+				KeyEnvVar, KeyFileEnvVar, strings.Join(m.state.Recipients, ", "))
+		}
+		return nil, fmt.Errorf("decrypting the data key: %w", err)
+	}
+	return ioutil.ReadAll(r)
+}
+
+func encryptDataKey(recipients []string, plaintextDataKey []byte) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("the age secrets provider needs at least one recipient")
+	}
+	parsed := make([]agelib.Recipient, len(recipients))
+	for i, s := range recipients {
+		r, err := agelib.ParseX25519Recipient(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", s, err)
+		}
+		parsed[i] = r
+	}
+
+	var buf bytes.Buffer
+	w, err := agelib.Encrypt(&buf, parsed...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintextDataKey); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeRecipients checks that each of the given recipients is an age public key, and returns them sorted and
+// without duplicates.
+func normalizeRecipients(recipients []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	for _, s := range recipients {
+		s = strings.TrimSpace(s)
+		if _, err := agelib.ParseX25519Recipient(s); err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", s, err)
+		}
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// loadIdentities reads the age identities from the environment.
+func loadIdentities() ([]agelib.Identity, error) {
+	var r io.Reader
+	var source string
+	if key := os.Getenv(KeyEnvVar); key != "" {
+		r, source = strings.NewReader(key), KeyEnvVar
+	} else if path := os.Getenv(KeyFileEnvVar); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading age identities: %w", err)
+		}
+		defer contract.IgnoreClose(f)
+		r, source = f, path
+	} else {
+		return nil, fmt.Errorf("set %s or %s to an age identity to decrypt the stack's secrets",
+			KeyEnvVar, KeyFileEnvVar)
+	}
+
+	identities, err := agelib.ParseIdentities(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing age identities from %s: %w", source, err)
+	}
+	return identities, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package age
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agelib "filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdentity(t *testing.T) *agelib.X25519Identity {
+	id, err := agelib.GenerateX25519Identity()
+	require.NoError(t, err)
+	return id
+}
+
+//nolint:paralleltest // sets environment variables
+func TestAgeSecretsManager(t *testing.T) {
+	alice, bob, carol := newIdentity(t), newIdentity(t), newIdentity(t)
+	t.Setenv(KeyEnvVar, alice.String())
+	t.Setenv(KeyFileEnvVar, "")
+
+	recipients := []string{alice.Recipient().String(), bob.Recipient().String()}
+	dataKey, err := GenerateNewDataKey(recipients)
+	require.NoError(t, err)
+	manager, err := NewAgeSecretsManager(recipients, dataKey)
+	require.NoError(t, err)
+	enc, err := manager.Encrypter()
+	require.NoError(t, err)
+	ciphertext, err := enc.EncryptValue("hunter2")
+	require.NoError(t, err)
+
+	// Bob can decrypt the secret with his own identity, read from a file.
+	keyFile := filepath.Join(t.TempDir(), "keys.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte("# bob\n"+bob.String()+"\n"), 0600))
+	t.Setenv(KeyEnvVar, "")
+	t.Setenv(KeyFileEnvVar, keyFile)
+	state, err := json.Marshal(manager.State())
+	require.NoError(t, err)
+	fromState, err := NewAgeSecretsManagerFromState(state)
+	require.NoError(t, err)
+	dec, err := fromState.Decrypter()
+	require.NoError(t, err)
+	plaintext, err := dec.DecryptValue(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", plaintext)
+
+	// Bob replaces Alice with Carol, who can decrypt the secret without it being reencrypted.
+	changed, err := fromState.(*Manager).WithRecipients([]string{bob.Recipient().String(), carol.Recipient().String()})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{bob.Recipient().String(), carol.Recipient().String()}, changed.Recipients())
+
+	t.Setenv(KeyEnvVar, carol.String())
+	manager, err = NewAgeSecretsManager(changed.Recipients(), changed.EncryptedKey())
+	require.NoError(t, err)
+	dec, err = manager.Decrypter()
+	require.NoError(t, err)
+	plaintext, err = dec.DecryptValue(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", plaintext)
+
+	// Alice no longer can.
+	t.Setenv(KeyEnvVar, alice.String())
+	manager, err = NewAgeSecretsManager(changed.Recipients(), changed.EncryptedKey())
+	require.NoError(t, err)
+	_, err = manager.Decrypter()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "none of the age identities")
+
+	t.Setenv(KeyEnvVar, "")
+	t.Setenv(KeyFileEnvVar, "")
+	_, err = NewAgeSecretsManager(changed.Recipients(), changed.EncryptedKey())
+	require.NoError(t, err)
+	_, err = changed.WithRecipients(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "set PULUMI_AGE_KEY or PULUMI_AGE_KEY_FILE")
+}
+
+//nolint:paralleltest // sets environment variables
+func TestParseSecretsProvider(t *testing.T) {
+	alice, bob := newIdentity(t), newIdentity(t)
+	t.Setenv(KeyEnvVar, alice.String())
+
+	recipients, err := ParseSecretsProvider("age")
+	require.NoError(t, err)
+	assert.Equal(t, []string{alice.Recipient().String()}, recipients)
+
+	recipients, err = ParseSecretsProvider("age://" + bob.Recipient().String() + "," + bob.Recipient().String())
+	require.NoError(t, err)
+	assert.Equal(t, []string{bob.Recipient().String()}, recipients)
+
+	_, err = ParseSecretsProvider("age://alice")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid age recipient "alice"`)
+}
@@ -0,0 +1,267 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	netUrl "net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+	gosecrets "gocloud.dev/secrets"
+	"gocloud.dev/secrets/driver"
+)
+
+// The ways in which the Vault transit secrets provider can authenticate with Vault, as given by the `auth` parameter
+// of a `hashivault://` URL.
+const (
+	// VaultAuthToken authenticates with the token in VAULT_TOKEN or VAULT_SERVER_TOKEN.
+	VaultAuthToken = "token"
+	// VaultAuthAppRole logs in with the role ID and secret ID in VAULT_ROLE_ID and VAULT_SECRET_ID.
+	VaultAuthAppRole = "approle"
+	// VaultAuthKubernetes logs in with the role in the URL's `role` parameter and the pod's service account token.
+	VaultAuthKubernetes = "kubernetes"
+)
+
+// defaultKubernetesTokenPath is where Kubernetes mounts the token of a pod's service account.
+const defaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// vaultKeeperOptions are the options of a Vault transit key, parsed from a URL of the form
+//
+//	hashivault://<key>?engine=<mount>&namespace=<namespace>&auth=<method>&authMount=<mount>&role=<role>
+//	    &keyVersion=<version>
+//
+// All of the parameters are optional. Credentials are never read from the URL, since it is saved in the stack's
+// settings and checkpoint.
+type vaultKeeperOptions struct {
+	// key is the name of the transit key.
+	key string
+	// engine is the path at which the transit secrets engine is mounted. It defaults to "transit".
+	engine string
+	// namespace is the Vault Enterprise namespace of the key. It defaults to VAULT_NAMESPACE.
+	namespace string
+	// auth is the auth method with which to log in. It defaults to VaultAuthToken.
+	auth string
+	// authMount is the path at which the auth method is mounted. It defaults to the name of the method.
+	authMount string
+	// role is the role to log in as with the Kubernetes auth method.
+	role string
+	// keyVersion is the version of the key with which to encrypt. Zero encrypts with the latest version. Ciphertexts
+	// record the version that encrypted them, so they are always decrypted with that version.
+	keyVersion int
+}
+
+func parseVaultKeeperURL(u *netUrl.URL) (vaultKeeperOptions, error) {
+	opts := vaultKeeperOptions{
+		key:    strings.TrimPrefix(path.Join(u.Host, u.Path), "/"),
+		engine: "transit",
+		auth:   VaultAuthToken,
+	}
+	if opts.key == "" {
+		return opts, errors.New("missing the name of the transit key")
+	}
+	for param, vals := range u.Query() {
+		val := vals[0]
+		switch param {
+		case "engine":
+			opts.engine = val
+		case "namespace":
+			opts.namespace = val
+		case "auth":
+			switch val {
+			case VaultAuthToken, VaultAuthAppRole, VaultAuthKubernetes:
+				opts.auth = val
+			default:
+				return opts, errors.Errorf("unknown auth method %q (supported values: %s, %s, %s)",
+					val, VaultAuthToken, VaultAuthAppRole, VaultAuthKubernetes)
+			}
+		case "authMount":
+			opts.authMount = val
+		case "role":
+			opts.role = val
+		case "keyVersion":
+			v, err := strconv.Atoi(val)
+			if err != nil || v < 0 {
+				return opts, errors.Errorf("invalid key version %q", val)
+			}
+			opts.keyVersion = v
+		default:
+			return opts, errors.Errorf("invalid query parameter %q", param)
+		}
+	}
+	if opts.authMount == "" {
+		opts.authMount = opts.auth
+	}
+	if opts.auth == VaultAuthKubernetes && opts.role == "" {
+		return opts, errors.New("the kubernetes auth method requires a role parameter")
+	}
+	return opts, nil
+}
+
+// openVaultKeeper opens a keeper for a key of a Vault transit secrets engine, logging in to Vault with the auth
+// method given by the URL.
+func openVaultKeeper(ctx context.Context, u *netUrl.URL) (*gosecrets.Keeper, error) {
+	opts, err := parseVaultKeeperURL(u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid secrets provider URL %v", u)
+	}
+
+	config := api.DefaultConfig()
+	if config.Error != nil {
+		return nil, errors.Wrap(config.Error, "configuring the Vault client")
+	}
+	// VAULT_SERVER_URL and VAULT_SERVER_TOKEN take precedence over VAULT_ADDR and VAULT_TOKEN, as they did before
+	// hashivault:// URLs were handled here.
+	if addr := os.Getenv("VAULT_SERVER_URL"); addr != "" {
+		config.Address = addr
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating the Vault client")
+	}
+	if opts.namespace != "" {
+		client.SetNamespace(opts.namespace)
+	}
+
+	if err := vaultLogin(client, opts); err != nil {
+		return nil, errors.Wrapf(err, "logging in to Vault at %s", client.Address())
+	}
+
+	return gosecrets.NewKeeper(&vaultKeeper{client: client, opts: opts}), nil
+}
+
+// vaultLogin sets the client's token, logging in with the auth method in opts if it isn't VaultAuthToken.
+func vaultLogin(client *api.Client, opts vaultKeeperOptions) error {
+	var data map[string]interface{}
+	switch opts.auth {
+	case VaultAuthToken:
+		if token := os.Getenv("VAULT_SERVER_TOKEN"); token != "" {
+			client.SetToken(token)
+		}
+		if client.Token() == "" {
+			return errors.New("neither VAULT_TOKEN nor VAULT_SERVER_TOKEN is set")
+		}
+		return nil
+	case VaultAuthAppRole:
+		roleID, secretID := os.Getenv("VAULT_ROLE_ID"), os.Getenv("VAULT_SECRET_ID")
+		if roleID == "" {
+			return errors.New("the approle auth method requires VAULT_ROLE_ID to be set")
+		}
+		data = map[string]interface{}{"role_id": roleID}
+		if secretID != "" {
+			data["secret_id"] = secretID
+		}
+	case VaultAuthKubernetes:
+		tokenPath := os.Getenv("VAULT_KUBERNETES_TOKEN_PATH")
+		if tokenPath == "" {
+			tokenPath = defaultKubernetesTokenPath
+		}
+		jwt, err := ioutil.ReadFile(tokenPath)
+		if err != nil {
+			return errors.Wrap(err, "reading the service account token")
+		}
+		data = map[string]interface{}{"role": opts.role, "jwt": strings.TrimSpace(string(jwt))}
+	}
+
+	// Logging in must not send a token that may be set in the environment.
+	client.ClearToken()
+	secret, err := client.Logical().Write(path.Join("auth", opts.authMount, "login"), data)
+	if err != nil {
+		return err
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return errors.Errorf("the %s auth method did not return a token", opts.auth)
+	}
+	client.SetToken(secret.Auth.ClientToken)
+	return nil
+}
+
+// vaultKeeper is a gocloud keeper driver for a key of a Vault transit secrets engine.
+type vaultKeeper struct {
+	client *api.Client
+	opts   vaultKeeperOptions
+}
+
+var _ driver.Keeper = (*vaultKeeper)(nil)
+
+func (k *vaultKeeper) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	data := map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	}
+	if k.opts.keyVersion != 0 {
+		data["key_version"] = k.opts.keyVersion
+	}
+	secret, err := k.client.Logical().Write(path.Join(k.opts.engine, "encrypt", k.opts.key), data)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := vaultResponseField(secret, "ciphertext")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(ciphertext), nil
+}
+
+func (k *vaultKeeper) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	secret, err := k.client.Logical().Write(path.Join(k.opts.engine, "decrypt", k.opts.key), map[string]interface{}{
+		"ciphertext": string(ciphertext),
+	})
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := vaultResponseField(secret, "plaintext")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(plaintext)
+}
+
+func (k *vaultKeeper) Close() error { return nil }
+
+func (k *vaultKeeper) ErrorAs(err error, i interface{}) bool { return false }
+
+func (k *vaultKeeper) ErrorCode(err error) gcerrors.ErrorCode {
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.StatusCode {
+		case 400:
+			return gcerrors.InvalidArgument
+		case 403:
+			return gcerrors.PermissionDenied
+		case 404:
+			return gcerrors.NotFound
+		}
+	}
+	return gcerrors.Unknown
+}
+
+// vaultResponseField returns a string field of the data of a response from Vault.
+func vaultResponseField(secret *api.Secret, field string) (string, error) {
+	if secret == nil {
+		return "", errors.New("no response from Vault")
+	}
+	v, ok := secret.Data[field].(string)
+	if !ok {
+		return "", errors.Errorf("the response from Vault has no %s", field)
+	}
+	return v, nil
+}
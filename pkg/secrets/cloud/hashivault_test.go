@@ -0,0 +1,237 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	netUrl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVault is a Vault server with just enough of the auth and transit APIs to test the Vault transit secrets
+// provider. Its "ciphertexts" are the plaintexts prefixed with the key version.
+type fakeVault struct {
+	t         *testing.T
+	namespace string
+	requests  []string
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.requests = append(v.requests, r.URL.Path)
+	assert.Equal(v.t, v.namespace, r.Header.Get("X-Vault-Namespace"))
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var resp interface{}
+	switch {
+	case r.URL.Path == "/v1/auth/approle/login":
+		if body["role_id"] != "my-role-id" || body["secret_id"] != "my-secret-id" {
+			http.Error(w, `{"errors":["invalid role or secret ID"]}`, http.StatusBadRequest)
+			return
+		}
+		resp = map[string]interface{}{"auth": map[string]interface{}{"client_token": "approle-token"}}
+	case r.URL.Path == "/v1/auth/k8s/login":
+		if body["role"] != "pulumi" || body["jwt"] != "service-account-jwt" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		resp = map[string]interface{}{"auth": map[string]interface{}{"client_token": "k8s-token"}}
+	case strings.HasPrefix(r.URL.Path, "/v1/transit/encrypt/"):
+		if r.Header.Get("X-Vault-Token") == "" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		version := "1"
+		if kv, ok := body["key_version"]; ok {
+			version = fmt.Sprintf("%v", kv)
+		}
+		resp = map[string]interface{}{"data": map[string]interface{}{
+			"ciphertext": fmt.Sprintf("vault:v%s:%s", version, body["plaintext"]),
+		}}
+	case strings.HasPrefix(r.URL.Path, "/v1/transit/decrypt/"):
+		parts := strings.SplitN(body["ciphertext"].(string), ":", 3)
+		resp = map[string]interface{}{"data": map[string]interface{}{"plaintext": parts[2]}}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	assert.NoError(v.t, json.NewEncoder(w).Encode(resp))
+}
+
+func startFakeVault(t *testing.T, namespace string) *fakeVault {
+	v := &fakeVault{t: t, namespace: namespace}
+	server := httptest.NewServer(v)
+	t.Cleanup(server.Close)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_SERVER_URL", "")
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("VAULT_SERVER_TOKEN", "")
+	t.Setenv("VAULT_NAMESPACE", "")
+	return v
+}
+
+func testVaultSecretsManager(t *testing.T, url string) []byte {
+	dataKey, err := GenerateNewDataKey(url)
+	require.NoError(t, err)
+
+	manager, err := NewCloudSecretsManager(url, dataKey)
+	require.NoError(t, err)
+	enc, err := manager.Encrypter()
+	require.NoError(t, err)
+	ciphertext, err := enc.EncryptValue("hunter2")
+	require.NoError(t, err)
+
+	// A new manager decrypts the secret with the same encrypted data key.
+	manager, err = NewCloudSecretsManager(url, dataKey)
+	require.NoError(t, err)
+	dec, err := manager.Decrypter()
+	require.NoError(t, err)
+	plaintext, err := dec.DecryptValue(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", plaintext)
+	return dataKey
+}
+
+//nolint:paralleltest // sets environment variables
+func TestVaultSecretsManagerToken(t *testing.T) {
+	v := startFakeVault(t, "team-a")
+	t.Setenv("VAULT_TOKEN", "root")
+
+	dataKey := testVaultSecretsManager(t, "hashivault://my-key?namespace=team-a&keyVersion=3")
+	assert.True(t, strings.HasPrefix(string(dataKey), "vault:v3:"))
+	assert.Equal(t, []string{
+		"/v1/transit/encrypt/my-key",
+		"/v1/transit/decrypt/my-key",
+		"/v1/transit/decrypt/my-key",
+	}, v.requests)
+}
+
+//nolint:paralleltest // sets environment variables
+func TestVaultSecretsManagerAppRole(t *testing.T) {
+	v := startFakeVault(t, "")
+	t.Setenv("VAULT_ROLE_ID", "my-role-id")
+	t.Setenv("VAULT_SECRET_ID", "my-secret-id")
+
+	testVaultSecretsManager(t, "hashivault://my-key?auth=approle")
+	assert.Equal(t, "/v1/auth/approle/login", v.requests[0])
+
+	t.Setenv("VAULT_SECRET_ID", "wrong")
+	_, err := GenerateNewDataKey("hashivault://my-key?auth=approle")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid role or secret ID")
+}
+
+//nolint:paralleltest // sets environment variables
+func TestVaultSecretsManagerKubernetes(t *testing.T) {
+	v := startFakeVault(t, "")
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("service-account-jwt\n"), 0600))
+	t.Setenv("VAULT_KUBERNETES_TOKEN_PATH", tokenPath)
+
+	testVaultSecretsManager(t, "hashivault://my-key?auth=kubernetes&authMount=k8s&role=pulumi")
+	assert.Equal(t, "/v1/auth/k8s/login", v.requests[0])
+}
+
+func TestParseVaultKeeperURL(t *testing.T) {
+	t.Parallel()
+
+	parse := func(url string) (vaultKeeperOptions, error) {
+		u, err := netUrl.Parse(url)
+		require.NoError(t, err)
+		return parseVaultKeeperURL(u)
+	}
+
+	opts, err := parse("hashivault://my-key")
+	require.NoError(t, err)
+	assert.Equal(t, vaultKeeperOptions{
+		key:       "my-key",
+		engine:    "transit",
+		auth:      VaultAuthToken,
+		authMount: VaultAuthToken,
+	}, opts)
+
+	opts, err = parse("hashivault://my-key?engine=kms&namespace=a/b&auth=approle&authMount=ci&keyVersion=2")
+	require.NoError(t, err)
+	assert.Equal(t, vaultKeeperOptions{
+		key:        "my-key",
+		engine:     "kms",
+		namespace:  "a/b",
+		auth:       VaultAuthAppRole,
+		authMount:  "ci",
+		keyVersion: 2,
+	}, opts)
+
+	for url, msg := range map[string]string{
+		"hashivault://":                        "missing the name of the transit key",
+		"hashivault://my-key?auth=ldap":        `unknown auth method "ldap"`,
+		"hashivault://my-key?auth=kubernetes":  "requires a role parameter",
+		"hashivault://my-key?keyVersion=first": `invalid key version "first"`,
+		"hashivault://my-key?token=s.1234":     `invalid query parameter "token"`,
+	} {
+		_, err := parse(url)
+		require.Error(t, err, url)
+		assert.Contains(t, err.Error(), msg, url)
+	}
+}
+
+// TestVaultDevServer tests the Vault transit secrets provider against a Vault dev server, which can be started with
+//
+//	vault server -dev -dev-root-token-id=root
+//
+// The test only runs if VAULT_ADDR and VAULT_TOKEN are set.
+//
+//nolint:paralleltest // reads environment variables
+func TestVaultDevServer(t *testing.T) {
+	if os.Getenv("VAULT_ADDR") == "" || os.Getenv("VAULT_TOKEN") == "" {
+		t.Skip("VAULT_ADDR and VAULT_TOKEN must be set to test against a Vault server")
+	}
+
+	client, err := api.NewClient(api.DefaultConfig())
+	require.NoError(t, err)
+	mounts, err := client.Sys().ListMounts()
+	require.NoError(t, err)
+	if _, ok := mounts["transit/"]; !ok {
+		require.NoError(t, client.Sys().Mount("transit", &api.MountInput{Type: "transit"}))
+	}
+
+	key := fmt.Sprintf("pulumi-test-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Int()) //nolint:gosec
+	_, err = client.Logical().Write("transit/keys/"+key, nil)
+	require.NoError(t, err)
+	_, err = client.Logical().Write("transit/keys/"+key+"/rotate", nil)
+	require.NoError(t, err)
+
+	// Data keys encrypted with an older version of the key can still be decrypted.
+	dataKey := testVaultSecretsManager(t, "hashivault://"+key+"?keyVersion=1")
+	assert.True(t, strings.HasPrefix(string(dataKey), "vault:v1:"))
+	dataKey = testVaultSecretsManager(t, "hashivault://"+key)
+	assert.True(t, strings.HasPrefix(string(dataKey), "vault:v2:"))
+}
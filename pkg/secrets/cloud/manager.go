@@ -26,7 +26,7 @@ import (
 	_ "gocloud.dev/secrets/awskms"        // support for awskms://
 	_ "gocloud.dev/secrets/azurekeyvault" // support for azurekeyvault://
 	"gocloud.dev/secrets/gcpkms"          // support for gcpkms://
-	_ "gocloud.dev/secrets/hashivault"    // support for hashivault://
+	"gocloud.dev/secrets/hashivault"      // support for hashivault://
 	"google.golang.org/api/cloudkms/v1"
 
 	"github.com/pkg/errors"
@@ -78,6 +78,8 @@ func openKeeper(ctx context.Context, url string) (*gosecrets.Keeper, error) {
 		}
 
 		return opener.OpenKeeperURL(ctx, u)
+	case hashivault.Scheme:
+		return openVaultKeeper(ctx, u)
 	default:
 		return gosecrets.OpenKeeper(ctx, url)
 	}
@@ -1,8 +1,239 @@
+// Package events contains the engine events that Automation API operations send to their event streams, along with
+// helpers to decode their resource states, filter them and subscribe to them.
 package events
 
-import "github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+import (
+	"fmt"
+	"sort"
 
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+// EngineEvent is an engine event sent by an operation, or the error with which reading the operation's events failed.
 type EngineEvent struct {
 	apitype.EngineEvent
 	Error error
 }
+
+// StepEventMetadata is the decoded form of the apitype.StepEventMetadata of a resource event.
+type StepEventMetadata struct {
+	// Op is the operation being performed.
+	Op   apitype.OpType
+	URN  resource.URN
+	Type tokens.Type
+
+	// Old is the state of the resource before performing the step.
+	Old *StepEventStateMetadata
+	// New is the state of the resource after performing the step.
+	New *StepEventStateMetadata
+
+	// Keys causing a replacement (only applicable for "create" and "replace" Ops).
+	Keys []resource.PropertyKey
+	// Keys that changed with this step.
+	Diffs []resource.PropertyKey
+	// The diff for this step, sorted by property path.
+	DetailedDiff []PropertyDiff
+	// Logical is set if the step is a logical operation in the program.
+	Logical bool
+	// Provider actually performing the step.
+	Provider string
+}
+
+// StepEventStateMetadata is the decoded form of the apitype.StepEventStateMetadata of a resource event.
+type StepEventStateMetadata struct {
+	Type tokens.Type
+	URN  resource.URN
+
+	// Custom indicates if the resource is managed by a plugin.
+	Custom bool
+	// Delete is true when the resource is pending deletion due to a replacement.
+	Delete bool
+	// ID is the resource's unique ID, assigned by the resource provider (or blank if none/uncreated).
+	ID resource.ID
+	// Parent is an optional parent URN that this resource belongs to.
+	Parent resource.URN
+	// Protect is true to "protect" this resource (protected resources cannot be deleted).
+	Protect bool
+	// Inputs contains the resource's input properties (as specified by the program).
+	Inputs resource.PropertyMap
+	// Outputs contains the resource's complete output state (as returned by the resource provider).
+	Outputs resource.PropertyMap
+	// Provider is the resource's provider reference
+	Provider string
+	// InitErrors is the set of errors encountered in the process of initializing resource.
+	InitErrors []string
+}
+
+// PropertyDiff describes the difference between a single property's old and new values.
+type PropertyDiff struct {
+	// Path is the path of the property.
+	Path resource.PropertyPath
+	// Kind is the kind of difference.
+	Kind apitype.DiffKind
+	// InputDiff is true if this is a difference between old and new inputs rather than old state and new inputs.
+	InputDiff bool
+	// Old is the old value of the property: its old input if InputDiff is set, and its old output otherwise. It is
+	// null if the property was added.
+	Old resource.PropertyValue
+	// New is the new input value of the property. It is null if the property was deleted.
+	New resource.PropertyValue
+}
+
+// metadata returns the step metadata of a resource event, if the event is one.
+func (e EngineEvent) metadata() *apitype.StepEventMetadata {
+	switch {
+	case e.ResourcePreEvent != nil:
+		return &e.ResourcePreEvent.Metadata
+	case e.ResOutputsEvent != nil:
+		return &e.ResOutputsEvent.Metadata
+	case e.ResOpFailedEvent != nil:
+		return &e.ResOpFailedEvent.Metadata
+	default:
+		return nil
+	}
+}
+
+// URN returns the URN of the resource that the event is about, if any.
+func (e EngineEvent) URN() resource.URN {
+	switch {
+	case e.metadata() != nil:
+		return resource.URN(e.metadata().URN)
+	case e.DiagnosticEvent != nil:
+		return resource.URN(e.DiagnosticEvent.URN)
+	case e.PolicyEvent != nil:
+		return resource.URN(e.PolicyEvent.ResourceURN)
+	default:
+		return ""
+	}
+}
+
+// Op returns the operation of a resource event, or "" if the event isn't a resource event.
+func (e EngineEvent) Op() apitype.OpType {
+	if md := e.metadata(); md != nil {
+		return md.Op
+	}
+	return ""
+}
+
+// Severity returns the severity of a diagnostic event, or "" if the event isn't a diagnostic event.
+func (e EngineEvent) Severity() diag.Severity {
+	if e.DiagnosticEvent != nil {
+		return diag.Severity(e.DiagnosticEvent.Severity)
+	}
+	return ""
+}
+
+// Step decodes the step metadata of a resource event: a ResourcePreEvent, ResOutputsEvent or ResOpFailedEvent. It
+// returns nil if the event isn't a resource event. Property values are decoded as DecodePropertyMap decodes them.
+func (e EngineEvent) Step() (*StepEventMetadata, error) {
+	md := e.metadata()
+	if md == nil {
+		return nil, nil
+	}
+
+	oldState, err := decodeStepEventStateMetadata(md.Old)
+	if err != nil {
+		return nil, fmt.Errorf("decoding old state of %s: %w", md.URN, err)
+	}
+	newState, err := decodeStepEventStateMetadata(md.New)
+	if err != nil {
+		return nil, fmt.Errorf("decoding new state of %s: %w", md.URN, err)
+	}
+	step := &StepEventMetadata{
+		Op:       md.Op,
+		URN:      resource.URN(md.URN),
+		Type:     tokens.Type(md.Type),
+		Old:      oldState,
+		New:      newState,
+		Keys:     propertyKeys(md.Keys),
+		Diffs:    propertyKeys(md.Diffs),
+		Logical:  md.Logical,
+		Provider: md.Provider,
+	}
+
+	paths := make([]string, 0, len(md.DetailedDiff))
+	for path := range md.DetailedDiff {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		diff := md.DetailedDiff[path]
+		propertyPath, err := resource.ParsePropertyPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("decoding diff of %s: %w", md.URN, err)
+		}
+
+		d := PropertyDiff{Path: propertyPath, Kind: diff.Kind, InputDiff: diff.InputDiff}
+		d.Old, d.New = resource.NewNullProperty(), resource.NewNullProperty()
+		if oldState != nil {
+			oldValues := oldState.Outputs
+			if diff.InputDiff {
+				oldValues = oldState.Inputs
+			}
+			if v, ok := propertyPath.Get(resource.NewObjectProperty(oldValues)); ok {
+				d.Old = v
+			}
+		}
+		if newState != nil {
+			if v, ok := propertyPath.Get(resource.NewObjectProperty(newState.Inputs)); ok {
+				d.New = v
+			}
+		}
+		step.DetailedDiff = append(step.DetailedDiff, d)
+	}
+	return step, nil
+}
+
+// Outputs decodes the outputs of the resource of a ResOutputsEvent. It returns nil if the event isn't a
+// ResOutputsEvent. Property values are decoded as DecodePropertyMap decodes them.
+func (e EngineEvent) Outputs() (resource.PropertyMap, error) {
+	if e.ResOutputsEvent == nil || e.ResOutputsEvent.Metadata.New == nil {
+		return nil, nil
+	}
+	outputs, err := DecodePropertyMap(e.ResOutputsEvent.Metadata.New.Outputs)
+	if err != nil {
+		return nil, fmt.Errorf("decoding outputs of %s: %w", e.ResOutputsEvent.Metadata.URN, err)
+	}
+	return outputs, nil
+}
+
+func decodeStepEventStateMetadata(md *apitype.StepEventStateMetadata) (*StepEventStateMetadata, error) {
+	if md == nil {
+		return nil, nil
+	}
+	inputs, err := DecodePropertyMap(md.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("decoding inputs: %w", err)
+	}
+	outputs, err := DecodePropertyMap(md.Outputs)
+	if err != nil {
+		return nil, fmt.Errorf("decoding outputs: %w", err)
+	}
+	return &StepEventStateMetadata{
+		Type:       tokens.Type(md.Type),
+		URN:        resource.URN(md.URN),
+		Custom:     md.Custom,
+		Delete:     md.Delete,
+		ID:         resource.ID(md.ID),
+		Parent:     resource.URN(md.Parent),
+		Protect:    md.Protect,
+		Inputs:     inputs,
+		Outputs:    outputs,
+		Provider:   md.Provider,
+		InitErrors: md.InitErrors,
+	}, nil
+}
+
+func propertyKeys(keys []string) []resource.PropertyKey {
+	if keys == nil {
+		return nil
+	}
+	result := make([]resource.PropertyKey, len(keys))
+	for i, k := range keys {
+		result[i] = resource.PropertyKey(k)
+	}
+	return result
+}
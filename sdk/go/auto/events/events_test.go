@@ -0,0 +1,243 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+const bucketURN = "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::bucket"
+
+// decodeEvent decodes an engine event as the Automation API reads it from the event log.
+func decodeEvent(t *testing.T, text string) EngineEvent {
+	var e apitype.EngineEvent
+	require.NoError(t, json.Unmarshal([]byte(text), &e))
+	return EngineEvent{EngineEvent: e}
+}
+
+func TestDecodePropertyMap(t *testing.T) {
+	t.Parallel()
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"string": "hello",
+		"number": 42,
+		"bool": true,
+		"null": null,
+		"array": ["a", {"b": 1}],
+		"computed": "04da6b54-80e4-46f7-96ec-b56ff0331ba9",
+		"secret": {"4dabf18193072939515e22adb298388d": "1b47061264138c4ac30d75fd1eb44270",
+			"plaintext": "{\"a\":\"b\"}"},
+		"blinded": {"4dabf18193072939515e22adb298388d": "1b47061264138c4ac30d75fd1eb44270", "ciphertext": "[secret]"},
+		"asset": {"4dabf18193072939515e22adb298388d": "c44067f5952c0a294b673a41bacd8c17", "text": "contents"},
+		"custom": {"4dabf18193072939515e22adb298388d": "5cf8f73096256a8f31e491e813e4eb8e",
+			"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::bucket", "id": "bucket-1234",
+			"packageVersion": "5.0.0"},
+		"component": {"4dabf18193072939515e22adb298388d": "5cf8f73096256a8f31e491e813e4eb8e",
+			"urn": "urn:pulumi:dev::proj::my:component:Thing::thing"}
+	}`), &m))
+
+	props, err := DecodePropertyMap(m)
+	require.NoError(t, err)
+
+	assert.Equal(t, resource.NewStringProperty("hello"), props["string"])
+	assert.Equal(t, resource.NewNumberProperty(42), props["number"])
+	assert.Equal(t, resource.NewBoolProperty(true), props["bool"])
+	assert.True(t, props["null"].IsNull())
+	assert.Equal(t, resource.NewArrayProperty([]resource.PropertyValue{
+		resource.NewStringProperty("a"),
+		resource.NewObjectProperty(resource.PropertyMap{"b": resource.NewNumberProperty(1)}),
+	}), props["array"])
+	assert.True(t, props["computed"].IsComputed())
+	assert.Equal(t, resource.MakeSecret(resource.NewObjectProperty(resource.PropertyMap{
+		"a": resource.NewStringProperty("b"),
+	})), props["secret"])
+	assert.Equal(t, resource.MakeSecret(resource.NewStringProperty("[secret]")), props["blinded"])
+	require.True(t, props["asset"].IsAsset())
+	assert.Equal(t, "contents", props["asset"].AssetValue().Text)
+	assert.Equal(t, resource.MakeCustomResourceReference(bucketURN, "bucket-1234", "5.0.0"), props["custom"])
+	assert.Equal(t, resource.MakeComponentResourceReference("urn:pulumi:dev::proj::my:component:Thing::thing", ""),
+		props["component"])
+
+	_, err = DecodePropertyMap(map[string]interface{}{
+		"bad": map[string]interface{}{resource.SigKey: "unknown"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `property "bad"`)
+}
+
+func TestStep(t *testing.T) {
+	t.Parallel()
+
+	e := decodeEvent(t, `{"resourcePreEvent": {"metadata": {
+		"op": "update",
+		"urn": "`+bucketURN+`",
+		"type": "aws:s3/bucket:Bucket",
+		"old": {
+			"type": "aws:s3/bucket:Bucket", "urn": "`+bucketURN+`", "custom": true, "id": "bucket-1234",
+			"inputs": {"acl": "private", "tags": {"env": "dev"}},
+			"outputs": {"acl": "private", "arn": "arn:aws:s3:::bucket-1234", "tags": {"env": "dev"}}
+		},
+		"new": {
+			"type": "aws:s3/bucket:Bucket", "urn": "`+bucketURN+`", "custom": true, "id": "bucket-1234",
+			"inputs": {"acl": "public-read", "tags": {"owner": "ops"}},
+			"outputs": {}
+		},
+		"diffs": ["acl", "tags"],
+		"detailedDiff": {
+			"tags.owner": {"diffKind": "add", "inputDiff": true},
+			"acl": {"diffKind": "update"},
+			"tags.env": {"diffKind": "delete", "inputDiff": true}
+		},
+		"provider": "urn:pulumi:dev::proj::pulumi:providers:aws::default::provider-id"
+	}}}`)
+
+	assert.Equal(t, resource.URN(bucketURN), e.URN())
+	assert.Equal(t, apitype.OpUpdate, e.Op())
+	assert.Equal(t, diag.Severity(""), e.Severity())
+
+	step, err := e.Step()
+	require.NoError(t, err)
+	assert.Equal(t, apitype.OpUpdate, step.Op)
+	assert.Equal(t, resource.ID("bucket-1234"), step.Old.ID)
+	assert.Equal(t, resource.NewStringProperty("arn:aws:s3:::bucket-1234"), step.Old.Outputs["arn"])
+	assert.Equal(t, resource.NewStringProperty("public-read"), step.New.Inputs["acl"])
+	assert.Equal(t, []resource.PropertyKey{"acl", "tags"}, step.Diffs)
+
+	require.Len(t, step.DetailedDiff, 3)
+	acl, env, owner := step.DetailedDiff[0], step.DetailedDiff[1], step.DetailedDiff[2]
+	assert.Equal(t, resource.PropertyPath{"acl"}, acl.Path)
+	assert.Equal(t, apitype.DiffUpdate, acl.Kind)
+	assert.Equal(t, resource.NewStringProperty("private"), acl.Old)
+	assert.Equal(t, resource.NewStringProperty("public-read"), acl.New)
+	assert.Equal(t, resource.PropertyPath{"tags", "env"}, env.Path)
+	assert.Equal(t, resource.NewStringProperty("dev"), env.Old)
+	assert.True(t, env.New.IsNull())
+	assert.Equal(t, resource.PropertyPath{"tags", "owner"}, owner.Path)
+	assert.True(t, owner.Old.IsNull())
+	assert.Equal(t, resource.NewStringProperty("ops"), owner.New)
+
+	outputs, err := e.Outputs()
+	require.NoError(t, err)
+	assert.Nil(t, outputs)
+}
+
+func TestOutputs(t *testing.T) {
+	t.Parallel()
+
+	e := decodeEvent(t, `{"resOutputsEvent": {"metadata": {
+		"op": "create",
+		"urn": "`+bucketURN+`",
+		"type": "aws:s3/bucket:Bucket",
+		"new": {
+			"type": "aws:s3/bucket:Bucket", "urn": "`+bucketURN+`",
+			"outputs": {"password": {"4dabf18193072939515e22adb298388d": "1b47061264138c4ac30d75fd1eb44270",
+				"ciphertext": "[secret]"}}
+		},
+		"provider": ""
+	}}}`)
+
+	outputs, err := e.Outputs()
+	require.NoError(t, err)
+	assert.True(t, outputs["password"].IsSecret())
+
+	step, err := e.Step()
+	require.NoError(t, err)
+	assert.Nil(t, step.Old)
+	assert.Equal(t, outputs, step.New.Outputs)
+
+	step, err = decodeEvent(t, `{"summaryEvent": {}}`).Step()
+	require.NoError(t, err)
+	assert.Nil(t, step)
+}
+
+func TestFilters(t *testing.T) {
+	t.Parallel()
+
+	create := decodeEvent(t, `{"resourcePreEvent": {"metadata": {"op": "create", "urn": "`+bucketURN+`"}}}`)
+	warning := decodeEvent(t, `{"diagnosticEvent": {"urn": "`+bucketURN+`", "message": "hmm", "severity": "warning"}}`)
+	failure := decodeEvent(t, `{"diagnosticEvent": {"message": "oops", "severity": "error"}}`)
+	summary := decodeEvent(t, `{"summaryEvent": {}}`)
+
+	byURN := ByURN(bucketURN)
+	assert.True(t, byURN(create))
+	assert.True(t, byURN(warning))
+	assert.False(t, byURN(failure))
+	assert.False(t, byURN(summary))
+
+	byOp := ByOp(apitype.OpCreate, apitype.OpDelete)
+	assert.True(t, byOp(create))
+	assert.False(t, byOp(warning))
+
+	bySeverity := BySeverity(diag.Warning, diag.Error)
+	assert.False(t, bySeverity(create))
+	assert.True(t, bySeverity(warning))
+	assert.True(t, bySeverity(failure))
+
+	assert.True(t, Match(warning, byURN, bySeverity))
+	assert.False(t, Match(failure, byURN, bySeverity))
+	assert.True(t, Match(summary))
+	assert.True(t, Any(byOp, bySeverity)(failure))
+	assert.False(t, Any(byOp, bySeverity)(summary))
+}
+
+func TestSubscription(t *testing.T) {
+	t.Parallel()
+
+	sub := Subscribe(BySeverity(diag.Error))
+
+	info := decodeEvent(t, `{"diagnosticEvent": {"message": "info", "severity": "info"}}`)
+	failure := decodeEvent(t, `{"diagnosticEvent": {"message": "error", "severity": "error"}}`)
+
+	// The operation can send all of its events and finish before any are read.
+	sent := make(chan bool)
+	go func() {
+		stream := sub.Stream()
+		for i := 0; i < 100; i++ {
+			failure.Sequence = i
+			stream <- info
+			stream <- failure
+		}
+		stream <- EngineEvent{Error: errors.New("bad line")}
+		close(stream)
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(10 * time.Second):
+		t.Fatal("sending events blocked")
+	}
+
+	var received []EngineEvent
+	for e := range sub.Events() {
+		received = append(received, e)
+	}
+	require.Len(t, received, 101)
+	for i, e := range received[:100] {
+		assert.Equal(t, "error", e.DiagnosticEvent.Message)
+		assert.Equal(t, i, e.Sequence)
+	}
+	assert.EqualError(t, received[100].Error, "bad line")
+}
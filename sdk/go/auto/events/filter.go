@@ -0,0 +1,79 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// Filter reports whether an engine event is of interest.
+type Filter func(e EngineEvent) bool
+
+// Match reports whether an engine event matches all of the given filters.
+func Match(e EngineEvent, filters ...Filter) bool {
+	for _, f := range filters {
+		if !f(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// ByURN matches the events about the resources with the given URNs.
+func ByURN(urns ...resource.URN) Filter {
+	set := make(map[resource.URN]bool, len(urns))
+	for _, urn := range urns {
+		set[urn] = true
+	}
+	return func(e EngineEvent) bool {
+		return set[e.URN()]
+	}
+}
+
+// ByOp matches the resource events of steps with the given operations.
+func ByOp(ops ...apitype.OpType) Filter {
+	set := make(map[apitype.OpType]bool, len(ops))
+	for _, op := range ops {
+		set[op] = true
+	}
+	return func(e EngineEvent) bool {
+		return set[e.Op()]
+	}
+}
+
+// BySeverity matches the diagnostic events with the given severities.
+func BySeverity(severities ...diag.Severity) Filter {
+	set := make(map[diag.Severity]bool, len(severities))
+	for _, severity := range severities {
+		set[severity] = true
+	}
+	return func(e EngineEvent) bool {
+		return set[e.Severity()]
+	}
+}
+
+// Any matches the events that match any of the given filters.
+func Any(filters ...Filter) Filter {
+	return func(e EngineEvent) bool {
+		for _, f := range filters {
+			if f(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
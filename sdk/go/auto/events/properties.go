@@ -0,0 +1,137 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// computedValuePlaceholder is the value with which the engine encodes values that are unknown during a preview.
+const computedValuePlaceholder = "04da6b54-80e4-46f7-96ec-b56ff0331ba9"
+
+// blindedSecret is the value of secrets whose plaintext is not included in an event.
+const blindedSecret = "[secret]"
+
+// DecodePropertyMap decodes the inputs or outputs of a resource in an engine event, as encoded in the Inputs and
+// Outputs of apitype.StepEventStateMetadata.
+//
+// Secret values remain secrets. Engine events don't include the plaintext of secrets unless they are shown, so
+// secrets are usually decoded as secrets of the string "[secret]". Values that are unknown during a preview are
+// decoded as computed values.
+func DecodePropertyMap(m map[string]interface{}) (resource.PropertyMap, error) {
+	if m == nil {
+		return nil, nil
+	}
+	result := make(resource.PropertyMap, len(m))
+	for k, v := range m {
+		value, err := DecodePropertyValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", k, err)
+		}
+		result[resource.PropertyKey(k)] = value
+	}
+	return result, nil
+}
+
+// DecodePropertyValue decodes a single property value in an engine event. See DecodePropertyMap.
+func DecodePropertyValue(v interface{}) (resource.PropertyValue, error) {
+	switch v := v.(type) {
+	case nil:
+		return resource.NewNullProperty(), nil
+	case bool:
+		return resource.NewBoolProperty(v), nil
+	case float64:
+		return resource.NewNumberProperty(v), nil
+	case string:
+		if v == computedValuePlaceholder {
+			return resource.MakeComputed(resource.NewStringProperty("")), nil
+		}
+		return resource.NewStringProperty(v), nil
+	case []interface{}:
+		arr := make([]resource.PropertyValue, len(v))
+		for i, elem := range v {
+			value, err := DecodePropertyValue(elem)
+			if err != nil {
+				return resource.PropertyValue{}, err
+			}
+			arr[i] = value
+		}
+		return resource.NewArrayProperty(arr), nil
+	case map[string]interface{}:
+		sig, hasSig := v[resource.SigKey]
+		if !hasSig {
+			obj, err := DecodePropertyMap(v)
+			if err != nil {
+				return resource.PropertyValue{}, err
+			}
+			return resource.NewObjectProperty(obj), nil
+		}
+		return decodeSignedValue(sig, v)
+	default:
+		return resource.PropertyValue{}, fmt.Errorf("unexpected value of type %T", v)
+	}
+}
+
+// decodeSignedValue decodes an asset, archive, secret or resource reference.
+func decodeSignedValue(sig interface{}, v map[string]interface{}) (resource.PropertyValue, error) {
+	switch sig {
+	case resource.AssetSig:
+		asset, _, err := resource.DeserializeAsset(v)
+		if err != nil {
+			return resource.PropertyValue{}, err
+		}
+		return resource.NewAssetProperty(asset), nil
+	case resource.ArchiveSig:
+		archive, _, err := resource.DeserializeArchive(v)
+		if err != nil {
+			return resource.PropertyValue{}, err
+		}
+		return resource.NewArchiveProperty(archive), nil
+	case resource.SecretSig:
+		plaintext, ok := v["plaintext"].(string)
+		if !ok {
+			if _, ok := v["ciphertext"].(string); !ok {
+				return resource.PropertyValue{}, errors.New(
+					"malformed secret value: one of `ciphertext` or `plaintext` must be supplied")
+			}
+			return resource.MakeSecret(resource.NewStringProperty(blindedSecret)), nil
+		}
+		var elem interface{}
+		if err := json.Unmarshal([]byte(plaintext), &elem); err != nil {
+			return resource.PropertyValue{}, fmt.Errorf("malformed secret value: %w", err)
+		}
+		value, err := DecodePropertyValue(elem)
+		if err != nil {
+			return resource.PropertyValue{}, err
+		}
+		return resource.MakeSecret(value), nil
+	case resource.ResourceReferenceSig:
+		urn, ok := v["urn"].(string)
+		if !ok {
+			return resource.PropertyValue{}, errors.New("malformed resource reference: missing urn")
+		}
+		packageVersion, _ := v["packageVersion"].(string)
+		if id, ok := v["id"].(string); ok {
+			return resource.MakeCustomResourceReference(resource.URN(urn), resource.ID(id), packageVersion), nil
+		}
+		return resource.MakeComponentResourceReference(resource.URN(urn), packageVersion), nil
+	default:
+		return resource.PropertyValue{}, fmt.Errorf("unrecognized signature '%v' in property map", sig)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+// Subscription receives the engine events of an operation without ever blocking the operation. Pass its Stream to
+// the operation, for example with optup.EventStreams, and read the events that match its filters from Events:
+//
+//	sub := events.Subscribe(events.BySeverity(diag.Error))
+//	go func() {
+//		for e := range sub.Events() {
+//			// ...
+//		}
+//	}()
+//	_, err := stack.Up(ctx, optup.EventStreams(sub.Stream()))
+//
+// Events are queued until they are read, however slowly they are read. Events with an Error always match.
+type Subscription struct {
+	stream chan EngineEvent
+	events chan EngineEvent
+}
+
+// Subscribe returns a subscription to the events that match all of the given filters.
+func Subscribe(filters ...Filter) *Subscription {
+	s := &Subscription{
+		stream: make(chan EngineEvent),
+		events: make(chan EngineEvent),
+	}
+	go s.run(filters)
+	return s
+}
+
+// Stream returns the channel to which the operation sends its events. The operation closes it when it finishes.
+func (s *Subscription) Stream() chan<- EngineEvent {
+	return s.stream
+}
+
+// Events returns the channel from which to read the events that match the subscription's filters. It is closed once
+// the stream is closed and all of the events have been read.
+func (s *Subscription) Events() <-chan EngineEvent {
+	return s.events
+}
+
+func (s *Subscription) run(filters []Filter) {
+	defer close(s.events)
+
+	var queue []EngineEvent
+	stream := s.stream
+	for stream != nil || len(queue) > 0 {
+		// Only offer an event to the reader while there is one. Receiving from and sending to nil channels blocks, so
+		// each case is disabled while it has nothing to do.
+		var events chan EngineEvent
+		var next EngineEvent
+		if len(queue) > 0 {
+			events, next = s.events, queue[0]
+		}
+
+		select {
+		case e, ok := <-stream:
+			if !ok {
+				stream = nil
+				continue
+			}
+			if e.Error != nil || Match(e, filters...) {
+				queue = append(queue, e)
+			}
+		case events <- next:
+			queue[0] = EngineEvent{}
+			queue = queue[1:]
+		}
+	}
+}
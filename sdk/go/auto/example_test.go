@@ -26,6 +26,8 @@ import (
 	"path/filepath"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optimport"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpolicy"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
@@ -1154,6 +1156,86 @@ func ExampleStack_Import() {
 	_ = stack.Import(ctx, dep)
 }
 
+func ExampleStack_ImportResources() {
+	ctx := context.Background()
+	stackName := FullyQualifiedStackName("org", "project", "stack")
+	stack, _ := SelectStackLocalSource(ctx, stackName, filepath.Join(".", "program"))
+	// adopt an existing bucket into the stack, without protecting it from deletion
+	res, _ := stack.ImportResources(ctx, []ImportResource{
+		{Type: "aws:s3/bucket:Bucket", Name: "logs", ID: "my-logs-bucket"},
+	}, optimport.Protect(false))
+	// the generated code should be added to the program so that the next update doesn't delete the bucket
+	fmt.Println(res.GeneratedCode)
+}
+
+func ExampleStack_State() {
+	ctx := context.Background()
+	stackName := FullyQualifiedStackName("org", "project", "stack")
+	stack, _ := SelectStackLocalSource(ctx, stackName, filepath.Join(".", "program"))
+	state := stack.State()
+	// list the resources in the stack's state
+	resources, _ := state.Resources(ctx)
+	for _, res := range resources {
+		fmt.Println(res.URN)
+	}
+	// forget about a bucket that was deleted out of band
+	_ = state.Delete(ctx, "urn:pulumi:stack::project::aws:s3/bucket:Bucket::logs", true /*force*/)
+	// or edit the state directly; the edited state is verified before it is imported
+	_ = state.Edit(ctx, func(deployment *apitype.DeploymentV3) error {
+		for i := range deployment.Resources {
+			deployment.Resources[i].Protect = true
+		}
+		return nil
+	})
+}
+
+func ExampleStack_ListTags() {
+	ctx := context.Background()
+	stackName := FullyQualifiedStackName("org", "project", "stack")
+	stack, _ := SelectStackLocalSource(ctx, stackName, filepath.Join(".", "program"))
+	// tag the stack, then list all of its tags
+	_ = stack.SetTag(ctx, "team", "platform")
+	tags, _ := stack.ListTags(ctx)
+	fmt.Println(tags["team"])
+}
+
+func ExampleStack_Rename() {
+	ctx := context.Background()
+	stackName := FullyQualifiedStackName("org", "project", "stack")
+	stack, _ := SelectStackLocalSource(ctx, stackName, filepath.Join(".", "program"))
+	_ = stack.Rename(ctx, "staging")
+	// the stack now refers to its new name
+	fmt.Println(stack.Name())
+}
+
+func ExampleStack_ChangeSecretsProvider() {
+	ctx := context.Background()
+	stackName := FullyQualifiedStackName("org", "project", "stack")
+	stack, _ := SelectStackLocalSource(ctx, stackName, filepath.Join(".", "program"))
+	// re-encrypt the stack's secrets with a KMS key
+	_ = stack.ChangeSecretsProvider(ctx, "awskms://alias/ExampleAlias?region=us-east-1")
+}
+
+func ExampleStack_About() {
+	ctx := context.Background()
+	stackName := FullyQualifiedStackName("org", "project", "stack")
+	stack, _ := SelectStackLocalSource(ctx, stackName, filepath.Join(".", "program"))
+	about, _ := stack.About(ctx)
+	fmt.Println(about.CLI.Version)
+	for _, p := range about.Plugins {
+		fmt.Println(p.Name, p.Version)
+	}
+}
+
+func ExampleLocalWorkspace_EnablePolicyPack() {
+	ctx := context.Background()
+	// create a workspace from a local project
+	w, _ := NewLocalWorkspace(ctx, WorkDir(filepath.Join(".", "program")))
+	// enforce the latest version of a policy pack for the stacks of a policy group
+	_ = w.EnablePolicyPack(ctx, "org/compliance", "latest",
+		optpolicy.PolicyGroup("production"), optpolicy.Config(filepath.Join(".", "policy-config.json")))
+}
+
 func ExampleLocalWorkspace_ExportStack() {
 	ctx := context.Background()
 	// create a workspace from a local project
@@ -22,10 +22,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
 
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpolicy"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optremove"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
@@ -44,13 +46,27 @@ import (
 // for Project and Stack settings. Modifying ProjectSettings will
 // alter the Workspace Pulumi.yaml file, and setting config on a Stack will modify the Pulumi.<stack>.yaml file.
 // This is identical to the behavior of Pulumi CLI driven workspaces.
+//
+// A LocalWorkspace may be used by many goroutines at once, and operations on different stacks of the workspace (such
+// as Stack.Up and Stack.Preview) run concurrently. Every command that operates on a stack names the stack with
+// --stack, so these operations don't depend on which stack is selected in the workspace, and each reads and writes
+// only its own Pulumi.<stack>.yaml file. Commands that change the selected stack or the settings files are
+// serialized by a workspace-level lock: creating, selecting, renaming and removing stacks, saving settings, setting,
+// removing and refreshing config, changing secrets providers, and reading the selected stack. Operations that change
+// the same stack at once, such as two concurrent updates of one stack, are still rejected by the backend.
 type LocalWorkspace struct {
 	workDir         string
 	pulumiHome      string
-	program         pulumi.RunFunc
-	envvars         map[string]string
 	secretsProvider string
 	pulumiVersion   semver.Version
+
+	// lock serializes the commands that change the selected stack or the settings files of the workspace.
+	lock sync.Mutex
+
+	// envLock guards the environment values and the program, which commands read while they run.
+	envLock sync.RWMutex
+	program pulumi.RunFunc
+	envvars map[string]string
 }
 
 var settingsExtensions = []string{".yaml", ".yml", ".json"}
@@ -68,6 +84,9 @@ func (l *LocalWorkspace) ProjectSettings(ctx context.Context) (*workspace.Projec
 // There can only be a single project per workspace. Fails is new project name does not match old.
 // LocalWorkspace writes this value to a Pulumi.yaml file in Workspace.WorkDir().
 func (l *LocalWorkspace) SaveProjectSettings(ctx context.Context, settings *workspace.Project) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	pulumiYamlPath := filepath.Join(l.WorkDir(), "Pulumi.yaml")
 	return settings.Save(pulumiYamlPath)
 }
@@ -96,6 +115,9 @@ func (l *LocalWorkspace) SaveStackSettings(
 	stackName string,
 	settings *workspace.ProjectStack,
 ) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	name := getStackSettingsName(stackName)
 	stackYamlPath := filepath.Join(l.WorkDir(), fmt.Sprintf("Pulumi.%s.yaml", name))
 	err := settings.Save(stackYamlPath)
@@ -155,6 +177,9 @@ func (l *LocalWorkspace) GetAllConfig(ctx context.Context, stackName string) (Co
 // SetConfig sets the specified key-value pair on the provided stack name.
 // LocalWorkspace writes this value to the matching Pulumi.<stack>.yaml file in Workspace.WorkDir().
 func (l *LocalWorkspace) SetConfig(ctx context.Context, stackName string, key string, val ConfigValue) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	secretArg := "--plaintext"
 	if val.Secret {
 		secretArg = "--secret"
@@ -172,6 +197,9 @@ func (l *LocalWorkspace) SetConfig(ctx context.Context, stackName string, key st
 // SetAllConfig sets all values in the provided config map for the specified stack name.
 // LocalWorkspace writes the config to the matching Pulumi.<stack>.yaml file in Workspace.WorkDir().
 func (l *LocalWorkspace) SetAllConfig(ctx context.Context, stackName string, config ConfigMap) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	args := []string{"config", "set-all", "--stack", stackName}
 
 	for k, v := range config {
@@ -192,6 +220,9 @@ func (l *LocalWorkspace) SetAllConfig(ctx context.Context, stackName string, con
 // RemoveConfig removes the specified key-value pair on the provided stack name.
 // It will remove any matching values in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
 func (l *LocalWorkspace) RemoveConfig(ctx context.Context, stackName string, key string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "config", "rm", key, "--stack", stackName)
 	if err != nil {
 		return newAutoError(errors.Wrap(err, "could not remove config"), stdout, stderr, errCode)
@@ -202,6 +233,9 @@ func (l *LocalWorkspace) RemoveConfig(ctx context.Context, stackName string, key
 // RemoveAllConfig removes all values in the provided key list for the specified stack name
 // It will remove any matching values in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
 func (l *LocalWorkspace) RemoveAllConfig(ctx context.Context, stackName string, keys []string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	args := []string{"config", "rm-all", "--stack", stackName}
 	args = append(args, keys...)
 	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, args...)
@@ -214,7 +248,9 @@ func (l *LocalWorkspace) RemoveAllConfig(ctx context.Context, stackName string,
 // RefreshConfig gets and sets the config map used with the last Update for Stack matching stack name.
 // It will overwrite all configuration in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
 func (l *LocalWorkspace) RefreshConfig(ctx context.Context, stackName string) (ConfigMap, error) {
+	l.lock.Lock()
 	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "config", "refresh", "--force", "--stack", stackName)
+	l.lock.Unlock()
 	if err != nil {
 		return nil, newAutoError(errors.Wrap(err, "could not refresh config"), stdout, stderr, errCode)
 	}
@@ -226,12 +262,19 @@ func (l *LocalWorkspace) RefreshConfig(ctx context.Context, stackName string) (C
 	return cfg, nil
 }
 
-// GetEnvVars returns the environment values scoped to the current workspace.
+// GetEnvVars returns a copy of the environment values scoped to the current workspace.
 func (l *LocalWorkspace) GetEnvVars() map[string]string {
+	l.envLock.RLock()
+	defer l.envLock.RUnlock()
+
 	if l.envvars == nil {
 		return nil
 	}
-	return l.envvars
+	envvars := make(map[string]string, len(l.envvars))
+	for k, v := range l.envvars {
+		envvars[k] = v
+	}
+	return envvars
 }
 
 // SetEnvVars sets the specified map of environment values scoped to the current workspace.
@@ -244,6 +287,9 @@ func setEnvVars(l *LocalWorkspace, envvars map[string]string) error {
 	if envvars == nil {
 		return errors.New("unable to set nil environment values")
 	}
+	l.envLock.Lock()
+	defer l.envLock.Unlock()
+
 	if l.envvars == nil {
 		l.envvars = map[string]string{}
 	}
@@ -256,6 +302,9 @@ func setEnvVars(l *LocalWorkspace, envvars map[string]string) error {
 // SetEnvVar sets the specified environment value scoped to the current workspace.
 // This value will be passed to all Workspace and Stack level commands.
 func (l *LocalWorkspace) SetEnvVar(key, value string) {
+	l.envLock.Lock()
+	defer l.envLock.Unlock()
+
 	if l.envvars == nil {
 		l.envvars = map[string]string{}
 	}
@@ -265,6 +314,9 @@ func (l *LocalWorkspace) SetEnvVar(key, value string) {
 // UnsetEnvVar unsets the specified environment value scoped to the current workspace.
 // This value will be removed from all Workspace and Stack level commands.
 func (l *LocalWorkspace) UnsetEnvVar(key string) {
+	l.envLock.Lock()
+	defer l.envLock.Unlock()
+
 	if l.envvars == nil {
 		return
 	}
@@ -301,6 +353,13 @@ func (l *LocalWorkspace) WhoAmI(ctx context.Context) (string, error) {
 
 // Stack returns a summary of the currently selected stack, if any.
 func (l *LocalWorkspace) Stack(ctx context.Context) (*StackSummary, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.selectedStack(ctx)
+}
+
+func (l *LocalWorkspace) selectedStack(ctx context.Context) (*StackSummary, error) {
 	stacks, err := l.ListStacks(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not determine selected stack")
@@ -315,6 +374,9 @@ func (l *LocalWorkspace) Stack(ctx context.Context) (*StackSummary, error) {
 
 // CreateStack creates and sets a new stack with the stack name, failing if one already exists.
 func (l *LocalWorkspace) CreateStack(ctx context.Context, stackName string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	args := []string{"stack", "init", stackName}
 	if l.secretsProvider != "" {
 		args = append(args, "--secrets-provider", l.secretsProvider)
@@ -329,6 +391,13 @@ func (l *LocalWorkspace) CreateStack(ctx context.Context, stackName string) erro
 
 // SelectStack selects and sets an existing stack matching the stack name, failing if none exists.
 func (l *LocalWorkspace) SelectStack(ctx context.Context, stackName string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.selectStack(ctx, stackName)
+}
+
+func (l *LocalWorkspace) selectStack(ctx context.Context, stackName string) error {
 	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "stack", "select", stackName)
 	if err != nil {
 		return newAutoError(errors.Wrap(err, "failed to select stack"), stdout, stderr, errCode)
@@ -339,6 +408,9 @@ func (l *LocalWorkspace) SelectStack(ctx context.Context, stackName string) erro
 
 // RemoveStack deletes the stack and all associated configuration and history.
 func (l *LocalWorkspace) RemoveStack(ctx context.Context, stackName string, opts ...optremove.Option) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	args := []string{"stack", "rm", "--yes", stackName}
 
 	optRemoveOpts := &optremove.Options{}
@@ -407,11 +479,17 @@ func (l *LocalWorkspace) ListPlugins(ctx context.Context) ([]workspace.PluginInf
 // Program returns the program `pulumi.RunFunc` to be used for Preview/Update if any.
 // If none is specified, the stack will refer to ProjectSettings for this information.
 func (l *LocalWorkspace) Program() pulumi.RunFunc {
+	l.envLock.RLock()
+	defer l.envLock.RUnlock()
+
 	return l.program
 }
 
 // SetProgram sets the program associated with the Workspace to the specified `pulumi.RunFunc`.
 func (l *LocalWorkspace) SetProgram(fn pulumi.RunFunc) {
+	l.envLock.Lock()
+	defer l.envLock.Unlock()
+
 	l.program = fn
 }
 
@@ -506,6 +584,156 @@ func (l *LocalWorkspace) StackOutputs(ctx context.Context, stackName string) (Ou
 	return res, nil
 }
 
+// GetTag returns the value of the tag with the given name on the stack matching the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (l *LocalWorkspace) GetTag(ctx context.Context, stackName string, key string) (string, error) {
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "stack", "tag", "get", key, "--stack", stackName)
+	if err != nil {
+		return "", newAutoError(errors.Wrap(err, "could not get stack tag"), stdout, stderr, errCode)
+	}
+	return strings.TrimSuffix(stdout, "\n"), nil
+}
+
+// SetTag sets the tag with the given name to the given value on the stack matching the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (l *LocalWorkspace) SetTag(ctx context.Context, stackName string, key string, value string) error {
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "stack", "tag", "set", key, value, "--stack", stackName)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, "could not set stack tag"), stdout, stderr, errCode)
+	}
+	return nil
+}
+
+// RemoveTag removes the tag with the given name from the stack matching the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (l *LocalWorkspace) RemoveTag(ctx context.Context, stackName string, key string) error {
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "stack", "tag", "rm", key, "--stack", stackName)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, "could not remove stack tag"), stdout, stderr, errCode)
+	}
+	return nil
+}
+
+// ListTags returns the tags of the stack matching the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (l *LocalWorkspace) ListTags(ctx context.Context, stackName string) (map[string]string, error) {
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "stack", "tag", "ls", "--json", "--stack", stackName)
+	if err != nil {
+		return nil, newAutoError(errors.Wrap(err, "could not list stack tags"), stdout, stderr, errCode)
+	}
+	var tags map[string]string
+	err = json.Unmarshal([]byte(stdout), &tags)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal stack tags")
+	}
+	return tags, nil
+}
+
+// RenameStack renames the stack matching the given name, along with its configuration. A fully qualified new name
+// (<org-name>/<project-name>/<stack-name>) also renames the stack's project, after which the stack can't be updated
+// until the project is renamed to match.
+func (l *LocalWorkspace) RenameStack(ctx context.Context, stackName string, newName string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "stack", "rename", newName, "--stack", stackName)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, "failed to rename stack"), stdout, stderr, errCode)
+	}
+	return nil
+}
+
+// ChangeSecretsProvider changes the secrets provider of the stack matching the given name, re-encrypting the
+// secrets in its configuration and state. The secrets provider is "default", or the URL of a cloud secrets provider
+// such as "awskms://alias/ExampleAlias?region=us-east-1". Changing to the "passphrase" provider prompts for the new
+// passphrase, so it fails unless the CLI runs in an interactive terminal.
+func (l *LocalWorkspace) ChangeSecretsProvider(ctx context.Context, stackName string, secretsProvider string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx,
+		"stack", "change-secrets-provider", secretsProvider, "--stack", stackName,
+	)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, "failed to change secrets provider"), stdout, stderr, errCode)
+	}
+	return nil
+}
+
+// EnablePolicyPack enables the given version ("latest" or a version number) of the Policy Pack with the given name
+// (<org-name>/<policy-pack-name>) for an organization. Policy Packs are only supported by the Pulumi Service backend.
+func (l *LocalWorkspace) EnablePolicyPack(
+	ctx context.Context,
+	policyPack string,
+	version string,
+	opts ...optpolicy.Option,
+) error {
+	policyOpts := &optpolicy.Options{}
+	for _, o := range opts {
+		o.ApplyOption(policyOpts)
+	}
+
+	args := []string{"policy", "enable", policyPack, version}
+	if policyOpts.PolicyGroup != "" {
+		args = append(args, "--policy-group", policyOpts.PolicyGroup)
+	}
+	if policyOpts.Config != "" {
+		args = append(args, "--config", policyOpts.Config)
+	}
+
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, args...)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, "failed to enable policy pack"), stdout, stderr, errCode)
+	}
+	return nil
+}
+
+// DisablePolicyPack disables the Policy Pack with the given name (<org-name>/<policy-pack-name>) for an
+// organization. Policy Packs are only supported by the Pulumi Service backend.
+func (l *LocalWorkspace) DisablePolicyPack(ctx context.Context, policyPack string, opts ...optpolicy.Option) error {
+	policyOpts := &optpolicy.Options{}
+	for _, o := range opts {
+		o.ApplyOption(policyOpts)
+	}
+
+	args := []string{"policy", "disable", policyPack}
+	if policyOpts.PolicyGroup != "" {
+		args = append(args, "--policy-group", policyOpts.PolicyGroup)
+	}
+	if policyOpts.Version != "" {
+		args = append(args, "--version", policyOpts.Version)
+	}
+
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, args...)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, "failed to disable policy pack"), stdout, stderr, errCode)
+	}
+	return nil
+}
+
+// About returns information about the Pulumi environment of the Workspace, and of the stack matching the given
+// name, or of the currently selected stack if the name is empty. Information that can't be gathered is left empty,
+// and the reason why is reported in the result's Errors.
+func (l *LocalWorkspace) About(ctx context.Context, stackName string) (AboutInfo, error) {
+	var about AboutInfo
+
+	args := []string{"about", "--json"}
+	if stackName != "" {
+		args = append(args, "--stack", stackName)
+	}
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, args...)
+	if err != nil {
+		return about, newAutoError(errors.Wrap(err, "could not get information about the environment"),
+			stdout, stderr, errCode)
+	}
+
+	err = json.Unmarshal([]byte(stdout), &about)
+	if err != nil {
+		return about, errors.Wrap(err, "unable to unmarshal about response")
+	}
+	return about, nil
+}
+
 func (l *LocalWorkspace) getPulumiVersion(ctx context.Context) (string, error) {
 	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "version")
 	if err != nil {
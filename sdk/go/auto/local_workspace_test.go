@@ -19,12 +19,14 @@ import (
 	"context"
 	cryptorand "crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/blang/semver"
@@ -1040,6 +1042,112 @@ func TestUpsertStackInlineSource(t *testing.T) {
 	assert.Equal(t, "succeeded", dRes.Summary.Result)
 }
 
+func TestConcurrentStackOperations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	w, err := NewLocalWorkspace(ctx,
+		Project(workspace.Project{
+			Name:    tokens.PackageName(pName),
+			Runtime: workspace.NewProjectRuntimeInfo("go", nil),
+		}),
+		Program(func(ctx *pulumi.Context) error {
+			c := config.New(ctx, "")
+			ctx.Export("tenant", pulumi.String(c.Require("tenant")))
+			return nil
+		}))
+	if err != nil {
+		t.Errorf("failed to create workspace, err: %v", err)
+		t.FailNow()
+	}
+
+	// Deploy and preview many stacks of the one workspace at once. Each stack's outputs must come from its own
+	// config, whichever stack is selected in the workspace while its operations run.
+	const stackCount = 8
+	var wg sync.WaitGroup
+	for i := 0; i < stackCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sName := randomStackName()
+			s, err := UpsertStack(ctx, FullyQualifiedStackName(pulumiOrg, pName, sName), w)
+			if !assert.NoError(t, err, "failed to initialize stack") {
+				return
+			}
+			defer func() {
+				err := w.RemoveStack(ctx, s.Name())
+				assert.NoError(t, err, "failed to remove stack. Resources have leaked.")
+			}()
+
+			err = s.SetConfig(ctx, "tenant", ConfigValue{Value: sName})
+			if !assert.NoError(t, err, "failed to set config") {
+				return
+			}
+
+			res, err := s.Up(ctx)
+			if !assert.NoError(t, err, "up failed") {
+				return
+			}
+			assert.Equal(t, sName, res.Outputs["tenant"].Value)
+			assert.Equal(t, "succeeded", res.Summary.Result)
+
+			prev, err := s.Preview(ctx)
+			if !assert.NoError(t, err, "preview failed") {
+				return
+			}
+			assert.Equal(t, 1, prev.ChangeSummary[apitype.OpSame])
+
+			info, err := s.Info(ctx)
+			if !assert.NoError(t, err, "failed to get stack info") {
+				return
+			}
+			assert.True(t, strings.HasSuffix(info.Name, sName), "info of stack %s names %s", sName, info.Name)
+
+			history, err := s.History(ctx, 1 /*pageSize*/, 1 /*page*/)
+			if !assert.NoError(t, err, "failed to get history") {
+				return
+			}
+			assert.Equal(t, sName, history[0].Config[pName+":tenant"].Value)
+
+			_, err = s.Destroy(ctx)
+			assert.NoError(t, err, "destroy failed")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLocalWorkspaceConcurrentEnvVars(t *testing.T) {
+	t.Parallel()
+
+	// Commands read the environment values of the workspace while other goroutines change them.
+	w := &LocalWorkspace{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		key := "KEY_" + string(rune('A'+i))
+		go func() {
+			defer wg.Done()
+			w.SetEnvVar(key, "value")
+			w.UnsetEnvVar(key)
+			assert.NoError(t, w.SetEnvVars(map[string]string{key: "value"}))
+		}()
+		go func() {
+			defer wg.Done()
+			for k, v := range w.GetEnvVars() {
+				assert.NotEmpty(t, k)
+				assert.Equal(t, "value", v)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, w.GetEnvVars(), 10)
+
+	// The returned values are a copy.
+	w.GetEnvVars()["KEY_A"] = "changed"
+	assert.Equal(t, "value", w.GetEnvVars()["KEY_A"])
+}
+
 func TestNestedStackFails(t *testing.T) {
 	t.Parallel()
 
@@ -1699,6 +1807,48 @@ func TestMinimumVersion(t *testing.T) {
 	}
 }
 
+func TestAboutInfo(t *testing.T) {
+	t.Parallel()
+
+	// The output of `pulumi about --json` for a stack of a Go project, using the local backend.
+	stdout := `{
+  "plugins": [{"name": "random", "version": "4.8.2"}, {"name": "go", "version": null}],
+  "host": {"os": "ubuntu", "version": "22.04", "arch": "x86_64"},
+  "backend": {"name": "host", "url": "file://~", "user": "alice", "organizations": null},
+  "currentStack": {
+    "name": "dev",
+    "resources": [
+      {"type": "pulumi:pulumi:Stack", "urn": "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev"}
+    ],
+    "pendingOps": []
+  },
+  "cliAbout": {"version": "3.40.0", "goVersion": "go1.18.5", "goCompiler": "gc"},
+  "runtime": {"language": "go", "version": "go1.18.5", "executable": "/usr/local/go/bin/go"},
+  "dependencies": [{"name": "github.com/pulumi/pulumi/sdk/v3", "version": "3.40.0"}],
+  "errors": ["Failed to get information about the current user: not logged in"]
+}`
+
+	var about AboutInfo
+	require.NoError(t, json.Unmarshal([]byte(stdout), &about))
+
+	assert.Equal(t, []AboutPlugin{{Name: "random", Version: "4.8.2"}, {Name: "go"}}, about.Plugins)
+	assert.Equal(t, &AboutHost{OS: "ubuntu", Version: "22.04", Arch: "x86_64"}, about.Host)
+	assert.Equal(t, &AboutBackend{Name: "host", URL: "file://~", User: "alice"}, about.Backend)
+	assert.Equal(t, &AboutStack{
+		Name: "dev",
+		Resources: []AboutResource{{
+			Type: "pulumi:pulumi:Stack",
+			URN:  "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev",
+		}},
+		PendingOps: []AboutResource{},
+	}, about.CurrentStack)
+	assert.Equal(t, &AboutCLI{Version: "3.40.0", GoVersion: "go1.18.5", GoCompiler: "gc"}, about.CLI)
+	assert.Equal(t, "go", about.Runtime["language"])
+	assert.Equal(t, "/usr/local/go/bin/go", about.Runtime["executable"])
+	assert.Equal(t, []AboutDependency{{Name: "github.com/pulumi/pulumi/sdk/v3", Version: "3.40.0"}}, about.Dependencies)
+	assert.Equal(t, []string{"Failed to get information about the current user: not logged in"}, about.Errors)
+}
+
 func TestProjectSettingsRespected(t *testing.T) {
 	t.Parallel()
 
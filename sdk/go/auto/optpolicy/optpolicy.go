@@ -0,0 +1,63 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package optpolicy contains functional options to be used with workspace policy pack operations
+// github.com/sdk/v3/go/auto workspace.EnablePolicyPack(ctx, policyPack, version, ...optpolicy.Option)
+package optpolicy
+
+// PolicyGroup selects the Policy Group for which the Policy Pack is enabled or disabled. If not specified, the
+// organization's default Policy Group is used.
+func PolicyGroup(name string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.PolicyGroup = name
+	})
+}
+
+// Config is the path of a JSON file containing the configuration of the Policy Pack being enabled.
+func Config(path string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Config = path
+	})
+}
+
+// Version is the version of the Policy Pack being disabled. If not specified, any enabled version is disabled.
+func Version(version string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Version = version
+	})
+}
+
+// Option is a parameter to be applied to a Workspace.EnablePolicyPack() or Workspace.DisablePolicyPack() operation
+type Option interface {
+	ApplyOption(*Options)
+}
+
+// ---------------------------------- implementation details ----------------------------------
+
+// Options is an implementation detail
+type Options struct {
+	// the Policy Group to enable or disable the Policy Pack for
+	PolicyGroup string
+	// the path of the Policy Pack's configuration file, when enabling it
+	Config string
+	// the version of the Policy Pack to disable
+	Version string
+}
+
+type optionFunc func(*Options)
+
+// ApplyOption is an implementation detail
+func (o optionFunc) ApplyOption(opts *Options) {
+	o(opts)
+}
@@ -80,6 +80,21 @@ func DebugLogging(debugOpts debug.LoggingOptions) Option {
 	})
 }
 
+// Exclude specifies a list of resource URNs to leave out of the operation. With ExcludeDependents, the resources
+// that depend on them are left out as well.
+func Exclude(urns []string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Exclude = urns
+	})
+}
+
+// ExcludeDependents leaves the dependents of the excluded resources out of the operation as well
+func ExcludeDependents() Option {
+	return optionFunc(func(opts *Options) {
+		opts.ExcludeDependents = true
+	})
+}
+
 // ProgressStreams allows specifying one or more io.Writers to redirect incremental preview output
 func ProgressStreams(writers ...io.Writer) Option {
 	return optionFunc(func(opts *Options) {
@@ -134,6 +149,10 @@ type Options struct {
 	TargetDependents bool
 	// DebugLogOpts specifies additional settings for debug logging
 	DebugLogOpts debug.LoggingOptions
+	// Specify a list of resource URNs to leave out of the operation
+	Exclude []string
+	// Leave the dependents of the excluded resources out of the operation as well
+	ExcludeDependents bool
 	// ProgressStreams allows specifying one or more io.Writers to redirect incremental preview output
 	ProgressStreams []io.Writer
 	// EventStreams allows specifying one or more channels to receive the Pulumi event stream
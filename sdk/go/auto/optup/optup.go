@@ -73,6 +73,29 @@ func TargetDependents() Option {
 	})
 }
 
+// ContinueOnError continues updating resources that do not depend on a failed resource instead of stopping the
+// update at the first failure
+func ContinueOnError() Option {
+	return optionFunc(func(opts *Options) {
+		opts.ContinueOnError = true
+	})
+}
+
+// Exclude specifies a list of resource URNs to leave out of the operation. With ExcludeDependents, the resources
+// that depend on them are left out as well.
+func Exclude(urns []string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Exclude = urns
+	})
+}
+
+// ExcludeDependents leaves the dependents of the excluded resources out of the operation as well
+func ExcludeDependents() Option {
+	return optionFunc(func(opts *Options) {
+		opts.ExcludeDependents = true
+	})
+}
+
 // ProgressStreams allows specifying one or more io.Writers to redirect incremental update output
 func ProgressStreams(writers ...io.Writer) Option {
 	return optionFunc(func(opts *Options) {
@@ -139,8 +162,14 @@ type Options struct {
 	Target []string
 	// Allows updating of dependent targets discovered but not specified in the Target list
 	TargetDependents bool
+	// Continue updating resources that do not depend on a failed resource
+	ContinueOnError bool
 	// DebugLogOpts specifies additional settings for debug logging
 	DebugLogOpts debug.LoggingOptions
+	// Specify a list of resource URNs to leave out of the operation
+	Exclude []string
+	// Leave the dependents of the excluded resources out of the operation as well
+	ExcludeDependents bool
 	// ProgressStreams allows specifying one or more io.Writers to redirect incremental update output
 	ProgressStreams []io.Writer
 	// EventStreams allows specifying one or more channels to receive the Pulumi event stream
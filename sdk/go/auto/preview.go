@@ -0,0 +1,137 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+// PolicyViolation is a violation of a policy reported during an operation.
+type PolicyViolation struct {
+	// URN is the resource that violates the policy, if the policy validates resources.
+	URN               resource.URN `json:"urn,omitempty"`
+	PolicyPackName    string       `json:"policyPackName"`
+	PolicyPackVersion string       `json:"policyPackVersion"`
+	PolicyName        string       `json:"policyName"`
+	Message           string       `json:"message"`
+	// EnforcementLevel is the enforcement level of the policy, such as "advisory" or "mandatory".
+	EnforcementLevel string `json:"enforcementLevel"`
+}
+
+// PreviewSteps assembles the steps and policy violations of a preview from its engine events. Stack.Preview uses it to
+// fill in PreviewResult; workspaces that run previews by other means can use it to do the same.
+func PreviewSteps(events []apitype.EngineEvent) ([]PreviewStep, []PolicyViolation) {
+	var steps []PreviewStep
+	var violations []PolicyViolation
+	for _, e := range events {
+		switch {
+		case e.ResourcePreEvent != nil:
+			steps = append(steps, newPreviewStep(e.ResourcePreEvent.Metadata))
+		case e.PolicyEvent != nil:
+			p := e.PolicyEvent
+			violations = append(violations, PolicyViolation{
+				URN:               resource.URN(p.ResourceURN),
+				PolicyPackName:    p.PolicyPackName,
+				PolicyPackVersion: p.PolicyPackVersion,
+				PolicyName:        p.PolicyName,
+				Message:           colors.Never.Colorize(p.Message),
+				EnforcementLevel:  p.EnforcementLevel,
+			})
+		}
+	}
+
+	// Policies are checked before the steps that they apply to are reported, so the violations are attributed to
+	// the steps once all of the events have been seen.
+	for _, v := range violations {
+		if v.URN == "" {
+			continue
+		}
+		for i := range steps {
+			if steps[i].URN == v.URN {
+				steps[i].PolicyViolations = append(steps[i].PolicyViolations, v)
+			}
+		}
+	}
+	return steps, violations
+}
+
+func newPreviewStep(md apitype.StepEventMetadata) PreviewStep {
+	step := PreviewStep{
+		Op:             string(md.Op),
+		URN:            resource.URN(md.URN),
+		Type:           tokens.Type(md.Type),
+		Provider:       md.Provider,
+		OldState:       newPreviewStepState(md.Old),
+		NewState:       newPreviewStepState(md.New),
+		DiffReasons:    propertyKeys(md.Diffs),
+		ReplaceReasons: propertyKeys(md.Keys),
+	}
+	if md.DetailedDiff != nil {
+		step.DetailedDiff = make(map[string]PropertyDiff, len(md.DetailedDiff))
+		for k, v := range md.DetailedDiff {
+			step.DetailedDiff[k] = PropertyDiff{Kind: string(v.Kind), InputDiff: v.InputDiff}
+		}
+	}
+	return step
+}
+
+func newPreviewStepState(md *apitype.StepEventStateMetadata) *apitype.ResourceV3 {
+	if md == nil {
+		return nil
+	}
+	return &apitype.ResourceV3{
+		URN:        resource.URN(md.URN),
+		Custom:     md.Custom,
+		Delete:     md.Delete,
+		ID:         resource.ID(md.ID),
+		Type:       tokens.Type(md.Type),
+		Inputs:     md.Inputs,
+		Outputs:    md.Outputs,
+		Parent:     resource.URN(md.Parent),
+		Protect:    md.Protect,
+		Provider:   md.Provider,
+		InitErrors: md.InitErrors,
+	}
+}
+
+func propertyKeys(keys []string) []resource.PropertyKey {
+	if keys == nil {
+		return nil
+	}
+	result := make([]resource.PropertyKey, len(keys))
+	for i, k := range keys {
+		result[i] = resource.PropertyKey(k)
+	}
+	return result
+}
+
+// WritePlan writes the update plan that the preview saved to the given path, so that an update can be constrained to
+// the previewed changes by passing the path to optup.Plan. It fails if the preview didn't save a plan.
+func (pr *PreviewResult) WritePlan(path string) error {
+	if len(pr.Plan) == 0 {
+		return errors.New("the preview did not save a plan; pass optpreview.Plan to the preview to save one")
+	}
+	if err := ioutil.WriteFile(path, pr.Plan, 0600); err != nil {
+		return errors.Wrap(err, "failed to write plan")
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+)
+
+const previewBucketURN = "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::bucket"
+
+func TestPreviewSteps(t *testing.T) {
+	t.Parallel()
+
+	var events []apitype.EngineEvent
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"preludeEvent": {"config": {}}},
+		{"policyEvent": {"resourceUrn": "`+previewBucketURN+`", "message": "<{%fg 1%}>bucket is public<{%reset%}>",
+			"policyName": "no-public-buckets", "policyPackName": "security", "policyPackVersion": "1",
+			"enforcementLevel": "advisory"}},
+		{"policyEvent": {"message": "too many buckets", "policyName": "bucket-count",
+			"policyPackName": "security", "policyPackVersion": "1", "enforcementLevel": "mandatory"}},
+		{"resourcePreEvent": {"metadata": {
+			"op": "replace",
+			"urn": "`+previewBucketURN+`",
+			"type": "aws:s3/bucket:Bucket",
+			"old": {"type": "aws:s3/bucket:Bucket", "urn": "`+previewBucketURN+`", "custom": true,
+				"id": "bucket-1234", "inputs": {"acl": "private"}, "outputs": {"acl": "private"}},
+			"new": {"type": "aws:s3/bucket:Bucket", "urn": "`+previewBucketURN+`", "custom": true,
+				"inputs": {"acl": "public-read"}},
+			"keys": ["acl"],
+			"diffs": ["acl"],
+			"detailedDiff": {"acl": {"diffKind": "update-replace", "inputDiff": true}},
+			"provider": "urn:pulumi:dev::proj::pulumi:providers:aws::default::provider-id"
+		}}},
+		{"summaryEvent": {"resourceChanges": {"replace": 1}}}
+	]`), &events))
+
+	steps, violations := PreviewSteps(events)
+	require.Len(t, violations, 2)
+	assert.Equal(t, PolicyViolation{
+		URN:               previewBucketURN,
+		PolicyPackName:    "security",
+		PolicyPackVersion: "1",
+		PolicyName:        "no-public-buckets",
+		Message:           "bucket is public",
+		EnforcementLevel:  "advisory",
+	}, violations[0])
+	assert.Equal(t, resource.URN(""), violations[1].URN)
+
+	require.Len(t, steps, 1)
+	step := steps[0]
+	assert.Equal(t, string(apitype.OpReplace), step.Op)
+	assert.Equal(t, resource.URN(previewBucketURN), step.URN)
+	assert.Equal(t, tokens.Type("aws:s3/bucket:Bucket"), step.Type)
+	assert.Equal(t, []resource.PropertyKey{"acl"}, step.ReplaceReasons)
+	assert.Equal(t, []resource.PropertyKey{"acl"}, step.DiffReasons)
+	assert.Equal(t, map[string]PropertyDiff{"acl": {Kind: "update-replace", InputDiff: true}}, step.DetailedDiff)
+	assert.Equal(t, resource.ID("bucket-1234"), step.OldState.ID)
+	assert.Equal(t, map[string]interface{}{"acl": "public-read"}, step.NewState.Inputs)
+	// Only the violation of the bucket's policy is attributed to its step.
+	assert.Equal(t, violations[:1], step.PolicyViolations)
+
+	// Preview results round-trip through JSON, so they can be saved for approval.
+	res := PreviewResult{
+		ChangeSummary:    map[apitype.OpType]int{apitype.OpReplace: 1},
+		Steps:            steps,
+		PolicyViolations: violations,
+		Plan:             json.RawMessage(`{"resourcePlans":{}}`),
+	}
+	b, err := json.Marshal(res)
+	require.NoError(t, err)
+	var decoded PreviewResult
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, res, decoded)
+}
+
+func TestPreviewResultWritePlan(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	var res PreviewResult
+	err := res.WritePlan(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did not save a plan")
+
+	res.Plan = json.RawMessage(`{"resourcePlans": {}}`)
+	require.NoError(t, res.WritePlan(path))
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"resourcePlans": {}}`, string(b))
+}
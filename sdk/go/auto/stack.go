@@ -42,7 +42,7 @@
 //	 err := stack.SetConfig(ctx, "key", ConfigValue{ Value: "value", Secret: true })
 //	 preRes, err := stack.Preview(ctx)
 //	 // detailed info about results
-//	 fmt.Println(preRes.prev.Steps[0].URN)
+//	 fmt.Println(preRes.Steps[0].URN)
 // The Automation API provides a natural way to orchestrate multiple stacks,
 // feeding the output of one stack as an input to the next as shown in the package-level example below.
 // The package can be used for a number of use cases:
@@ -80,6 +80,15 @@
 // the backing Workspace implementation, the Pulumi SaaS Console will still be able to display configuration
 // applied to updates as it does with the local version of the Workspace today.
 //
+// Operations on different stacks of a LocalWorkspace can run concurrently, so a single workspace can deploy many
+// stacks of a project at once:
+//	 for _, name := range []string{"tenant-a", "tenant-b", "tenant-c"} {
+//		 go func(name string) {
+//			 s, err := UpsertStack(ctx, name, w)
+//			 // ... s.Up(ctx)
+//		 }(name)
+//	 }
+//
 // The Automation API also provides error handling utilities to detect common cases such as concurrent update
 // conflicts:
 // 	uRes, err :=stack.Up(ctx)
@@ -108,12 +117,14 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/opthistory"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optimport"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/constant"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/rpcutil"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -233,6 +244,12 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 	for _, tURN := range preOpts.Target {
 		sharedArgs = append(sharedArgs, fmt.Sprintf("--target=%s", tURN))
 	}
+	for _, eURN := range preOpts.Exclude {
+		sharedArgs = append(sharedArgs, fmt.Sprintf("--exclude=%s", eURN))
+	}
+	if preOpts.ExcludeDependents {
+		sharedArgs = append(sharedArgs, "--exclude-dependents")
+	}
 	for _, pack := range preOpts.PolicyPacks {
 		sharedArgs = append(sharedArgs, fmt.Sprintf("--policy-pack=%s", pack))
 	}
@@ -270,6 +287,7 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 	args = append(args, sharedArgs...)
 
 	var summaryEvents []apitype.SummaryEvent
+	var engineEvents []apitype.EngineEvent
 	eventChannel := make(chan events.EngineEvent)
 	eventsDone := make(chan bool)
 	go func() {
@@ -279,6 +297,9 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 				close(eventsDone)
 				return
 			}
+			if event.Error == nil {
+				engineEvents = append(engineEvents, event.EngineEvent)
+			}
 			if event.SummaryEvent != nil {
 				summaryEvents = append(summaryEvents, *event.SummaryEvent)
 			}
@@ -314,6 +335,12 @@ func (s *Stack) Preview(ctx context.Context, opts ...optpreview.Option) (Preview
 	res.StdOut = stdout
 	res.StdErr = stderr
 	res.ChangeSummary = summaryEvents[0].ResourceChanges
+	res.Steps, res.PolicyViolations = PreviewSteps(engineEvents)
+	if preOpts.Plan != "" {
+		if res.Plan, err = ioutil.ReadFile(preOpts.Plan); err != nil {
+			return res, errors.Wrap(err, "failed to read the saved plan")
+		}
+	}
 
 	return res, nil
 }
@@ -346,6 +373,12 @@ func (s *Stack) Up(ctx context.Context, opts ...optup.Option) (UpResult, error)
 	for _, tURN := range upOpts.Target {
 		sharedArgs = append(sharedArgs, fmt.Sprintf("--target=%s", tURN))
 	}
+	for _, eURN := range upOpts.Exclude {
+		sharedArgs = append(sharedArgs, fmt.Sprintf("--exclude=%s", eURN))
+	}
+	if upOpts.ExcludeDependents {
+		sharedArgs = append(sharedArgs, "--exclude-dependents")
+	}
 	for _, pack := range upOpts.PolicyPacks {
 		sharedArgs = append(sharedArgs, fmt.Sprintf("--policy-pack=%s", pack))
 	}
@@ -355,6 +388,9 @@ func (s *Stack) Up(ctx context.Context, opts ...optup.Option) (UpResult, error)
 	if upOpts.TargetDependents {
 		sharedArgs = append(sharedArgs, "--target-dependents")
 	}
+	if upOpts.ContinueOnError {
+		sharedArgs = append(sharedArgs, "--continue-on-error")
+	}
 	if upOpts.Parallel > 0 {
 		sharedArgs = append(sharedArgs, fmt.Sprintf("--parallel=%d", upOpts.Parallel))
 	}
@@ -446,6 +482,12 @@ func (s *Stack) Refresh(ctx context.Context, opts ...optrefresh.Option) (Refresh
 	for _, tURN := range refreshOpts.Target {
 		args = append(args, fmt.Sprintf("--target=%s", tURN))
 	}
+	for _, eURN := range refreshOpts.Exclude {
+		args = append(args, fmt.Sprintf("--exclude=%s", eURN))
+	}
+	if refreshOpts.ExcludeDependents {
+		args = append(args, "--exclude-dependents")
+	}
 	if refreshOpts.Parallel > 0 {
 		args = append(args, fmt.Sprintf("--parallel=%d", refreshOpts.Parallel))
 	}
@@ -518,6 +560,12 @@ func (s *Stack) Destroy(ctx context.Context, opts ...optdestroy.Option) (Destroy
 	for _, tURN := range destroyOpts.Target {
 		args = append(args, fmt.Sprintf("--target=%s", tURN))
 	}
+	for _, eURN := range destroyOpts.Exclude {
+		args = append(args, fmt.Sprintf("--exclude=%s", eURN))
+	}
+	if destroyOpts.ExcludeDependents {
+		args = append(args, "--exclude-dependents")
+	}
 	if destroyOpts.TargetDependents {
 		args = append(args, "--target-dependents")
 	}
@@ -574,6 +622,114 @@ func (s *Stack) Destroy(ctx context.Context, opts ...optdestroy.Option) (Destroy
 	return res, nil
 }
 
+// ImportResources adopts existing resources into the stack, as `pulumi import --file` does. Unless
+// optimport.GenerateCode(false) is passed, the code declaring the imported resources is generated in the project's
+// language and returned with the result; it should be added to the program, otherwise the next Stack.Up() will delete
+// the imported resources.
+func (s *Stack) ImportResources(ctx context.Context, resources []ImportResource,
+	opts ...optimport.Option) (ImportResult, error) {
+	var res ImportResult
+
+	importOpts := &optimport.Options{}
+	for _, o := range opts {
+		o.ApplyOption(importOpts)
+	}
+
+	tempDir, err := ioutil.TempDir("", "automation-import-")
+	if err != nil {
+		return res, errors.Wrap(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+
+	importFile := filepath.Join(tempDir, "resources.json")
+	spec, err := json.Marshal(importSpec{NameTable: importOpts.NameTable, Resources: resources})
+	if err != nil {
+		return res, errors.Wrap(err, "failed to serialize resources")
+	}
+	if err = ioutil.WriteFile(importFile, spec, 0600); err != nil {
+		return res, errors.Wrap(err, "failed to write resources")
+	}
+
+	var args []string
+
+	args = debug.AddArgs(&importOpts.DebugLogOpts, args)
+	args = append(args, "import", "--yes", "--skip-preview", "--file", importFile)
+	generateCode := importOpts.GenerateCode == nil || *importOpts.GenerateCode
+	codeFile := filepath.Join(tempDir, "code.txt")
+	if generateCode {
+		args = append(args, "--out", codeFile)
+	} else {
+		args = append(args, "--generate-code=false")
+	}
+	if importOpts.Protect != nil {
+		args = append(args, fmt.Sprintf("--protect=%t", *importOpts.Protect))
+	}
+	if importOpts.Message != "" {
+		args = append(args, fmt.Sprintf("--message=%q", importOpts.Message))
+	}
+	if importOpts.Parallel > 0 {
+		args = append(args, fmt.Sprintf("--parallel=%d", importOpts.Parallel))
+	}
+	if importOpts.UserAgent != "" {
+		args = append(args, fmt.Sprintf("--exec-agent=%s", importOpts.UserAgent))
+	}
+	if importOpts.Color != "" {
+		args = append(args, fmt.Sprintf("--color=%s", importOpts.Color))
+	}
+	execKind := constant.ExecKindAutoLocal
+	if s.Workspace().Program() != nil {
+		execKind = constant.ExecKindAutoInline
+	}
+	args = append(args, fmt.Sprintf("--exec-kind=%s", execKind))
+
+	if len(importOpts.EventStreams) > 0 {
+		eventChannels := importOpts.EventStreams
+		t, err := tailLogs("import", eventChannels)
+		if err != nil {
+			return res, errors.Wrap(err, "failed to tail logs")
+		}
+		defer t.Close()
+		args = append(args, "--event-log", t.Filename)
+	}
+
+	stdout, stderr, code, err := s.runPulumiCmdSync(ctx, importOpts.ProgressStreams, args...)
+	if err != nil {
+		return res, newAutoError(errors.Wrap(err, "failed to import resources"), stdout, stderr, code)
+	}
+
+	var generatedCode []byte
+	if generateCode {
+		// No code is generated if none of the resources could be imported.
+		generatedCode, err = ioutil.ReadFile(codeFile)
+		if err != nil && !os.IsNotExist(err) {
+			return res, errors.Wrap(err, "failed to read generated code")
+		}
+	}
+
+	historyOpts := []opthistory.Option{}
+	if showSecrets := importOpts.ShowSecrets; showSecrets != nil {
+		historyOpts = append(historyOpts, opthistory.ShowSecrets(*showSecrets))
+	}
+	history, err := s.History(ctx, 1 /*pageSize*/, 1 /*page*/, historyOpts...)
+	if err != nil {
+		return res, errors.Wrap(err, "failed to import resources")
+	}
+
+	var summary UpdateSummary
+	if len(history) > 0 {
+		summary = history[0]
+	}
+
+	res = ImportResult{
+		GeneratedCode: string(generatedCode),
+		Summary:       summary,
+		StdOut:        stdout,
+		StdErr:        stderr,
+	}
+
+	return res, nil
+}
+
 // Outputs get the current set of Stack outputs from the last Stack.Up().
 func (s *Stack) Outputs(ctx context.Context) (OutputMap, error) {
 	return s.Workspace().StackOutputs(ctx, s.Name())
@@ -583,10 +739,6 @@ func (s *Stack) Outputs(ctx context.Context) (OutputMap, error) {
 // (up/preview/refresh/destroy).
 func (s *Stack) History(ctx context.Context,
 	pageSize int, page int, opts ...opthistory.Option) ([]UpdateSummary, error) {
-	err := s.Workspace().SelectStack(ctx, s.Name())
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get stack history")
-	}
 	var options opthistory.Options
 	for _, opt := range opts {
 		opt.ApplyOption(&options)
@@ -656,15 +808,10 @@ func (s *Stack) RefreshConfig(ctx context.Context) (ConfigMap, error) {
 	return s.Workspace().RefreshConfig(ctx, s.Name())
 }
 
-// Info returns a summary of the Stack including its URL.
+// Info returns a summary of the Stack including its URL. It selects the stack in the workspace.
 func (s *Stack) Info(ctx context.Context) (StackSummary, error) {
 	var info StackSummary
-	err := s.Workspace().SelectStack(ctx, s.Name())
-	if err != nil {
-		return info, errors.Wrap(err, "failed to fetch stack info")
-	}
-
-	summary, err := s.Workspace().Stack(ctx)
+	summary, err := s.selectAndSummarize(ctx)
 	if err != nil {
 		return info, errors.Wrap(err, "failed to fetch stack info")
 	}
@@ -676,6 +823,25 @@ func (s *Stack) Info(ctx context.Context) (StackSummary, error) {
 	return info, nil
 }
 
+// selectAndSummarize selects the stack and returns the summary of the selected stack. A LocalWorkspace holds its lock
+// while it does so, so that the summary is of this stack even if other goroutines select other stacks.
+func (s *Stack) selectAndSummarize(ctx context.Context) (*StackSummary, error) {
+	if l, ok := s.Workspace().(*LocalWorkspace); ok {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		if err := l.selectStack(ctx, s.Name()); err != nil {
+			return nil, err
+		}
+		return l.selectedStack(ctx)
+	}
+
+	if err := s.Workspace().SelectStack(ctx, s.Name()); err != nil {
+		return nil, err
+	}
+	return s.Workspace().Stack(ctx)
+}
+
 // Cancel stops a stack's currently running update. It returns an error if no update is currently running.
 // Note that this operation is _very dangerous_, and may leave the stack in an inconsistent state
 // if a resource operation was pending when the update was canceled.
@@ -704,6 +870,50 @@ func (s *Stack) Import(ctx context.Context, state apitype.UntypedDeployment) err
 	return s.Workspace().ImportStack(ctx, s.Name(), state)
 }
 
+// GetTag returns the value of the stack's tag with the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (s *Stack) GetTag(ctx context.Context, key string) (string, error) {
+	return s.Workspace().GetTag(ctx, s.Name(), key)
+}
+
+// SetTag sets the stack's tag with the given name to the given value.
+// Stack tags are only supported by the Pulumi Service backend.
+func (s *Stack) SetTag(ctx context.Context, key string, value string) error {
+	return s.Workspace().SetTag(ctx, s.Name(), key, value)
+}
+
+// RemoveTag removes the stack's tag with the given name.
+// Stack tags are only supported by the Pulumi Service backend.
+func (s *Stack) RemoveTag(ctx context.Context, key string) error {
+	return s.Workspace().RemoveTag(ctx, s.Name(), key)
+}
+
+// ListTags returns the stack's tags.
+// Stack tags are only supported by the Pulumi Service backend.
+func (s *Stack) ListTags(ctx context.Context) (map[string]string, error) {
+	return s.Workspace().ListTags(ctx, s.Name())
+}
+
+// Rename renames the stack, along with its configuration. Once renamed, the Stack refers to the stack's new name.
+func (s *Stack) Rename(ctx context.Context, newName string) error {
+	if err := s.Workspace().RenameStack(ctx, s.Name(), newName); err != nil {
+		return err
+	}
+	s.stackName = newName
+	return nil
+}
+
+// ChangeSecretsProvider changes the stack's secrets provider, re-encrypting the secrets in its configuration and
+// state. See Workspace.ChangeSecretsProvider for the supported secrets providers.
+func (s *Stack) ChangeSecretsProvider(ctx context.Context, secretsProvider string) error {
+	return s.Workspace().ChangeSecretsProvider(ctx, s.Name(), secretsProvider)
+}
+
+// About returns information about the stack and the Pulumi environment of its Workspace.
+func (s *Stack) About(ctx context.Context) (AboutInfo, error) {
+	return s.Workspace().About(ctx, s.Name())
+}
+
 // UpdateSummary provides a summary of a Stack lifecycle operation (up/preview/refresh/destroy).
 type UpdateSummary struct {
 	Version     int               `json:"version"`
@@ -774,6 +984,8 @@ type PreviewStep struct {
 	Op string `json:"op"`
 	// URN is the resource being affected by this operation.
 	URN resource.URN `json:"urn"`
+	// Type is the type of the resource.
+	Type tokens.Type `json:"type,omitempty"`
 	// Provider is the provider that will perform this step.
 	Provider string `json:"provider,omitempty"`
 	// OldState is the old state for this resource, if appropriate given the operation type.
@@ -786,6 +998,8 @@ type PreviewStep struct {
 	ReplaceReasons []resource.PropertyKey `json:"replaceReasons,omitempty"`
 	// DetailedDiff is a structured diff that indicates precise per-property differences.
 	DetailedDiff map[string]PropertyDiff `json:"detailedDiff"`
+	// PolicyViolations are the violations of policies by the resource's new state.
+	PolicyViolations []PolicyViolation `json:"policyViolations,omitempty"`
 }
 
 // PropertyDiff contains information about the difference in a single property value.
@@ -796,11 +1010,20 @@ type PropertyDiff struct {
 	InputDiff bool `json:"inputDiff"`
 }
 
-// PreviewResult is the output of Stack.Preview() describing the expected set of changes from the next Stack.Up()
+// PreviewResult is the output of Stack.Preview() describing the expected set of changes from the next Stack.Up().
+// It can be serialized as JSON, for example to be approved before the update is run.
 type PreviewResult struct {
-	StdOut        string
-	StdErr        string
-	ChangeSummary map[apitype.OpType]int
+	StdOut        string                 `json:"stdout"`
+	StdErr        string                 `json:"stderr"`
+	ChangeSummary map[apitype.OpType]int `json:"changeSummary"`
+	// Steps are the steps that the update would take, in the order in which the preview reported them, including the
+	// steps that leave resources unchanged.
+	Steps []PreviewStep `json:"steps,omitempty"`
+	// PolicyViolations are all of the violations of policies that the preview reported.
+	PolicyViolations []PolicyViolation `json:"policyViolations,omitempty"`
+	// Plan is the update plan saved by the preview if optpreview.Plan was given. Write it with WritePlan to constrain
+	// an update to the previewed changes.
+	Plan json.RawMessage `json:"plan,omitempty"`
 }
 
 // GetPermalink returns the permalink URL in the Pulumi Console for the preview operation.
@@ -832,6 +1055,46 @@ func (dr *DestroyResult) GetPermalink() (string, error) {
 	return GetPermalink(dr.StdOut)
 }
 
+// ImportResource describes an existing resource to adopt into a stack with Stack.ImportResources. It has the same
+// shape as the resources listed in the file passed to `pulumi import --file`.
+type ImportResource struct {
+	// Type is the type token of the resource, such as "aws:s3/bucket:Bucket".
+	Type string `json:"type"`
+	// Name is the name to give the resource in the stack.
+	Name string `json:"name"`
+	// ID is the provider's ID of the existing resource.
+	ID string `json:"id"`
+	// Parent (optional) is the name of the resource's parent in the name table.
+	Parent string `json:"parent,omitempty"`
+	// Provider (optional) is the name of the provider to import the resource with in the name table.
+	Provider string `json:"provider,omitempty"`
+	// Version (optional) is the version of the provider plugin to import the resource with.
+	Version string `json:"version,omitempty"`
+	// Properties (optional) are the names of the input properties to import. Defaults to all of them.
+	Properties []string `json:"properties,omitempty"`
+}
+
+// importSpec is the format of the file passed to `pulumi import --file`.
+type importSpec struct {
+	NameTable map[string]string `json:"nameTable,omitempty"`
+	Resources []ImportResource  `json:"resources"`
+}
+
+// ImportResult is the output of a successful Stack.ImportResources operation
+type ImportResult struct {
+	StdOut string
+	StdErr string
+	// GeneratedCode declares the imported resources in the language of the project. It is empty if
+	// optimport.GenerateCode(false) was passed.
+	GeneratedCode string
+	Summary       UpdateSummary
+}
+
+// GetPermalink returns the permalink URL in the Pulumi Console for the import operation.
+func (ir *ImportResult) GetPermalink() (string, error) {
+	return GetPermalink(ir.StdOut)
+}
+
 // secretSentinel represents the CLI response for an output marked as "secret"
 const secretSentinel = "[secret]"
 
@@ -839,6 +1102,17 @@ func (s *Stack) runPulumiCmdSync(
 	ctx context.Context,
 	additionalOutput []io.Writer,
 	args ...string,
+) (string, string, int, error) {
+	return s.runPulumiCmdSyncWithStackFlag(ctx, "--stack", additionalOutput, args...)
+}
+
+// runPulumiCmdSyncWithStackFlag runs a command that selects the stack to operate on with the given flag, rather than
+// with --stack.
+func (s *Stack) runPulumiCmdSyncWithStackFlag(
+	ctx context.Context,
+	stackFlag string,
+	additionalOutput []io.Writer,
+	args ...string,
 ) (string, string, int, error) {
 	var env []string
 	debugEnv := fmt.Sprintf("%s=%s", "PULUMI_DEBUG_COMMANDS", "true")
@@ -858,7 +1132,7 @@ func (s *Stack) runPulumiCmdSync(
 		return "", "", -1, errors.Wrap(err, "failed to exec command, error getting additional args")
 	}
 	args = append(args, additionalArgs...)
-	args = append(args, "--stack", s.Name())
+	args = append(args, stackFlag, s.Name())
 
 	stdout, stderr, errCode, err := runPulumiCommandSync(ctx, s.Workspace().WorkDir(), additionalOutput, env, args...)
 	if err != nil {
@@ -0,0 +1,181 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// StackState edits the state of a stack, as the `pulumi state` commands do. These edits change what Pulumi knows
+// about the stack's resources, not the resources themselves.
+type StackState struct {
+	stack *Stack
+}
+
+// State returns a handle that lists and edits the resources in the stack's state.
+func (s *Stack) State() *StackState {
+	return &StackState{stack: s}
+}
+
+// Resources returns the resources in the stack's state, in dependency order.
+func (st *StackState) Resources(ctx context.Context) ([]apitype.ResourceV3, error) {
+	dep, err := st.stack.Export(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to export stack state")
+	}
+	deployment, err := DecodeDeployment(dep)
+	if err != nil {
+		return nil, err
+	}
+	return deployment.Resources, nil
+}
+
+// Delete deletes the resource with the given URN from the stack's state. Resources that other resources depend on or
+// are parented to can't be deleted, and protected resources are only deleted if force is true.
+func (st *StackState) Delete(ctx context.Context, urn string, force bool) error {
+	args := []string{"state", "delete", urn, "--yes"}
+	if force {
+		args = append(args, "--force")
+	}
+	return st.run(ctx, "failed to delete resource", args...)
+}
+
+// Rename renames the resource with the given URN in the stack's state.
+func (st *StackState) Rename(ctx context.Context, urn, newName string) error {
+	return st.run(ctx, "failed to rename resource", "state", "rename", urn, newName, "--yes")
+}
+
+// Protect protects the resources with the given URNs from deletion. The URNs may contain wildcards.
+func (st *StackState) Protect(ctx context.Context, urns ...string) error {
+	args := append([]string{"state", "protect", "--yes"}, urns...)
+	return st.run(ctx, "failed to protect resources", args...)
+}
+
+// Unprotect removes the protection of the resources with the given URNs. The URNs may contain wildcards.
+func (st *StackState) Unprotect(ctx context.Context, urns ...string) error {
+	args := append([]string{"state", "unprotect", "--yes"}, urns...)
+	return st.run(ctx, "failed to unprotect resources", args...)
+}
+
+// Move moves the resources with the given URNs, along with their children, to the state of another stack of the same
+// backend.
+func (st *StackState) Move(ctx context.Context, destStack string, urns ...string) error {
+	args := append([]string{"state", "move", "--yes", "--dest", destStack}, urns...)
+	stdout, stderr, code, err := st.stack.runPulumiCmdSyncWithStackFlag(ctx, "--source", nil, args...)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, "failed to move resources"), stdout, stderr, code)
+	}
+	return nil
+}
+
+// Edit exports the stack's state, applies the given edit to it, and imports the result back into the stack. The
+// edited state is checked with VerifyDeployment before it is imported, and left unimported if the check fails.
+func (st *StackState) Edit(ctx context.Context, edit func(deployment *apitype.DeploymentV3) error) error {
+	dep, err := st.stack.Export(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to export stack state")
+	}
+	deployment, err := DecodeDeployment(dep)
+	if err != nil {
+		return err
+	}
+	if err = edit(deployment); err != nil {
+		return err
+	}
+	if err = VerifyDeployment(deployment); err != nil {
+		return errors.Wrap(err, "the edited state is invalid")
+	}
+	if dep, err = EncodeDeployment(deployment); err != nil {
+		return err
+	}
+	return st.stack.Import(ctx, dep)
+}
+
+func (st *StackState) run(ctx context.Context, msg string, args ...string) error {
+	stdout, stderr, code, err := st.stack.runPulumiCmdSync(ctx, nil, args...)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, msg), stdout, stderr, code)
+	}
+	return nil
+}
+
+// DecodeDeployment decodes an exported deployment, such as one returned by Stack.Export.
+func DecodeDeployment(dep apitype.UntypedDeployment) (*apitype.DeploymentV3, error) {
+	if dep.Version != apitype.DeploymentSchemaVersionCurrent {
+		return nil, errors.Errorf("unsupported deployment version %d; expected %d",
+			dep.Version, apitype.DeploymentSchemaVersionCurrent)
+	}
+	var deployment apitype.DeploymentV3
+	if err := json.Unmarshal(dep.Deployment, &deployment); err != nil {
+		return nil, errors.Wrap(err, "failed to decode deployment")
+	}
+	return &deployment, nil
+}
+
+// EncodeDeployment encodes a deployment so that it can be imported with Stack.Import.
+func EncodeDeployment(deployment *apitype.DeploymentV3) (apitype.UntypedDeployment, error) {
+	bytes, err := json.Marshal(deployment)
+	if err != nil {
+		return apitype.UntypedDeployment{}, errors.Wrap(err, "failed to encode deployment")
+	}
+	return apitype.UntypedDeployment{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Deployment: bytes,
+	}, nil
+}
+
+// VerifyDeployment checks that a deployment is consistent, as Pulumi does before importing it: every resource must
+// have a valid URN, and must come after its parent, its dependencies and its provider, and no two resources may have
+// the same URN unless all but one of them are pending deletion.
+func VerifyDeployment(deployment *apitype.DeploymentV3) error {
+	urns := make(map[resource.URN]bool)
+	providers := make(map[string]bool)
+	for _, res := range deployment.Resources {
+		urn := res.URN
+		if !urn.IsValid() {
+			return fmt.Errorf("resource %q has an invalid URN", urn)
+		}
+
+		if strings.HasPrefix(string(res.Type), "pulumi:providers:") {
+			providers[string(urn)+resource.URNNameDelimiter+string(res.ID)] = true
+		}
+		if res.Provider != "" && !providers[res.Provider] {
+			return fmt.Errorf("resource %s refers to unknown provider %s", urn, res.Provider)
+		}
+
+		if res.Parent != "" && !urns[res.Parent] {
+			return fmt.Errorf("resource %s's parent %s is missing or comes after it", urn, res.Parent)
+		}
+		for _, dep := range res.Dependencies {
+			if !urns[dep] {
+				return fmt.Errorf("resource %s's dependency %s is missing or comes after it", urn, dep)
+			}
+		}
+
+		if urns[urn] && !res.Delete {
+			return fmt.Errorf("duplicate resource %s (not marked for deletion)", urn)
+		}
+		urns[urn] = true
+	}
+	return nil
+}
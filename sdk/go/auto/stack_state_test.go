@@ -0,0 +1,129 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func testDeployment() *apitype.DeploymentV3 {
+	return &apitype.DeploymentV3{
+		Resources: []apitype.ResourceV3{
+			{
+				URN:  "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev",
+				Type: "pulumi:pulumi:Stack",
+			},
+			{
+				URN:    "urn:pulumi:dev::proj::pulumi:providers:aws::default",
+				Type:   "pulumi:providers:aws",
+				ID:     "b2ab5c5a",
+				Custom: true,
+			},
+			{
+				URN:      "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::logs",
+				Type:     "aws:s3/bucket:Bucket",
+				ID:       "logs-1234",
+				Custom:   true,
+				Parent:   "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev",
+				Provider: "urn:pulumi:dev::proj::pulumi:providers:aws::default::b2ab5c5a",
+			},
+			{
+				URN:          "urn:pulumi:dev::proj::aws:s3/bucketPolicy:BucketPolicy::logs",
+				Type:         "aws:s3/bucketPolicy:BucketPolicy",
+				ID:           "logs-policy",
+				Custom:       true,
+				Parent:       "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev",
+				Provider:     "urn:pulumi:dev::proj::pulumi:providers:aws::default::b2ab5c5a",
+				Dependencies: []resource.URN{"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::logs"},
+			},
+		},
+	}
+}
+
+func TestDeploymentRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	deployment := testDeployment()
+	dep, err := EncodeDeployment(deployment)
+	require.NoError(t, err)
+	assert.Equal(t, apitype.DeploymentSchemaVersionCurrent, dep.Version)
+
+	decoded, err := DecodeDeployment(dep)
+	require.NoError(t, err)
+	assert.Equal(t, deployment, decoded)
+
+	_, err = DecodeDeployment(apitype.UntypedDeployment{Version: 2, Deployment: dep.Deployment})
+	assert.EqualError(t, err, "unsupported deployment version 2; expected 3")
+}
+
+func TestVerifyDeployment(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, VerifyDeployment(testDeployment()))
+	assert.NoError(t, VerifyDeployment(&apitype.DeploymentV3{}))
+
+	cases := map[string]struct {
+		edit func(d *apitype.DeploymentV3)
+		err  string
+	}{
+		"invalid URN": {
+			edit: func(d *apitype.DeploymentV3) { d.Resources[2].URN = "logs" },
+			err:  `resource "logs" has an invalid URN`,
+		},
+		"missing dependency": {
+			edit: func(d *apitype.DeploymentV3) { d.Resources = append(d.Resources[:2], d.Resources[3]) },
+			err: "resource urn:pulumi:dev::proj::aws:s3/bucketPolicy:BucketPolicy::logs's dependency " +
+				"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::logs is missing or comes after it",
+		},
+		"parent after child": {
+			edit: func(d *apitype.DeploymentV3) { d.Resources = append(d.Resources[1:], d.Resources[0]) },
+			err: "resource urn:pulumi:dev::proj::aws:s3/bucket:Bucket::logs's parent " +
+				"urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev is missing or comes after it",
+		},
+		"unknown provider": {
+			edit: func(d *apitype.DeploymentV3) { d.Resources[1].ID = "c3bc6d6b" },
+			err: "resource urn:pulumi:dev::proj::aws:s3/bucket:Bucket::logs refers to unknown provider " +
+				"urn:pulumi:dev::proj::pulumi:providers:aws::default::b2ab5c5a",
+		},
+		"duplicate": {
+			edit: func(d *apitype.DeploymentV3) { d.Resources = append(d.Resources, d.Resources[2]) },
+			err:  "duplicate resource urn:pulumi:dev::proj::aws:s3/bucket:Bucket::logs (not marked for deletion)",
+		},
+	}
+	//nolint:paralleltest // false positive because range var isn't used directly in t.Run(name) arg
+	for name, c := range cases {
+		name, c := name, c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			d := testDeployment()
+			c.edit(d)
+			assert.EqualError(t, VerifyDeployment(d), c.err)
+		})
+	}
+
+	// A duplicate is allowed when pending deletion.
+	d := testDeployment()
+	pending := d.Resources[2]
+	pending.Delete = true
+	d.Resources = append(d.Resources, pending)
+	assert.NoError(t, VerifyDeployment(d))
+}
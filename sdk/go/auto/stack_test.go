@@ -16,6 +16,7 @@ package auto
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
@@ -137,3 +138,27 @@ func TestUpdatePlans(t *testing.T) {
 	assert.Equal(t, "succeeded", dRes.Summary.Result)
 
 }
+
+func TestImportSpec(t *testing.T) {
+	t.Parallel()
+
+	// The spec must be readable by `pulumi import --file`.
+	spec := importSpec{
+		NameTable: map[string]string{
+			"provider": "urn:pulumi:dev::proj::pulumi:providers:aws::provider",
+		},
+		Resources: []ImportResource{
+			{Type: "aws:s3/bucket:Bucket", Name: "logs", ID: "my-logs-bucket", Provider: "provider"},
+			{Type: "aws:s3/bucket:Bucket", Name: "data", ID: "my-data-bucket", Properties: []string{"bucket"}},
+		},
+	}
+	bytes, err := json.Marshal(spec)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"nameTable": {"provider": "urn:pulumi:dev::proj::pulumi:providers:aws::provider"},
+		"resources": [
+			{"type": "aws:s3/bucket:Bucket", "name": "logs", "id": "my-logs-bucket", "provider": "provider"},
+			{"type": "aws:s3/bucket:Bucket", "name": "data", "id": "my-data-bucket", "properties": ["bucket"]}
+		]
+	}`, string(bytes))
+}
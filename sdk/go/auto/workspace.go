@@ -17,6 +17,7 @@ package auto
 import (
 	"context"
 
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpolicy"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optremove"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
@@ -109,6 +110,28 @@ type Workspace interface {
 	ImportStack(context.Context, string, apitype.UntypedDeployment) error
 	// Outputs get the current set of Stack outputs from the last Stack.Up().
 	StackOutputs(context.Context, string) (OutputMap, error)
+	// GetTag returns the value of the tag with the given name on the stack matching the given name.
+	GetTag(context.Context, string, string) (string, error)
+	// SetTag sets the tag with the given name to the given value on the stack matching the given name.
+	SetTag(context.Context, string, string, string) error
+	// RemoveTag removes the tag with the given name from the stack matching the given name.
+	RemoveTag(context.Context, string, string) error
+	// ListTags returns the tags of the stack matching the given name.
+	ListTags(context.Context, string) (map[string]string, error)
+	// RenameStack renames the stack matching the given name, along with its configuration.
+	RenameStack(context.Context, string, string) error
+	// ChangeSecretsProvider changes the secrets provider of the stack matching the given name, re-encrypting the
+	// secrets in its configuration and state.
+	ChangeSecretsProvider(context.Context, string, string) error
+	// EnablePolicyPack enables the given version ("latest" or a version number) of the Policy Pack with the given name
+	// (<org-name>/<policy-pack-name>) for an organization.
+	EnablePolicyPack(context.Context, string, string, ...optpolicy.Option) error
+	// DisablePolicyPack disables the Policy Pack with the given name (<org-name>/<policy-pack-name>) for an
+	// organization.
+	DisablePolicyPack(context.Context, string, ...optpolicy.Option) error
+	// About returns information about the Pulumi environment of the Workspace, and of the stack matching the given
+	// name, or of the currently selected stack if the name is empty.
+	About(context.Context, string) (AboutInfo, error)
 }
 
 // ConfigValue is a configuration value used by a Pulumi program.
@@ -131,3 +154,71 @@ type StackSummary struct {
 	ResourceCount    *int   `json:"resourceCount,omitempty"`
 	URL              string `json:"url,omitempty"`
 }
+
+// AboutInfo describes the Pulumi environment of a Workspace, as reported by `pulumi about`.
+type AboutInfo struct {
+	// Plugins are the plugins installed in the Workspace.
+	Plugins []AboutPlugin `json:"plugins"`
+	// Host describes the machine that the Pulumi CLI runs on.
+	Host *AboutHost `json:"host"`
+	// Backend describes the backend that the Workspace is logged in to.
+	Backend *AboutBackend `json:"backend"`
+	// CurrentStack describes the stack that the information was gathered for.
+	CurrentStack *AboutStack `json:"currentStack"`
+	// CLI describes the Pulumi CLI.
+	CLI *AboutCLI `json:"cliAbout"`
+	// Runtime describes the language runtime of the project, such as its name, executable and version.
+	Runtime map[string]string `json:"runtime"`
+	// Dependencies are the project's dependencies.
+	Dependencies []AboutDependency `json:"dependencies"`
+	// Errors are the errors encountered while gathering the information. The information that could not be gathered is
+	// left empty.
+	Errors []string `json:"errors"`
+}
+
+// AboutPlugin is a plugin installed in a Workspace.
+type AboutPlugin struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// AboutHost describes the machine that the Pulumi CLI runs on.
+type AboutHost struct {
+	OS      string `json:"os"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+}
+
+// AboutBackend describes the backend that a Workspace is logged in to.
+type AboutBackend struct {
+	Name          string   `json:"name"`
+	URL           string   `json:"url"`
+	User          string   `json:"user"`
+	Organizations []string `json:"organizations"`
+}
+
+// AboutStack describes a stack and the resources in its state.
+type AboutStack struct {
+	Name       string          `json:"name"`
+	Resources  []AboutResource `json:"resources"`
+	PendingOps []AboutResource `json:"pendingOps"`
+}
+
+// AboutResource is a resource in the state of a stack.
+type AboutResource struct {
+	Type string `json:"type"`
+	URN  string `json:"urn"`
+}
+
+// AboutCLI describes the Pulumi CLI.
+type AboutCLI struct {
+	Version    string `json:"version"`
+	GoVersion  string `json:"goVersion"`
+	GoCompiler string `json:"goCompiler"`
+}
+
+// AboutDependency is a dependency of a project.
+type AboutDependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
@@ -80,6 +80,22 @@ type SummaryEvent struct {
 	// compatibility. For older clients this will map to the version, while for newer ones
 	// it will be the version tag prepended with "v".
 	PolicyPacks map[string]string `json:"PolicyPacks"`
+	// StepTimings records when each of the steps performed by the update started and finished, in the order in which
+	// they finished. It is only set for updates that change resources, and steps that left a resource unchanged are
+	// omitted.
+	StepTimings []StepTiming `json:"stepTimings,omitempty"`
+}
+
+// StepTiming records when the engine started and finished performing a step.
+type StepTiming struct {
+	URN string `json:"urn"`
+	Op  OpType `json:"op"`
+	// StartTime is a Unix timestamp (milliseconds) of when the step started.
+	StartTime int64 `json:"startTime"`
+	// EndTime is a Unix timestamp (milliseconds) of when the step finished.
+	EndTime int64 `json:"endTime"`
+	// Failed is set if the step failed.
+	Failed bool `json:"failed,omitempty"`
 }
 
 // DiffKind describes the kind of a particular property diff.
@@ -171,6 +187,10 @@ type ResourcePreEvent struct {
 type ResOutputsEvent struct {
 	Metadata StepEventMetadata `json:"metadata"`
 	Planning bool              `json:"planning,omitempty"`
+	// StartTime and EndTime are Unix timestamps (milliseconds) of when the step that produced the outputs started and
+	// finished. They are only set when the outputs are reported by a step that changed resources.
+	StartTime int64 `json:"startTime,omitempty"`
+	EndTime   int64 `json:"endTime,omitempty"`
 }
 
 // ResOpFailedEvent is emitted when a resource operation fails. Typically a DiagnosticEvent is
@@ -179,6 +199,9 @@ type ResOpFailedEvent struct {
 	Metadata StepEventMetadata `json:"metadata"`
 	Status   int               `json:"status"`
 	Steps    int               `json:"steps"`
+	// StartTime and EndTime are Unix timestamps (milliseconds) of when the failed step started and finished.
+	StartTime int64 `json:"startTime,omitempty"`
+	EndTime   int64 `json:"endTime,omitempty"`
 }
 
 // EngineEvent describes a Pulumi engine event, such as a change to a resource or diagnostic
@@ -14,7 +14,13 @@
 
 package deepcopy
 
-import "reflect"
+import (
+	"reflect"
+	"time"
+)
+
+// timeType is the type of time.Time, which has value semantics but whose fields are unexported.
+var timeType = reflect.TypeOf(time.Time{})
 
 // Copy returns a deep copy of the provided value.
 //
@@ -89,6 +95,9 @@ func copy(v reflect.Value) reflect.Value {
 		}
 		return rv
 	case reflect.Struct:
+		if typ == timeType {
+			return v
+		}
 		rv := reflect.New(typ).Elem()
 		for i := 0; i < typ.NumField(); i++ {
 			if f := rv.Field(i); f.CanSet() {
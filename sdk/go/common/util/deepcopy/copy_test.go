@@ -17,6 +17,7 @@ package deepcopy
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -73,6 +74,11 @@ func TestDeepCopy(t *testing.T) {
 			},
 			"bar": []int{42},
 		},
+		struct {
+			Start time.Time
+		}{
+			Start: time.Unix(1650000000, 123),
+		},
 	}
 	//nolint:paralleltest // false positive because range var isn't used directly in t.Run(name) arg
 	for i, c := range cases {
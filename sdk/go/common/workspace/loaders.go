@@ -19,6 +19,9 @@ import (
 	"os"
 	"sync"
 
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/encoding"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
 )
@@ -92,6 +95,10 @@ func (singleton *projectLoader) load(path string) (*Project, error) {
 	if err != nil {
 		return nil, err
 	}
+	b, err = rewriteLegacyConfigDir(marshaller, b)
+	if err != nil {
+		return nil, err
+	}
 
 	var project Project
 	err = marshaller.Unmarshal(b, &project)
@@ -108,6 +115,27 @@ func (singleton *projectLoader) load(path string) (*Project, error) {
 	return &project, nil
 }
 
+// rewriteLegacyConfigDir rewrites a project whose config is a string, which is the directory that StackConfigDir now
+// sets, to set StackConfigDir instead. Config is now the project's config schema.
+func rewriteLegacyConfigDir(marshaller encoding.Marshaler, b []byte) ([]byte, error) {
+	var project map[string]interface{}
+	if err := marshaller.Unmarshal(b, &project); err != nil {
+		return nil, err
+	}
+	dir, ok := project["config"].(string)
+	if !ok {
+		return b, nil
+	}
+	if dir != "" {
+		if stackConfigDir, _ := project["stackConfigDir"].(string); stackConfigDir != "" {
+			return nil, errors.New("can not set `config` and `stackConfigDir`, remove the `config` entry")
+		}
+		project["stackConfigDir"] = dir
+	}
+	delete(project, "config")
+	return marshaller.Marshal(project)
+}
+
 // projectStackLoader is used to load a single global instance of a ProjectStack config.
 type projectStackLoader struct {
 	sync.RWMutex
@@ -100,25 +100,10 @@ func DetectProjectStackPath(stackName tokens.QName) (string, error) {
 
 	fileName := fmt.Sprintf("%s.%s%s", ProjectFile, qnameFileName(stackName), filepath.Ext(projPath))
 
-	// Back compat: StackConfigDir used to be called Config.
-	configValue, hasConfigValue := proj.Config.(string)
-	hasConfigValue = hasConfigValue && configValue != ""
-
 	if proj.StackConfigDir != "" {
-		// If config and stackConfigDir are both set return an error
-		if hasConfigValue {
-			return "", fmt.Errorf("can not set `config` and `stackConfigDir`, remove the `config` entry")
-		}
-
 		return filepath.Join(filepath.Dir(projPath), proj.StackConfigDir, fileName), nil
 	}
 
-	// Back compat: If StackConfigDir is not present and Config is given and it's a non-empty string use it
-	// for the stacks directory.
-	if hasConfigValue {
-		return filepath.Join(filepath.Dir(projPath), configValue, fileName), nil
-	}
-
 	return filepath.Join(filepath.Dir(projPath), fileName), nil
 }
 
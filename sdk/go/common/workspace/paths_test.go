@@ -85,6 +85,10 @@ func TestProjectStackPath(t *testing.T) {
 		"WithConfig",
 		"name: some_project\ndescription: Some project\nruntime: nodejs\nconfig: stacks\n",
 		expectedPath(filepath.Join("stacks", "Pulumi.my_stack.yaml")),
+	}, {
+		"WithConfigSchema",
+		"name: some_project\ndescription: Some project\nruntime: nodejs\nconfig:\n  region:\n    type: string\n",
+		expectedPath("Pulumi.my_stack.yaml"),
 	}, {
 		"WithBoth",
 		"name: some_project\ndescription: Some project\nruntime: nodejs\nconfig: stacksA\nstackConfigDir: stacksB\n",
@@ -52,6 +52,21 @@ type ProjectTemplateConfigValue struct {
 	Secret bool `json:"secret,omitempty" yaml:"secret,omitempty"`
 }
 
+// ProjectConfigType declares a config key in a project's config schema.
+type ProjectConfigType struct {
+	// Type is the type of the config value: "string", "integer", "boolean", "array" or "object". It defaults to
+	// "string".
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Description is an optional description of the config value.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Default is an optional default for the config value, used by stacks that don't set it.
+	Default interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	// Secret may be set to true to require that stacks encrypt the config value.
+	Secret bool `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// Required may be set to true to require that stacks set the config value.
+	Required bool `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
 // ProjectBackend is a configuration for backend used by project
 type ProjectBackend struct {
 	// URL is optional field to explicitly set backend url
@@ -87,8 +102,10 @@ type Project struct {
 	// License is the optional license governing this project's usage.
 	License *string `json:"license,omitempty" yaml:"license,omitempty"`
 
-	// Config has been renamed to StackConfigDir.
-	Config interface{} `json:"config,omitempty" yaml:"config,omitempty"`
+	// Config is an optional schema for the project's config, keyed by config key. Keys without a namespace are in
+	// the project's namespace. Projects used to set config to the directory that is now set by StackConfigDir;
+	// LoadProject still accepts that form.
+	Config map[string]ProjectConfigType `json:"config,omitempty" yaml:"config,omitempty"`
 
 	// StackConfigDir indicates where to store the Pulumi.<stack-name>.yaml files, combined with the folder
 	// Pulumi.yaml is in.
@@ -111,6 +128,9 @@ func (proj *Project) Validate() error {
 	if proj.Runtime.Name() == "" {
 		return errors.New("project is missing a 'runtime' attribute")
 	}
+	if err := proj.validateConfig(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -189,6 +209,12 @@ type ProjectStack struct {
 	// EncryptionSalt is this stack's base64 encoded encryption salt.  Only used for
 	// passphrase-based secrets providers.
 	EncryptionSalt string `json:"encryptionsalt,omitempty" yaml:"encryptionsalt,omitempty"`
+	// SecretsRecipients are the public keys to which EncryptedKey is encrypted. Only used for the age secrets
+	// provider.
+	SecretsRecipients []string `json:"secretsrecipients,omitempty" yaml:"secretsrecipients,omitempty"`
+	// Extends is an optional list of stack settings files, relative to this one, whose config this stack's config
+	// extends. See LoadProjectStackLayers.
+	Extends []string `json:"extends,omitempty" yaml:"extends,omitempty"`
 	// Config is an optional config bag.
 	Config config.Map `json:"config,omitempty" yaml:"config,omitempty"`
 }
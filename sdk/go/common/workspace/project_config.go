@@ -0,0 +1,306 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/texttheater/golang-levenshtein/levenshtein"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+)
+
+// The types of config values that a project's config schema can declare.
+const (
+	ConfigTypeString  = "string"
+	ConfigTypeInteger = "integer"
+	ConfigTypeBoolean = "boolean"
+	ConfigTypeArray   = "array"
+	ConfigTypeObject  = "object"
+)
+
+// ConfigKey returns the config key that a key in the project's config schema declares. Keys without a namespace are
+// in the project's namespace.
+func (proj *Project) ConfigKey(name string) (config.Key, error) {
+	if !strings.Contains(name, ":") {
+		return config.MustMakeKey(string(proj.Name), name), nil
+	}
+	return config.ParseKey(name)
+}
+
+// ConfigType returns the declaration of a config key in the project's config schema, if the schema declares it.
+func (proj *Project) ConfigType(key config.Key) (ProjectConfigType, bool) {
+	for name, t := range proj.Config {
+		if k, err := proj.ConfigKey(name); err == nil && k == key {
+			return t, true
+		}
+	}
+	return ProjectConfigType{}, false
+}
+
+// ValidateConfigValue checks a stack's value for a config key against the project's config schema. The value must be
+// encrypted if the schema declares it secret, and must have the declared type. If the schema declares any keys in
+// the project's namespace, keys in the project's namespace that it doesn't declare are rejected, since they are
+// likely to be typos. The types of encrypted values are only checked if dec is not nil.
+func (proj *Project) ValidateConfigValue(key config.Key, value config.Value, dec config.Decrypter) error {
+	t, ok := proj.ConfigType(key)
+	if !ok {
+		if key.Namespace() == string(proj.Name) && proj.declaresProjectConfig() {
+			return proj.newUnknownConfigKeyError(key)
+		}
+		return nil
+	}
+	if t.Secret && !value.Secure() {
+		return errors.Errorf("config key '%v' is secret; set it with `pulumi config set --secret`", key)
+	}
+	if value.Secure() && dec == nil {
+		return nil
+	}
+	raw, err := value.Value(dec)
+	if err != nil {
+		return errors.Wrapf(err, "could not read config key '%v'", key)
+	}
+	if err := checkConfigType(t.typ(), raw, value.Object()); err != nil {
+		return errors.Wrapf(err, "config key '%v'", key)
+	}
+	return nil
+}
+
+// ApplyConfig validates a stack's config against the project's config schema, as ValidateConfigValue does, and
+// checks that the stack sets every required config key. It returns the stack's config merged over the defaults that
+// the schema declares; the stack's config itself is left unchanged, so that the defaults aren't saved with it.
+func (proj *Project) ApplyConfig(stackConfig config.Map, dec config.Decrypter) (config.Map, error) {
+	var result error
+
+	keys := make(config.KeyArray, 0, len(stackConfig))
+	for k := range stackConfig {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+	for _, k := range keys {
+		if err := proj.ValidateConfigValue(k, stackConfig[k], dec); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	names := make([]string, 0, len(proj.Config))
+	for name := range proj.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := proj.Config[name]
+		key, err := proj.ConfigKey(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, has := stackConfig[key]; has || t.Default != nil || !t.Required {
+			continue
+		}
+		msg := fmt.Sprintf("missing required config key '%v'", key)
+		if t.Description != "" {
+			msg += fmt.Sprintf(" (%s)", t.Description)
+		}
+		result = multierror.Append(result, errors.New(msg))
+	}
+
+	if result != nil {
+		return nil, result
+	}
+	return proj.MergeConfigDefaults(stackConfig)
+}
+
+// MergeConfigDefaults returns the stack's config merged over the defaults that the project's config schema declares,
+// without validating it. It is used by operations that must work on stacks whose config no longer matches the schema,
+// such as destroy and refresh. The stack's config itself is left unchanged.
+func (proj *Project) MergeConfigDefaults(stackConfig config.Map) (config.Map, error) {
+	merged := make(config.Map, len(stackConfig)+len(proj.Config))
+	for k, v := range stackConfig {
+		merged[k] = v
+	}
+	for name, t := range proj.Config {
+		if t.Default == nil {
+			continue
+		}
+		key, err := proj.ConfigKey(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, has := stackConfig[key]; has {
+			continue
+		}
+		v, err := t.defaultValue()
+		if err != nil {
+			return nil, errors.Wrapf(err, "default of config key '%v'", key)
+		}
+		merged[key] = v
+	}
+	return merged, nil
+}
+
+// validateConfig checks that the project's config schema is well formed.
+func (proj *Project) validateConfig() error {
+	for name, t := range proj.Config {
+		if _, err := proj.ConfigKey(name); err != nil {
+			return errors.Wrapf(err, "invalid config key '%s'", name)
+		}
+		switch t.typ() {
+		case ConfigTypeString, ConfigTypeInteger, ConfigTypeBoolean, ConfigTypeArray, ConfigTypeObject:
+		default:
+			return errors.Errorf("config key '%s' has unknown type '%s'; expected one of %s, %s, %s, %s or %s",
+				name, t.Type, ConfigTypeString, ConfigTypeInteger, ConfigTypeBoolean, ConfigTypeArray, ConfigTypeObject)
+		}
+		if t.Default == nil {
+			continue
+		}
+		if t.Required {
+			return errors.Errorf("config key '%s' is required, so it can't have a default", name)
+		}
+		if t.Secret {
+			return errors.Errorf("config key '%s' is secret, so it can't have a default", name)
+		}
+		v, err := t.defaultValue()
+		if err != nil {
+			return errors.Wrapf(err, "default of config key '%s'", name)
+		}
+		raw, err := v.Value(config.NopDecrypter)
+		contract.AssertNoError(err)
+		if err := checkConfigType(t.typ(), raw, v.Object()); err != nil {
+			return errors.Wrapf(err, "default of config key '%s'", name)
+		}
+	}
+	return nil
+}
+
+// declaresProjectConfig returns true if the project's config schema declares any keys in the project's namespace.
+func (proj *Project) declaresProjectConfig() bool {
+	for name := range proj.Config {
+		if k, err := proj.ConfigKey(name); err == nil && k.Namespace() == string(proj.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// newUnknownConfigKeyError returns an error for a key that the project's config schema doesn't declare, suggesting
+// the declared keys with similar names.
+func (proj *Project) newUnknownConfigKeyError(key config.Key) error {
+	message := fmt.Sprintf("config key '%v' is not declared in the project's config", key)
+
+	var suggestions []string
+	const maxDistance = 2
+	op := levenshtein.DefaultOptions
+	for name := range proj.Config {
+		k, err := proj.ConfigKey(name)
+		if err != nil || k.Namespace() != key.Namespace() {
+			continue
+		}
+		if levenshtein.DistanceForStrings([]rune(key.Name()), []rune(k.Name()), op) <= maxDistance {
+			suggestions = append(suggestions, k.String())
+		}
+	}
+	sort.Strings(suggestions)
+	if len(suggestions) > 0 {
+		message += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, " or "))
+	}
+	return errors.New(message)
+}
+
+func (t ProjectConfigType) typ() string {
+	if t.Type == "" {
+		return ConfigTypeString
+	}
+	return t.Type
+}
+
+// defaultValue returns the default of the config key as a config value.
+func (t ProjectConfigType) defaultValue() (config.Value, error) {
+	switch d := t.Default.(type) {
+	case string:
+		return config.NewValue(d), nil
+	case bool, int, int64, uint64, float64:
+		return config.NewValue(fmt.Sprintf("%v", d)), nil
+	default:
+		b, err := json.Marshal(yamlToJSON(d))
+		if err != nil {
+			return config.Value{}, err
+		}
+		return config.NewObjectValue(string(b)), nil
+	}
+}
+
+// yamlToJSON converts the `map[interface{}]interface{}`s with which YAML decodes objects to the
+// `map[string]interface{}`s that JSON can encode.
+func yamlToJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprintf("%v", k)] = yamlToJSON(e)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = yamlToJSON(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = yamlToJSON(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+// checkConfigType checks that a config value has the given type. Arrays and objects may be set either as objects or
+// as strings of JSON.
+func checkConfigType(typ, raw string, object bool) error {
+	switch typ {
+	case ConfigTypeString:
+		if object {
+			return errors.New("expected a string")
+		}
+	case ConfigTypeInteger:
+		if _, err := strconv.ParseInt(raw, 10, 64); object || err != nil {
+			return errors.Errorf("expected an integer, got %q", raw)
+		}
+	case ConfigTypeBoolean:
+		if _, err := strconv.ParseBool(raw); object || err != nil {
+			return errors.Errorf("expected a boolean, got %q", raw)
+		}
+	case ConfigTypeArray:
+		var v []interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return errors.New("expected an array")
+		}
+	case ConfigTypeObject:
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return errors.New("expected an object")
+		}
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
 )
 
 func TestProjectRuntimeInfoRoundtripYAML(t *testing.T) {
@@ -38,3 +40,122 @@ func TestProjectRuntimeInfoRoundtripYAML(t *testing.T) {
 	doTest(yaml.Marshal, yaml.Unmarshal)
 	doTest(json.Marshal, json.Unmarshal)
 }
+
+func TestProjectConfigSchema(t *testing.T) {
+	t.Parallel()
+
+	var proj Project
+	err := yaml.Unmarshal([]byte(`name: proj
+runtime: go
+config:
+  region:
+    description: The region to deploy to.
+    default: us-west-2
+  instanceCount:
+    type: integer
+    default: 3
+  tags:
+    type: object
+    default:
+      team: infra
+  password:
+    secret: true
+    required: true
+  debug:
+    type: boolean
+  aws:profile:
+    default: dev
+`), &proj)
+	assert.NoError(t, err)
+	assert.NoError(t, proj.Validate())
+
+	// Stack config is merged over the defaults, and left unchanged.
+	stackConfig := config.Map{
+		config.MustMakeKey("proj", "region"):   config.NewValue("eu-west-1"),
+		config.MustMakeKey("proj", "password"): config.NewSecureValue("ciphertext"),
+		config.MustMakeKey("proj", "debug"):    config.NewValue("true"),
+		config.MustMakeKey("aws", "region"):    config.NewValue("eu-west-1"),
+	}
+	merged, err := proj.ApplyConfig(stackConfig, nil /*dec*/)
+	assert.NoError(t, err)
+	assert.Len(t, stackConfig, 4)
+	assert.Equal(t, config.Map{
+		config.MustMakeKey("proj", "region"):        config.NewValue("eu-west-1"),
+		config.MustMakeKey("proj", "password"):      config.NewSecureValue("ciphertext"),
+		config.MustMakeKey("proj", "debug"):         config.NewValue("true"),
+		config.MustMakeKey("proj", "instanceCount"): config.NewValue("3"),
+		config.MustMakeKey("proj", "tags"):          config.NewObjectValue(`{"team":"infra"}`),
+		config.MustMakeKey("aws", "region"):         config.NewValue("eu-west-1"),
+		config.MustMakeKey("aws", "profile"):        config.NewValue("dev"),
+	}, merged)
+
+	// Every problem with the stack's config is reported.
+	_, err = proj.ApplyConfig(config.Map{
+		config.MustMakeKey("proj", "regoin"):        config.NewValue("eu-west-1"),
+		config.MustMakeKey("proj", "instanceCount"): config.NewValue("three"),
+		config.MustMakeKey("proj", "debug"):         config.NewObjectValue(`{"enabled":true}`),
+	}, nil /*dec*/)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(),
+		"config key 'proj:regoin' is not declared in the project's config; did you mean proj:region?")
+	assert.Contains(t, err.Error(), `config key 'proj:instanceCount': expected an integer, got "three"`)
+	assert.Contains(t, err.Error(), `config key 'proj:debug': expected a boolean`)
+	assert.Contains(t, err.Error(), "missing required config key 'proj:password'")
+
+	// Defaults can be merged into config that doesn't match the schema, without validating it.
+	merged, err = proj.MergeConfigDefaults(config.Map{
+		config.MustMakeKey("proj", "regoin"):        config.NewValue("eu-west-1"),
+		config.MustMakeKey("proj", "instanceCount"): config.NewValue("three"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, config.Map{
+		config.MustMakeKey("proj", "regoin"):        config.NewValue("eu-west-1"),
+		config.MustMakeKey("proj", "region"):        config.NewValue("us-west-2"),
+		config.MustMakeKey("proj", "instanceCount"): config.NewValue("three"),
+		config.MustMakeKey("proj", "tags"):          config.NewObjectValue(`{"team":"infra"}`),
+		config.MustMakeKey("aws", "profile"):        config.NewValue("dev"),
+	}, merged)
+
+	err = proj.ValidateConfigValue(config.MustMakeKey("proj", "password"), config.NewValue("hunter2"), nil /*dec*/)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is secret")
+
+	// The types of secrets are checked when they can be decrypted.
+	proj.Config["pin"] = ProjectConfigType{Type: ConfigTypeInteger, Secret: true}
+	err = proj.ValidateConfigValue(config.MustMakeKey("proj", "pin"), config.NewSecureValue("1234"),
+		config.NopDecrypter)
+	assert.NoError(t, err)
+	err = proj.ValidateConfigValue(config.MustMakeKey("proj", "pin"), config.NewSecureValue("abcd"),
+		config.NopDecrypter)
+	assert.Error(t, err)
+}
+
+func TestProjectConfigSchemaValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config ProjectConfigType
+		err    string
+	}{
+		{"UnknownType", ProjectConfigType{Type: "float"}, "config key 'key' has unknown type 'float'"},
+		{"RequiredDefault", ProjectConfigType{Required: true, Default: "a"}, "is required, so it can't have a default"},
+		{"SecretDefault", ProjectConfigType{Secret: true, Default: "a"}, "is secret, so it can't have a default"},
+		{"WrongDefault", ProjectConfigType{Type: ConfigTypeArray, Default: "a"}, "expected an array"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			proj := Project{
+				Name:    "proj",
+				Runtime: NewProjectRuntimeInfo("go", nil),
+				Config:  map[string]ProjectConfigType{"key": tt.config},
+			}
+			err := proj.Validate()
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
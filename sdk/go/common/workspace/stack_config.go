@@ -0,0 +1,106 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+)
+
+// ProjectStackLayer is one of the stack settings files from which a stack's config is layered.
+type ProjectStackLayer struct {
+	// Path is the path of the file.
+	Path string
+	// Stack is the content of the file.
+	Stack *ProjectStack
+}
+
+// LoadProjectStackLayers loads the stack settings file at the given path along with the files that it extends, and
+// the files that those extend in turn. The layers are returned in order of increasing precedence: the files that a
+// file extends come before it, in the order in which it lists them, and the file at the given path comes last. A file
+// that is extended more than once is only included where it is first extended.
+func LoadProjectStackLayers(path string) ([]ProjectStackLayer, error) {
+	var layers []ProjectStackLayer
+	loaded := make(map[string]bool)
+	loading := make(map[string]bool)
+
+	var load func(path string) error
+	load = func(path string) error {
+		path = filepath.Clean(path)
+		if loading[path] {
+			return errors.Errorf("stack settings file %s extends itself", path)
+		}
+		if loaded[path] {
+			return nil
+		}
+		loading[path] = true
+
+		ps, err := LoadProjectStack(path)
+		if err != nil {
+			return err
+		}
+		for _, extended := range ps.Extends {
+			if !filepath.IsAbs(extended) {
+				extended = filepath.Join(filepath.Dir(path), extended)
+			}
+			if _, err := os.Stat(extended); err != nil {
+				return errors.Wrapf(err, "stack settings file %s extends %s", path, extended)
+			}
+			if err := load(extended); err != nil {
+				return err
+			}
+		}
+
+		delete(loading, path)
+		loaded[path] = true
+		layers = append(layers, ProjectStackLayer{Path: path, Stack: ps})
+		return nil
+	}
+
+	if err := load(path); err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
+
+// MergeConfigLayers merges the config of the layers of a stack, as returned by LoadProjectStackLayers. The value of a
+// key in a layer replaces its value in the layers before it. MergeConfigLayers returns the merged config, along with
+// the path of the layer that each value came from.
+//
+// The secure values of each layer are encrypted by the secrets provider of its own file. If reencrypt is not nil, it
+// is called with each secure value that comes from a layer other than the last, which is the stack's own, to
+// reencrypt the value for the stack's secrets provider. Otherwise, secure values are left as they are.
+func MergeConfigLayers(layers []ProjectStackLayer,
+	reencrypt func(ProjectStackLayer, config.Value) (config.Value, error)) (config.Map, map[config.Key]string, error) {
+	merged := make(config.Map)
+	origins := make(map[config.Key]string)
+	for i, layer := range layers {
+		for k, v := range layer.Stack.Config {
+			if v.Secure() && reencrypt != nil && i < len(layers)-1 {
+				reencrypted, err := reencrypt(layer, v)
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "reencrypting config key '%v' from %s", k, layer.Path)
+				}
+				v = reencrypted
+			}
+			merged[k], origins[k] = v, layer.Path
+		}
+	}
+	return merged, origins, nil
+}
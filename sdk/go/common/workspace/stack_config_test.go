@@ -0,0 +1,106 @@
+// Copyright 2016-2022, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+)
+
+func writeStackFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestLoadProjectStackLayers(t *testing.T) {
+	t.Parallel()
+
+	dir := writeStackFiles(t, map[string]string{
+		"shared/Pulumi.base.yaml": "config:\n  proj:size: small\n  proj:region: us-east-1\n",
+		"Pulumi.prod.yaml": "extends: [shared/Pulumi.base.yaml]\n" +
+			"encryptionsalt: prod-salt\n" +
+			"config:\n  proj:size: large\n  proj:token:\n    secure: prod-ciphertext\n",
+		"Pulumi.tags.yaml": "extends: [shared/Pulumi.base.yaml]\nconfig:\n  proj:team: infra\n",
+		"Pulumi.prod-eu.yaml": "extends: [Pulumi.prod.yaml, Pulumi.tags.yaml]\n" +
+			"config:\n  proj:region: eu-west-1\n",
+	})
+	path := filepath.Join(dir, "Pulumi.prod-eu.yaml")
+
+	layers, err := LoadProjectStackLayers(path)
+	require.NoError(t, err)
+	var paths []string
+	for _, l := range layers {
+		paths = append(paths, l.Path)
+	}
+	// The base file is only included where it is first extended.
+	assert.Equal(t, []string{
+		filepath.Join(dir, "shared", "Pulumi.base.yaml"),
+		filepath.Join(dir, "Pulumi.prod.yaml"),
+		filepath.Join(dir, "Pulumi.tags.yaml"),
+		path,
+	}, paths)
+
+	cfg, origins, err := MergeConfigLayers(layers, nil /*reencrypt*/)
+	require.NoError(t, err)
+	assert.Equal(t, config.Map{
+		config.MustMakeKey("proj", "size"):   config.NewValue("large"),
+		config.MustMakeKey("proj", "region"): config.NewValue("eu-west-1"),
+		config.MustMakeKey("proj", "team"):   config.NewValue("infra"),
+		config.MustMakeKey("proj", "token"):  config.NewSecureValue("prod-ciphertext"),
+	}, cfg)
+	assert.Equal(t, map[config.Key]string{
+		config.MustMakeKey("proj", "size"):   filepath.Join(dir, "Pulumi.prod.yaml"),
+		config.MustMakeKey("proj", "region"): path,
+		config.MustMakeKey("proj", "team"):   filepath.Join(dir, "Pulumi.tags.yaml"),
+		config.MustMakeKey("proj", "token"):  filepath.Join(dir, "Pulumi.prod.yaml"),
+	}, origins)
+
+	// Secrets from other layers are reencrypted for the stack.
+	cfg, _, err = MergeConfigLayers(layers, func(l ProjectStackLayer, v config.Value) (config.Value, error) {
+		assert.Equal(t, "prod-salt", l.Stack.EncryptionSalt)
+		return config.NewSecureValue("prod-eu-ciphertext"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, config.NewSecureValue("prod-eu-ciphertext"), cfg[config.MustMakeKey("proj", "token")])
+}
+
+func TestLoadProjectStackLayersErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := writeStackFiles(t, map[string]string{
+		"Pulumi.a.yaml":       "extends: [Pulumi.b.yaml]\n",
+		"Pulumi.b.yaml":       "extends: [Pulumi.a.yaml]\n",
+		"Pulumi.missing.yaml": "extends: [Pulumi.nope.yaml]\n",
+	})
+
+	_, err := LoadProjectStackLayers(filepath.Join(dir, "Pulumi.a.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Pulumi.a.yaml extends itself")
+
+	_, err = LoadProjectStackLayers(filepath.Join(dir, "Pulumi.missing.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Pulumi.missing.yaml extends "+filepath.Join(dir, "Pulumi.nope.yaml"))
+}